@@ -0,0 +1,296 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/urfave/cli"
+
+	"github.com/kfrico/BitfinexLendingBot/internal/backtest"
+	"github.com/kfrico/BitfinexLendingBot/internal/bitfinex"
+	"github.com/kfrico/BitfinexLendingBot/internal/config"
+	"github.com/kfrico/BitfinexLendingBot/internal/constants"
+	"github.com/kfrico/BitfinexLendingBot/internal/history"
+	"github.com/kfrico/BitfinexLendingBot/internal/optimizer"
+	"github.com/kfrico/BitfinexLendingBot/internal/strategy"
+)
+
+// backtestCommand 回測指令
+func backtestCommand() cli.Command {
+	return cli.Command{
+		Name:  "backtest",
+		Usage: "Replay stored funding history through the lending strategies",
+		Flags: []cli.Flag{
+			cli.StringFlag{Name: "from", Usage: "Start date (YYYY-MM-DD), defaults to the start of stored history"},
+			cli.StringFlag{Name: "to", Usage: "End date (YYYY-MM-DD), defaults to the end of stored history"},
+			cli.StringFlag{Name: "strategies", Usage: "Comma separated strategies to compare: traditional,smart,kline,blend"},
+			cli.StringSliceFlag{Name: "params", Usage: "Parameter set as KEY=VALUE;KEY=VALUE (repeatable)", Value: &cli.StringSlice{}},
+			cli.Int64Flag{Name: "seed", Value: 1, Usage: "Random seed for hidden offer selection"},
+			cli.BoolFlag{Name: "verbose", Usage: "Show strategy logs during replay"},
+		},
+		Action: runBacktest,
+	}
+}
+
+// optimizeCommand 參數最佳化指令
+func optimizeCommand() cli.Command {
+	return cli.Command{
+		Name:  "optimize",
+		Usage: "Search strategy parameters over stored funding history and print the best set as YAML",
+		Flags: []cli.Flag{
+			cli.StringSliceFlag{Name: "param", Usage: "Parameter range KEY=min:max:step, KEY=min:max or KEY=a,b,c (repeatable)", Value: &cli.StringSlice{}},
+			cli.StringFlag{Name: "strategy", Usage: "Strategy to optimise: traditional, smart, kline or blend, defaults to the config"},
+			cli.StringFlag{Name: "mode", Value: constants.SearchModeGrid, Usage: "Search mode: grid or random"},
+			cli.IntFlag{Name: "samples", Value: constants.DefaultOptimizeSamples, Usage: "Number of random samples"},
+			cli.StringFlag{Name: "objective", Value: constants.ObjectiveAPR, Usage: "Ranking objective: apr, apr_per_idle or utilization_drawdown"},
+			cli.IntFlag{Name: "workers", Usage: "Parallel backtests, defaults to the number of CPUs"},
+			cli.IntFlag{Name: "top", Value: constants.DefaultOptimizeTop, Usage: "Number of ranked results to show"},
+			cli.StringFlag{Name: "output", Usage: "Write the best parameter set as a YAML fragment to this file"},
+			cli.StringFlag{Name: "from", Usage: "Start date (YYYY-MM-DD), defaults to the start of stored history"},
+			cli.StringFlag{Name: "to", Usage: "End date (YYYY-MM-DD), defaults to the end of stored history"},
+			cli.Int64Flag{Name: "seed", Value: 1, Usage: "Random seed for sampling and hidden offer selection"},
+		},
+		Action: runOptimize,
+	}
+}
+
+// collectCommand 歷史數據收集指令
+func collectCommand() cli.Command {
+	return cli.Command{
+		Name:  "collect",
+		Usage: "Download funding candles and record order book snapshots for backtesting",
+		Flags: []cli.Flag{
+			cli.StringFlag{Name: "timeframes", Usage: "Comma separated candle timeframes, defaults to the ones used by the config"},
+			cli.IntFlag{Name: "interval", Usage: "Minutes between collections, 0 collects once and exits"},
+		},
+		Action: runCollect,
+	}
+}
+
+// runBacktest 執行回測
+func runBacktest(c *cli.Context) error {
+	cfg, err := config.LoadConfig(c.GlobalString("config"))
+	if err != nil {
+		return err
+	}
+
+	opts := backtest.Options{Seed: c.Int64("seed")}
+	if opts.Start, err = parseDateFlag(c.String("from")); err != nil {
+		return err
+	}
+	if opts.End, err = parseDateFlag(c.String("to")); err != nil {
+		return err
+	}
+	opts.Strategies = splitList(c.String("strategies"))
+	for _, text := range c.StringSlice("params") {
+		params, err := config.ParseOverrides(text)
+		if err != nil {
+			return err
+		}
+		opts.ParamSets = append(opts.ParamSets, params)
+	}
+
+	// 回放期間策略日誌量很大，預設不輸出
+	if !c.Bool("verbose") {
+		log.SetOutput(io.Discard)
+		defer log.SetOutput(os.Stderr)
+	}
+
+	engine := backtest.NewEngine(cfg, history.NewStore(cfg.DataDir))
+	results, err := engine.Run(opts)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("回測幣種: %s, 成交模型: %s (比例 %.2f), K線: %s, 初始資金: %.2f\n\n",
+		cfg.Currency, cfg.BacktestFillModel, cfg.BacktestFillRatio, cfg.BacktestTimeFrame, cfg.BacktestInitialBalance)
+	fmt.Print(backtest.FormatResults(results))
+
+	return nil
+}
+
+// runOptimize 執行參數最佳化
+func runOptimize(c *cli.Context) error {
+	cfg, err := config.LoadConfig(c.GlobalString("config"))
+	if err != nil {
+		return err
+	}
+
+	opts := optimizer.Options{
+		Strategy:  strings.ToLower(c.String("strategy")),
+		Mode:      strings.ToLower(c.String("mode")),
+		Samples:   c.Int("samples"),
+		Objective: strings.ToLower(c.String("objective")),
+		Workers:   c.Int("workers"),
+		Backtest:  backtest.Options{Seed: c.Int64("seed")},
+	}
+	if opts.Strategy == "" {
+		opts.Strategy = backtest.StrategyName(cfg)
+	}
+	if opts.Backtest.Start, err = parseDateFlag(c.String("from")); err != nil {
+		return err
+	}
+	if opts.Backtest.End, err = parseDateFlag(c.String("to")); err != nil {
+		return err
+	}
+	for _, spec := range c.StringSlice("param") {
+		paramRange, err := optimizer.ParseParamRange(spec)
+		if err != nil {
+			return err
+		}
+		opts.Ranges = append(opts.Ranges, paramRange)
+	}
+
+	opts.Progress = func(done, total int) {
+		fmt.Fprintf(os.Stderr, "\r回測進度: %d/%d", done, total)
+		if done == total {
+			fmt.Fprintln(os.Stderr)
+		}
+	}
+
+	// 回放期間策略日誌量很大，最佳化時不輸出
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	engine := backtest.NewEngine(cfg, history.NewStore(cfg.DataDir))
+	trials, err := optimizer.Run(engine, opts)
+	if err != nil {
+		return err
+	}
+
+	top := c.Int("top")
+	if top <= 0 || top > len(trials) {
+		top = len(trials)
+	}
+	results := make([]*backtest.Result, 0, top)
+	for _, trial := range trials[:top] {
+		results = append(results, trial.Result)
+	}
+
+	fmt.Printf("最佳化策略: %s, 目標: %s, 共 %d 組參數\n\n", opts.Strategy, opts.Objective, len(trials))
+	fmt.Print(backtest.FormatResults(results))
+
+	best := trials[0]
+	if best.Result.Err != nil {
+		return fmt.Errorf("所有參數組合回測失敗: %v", best.Result.Err)
+	}
+
+	fragment := optimizer.FormatYAML(opts.Strategy, opts.Objective, best)
+	fmt.Printf("\n%s", fragment)
+
+	if output := c.String("output"); output != "" {
+		if err := os.WriteFile(output, []byte(fragment), 0644); err != nil {
+			return fmt.Errorf("寫入 %s 失敗: %v", output, err)
+		}
+		fmt.Printf("\n已寫入 %s\n", output)
+	}
+
+	return nil
+}
+
+// runCollect 收集歷史數據
+func runCollect(c *cli.Context) error {
+	cfg, err := config.LoadConfig(c.GlobalString("config"))
+	if err != nil {
+		return err
+	}
+
+	timeFrames := splitList(c.String("timeframes"))
+	if len(timeFrames) == 0 {
+		timeFrames = defaultCollectTimeFrames(cfg)
+	}
+
+	symbol := cfg.GetFundingSymbol()
+	collector := history.NewCollector(bitfinex.NewClient(cfg.BitfinexApiKey, cfg.BitfinexSecretKey), history.NewStore(cfg.DataDir))
+
+	collect := func() {
+		for _, timeFrame := range timeFrames {
+			if _, err := collector.CollectCandles(symbol, timeFrame); err != nil {
+				log.Printf("收集K線失敗: %v", err)
+			}
+		}
+		if err := collector.SnapshotBook(symbol, time.Now()); err != nil {
+			log.Printf("保存訂單簿快照失敗: %v", err)
+		}
+	}
+
+	collect()
+
+	interval := c.Int("interval")
+	if interval <= 0 {
+		return nil
+	}
+
+	log.Printf("每 %d 分鐘收集一次 %s 歷史數據，按 Ctrl+C 結束", interval, symbol)
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+
+	ticker := time.NewTicker(time.Duration(interval) * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-sigChan:
+			log.Println("停止收集歷史數據")
+			return nil
+		case <-ticker.C:
+			collect()
+		}
+	}
+}
+
+// defaultCollectTimeFrames 返回配置中會用到的K線時間框架：回測撮合、K線策略各視窗、
+// 市場分析器補足快照、利率閾值檢查（5m），以及季節性模型、風險控管與再融資使用的小時K線
+func defaultCollectTimeFrames(cfg *config.Config) []string {
+	candidates := []string{cfg.BacktestTimeFrame}
+	for _, window := range cfg.GetKlineWindows() {
+		candidates = append(candidates, window.TimeFrame)
+	}
+	candidates = append(candidates,
+		strategy.AnalyzerTimeFrame(cfg.MinutesRun),
+		"5m",
+		constants.SeasonalityTimeFrame,
+		constants.RiskCandleTimeFrame,
+		constants.RefinanceCandleTimeFrame,
+	)
+
+	seen := make(map[string]bool)
+	var timeFrames []string
+	for _, timeFrame := range candidates {
+		if timeFrame == "" || seen[timeFrame] {
+			continue
+		}
+		seen[timeFrame] = true
+		timeFrames = append(timeFrames, timeFrame)
+	}
+	return timeFrames
+}
+
+// parseDateFlag 解析日期參數，空字串返回零值
+func parseDateFlag(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.ParseInLocation(constants.DateLayout, value, time.UTC)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("日期格式錯誤 %q，應為 YYYY-MM-DD", value)
+	}
+	return t, nil
+}
+
+// splitList 解析逗號分隔的清單
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
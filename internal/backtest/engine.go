@@ -0,0 +1,288 @@
+package backtest
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kfrico/BitfinexLendingBot/internal/config"
+	"github.com/kfrico/BitfinexLendingBot/internal/constants"
+	"github.com/kfrico/BitfinexLendingBot/internal/history"
+	"github.com/kfrico/BitfinexLendingBot/internal/simulator"
+	"github.com/kfrico/BitfinexLendingBot/internal/strategy"
+)
+
+// Options 回測設定
+type Options struct {
+	Start      time.Time           // 零值代表從歷史數據開始（保留暖機區間）
+	End        time.Time           // 零值代表到歷史數據結束
+	Strategies []string            // 要比較的策略，空代表使用配置中的策略
+	ParamSets  []map[string]string // 要比較的參數組合，空代表只使用配置本身
+	Seed       int64               // 隨機隱藏掛單使用的種子
+}
+
+// Metrics 單次回測績效
+type Metrics struct {
+	Start          time.Time
+	End            time.Time
+	Executions     int     // 策略執行次數
+	ExecErrors     int     // 策略執行失敗次數
+	InitialBalance float64 // 初始資金
+	FinalBalance   float64 // 結束資金
+	InterestEarned float64 // 扣除手續費後的利息
+	FeesPaid       float64 // 利息手續費
+	APR            float64 // 實現年化報酬率（%）
+	Utilization    float64 // 資金借出比例（時間加權，%）
+	UtilDrawdown   float64 // 資金利用率自高點的最大回落（百分點）
+	IdleShare      float64 // 閒置資金達 MIN_LOAN 的時間比例（%）
+	IdleTime       time.Duration
+	Turnover       float64 // 成交金額 / 平均資金
+	Fills          int     // 成交筆數
+	AvgFillRate    float64 // 成交金額加權平均日利率（小數格式）
+
+	ByStrategy map[string]strategy.StrategyStats // 各策略（含高額持有、飆升掛單）的掛單與成交統計
+}
+
+// Result 一組策略與參數的回測結果
+type Result struct {
+	Strategy string
+	Params   string
+	Metrics  *Metrics
+	Err      error
+}
+
+// Engine 回測引擎，以模擬交易所回放歷史數據並執行原有的策略程式
+type Engine struct {
+	base  *config.Config
+	store *history.Store
+
+	mu      sync.Mutex
+	replays map[string]*history.Replay // 依幣種與時間框架快取，平行回測時共用
+}
+
+// NewEngine 創建回測引擎
+func NewEngine(base *config.Config, store *history.Store) *Engine {
+	return &Engine{
+		base:    base,
+		store:   store,
+		replays: make(map[string]*history.Replay),
+	}
+}
+
+// replayFor 返回（並快取）指定幣種與時間框架的歷史回放
+func (e *Engine) replayFor(symbol, timeFrame string) (*history.Replay, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	key := symbol + ":" + timeFrame
+	if replay, ok := e.replays[key]; ok {
+		return replay, nil
+	}
+
+	replay, err := history.NewReplay(e.store, symbol, timeFrame)
+	if err != nil {
+		return nil, err
+	}
+	e.replays[key] = replay
+	return replay, nil
+}
+
+// Run 依序回測所有策略與參數組合
+func (e *Engine) Run(opts Options) ([]*Result, error) {
+	strategies := opts.Strategies
+	if len(strategies) == 0 {
+		strategies = []string{StrategyName(e.base)}
+	}
+	paramSets := opts.ParamSets
+	if len(paramSets) == 0 {
+		paramSets = []map[string]string{nil}
+	}
+
+	results := make([]*Result, 0, len(strategies)*len(paramSets))
+	for _, name := range strategies {
+		for _, params := range paramSets {
+			result := &Result{Strategy: name, Params: FormatParams(params)}
+
+			cfg, err := e.PrepareConfig(name, params)
+			if err != nil {
+				result.Err = err
+			} else {
+				result.Metrics, result.Err = e.RunConfig(cfg, opts)
+			}
+
+			results = append(results, result)
+		}
+	}
+
+	return results, nil
+}
+
+// PrepareConfig 複製基礎配置並套用參數與策略
+func (e *Engine) PrepareConfig(strategyName string, params map[string]string) (*config.Config, error) {
+	cfg := e.base.Clone()
+	if err := cfg.ApplyOverrides(params); err != nil {
+		return nil, err
+	}
+	if err := ApplyStrategy(cfg, strategyName); err != nil {
+		return nil, err
+	}
+
+	cfg.ApplyDefaults()
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// RunConfig 以指定配置執行一次回測
+func (e *Engine) RunConfig(cfg *config.Config, opts Options) (*Metrics, error) {
+	replay, err := e.replayFor(cfg.GetFundingSymbol(), cfg.BacktestTimeFrame)
+	if err != nil {
+		return nil, err
+	}
+
+	fillInterval, err := history.TimeFrameDuration(cfg.BacktestTimeFrame)
+	if err != nil {
+		return nil, err
+	}
+
+	fillModel, err := simulator.NewFillModel(cfg.BacktestFillModel, cfg.BacktestFillRatio)
+	if err != nil {
+		return nil, err
+	}
+
+	dataStart, dataEnd := replay.Range()
+	start := opts.Start
+	if earliest := dataStart.Add(constants.BacktestWarmup); start.IsZero() || start.Before(earliest) {
+		start = earliest
+	}
+	end := opts.End
+	if end.IsZero() || end.After(dataEnd) {
+		end = dataEnd
+	}
+	if !end.After(start) {
+		return nil, fmt.Errorf("回測區間無效: %s - %s（歷史數據需多於 %s 暖機區間）",
+			start.Format(time.RFC3339), end.Format(time.RFC3339), constants.BacktestWarmup)
+	}
+
+	exchange := simulator.NewExchange(replay, simulator.Options{
+		Currency:       cfg.Currency,
+		InitialBalance: cfg.BacktestInitialBalance,
+		FillModel:      fillModel,
+		FillTimeFrame:  cfg.BacktestTimeFrame,
+		FillInterval:   fillInterval,
+		IdleThreshold:  cfg.MinLoan,
+		Start:          start,
+		Fees:           cfg.GetFeeModel(),
+	})
+
+	// 模擬交易所中需要實際下單，不使用測試模式
+	cfg.TestMode = false
+
+	bot := strategy.NewLendingBot(cfg, exchange)
+	bot.SetClock(exchange.Now, func(time.Duration) {})
+	bot.SetRandSource(rand.New(rand.NewSource(opts.Seed)))
+	bot.BootstrapMarketAnalyzer(false)
+
+	step := time.Duration(cfg.MinutesRun) * time.Minute
+	if step <= 0 {
+		step = constants.DefaultMinutesRun * time.Minute
+	}
+
+	metrics := &Metrics{Start: start, End: end, InitialBalance: cfg.BacktestInitialBalance}
+	for t := start; t.Before(end); t = t.Add(step) {
+		if err := exchange.Advance(t); err != nil {
+			return nil, err
+		}
+		if err := bot.Execute(); err != nil {
+			metrics.ExecErrors++
+		}
+		metrics.Executions++
+	}
+	if err := exchange.Advance(end); err != nil {
+		return nil, err
+	}
+
+	fillMetrics(metrics, exchange.Stats(), exchange.Balance())
+	metrics.ByStrategy = bot.GetStrategyStats()
+	return metrics, nil
+}
+
+// fillMetrics 由模擬帳戶統計計算績效指標
+func fillMetrics(metrics *Metrics, stats simulator.Stats, finalBalance float64) {
+	metrics.FinalBalance = finalBalance
+	metrics.InterestEarned = stats.InterestEarned
+	metrics.FeesPaid = stats.FeesPaid
+	metrics.IdleTime = stats.IdleDuration
+	metrics.Fills = stats.FilledCount
+	metrics.UtilDrawdown = stats.MaxUtilizationDrawdown * 100
+
+	days := stats.Elapsed.Hours() / 24
+	if days <= 0 || stats.CapitalDays <= 0 {
+		return
+	}
+
+	avgCapital := stats.CapitalDays / days
+	metrics.APR = stats.InterestEarned / avgCapital / days * constants.DaysPerYear * 100
+	metrics.Utilization = stats.LentDays / stats.CapitalDays * 100
+	metrics.IdleShare = stats.IdleDuration.Hours() / stats.Elapsed.Hours() * 100
+	metrics.Turnover = stats.FilledAmount / avgCapital
+	if stats.FilledAmount > 0 {
+		metrics.AvgFillRate = stats.FilledRateSum / stats.FilledAmount
+	}
+}
+
+// StrategyName 返回配置目前使用的策略名稱（依優先級：組合 > K線 > 智能 > 傳統）
+func StrategyName(cfg *config.Config) string {
+	return strategy.ActiveStrategyName(cfg)
+}
+
+// ApplyStrategy 依策略名稱設置策略開關
+func ApplyStrategy(cfg *config.Config, name string) error {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case constants.StrategyBlend:
+		// 組合模式使用配置中的 STRATEGY_WEIGHTS（未設定時平均分配）
+		cfg.EnableStrategyBlend = true
+		cfg.EnableKlineStrategy = false
+		cfg.EnableSmartStrategy = false
+	case constants.StrategyKline:
+		cfg.EnableStrategyBlend = false
+		cfg.EnableKlineStrategy = true
+		cfg.EnableSmartStrategy = false
+	case constants.StrategySmart:
+		cfg.EnableStrategyBlend = false
+		cfg.EnableKlineStrategy = false
+		cfg.EnableSmartStrategy = true
+	case constants.StrategyTraditional:
+		cfg.EnableStrategyBlend = false
+		cfg.EnableKlineStrategy = false
+		cfg.EnableSmartStrategy = false
+	default:
+		return fmt.Errorf("未知的策略: %s（可用: %s, %s, %s, %s）", name,
+			constants.StrategyTraditional, constants.StrategySmart, constants.StrategyKline, constants.StrategyBlend)
+	}
+	return nil
+}
+
+// FormatParams 將參數組合格式化為穩定排序的字串
+func FormatParams(params map[string]string) string {
+	if len(params) == 0 {
+		return "(config)"
+	}
+
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, fmt.Sprintf("%s=%s", key, params[key]))
+	}
+	return strings.Join(parts, ";")
+}
@@ -0,0 +1,107 @@
+package backtest
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/kfrico/BitfinexLendingBot/internal/bitfinex"
+	"github.com/kfrico/BitfinexLendingBot/internal/config"
+	"github.com/kfrico/BitfinexLendingBot/internal/history"
+)
+
+func newBacktestConfig(dataDir string) *config.Config {
+	cfg := &config.Config{
+		BitfinexApiKey:    "test_api_key",
+		BitfinexSecretKey: "test_secret_key",
+		Currency:          "USD",
+		MinLoan:           150,
+		MinDailyLendRate:  0.01,
+		SpreadLend:        3,
+		GapBottom:         1,
+		GapTop:            20,
+		MinutesRun:        60,
+		DataDir:           dataDir,
+	}
+	cfg.ApplyDefaults()
+	return cfg
+}
+
+func seedCandles(t *testing.T, store *history.Store, start time.Time, days int) {
+	t.Helper()
+
+	candles := make([]*bitfinex.Candle, 0, days*96)
+	for i := 0; i < days*96; i++ {
+		// 利率在 0.02%-0.06% 之間以日為週期擺動
+		wave := math.Sin(float64(i) / 96 * 2 * math.Pi)
+		close := 0.0004 + 0.0001*wave
+		candles = append(candles, &bitfinex.Candle{
+			MTS:    start.Add(time.Duration(i)*15*time.Minute).UnixNano() / int64(time.Millisecond),
+			Open:   close,
+			Close:  close,
+			High:   close * 1.5,
+			Low:    close * 0.8,
+			Volume: 50000,
+		})
+	}
+
+	if _, err := store.SaveCandles("fUSD", "15m", candles); err != nil {
+		t.Fatalf("SaveCandles() error = %v", err)
+	}
+}
+
+func TestEngine_RunComparesStrategiesAndParams(t *testing.T) {
+	dir := t.TempDir()
+	store := history.NewStore(dir)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	seedCandles(t, store, start, 6)
+
+	engine := NewEngine(newBacktestConfig(dir), store)
+	results, err := engine.Run(Options{
+		Strategies: []string{"traditional", "kline"},
+		ParamSets: []map[string]string{
+			{"GAP_TOP": "20"},
+			{"GAP_TOP": "40", "KLINE_SMOOTH_METHOD": "max"},
+		},
+		Seed: 1,
+	})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if len(results) != 4 {
+		t.Fatalf("results = %d, want 4", len(results))
+	}
+
+	for _, result := range results {
+		if result.Err != nil {
+			t.Fatalf("%s %s error = %v", result.Strategy, result.Params, result.Err)
+		}
+		m := result.Metrics
+		if m.Executions == 0 || m.Fills == 0 {
+			t.Errorf("%s %s: executions = %d, fills = %d", result.Strategy, result.Params, m.Executions, m.Fills)
+		}
+		if m.APR <= 0 || m.Utilization <= 0 || m.Utilization > 100 {
+			t.Errorf("%s %s: APR = %.2f, utilization = %.2f", result.Strategy, result.Params, m.APR, m.Utilization)
+		}
+		if m.FinalBalance <= m.InitialBalance {
+			t.Errorf("%s %s: final balance %.2f not above initial %.2f", result.Strategy, result.Params, m.FinalBalance, m.InitialBalance)
+		}
+	}
+
+	if out := FormatResults(results); out == "" {
+		t.Error("FormatResults() returned empty output")
+	}
+}
+
+func TestEngine_RejectsUnknownStrategy(t *testing.T) {
+	dir := t.TempDir()
+	engine := NewEngine(newBacktestConfig(dir), history.NewStore(dir))
+
+	results, err := engine.Run(Options{Strategies: []string{"unknown"}})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if len(results) != 1 || results[0].Err == nil {
+		t.Error("expected per-result error for unknown strategy")
+	}
+}
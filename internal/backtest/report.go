@@ -0,0 +1,76 @@
+package backtest
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kfrico/BitfinexLendingBot/internal/constants"
+)
+
+// FormatResults 將回測結果格式化為表格
+func FormatResults(results []*Result) string {
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(w, "策略\t參數\tAPR%\t利用率%\t利用率回落\t閒置%\t閒置時間\t週轉\t成交\t平均日利率%\t利息\t手續費\t執行")
+	for _, result := range results {
+		if result.Err != nil {
+			fmt.Fprintf(w, "%s\t%s\t錯誤: %v\n", result.Strategy, result.Params, result.Err)
+			continue
+		}
+
+		m := result.Metrics
+		fmt.Fprintf(w, "%s\t%s\t%.2f\t%.1f\t%.1f\t%.1f\t%s\t%.2f\t%d\t%.4f\t%.2f\t%.2f\t%d\n",
+			result.Strategy,
+			result.Params,
+			m.APR,
+			m.Utilization,
+			m.UtilDrawdown,
+			m.IdleShare,
+			m.IdleTime.Round(time.Minute),
+			m.Turnover,
+			m.Fills,
+			m.AvgFillRate*100,
+			m.InterestEarned,
+			m.FeesPaid,
+			m.Executions,
+		)
+	}
+	w.Flush()
+
+	// 策略組合的各策略績效比較
+	for _, result := range results {
+		if result.Err != nil || result.Strategy != constants.StrategyBlend || len(result.Metrics.ByStrategy) == 0 {
+			continue
+		}
+		buf.WriteString(fmt.Sprintf("\n%s %s 各策略:\n", result.Strategy, result.Params))
+		buf.WriteString(formatStrategyStats(result.Metrics))
+	}
+
+	return buf.String()
+}
+
+// formatStrategyStats 將組合模式中各策略的掛單與成交統計格式化為表格
+func formatStrategyStats(m *Metrics) string {
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+
+	names := make([]string, 0, len(m.ByStrategy))
+	for name := range m.ByStrategy {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(w, "  策略\t掛單\t掛單金額\t成交金額\t成交比例%\t平均日利率%")
+	for _, name := range names {
+		stats := m.ByStrategy[name]
+		fmt.Fprintf(w, "  %s\t%d\t%.2f\t%.2f\t%.1f\t%.4f\n",
+			name, stats.PlacedCount, stats.PlacedAmount, stats.FilledAmount, stats.FillRatio()*100, stats.AvgFillRate()*100)
+	}
+	w.Flush()
+
+	return buf.String()
+}
@@ -0,0 +1,25 @@
+package bitfinex
+
+// FundingAPI 貸出機器人使用的資金市場操作介面
+// 正式環境由 Client 實作，回測與模擬交易由 simulator.Exchange 實作
+type FundingAPI interface {
+	GetFundingOffers(symbol string) ([]*FundingOffer, error)
+	CancelFundingOffer(offerID int64) error
+	SubmitFundingOffer(symbol string, amount float64, dailyRate float64, period int, hidden bool) (int64, error)
+	SubmitFundingOfferFRR(symbol string, amount float64, period int, hidden bool) (int64, error)
+	GetFundingBalance(currency string) (float64, error)
+	GetFundingBook(symbol string, limit int) ([]*FundingBookEntry, error)
+	GetCurrentFundingRate(symbol string) (float64, error)
+	GetFundingCredits(symbol string) ([]*FundingCredit, error)
+	CloseFundingCredit(creditID int64) error
+	GetFundingCandles(symbol string, timeFrame string, limit int) ([]*Candle, error)
+}
+
+var _ FundingAPI = (*Client)(nil)
+
+// FundingTradeAPI 可選的資金市場公開成交查詢介面（模擬交易所未實作，使用前需型別判斷）
+type FundingTradeAPI interface {
+	GetFundingTrades(symbol string, limit int) ([]*FundingTrade, error)
+}
+
+var _ FundingTradeAPI = (*Client)(nil)
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"reflect"
 	"strings"
+	"time"
 
 	"github.com/bitfinexcom/bitfinex-api-go/pkg/models/common"
 	"github.com/bitfinexcom/bitfinex-api-go/pkg/models/fundingoffer"
@@ -34,6 +35,7 @@ type FundingOffer struct {
 	Amount float64
 	Rate   float64 // 日利率（小數格式）
 	Period int
+	Hidden bool // 是否為隱藏掛單
 }
 
 // Wallet 代表錢包信息
@@ -52,6 +54,15 @@ type FundingBookEntry struct {
 	Count  int
 }
 
+// FundingTrade 代表資金市場的一筆公開成交
+type FundingTrade struct {
+	ID     int64
+	MTS    int64   // 成交時間戳（毫秒）
+	Amount float64 // 成交金額（正負號代表成交方向）
+	Rate   float64 // 日利率（小數格式）
+	Period int
+}
+
 // FundingCredit 代表活躍的借貸訂單
 type FundingCredit struct {
 	ID         int64
@@ -120,6 +131,7 @@ func (c *Client) GetFundingOffers(symbol string) ([]*FundingOffer, error) {
 			Amount: offer.Amount,
 			Rate:   offer.Rate, // API 已返回日利率
 			Period: int(offer.Period),
+			Hidden: offer.Hidden,
 		})
 	}
 
@@ -284,6 +296,45 @@ func (c *Client) GetCurrentFundingRate(symbol string) (float64, error) {
 	return 0, errors.NewAPIError("failed to parse FRR from ticker", nil)
 }
 
+// GetCurrencyConfs 獲取幣種列表與各幣種可交易的交易對
+func (c *Client) GetCurrencyConfs() (result []*CurrencyConf, err error) {
+	// SDK 解析交易對時遇到非預期格式會 panic，轉為錯誤返回
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = nil, errors.NewAPIError(fmt.Sprintf("failed to parse currency conf: %v", r), nil)
+		}
+	}()
+
+	confs, err := c.restClient.Currencies.Conf(true, false, false, false, true)
+	if err != nil {
+		return nil, errors.NewAPIError("failed to get currency conf", err)
+	}
+
+	result = make([]*CurrencyConf, 0, len(confs))
+	for _, conf := range confs {
+		result = append(result, &CurrencyConf{
+			Currency: conf.Currency,
+			Label:    conf.Label,
+			Pairs:    conf.Pairs,
+		})
+	}
+
+	return result, nil
+}
+
+// GetTickerPrice 獲取交易對最新成交價（pair 不含 t 前綴，如 BTCUSD）
+func (c *Client) GetTickerPrice(pair string) (float64, error) {
+	tickers, err := c.restClient.Tickers.GetMulti([]string{"t" + pair})
+	if err != nil {
+		return 0, errors.NewAPIError("failed to get ticker", err)
+	}
+	if len(tickers) == 0 || tickers[0] == nil {
+		return 0, errors.NewAPIError(fmt.Sprintf("ticker t%s not found", pair), nil)
+	}
+
+	return tickers[0].LastPrice, nil
+}
+
 // GetFundingCredits 獲取活躍的借貸訂單
 func (c *Client) GetFundingCredits(symbol string) ([]*FundingCredit, error) {
 	credits, err := c.restClient.Funding.Credits(symbol)
@@ -323,6 +374,49 @@ func (c *Client) GetFundingCredits(symbol string) ([]*FundingCredit, error) {
 	return result, nil
 }
 
+// CloseFundingCredit 提前關閉借出中的資金，本金退回資金錢包
+func (c *Client) CloseFundingCredit(creditID int64) error {
+	req, err := c.restClient.NewAuthenticatedRequestWithData(common.PermissionWrite, "funding/close", map[string]interface{}{
+		"id":   creditID,
+		"type": "credit",
+	})
+	if err != nil {
+		return errors.NewOrderError("failed to build close funding request", err)
+	}
+
+	if _, err := c.restClient.Request(req); err != nil {
+		return errors.NewOrderError("failed to close funding credit", err)
+	}
+
+	return nil
+}
+
+// GetFundingTrades 獲取資金市場最近的公開成交（由新到舊）
+func (c *Client) GetFundingTrades(symbol string, limit int) ([]*FundingTrade, error) {
+	if limit <= 0 || limit > constants.MaxFundingTradesLimit {
+		limit = constants.MaxFundingTradesLimit
+	}
+
+	end := common.Mts(time.Now().UnixNano() / int64(time.Millisecond))
+	snapshot, err := c.restClient.Trades.PublicHistoryWithQuery(symbol, 0, end, common.QueryLimit(limit), common.NewestFirst)
+	if err != nil {
+		return nil, errors.NewAPIError("failed to get funding trades", err)
+	}
+
+	result := make([]*FundingTrade, 0, len(snapshot.Snapshot))
+	for _, trade := range snapshot.Snapshot {
+		result = append(result, &FundingTrade{
+			ID:     trade.ID,
+			MTS:    trade.MTS,
+			Amount: trade.Amount,
+			Rate:   trade.Rate,
+			Period: trade.Period,
+		})
+	}
+
+	return result, nil
+}
+
 // GetFundingCandles 獲取資金 K 線數據
 func (c *Client) GetFundingCandles(symbol string, timeFrame string, limit int) ([]*Candle, error) {
 	// 構建 candle key，格式: trade:15m:fUSD:a30:p2:p30
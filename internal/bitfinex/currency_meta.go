@@ -0,0 +1,213 @@
+package bitfinex
+
+import (
+	"log"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kfrico/BitfinexLendingBot/internal/constants"
+)
+
+// CurrencyConf 幣種設定（來自 conf 端點）
+type CurrencyConf struct {
+	Currency string
+	Label    string
+	Pairs    []string // 可交易的交易對，如 BTCUSD、TESTBTC:TESTUSD
+}
+
+// CurrencyInfo 幣種金額規格
+type CurrencyInfo struct {
+	Currency       string
+	Label          string
+	Precision      int       // 金額小數位數
+	MinOfferAmount float64   // 交易所最低掛單金額（該幣種單位），0 表示未知
+	USDPrice       float64   // 美元價格，0 表示未知
+	UpdatedAt      time.Time // 價格更新時間
+}
+
+// CurrencyMetadataAPI 幣種資訊服務使用的公開 API
+type CurrencyMetadataAPI interface {
+	GetCurrencyConfs() ([]*CurrencyConf, error)
+	GetTickerPrice(pair string) (float64, error)
+}
+
+var _ CurrencyMetadataAPI = (*Client)(nil)
+
+// NewCurrencyInfo 依美元價格計算金額規格：小數位數使最小單位不超過 1 美分，最低掛單金額為 150 美元等值
+// 價格未知時使用交易所允許的最多小數位數，最低掛單金額未知
+func NewCurrencyInfo(currency string, usdPrice float64) CurrencyInfo {
+	info := CurrencyInfo{
+		Currency:  strings.ToUpper(currency),
+		Precision: constants.MaxAmountPrecision,
+		USDPrice:  usdPrice,
+	}
+	if usdPrice <= 0 {
+		info.USDPrice = 0
+		return info
+	}
+
+	precision := int(math.Ceil(math.Log10(usdPrice*100) - 1e-9))
+	if precision < constants.DefaultAmountPrecision {
+		precision = constants.DefaultAmountPrecision
+	}
+	if precision > constants.MaxAmountPrecision {
+		precision = constants.MaxAmountPrecision
+	}
+	info.Precision = precision
+
+	scale := math.Pow10(precision)
+	info.MinOfferAmount = math.Ceil(constants.MinOfferUSD/usdPrice*scale-1e-6) / scale
+	return info
+}
+
+// DefaultCurrencyInfo 無法取得幣種資訊時的金額規格：USD 本身即為美元，其他幣種使用最多小數位數且最低掛單金額未知
+func DefaultCurrencyInfo(currency string) CurrencyInfo {
+	if strings.EqualFold(currency, constants.QuoteCurrencyUSD) {
+		return NewCurrencyInfo(currency, 1)
+	}
+	return NewCurrencyInfo(currency, 0)
+}
+
+// CurrencyMetadata 幣種資訊服務，快取 conf 端點的幣種列表與各幣種美元價格，可供多個幣種共用
+// 查詢 API 時不持有鎖，同一幣種同時只會有一個查詢，其他呼叫等待其結果
+type CurrencyMetadata struct {
+	api      CurrencyMetadataAPI
+	ttl      time.Duration
+	now      func() time.Time
+	mu       sync.Mutex
+	confs    map[string]*CurrencyConf
+	cache    map[string]CurrencyInfo
+	inflight map[string]*currencyInfoCall
+}
+
+// currencyInfoCall 進行中的幣種資訊查詢
+type currencyInfoCall struct {
+	done chan struct{}
+	info CurrencyInfo
+}
+
+// NewCurrencyMetadata 創建幣種資訊服務
+func NewCurrencyMetadata(api CurrencyMetadataAPI) *CurrencyMetadata {
+	return &CurrencyMetadata{
+		api:      api,
+		ttl:      constants.CurrencyPriceCacheTTL,
+		now:      time.Now,
+		cache:    make(map[string]CurrencyInfo),
+		inflight: make(map[string]*currencyInfoCall),
+	}
+}
+
+// Info 返回幣種金額規格，價格快取過期時重新查詢；查詢失敗時沿用舊快取，沒有快取則使用預設規格
+func (m *CurrencyMetadata) Info(currency string) CurrencyInfo {
+	currency = strings.ToUpper(currency)
+
+	m.mu.Lock()
+	cached, ok := m.cache[currency]
+	if ok && m.now().Sub(cached.UpdatedAt) < m.ttl {
+		m.mu.Unlock()
+		return cached
+	}
+	if call, running := m.inflight[currency]; running {
+		m.mu.Unlock()
+		<-call.done
+		return call.info
+	}
+	call := &currencyInfoCall{done: make(chan struct{})}
+	m.inflight[currency] = call
+	m.mu.Unlock()
+
+	call.info = m.fetch(currency, cached, ok)
+
+	m.mu.Lock()
+	delete(m.inflight, currency)
+	m.mu.Unlock()
+	close(call.done)
+	return call.info
+}
+
+// fetch 查詢幣種名稱與美元價格（不持有鎖），成功時寫入快取
+func (m *CurrencyMetadata) fetch(currency string, cached CurrencyInfo, hasCached bool) CurrencyInfo {
+	if currency == constants.QuoteCurrencyUSD {
+		info := NewCurrencyInfo(currency, 1)
+		info.Label = m.label(currency)
+		return m.store(info)
+	}
+
+	price, err := m.api.GetTickerPrice(m.usdPair(currency))
+	if err != nil || price <= 0 {
+		log.Printf("獲取 %s 美元價格失敗: %v", currency, err)
+		if hasCached {
+			return cached
+		}
+		return DefaultCurrencyInfo(currency)
+	}
+
+	info := NewCurrencyInfo(currency, price)
+	info.Label = m.label(currency)
+	info = m.store(info)
+	log.Printf("%s 金額規格 - 價格: %.4f USD, 小數位數: %d, 最低掛單: %v", currency, price, info.Precision, info.MinOfferAmount)
+	return info
+}
+
+// store 記錄更新時間並寫入快取
+func (m *CurrencyMetadata) store(info CurrencyInfo) CurrencyInfo {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	info.UpdatedAt = m.now()
+	m.cache[info.Currency] = info
+	return info
+}
+
+// conf 返回幣種設定，首次使用時載入（不持有鎖），失敗時下次再試
+func (m *CurrencyMetadata) conf(currency string) (*CurrencyConf, bool) {
+	m.mu.Lock()
+	loaded := m.confs != nil
+	conf, ok := m.confs[currency]
+	m.mu.Unlock()
+	if loaded {
+		return conf, ok
+	}
+
+	confs, err := m.api.GetCurrencyConfs()
+	if err != nil {
+		log.Printf("獲取幣種設定失敗: %v", err)
+		return nil, false
+	}
+	byCurrency := make(map[string]*CurrencyConf, len(confs))
+	for _, c := range confs {
+		byCurrency[strings.ToUpper(c.Currency)] = c
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.confs == nil {
+		m.confs = byCurrency
+	}
+	conf, ok = m.confs[currency]
+	return conf, ok
+}
+
+// label 返回幣種名稱
+func (m *CurrencyMetadata) label(currency string) string {
+	if conf, ok := m.conf(currency); ok {
+		return conf.Label
+	}
+	return ""
+}
+
+// usdPair 從幣種設定中找出對美元的交易對，找不到時使用慣用格式
+func (m *CurrencyMetadata) usdPair(currency string) string {
+	if conf, ok := m.conf(currency); ok {
+		for _, pair := range conf.Pairs {
+			if pair == currency+constants.QuoteCurrencyUSD || pair == currency+":"+constants.QuoteCurrencyUSD {
+				return pair
+			}
+		}
+	}
+	if len(currency) > 3 {
+		return currency + ":" + constants.QuoteCurrencyUSD
+	}
+	return currency + constants.QuoteCurrencyUSD
+}
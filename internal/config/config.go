@@ -2,11 +2,15 @@ package config
 
 import (
 	"fmt"
+	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/kfrico/BitfinexLendingBot/internal/constants"
 	"github.com/kfrico/BitfinexLendingBot/internal/errors"
+	"github.com/kfrico/BitfinexLendingBot/internal/rates"
 	"github.com/spf13/viper"
 )
 
@@ -20,6 +24,9 @@ type Config struct {
 	Currency   string `mapstructure:"CURRENCY"`
 	OrderLimit int    `mapstructure:"ORDER_LIMIT"`
 
+	// 多幣種：每個幣種以上述設定為基礎，覆寫該幣種的參數，例如 BTC: {MIN_LOAN: 0.005}
+	Currencies map[string]map[string]interface{} `mapstructure:"CURRENCIES"`
+
 	RunOnlyOnNewCredits bool `mapstructure:"RUN_ONLY_ON_NEW_CREDITS"` // 是否僅在滿足觸發條件時執行（新借貸訂單或餘額顯著變化）
 	// 如果 RunOnlyOnNewCredits 設定 true 則 MinutesRun 無用
 	MinutesRun int `mapstructure:"MINUTES_RUN"` // 每隔幾分鐘清除訂單重新產生新訂單
@@ -33,15 +40,97 @@ type Config struct {
 	SpreadLend                    int     `mapstructure:"SPREAD_LEND"`         // 分散單最大目標筆數
 	GapBottom                     float64 `mapstructure:"GAP_BOTTOM"`
 	GapTop                        float64 `mapstructure:"GAP_TOP"`
+	GapMode                       string  `mapstructure:"GAP_MODE"` // 深度模式：index（訂單簿檔位）或 volume（累計掛單金額）
 	ThirtyDayLendRateThreshold    float64 `mapstructure:"THIRTY_DAY_LEND_RATE_THRESHOLD"`
 	OneTwentyDayLendRateThreshold float64 `mapstructure:"ONE_TWENTY_DAY_LEND_RATE_THRESHOLD"`
 	RateBonus                     float64 `mapstructure:"RATE_BONUS"`
+	EnableAdaptiveRateBonus       bool    `mapstructure:"ENABLE_ADAPTIVE_RATE_BONUS"`     // 依實際成交比例自動調整利率加成，取代固定 RATE_BONUS
+	RateBonusTargetFillPercent    float64 `mapstructure:"RATE_BONUS_TARGET_FILL_PERCENT"` // 目標成交比例（%），預設 70
+	RateBonusGain                 float64 `mapstructure:"RATE_BONUS_GAIN"`                // 成交比例偏離目標 100% 時每次調整的加成（%），預設 0.002
+	RateBonusMin                  float64 `mapstructure:"RATE_BONUS_MIN"`                 // 加成下限（%），可為負數，預設 0
+	RateBonusMax                  float64 `mapstructure:"RATE_BONUS_MAX"`                 // 加成上限（%），預設 0.01
+	RateBonusSmoothing            float64 `mapstructure:"RATE_BONUS_SMOOTHING"`           // 成交比例 EMA 平滑係數 (0-1]，預設 0.3
+	RateBonusStateFile            string  `mapstructure:"RATE_BONUS_STATE_FILE"`          // 加成調整記錄保存路徑，預設 DATA_DIR/rate_bonus_<symbol>.json
+	AllocationProfile             string  `mapstructure:"ALLOCATION_PROFILE"`             // 分散單金額分配：equal、geometric、inverse、book、absorption
+	AllocationRatio               float64 `mapstructure:"ALLOCATION_RATIO"`               // geometric/inverse 相鄰兩筆金額比例 (0-1)，預設 0.7
 
 	// 高額持有策略
 	HighHoldRate   float64 `mapstructure:"HIGH_HOLD_RATE"`
 	HighHoldAmount float64 `mapstructure:"HIGH_HOLD_AMOUNT"`
 	HighHoldOrders int     `mapstructure:"HIGH_HOLD_ORDERS"`
 
+	// 隱藏掛單策略
+	HideHighHoldOffers       bool    `mapstructure:"HIDE_HIGH_HOLD_OFFERS"`       // 高額持有單使用隱藏掛單
+	HiddenOfferMinAmount     float64 `mapstructure:"HIDDEN_OFFER_MIN_AMOUNT"`     // 單筆金額達此值即隱藏，0 為停用
+	HiddenOfferRandomPercent float64 `mapstructure:"HIDDEN_OFFER_RANDOM_PERCENT"` // 分散單隨機隱藏比例 (0-100)
+
+	// 利息手續費（依帳戶等級設定）
+	FundingFeePercent       *float64 `mapstructure:"FUNDING_FEE_PERCENT"`        // 一般掛單利息手續費（%），未設定為 15，可設為 0
+	HiddenFundingFeePercent *float64 `mapstructure:"HIDDEN_FUNDING_FEE_PERCENT"` // 隱藏掛單利息手續費（%），未設定為 18，可設為 0
+	RateThresholdMode       string   `mapstructure:"RATE_THRESHOLD_MODE"`        // 30/120 天閾值比較方式：gross（毛利率）或 net（扣除手續費後）
+
+	// 到期分散規劃
+	EnableMaturityPlanner bool      `mapstructure:"ENABLE_MATURITY_PLANNER"` // 依到期分布調整新訂單期間
+	MaturityBucketDays    int       `mapstructure:"MATURITY_BUCKET_DAYS"`    // 到期分桶天數，預設 7
+	MaturityTargetWeights []float64 `mapstructure:"MATURITY_TARGET_WEIGHTS"` // 各分桶目標權重，未設定為平均分布
+	MaturityPeriodFlex    *float64  `mapstructure:"MATURITY_PERIOD_FLEX"`    // 期間可調整幅度 (0-1)，未設定為 0.5，可設為 0
+
+	// 期間曝險限制
+	PeriodExposureLimits map[string]float64 `mapstructure:"PERIOD_EXPOSURE_LIMITS"` // 剩餘期間超過鍵值天數的借出與掛單占總資金上限（%），例如 30: 40
+
+	// 風險控管（熔斷器）
+	EnableRiskGuard          bool    `mapstructure:"ENABLE_RISK_GUARD"`            // 啟用下單前風險檢查
+	RiskMaxRateFRRMultiplier float64 `mapstructure:"RISK_MAX_RATE_FRR_MULTIPLIER"` // 訂單利率上限為 FRR 的倍數，預設 5
+	RiskCandleBandPercent    float64 `mapstructure:"RISK_CANDLE_BAND_PERCENT"`     // 允許超出近24小時K線高低點的百分比，預設 50
+	RiskMaxNotionalPerWindow float64 `mapstructure:"RISK_MAX_NOTIONAL_PER_WINDOW"` // 時間窗口內最大下單金額，0 為不限制
+	RiskMaxOrdersPerWindow   int     `mapstructure:"RISK_MAX_ORDERS_PER_WINDOW"`   // 時間窗口內最大下單筆數，0 為不限制
+	RiskWindowMinutes        int     `mapstructure:"RISK_WINDOW_MINUTES"`          // 限額時間窗口（分鐘），預設 60
+
+	// 季節性模型（依星期與小時學習利率溢價）
+	EnableSeasonality       bool    `mapstructure:"ENABLE_SEASONALITY"`             // 依歷史時段溢價調整掛單利率
+	SeasonalityLookbackDays int     `mapstructure:"SEASONALITY_LOOKBACK_DAYS"`      // 學習使用的歷史天數，預設 28
+	SeasonalityMaxAdjustPct float64 `mapstructure:"SEASONALITY_MAX_ADJUST_PERCENT"` // 季節性係數最大調整幅度（%），預設 20
+	SeasonalityLeadHours    *int    `mapstructure:"SEASONALITY_LEAD_HOURS"`         // 提前反應的小時數，未設定為 1，可設為 0
+	SeasonalityRefreshHours int     `mapstructure:"SEASONALITY_REFRESH_HOURS"`      // 重新學習間隔（小時），預設 24
+
+	// 利率飆升狙擊（高頻監控訂單簿，利率飆升時立即動用保留資金）
+	EnableSpikeSniper     bool    `mapstructure:"ENABLE_SPIKE_SNIPER"`     // 啟用利率飆升狙擊
+	SpikePollSeconds      int     `mapstructure:"SPIKE_POLL_SECONDS"`      // 訂單簿輪詢間隔（秒），預設 10
+	SpikeEMAPeriod        int     `mapstructure:"SPIKE_EMA_PERIOD"`        // 最佳 ask 利率 EMA 樣本數，預設 30
+	SpikeThresholdPercent float64 `mapstructure:"SPIKE_THRESHOLD_PERCENT"` // 最佳 ask 高於 EMA 的百分比門檻，預設 30
+	SpikeCapital          float64 `mapstructure:"SPIKE_CAPITAL"`           // 保留給飆升掛單的資金上限
+	SpikeOrders           int     `mapstructure:"SPIKE_ORDERS"`            // 飆升資金拆分筆數，預設 1
+	SpikePeriodDays       int     `mapstructure:"SPIKE_PERIOD_DAYS"`       // 飆升掛單期間（天），預設 120
+	SpikeCooldownMinutes  int     `mapstructure:"SPIKE_COOLDOWN_MINUTES"`  // 觸發後冷卻時間（分鐘），預設 30
+	SpikeOfferTTLMinutes  int     `mapstructure:"SPIKE_OFFER_TTL_MINUTES"` // 未成交飆升掛單保留時間（分鐘），預設 30
+	SpikeRepriceOffers    bool    `mapstructure:"SPIKE_REPRICE_OFFERS"`    // 觸發後立即重跑策略，依新訂單簿重新掛單
+
+	// 再融資（市場利率大幅上升時提前關閉低利率借出）
+	EnableRefinancing              bool    `mapstructure:"ENABLE_REFINANCING"`                // 啟用再融資
+	RefinanceMode                  string  `mapstructure:"REFINANCE_MODE"`                    // confirm（Telegram 確認）或 auto（自動關閉）
+	RefinanceMinImprovementPercent float64 `mapstructure:"REFINANCE_MIN_IMPROVEMENT_PERCENT"` // 市場利率需高於原利率的百分比，預設 50
+	RefinanceMinGain               float64 `mapstructure:"REFINANCE_MIN_GAIN"`                // 預估淨利息增加的最小金額，預設 0
+	RefinanceMinRemainingDays      float64 `mapstructure:"REFINANCE_MIN_REMAINING_DAYS"`      // 剩餘天數低於此值不關閉，預設 2
+	RefinanceRefillHours           float64 `mapstructure:"REFINANCE_REFILL_HOURS"`            // 預估重新貸出前的閒置時間（小時），預設 1
+	RefinanceMaxClosesPerDay       int     `mapstructure:"REFINANCE_MAX_CLOSES_PER_DAY"`      // 24 小時內最多關閉筆數，預設 3
+	RefinanceMinIntervalMinutes    int     `mapstructure:"REFINANCE_MIN_INTERVAL_MINUTES"`    // 兩次關閉的最短間隔（分鐘），預設 60
+
+	// 訂單歸屬（重啟後未被程式追蹤的掛單）
+	OrphanPolicy          string `mapstructure:"ORPHAN_POLICY"`            // manual（不處理，預設）、adopt_all（啟動時接管全部掛單）、signature（啟動時接管符合程式下單特徵的掛單）
+	OrderTrackerStateFile string `mapstructure:"ORDER_TRACKER_STATE_FILE"` // 程式訂單追蹤記錄保存路徑，預設 DATA_DIR/order_tracker_<symbol>.json
+
+	// 策略組合（同時以多個策略分配資金）
+	EnableStrategyBlend bool               `mapstructure:"ENABLE_STRATEGY_BLEND"` // 啟用策略組合模式
+	StrategyWeights     map[string]float64 `mapstructure:"STRATEGY_WEIGHTS"`      // 各策略資金權重，例如 kline: 50, smart: 30, traditional: 20
+	BlendDedupePercent  float64            `mapstructure:"BLEND_DEDUPE_PERCENT"`  // 利率相差在此百分比內的訂單合併，預設 1
+
+	// 歷史數據與回測
+	DataDir                string  `mapstructure:"DATA_DIR"`                 // 歷史數據儲存目錄，預設 data
+	BacktestTimeFrame      string  `mapstructure:"BACKTEST_TIME_FRAME"`      // 回測撮合使用的K線時間框架，預設 15m
+	BacktestFillModel      string  `mapstructure:"BACKTEST_FILL_MODEL"`      // 成交模型：touch（K線高點觸及即成交）、close（收盤利率達到才成交）
+	BacktestFillRatio      float64 `mapstructure:"BACKTEST_FILL_RATIO"`      // 每根符合條件的K線成交比例 (0-1]，預設 1
+	BacktestInitialBalance float64 `mapstructure:"BACKTEST_INITIAL_BALANCE"` // 回測初始資金，預設 10000
+
 	// Telegram 設定
 	TelegramBotToken  string `mapstructure:"TELEGRAM_BOT_TOKEN"`
 	TelegramAuthToken string `mapstructure:"TELEGRAM_AUTH_TOKEN"`
@@ -56,21 +145,42 @@ type Config struct {
 	MaxRateMultiplier        float64 `mapstructure:"MAX_RATE_MULTIPLIER"`
 	MinRateMultiplier        float64 `mapstructure:"MIN_RATE_MULTIPLIER"`
 	RateRangeIncreasePercent float64 `mapstructure:"RATE_RANGE_INCREASE_PERCENT"` // 利率範圍增加百分比
+	AnalyzerStateFile        string  `mapstructure:"ANALYZER_STATE_FILE"`         // 市場分析器快照保存路徑，預設 DATA_DIR/analyzer_<symbol>.json
+	SmartTrendEstimator      string  `mapstructure:"SMART_TREND_ESTIMATOR"`       // 趨勢估計器：count, regression, ema，預設 count
+	SmartVolatilityEstimator string  `mapstructure:"SMART_VOLATILITY_ESTIMATOR"`  // 波動率估計器：stddev, ewma, atr，預設 stddev
 
 	// K線策略設定
 	EnableKlineStrategy bool    `mapstructure:"ENABLE_KLINE_STRATEGY"` // 啟用K線策略
 	KlineTimeFrame      string  `mapstructure:"KLINE_TIME_FRAME"`      // K線時間框架，預設15m
 	KlinePeriod         int     `mapstructure:"KLINE_PERIOD"`          // K線週期數量，預設24（6小時）
 	KlineSpreadPercent  float64 `mapstructure:"KLINE_SPREAD_PERCENT"`  // K線最高點加成百分比，預設0%
-	KlineSmoothMethod   string  `mapstructure:"KLINE_SMOOTH_METHOD"`   // K線利率平滑方法：max, sma, ema, hla, p90
+	KlineSmoothMethod   string  `mapstructure:"KLINE_SMOOTH_METHOD"`   // K線利率平滑方法：max, sma, ema, hla, p90, vwap, vwp90, median, bbu, bbm
+	// KlineWindows 多時間框架視窗，格式為 時間框架:週期數[:權重[:平滑方法]]，例如 5m:12:1:max；未設定時使用 KLINE_TIME_FRAME/KLINE_PERIOD
+	KlineWindows     []string `mapstructure:"KLINE_WINDOWS"`
+	KlineAggregation string   `mapstructure:"KLINE_AGGREGATION"` // 多視窗合併方式：weighted（加權平均）、min、median，預設 weighted
 
 	// 測試模式設定
 	TestMode bool `mapstructure:"TEST_MODE"`
 
+	// 模擬交易設定
+	PaperTrading        bool    `mapstructure:"PAPER_TRADING"`         // 以模擬帳戶對公開市場數據下單
+	PaperMarketSource   string  `mapstructure:"PAPER_MARKET_SOURCE"`   // 市場數據來源：live（即時）或 replay（回放 DATA_DIR 歷史數據）
+	PaperReplayStart    string  `mapstructure:"PAPER_REPLAY_START"`    // replay 模式的起始日期 (YYYY-MM-DD)，空白為歷史數據開頭
+	PaperInitialBalance float64 `mapstructure:"PAPER_INITIAL_BALANCE"` // 模擬帳戶初始資金，預設 10000
+
 	// 借貸通知設定
 	LastLendingCheckTime int64   // 上次檢查借貸訂單的時間戳
 	LastAvailableBalance float64 // 上次檢查時的可用餘額
 	LendingCheckMinutes  int     `mapstructure:"LENDING_CHECK_MINUTES"` // 借貸訂單檢查間隔（分鐘）
+
+	// 資金使用率目標（閒置資金掃描與利率下限調整）
+	EnableUtilizationTarget       bool    `mapstructure:"ENABLE_UTILIZATION_TARGET"`         // 啟用資金使用率控制
+	UtilizationTargetPercent      float64 `mapstructure:"UTILIZATION_TARGET_PERCENT"`        // 目標借出比例（%），預設 90
+	IdleCashThreshold             float64 `mapstructure:"IDLE_CASH_THRESHOLD"`               // 閒置資金超過此金額視為需要掛單，預設為 MIN_LOAN
+	IdleGraceMinutes              int     `mapstructure:"IDLE_GRACE_MINUTES"`                // 閒置資金持續超過此時間（分鐘）即觸發掛單，預設 30
+	UtilizationWindowHours        int     `mapstructure:"UTILIZATION_WINDOW_HOURS"`          // 計算平均使用率與調整利率下限的時間窗口（小時），預設 24
+	UtilizationFloorStepPercent   float64 `mapstructure:"UTILIZATION_FLOOR_STEP_PERCENT"`    // 每個窗口調整最低日利率的比例（%），預設 5
+	UtilizationFloorMaxCutPercent float64 `mapstructure:"UTILIZATION_FLOOR_MAX_CUT_PERCENT"` // 使用率持續低於目標時最低日利率最多調降的比例（%），預設 0 不調整
 }
 
 // LoadConfig 從文件加載配置
@@ -87,20 +197,68 @@ func LoadConfig(configPath string) (*Config, error) {
 		return nil, errors.NewConfigError("failed to unmarshal config", err)
 	}
 
+	config.ApplyDefaults()
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
+
+// ApplyDefaults 設置所有未填寫參數的預設值
+// 回測或覆寫設定切換策略後需再次呼叫，以補齊新啟用策略的參數
+func (c *Config) ApplyDefaults() {
 	// 設置智能策略參數的預設值
-	config.setSmartStrategyDefaults()
+	c.setSmartStrategyDefaults()
 
 	// 設置K線策略參數的預設值
-	config.setKlineStrategyDefaults()
+	c.setKlineStrategyDefaults()
 
 	// 設置借貸檢查間隔的預設值
-	config.setLendingCheckDefaults()
+	c.setLendingCheckDefaults()
 
-	if err := config.Validate(); err != nil {
-		return nil, err
-	}
+	// 設置資金分配方式的預設值
+	c.setAllocationDefaults()
 
-	return &config, nil
+	// 設置自適應利率加成的預設值
+	c.setRateBonusDefaults()
+
+	// 設置資金使用率的預設值
+	c.setUtilizationDefaults()
+
+	// 設置手續費的預設值
+	c.setFeeDefaults()
+
+	// 設置策略組合的預設值（需在K線策略預設值之前，權重決定是否使用K線策略）
+	c.setStrategyBlendDefaults()
+
+	// 設置到期分散規劃的預設值
+	c.setMaturityPlannerDefaults()
+
+	// 設置風險控管的預設值
+	c.setRiskGuardDefaults()
+
+	// 設置季節性模型的預設值
+	c.setSeasonalityDefaults()
+
+	// 設置利率飆升狙擊的預設值
+	c.setSpikeSniperDefaults()
+
+	// 設置再融資的預設值
+	c.setRefinanceDefaults()
+
+	// 設置訂單歸屬的預設值
+	c.setOwnershipDefaults()
+
+	// 設置歷史數據與回測的預設值
+	c.setBacktestDefaults()
+
+	// 設置模擬交易的預設值
+	c.setPaperTradingDefaults()
+
+	// 設置多幣種的預設值
+	c.setCurrencyDefaults()
 }
 
 // Validate 驗證配置有效性
@@ -134,8 +292,98 @@ func (c *Config) Validate() error {
 		return errors.NewValidationError("invalid GAP_BOTTOM or GAP_TOP values")
 	}
 
+	if gapMode := strings.ToLower(c.GapMode); gapMode != "" && gapMode != constants.GapModeIndex && gapMode != constants.GapModeVolume {
+		return errors.NewValidationError("GAP_MODE must be one of: index, volume")
+	}
+
+	// 驗證資金分配方式
+	switch c.GetAllocationProfile() {
+	case constants.AllocationProfileEqual, constants.AllocationProfileGeometric,
+		constants.AllocationProfileInverse, constants.AllocationProfileBook, constants.AllocationProfileAbsorption:
+	default:
+		return errors.NewValidationError("ALLOCATION_PROFILE must be one of: equal, geometric, inverse, book, absorption")
+	}
+	if c.AllocationRatio < 0 || c.AllocationRatio >= 1 {
+		return errors.NewValidationError("ALLOCATION_RATIO must be between 0 and 1")
+	}
+
+	// 驗證自適應利率加成參數
+	if c.EnableAdaptiveRateBonus {
+		if c.RateBonusTargetFillPercent <= 0 || c.RateBonusTargetFillPercent > 100 {
+			return errors.NewValidationError("RATE_BONUS_TARGET_FILL_PERCENT must be between 0 and 100")
+		}
+		if c.RateBonusGain <= 0 {
+			return errors.NewValidationError("RATE_BONUS_GAIN must be positive")
+		}
+		if c.RateBonusMax < c.RateBonusMin {
+			return errors.NewValidationError("RATE_BONUS_MAX cannot be less than RATE_BONUS_MIN")
+		}
+		if c.RateBonusSmoothing <= 0 || c.RateBonusSmoothing > 1 {
+			return errors.NewValidationError("RATE_BONUS_SMOOTHING must be between 0 and 1")
+		}
+	}
+
+	// 驗證資金使用率參數
+	if c.EnableUtilizationTarget {
+		if c.UtilizationTargetPercent <= 0 || c.UtilizationTargetPercent > 100 {
+			return errors.NewValidationError("UTILIZATION_TARGET_PERCENT must be between 0 and 100")
+		}
+		if c.IdleCashThreshold < 0 || c.IdleGraceMinutes < 0 {
+			return errors.NewValidationError("IDLE_CASH_THRESHOLD and IDLE_GRACE_MINUTES cannot be negative")
+		}
+		if c.UtilizationWindowHours <= 0 {
+			return errors.NewValidationError("UTILIZATION_WINDOW_HOURS must be positive")
+		}
+		if c.UtilizationFloorStepPercent < 0 || c.UtilizationFloorMaxCutPercent < 0 || c.UtilizationFloorMaxCutPercent >= 100 {
+			return errors.NewValidationError("UTILIZATION_FLOOR_STEP_PERCENT must not be negative and UTILIZATION_FLOOR_MAX_CUT_PERCENT must be between 0 and 100")
+		}
+	}
+
+	// 驗證手續費參數
+	if fee := c.GetFundingFeePercent(); fee < 0 || fee >= 100 {
+		return errors.NewValidationError("FUNDING_FEE_PERCENT must be between 0 and 100")
+	}
+	if fee := c.GetHiddenFundingFeePercent(); fee < 0 || fee >= 100 {
+		return errors.NewValidationError("HIDDEN_FUNDING_FEE_PERCENT must be between 0 and 100")
+	}
+	switch c.RateThresholdMode {
+	case "", constants.RateThresholdGross, constants.RateThresholdNet:
+	default:
+		return errors.NewValidationError("RATE_THRESHOLD_MODE must be gross or net")
+	}
+
+	// 驗證隱藏掛單參數
+	if c.HiddenOfferMinAmount < 0 {
+		return errors.NewValidationError("HIDDEN_OFFER_MIN_AMOUNT cannot be negative")
+	}
+	if c.HiddenOfferRandomPercent < 0 || c.HiddenOfferRandomPercent > 100 {
+		return errors.NewValidationError("HIDDEN_OFFER_RANDOM_PERCENT must be between 0 and 100")
+	}
+
+	// 驗證策略組合參數
+	if c.EnableStrategyBlend {
+		total := 0.0
+		for name, weight := range c.StrategyWeights {
+			switch strings.ToLower(name) {
+			case constants.StrategyTraditional, constants.StrategySmart, constants.StrategyKline:
+			default:
+				return errors.NewValidationError("STRATEGY_WEIGHTS keys must be one of: traditional, smart, kline")
+			}
+			if weight < 0 {
+				return errors.NewValidationError("STRATEGY_WEIGHTS cannot be negative")
+			}
+			total += weight
+		}
+		if total <= 0 {
+			return errors.NewValidationError("STRATEGY_WEIGHTS must contain at least one positive weight")
+		}
+		if c.BlendDedupePercent < 0 || c.BlendDedupePercent > 100 {
+			return errors.NewValidationError("BLEND_DEDUPE_PERCENT must be between 0 and 100")
+		}
+	}
+
 	// 驗證智能策略參數
-	if c.EnableSmartStrategy {
+	if c.UsesStrategy(constants.StrategySmart) {
 		if c.VolatilityThreshold <= 0 || c.VolatilityThreshold > 0.01 {
 			return errors.NewValidationError("VOLATILITY_THRESHOLD must be between 0 and 0.01")
 		}
@@ -151,10 +399,20 @@ func (c *Config) Validate() error {
 		if c.RateRangeIncreasePercent <= 0 || c.RateRangeIncreasePercent > 1.0 {
 			return errors.NewValidationError("RATE_RANGE_INCREASE_PERCENT must be between 0 and 1.0 (0-100%)")
 		}
+		switch strings.ToLower(c.SmartTrendEstimator) {
+		case "", constants.TrendEstimatorCount, constants.TrendEstimatorRegression, constants.TrendEstimatorEMA:
+		default:
+			return errors.NewValidationError("SMART_TREND_ESTIMATOR must be one of: count, regression, ema")
+		}
+		switch strings.ToLower(c.SmartVolatilityEstimator) {
+		case "", constants.VolatilityEstimatorStdDev, constants.VolatilityEstimatorEWMA, constants.VolatilityEstimatorATR:
+		default:
+			return errors.NewValidationError("SMART_VOLATILITY_ESTIMATOR must be one of: stddev, ewma, atr")
+		}
 	}
 
 	// 驗證K線策略參數
-	if c.EnableKlineStrategy {
+	if c.UsesStrategy(constants.StrategyKline) {
 		if c.KlineTimeFrame == "" {
 			return errors.NewValidationError("KLINE_TIME_FRAME is required when ENABLE_KLINE_STRATEGY is true")
 		}
@@ -165,16 +423,178 @@ func (c *Config) Validate() error {
 			return errors.NewValidationError("KLINE_SPREAD_PERCENT must be between 0 and 100")
 		}
 		// 驗證平滑方法
-		validMethods := []string{"max", "sma", "ema", "hla", "p90"}
-		isValidMethod := false
-		for _, method := range validMethods {
-			if c.KlineSmoothMethod == method {
-				isValidMethod = true
-				break
+		if !isValidKlineSmoothMethod(c.KlineSmoothMethod) {
+			return errors.NewValidationError("KLINE_SMOOTH_METHOD must be one of: max, sma, ema, hla, p90, vwap, vwp90, median, bbu, bbm")
+		}
+		// 驗證多時間框架視窗
+		totalWeight := 0.0
+		for _, text := range c.KlineWindows {
+			window, err := ParseKlineWindow(text)
+			if err != nil {
+				return err
 			}
+			totalWeight += window.Weight
 		}
-		if !isValidMethod {
-			return errors.NewValidationError("KLINE_SMOOTH_METHOD must be one of: max, sma, ema, hla, p90")
+		switch c.KlineAggregation {
+		case constants.KlineAggregationWeighted:
+			if len(c.KlineWindows) > 0 && totalWeight <= 0 {
+				return errors.NewValidationError("KLINE_WINDOWS weights must sum to a positive value")
+			}
+		case constants.KlineAggregationMin, constants.KlineAggregationMedian:
+		default:
+			return errors.NewValidationError("KLINE_AGGREGATION must be one of: weighted, min, median")
+		}
+	}
+
+	// 驗證到期分散規劃參數
+	if c.EnableMaturityPlanner {
+		if c.MaturityBucketDays <= 0 || c.MaturityBucketDays > constants.MaturityHorizonDays {
+			return errors.NewValidationError("MATURITY_BUCKET_DAYS must be between 1 and 120")
+		}
+		if flex := c.GetMaturityPeriodFlex(); flex < 0 || flex > 1 {
+			return errors.NewValidationError("MATURITY_PERIOD_FLEX must be between 0 and 1")
+		}
+		if len(c.MaturityTargetWeights) > 0 {
+			bucketCount := c.GetMaturityBucketCount()
+			if len(c.MaturityTargetWeights) != bucketCount {
+				return errors.NewValidationError(fmt.Sprintf("MATURITY_TARGET_WEIGHTS must have %d entries for MATURITY_BUCKET_DAYS=%d", bucketCount, c.MaturityBucketDays))
+			}
+			sum := 0.0
+			for _, weight := range c.MaturityTargetWeights {
+				if weight < 0 {
+					return errors.NewValidationError("MATURITY_TARGET_WEIGHTS cannot contain negative values")
+				}
+				sum += weight
+			}
+			if sum <= 0 {
+				return errors.NewValidationError("MATURITY_TARGET_WEIGHTS must sum to a positive value")
+			}
+		}
+	}
+
+	// 驗證期間曝險限制
+	for key, percent := range c.PeriodExposureLimits {
+		days, err := strconv.Atoi(strings.TrimSpace(key))
+		if err != nil || days < constants.DefaultPeriodDays || days >= constants.Period120Days {
+			return errors.NewValidationError(fmt.Sprintf("PERIOD_EXPOSURE_LIMITS keys must be days between %d and %d, got %q", constants.DefaultPeriodDays, constants.Period120Days-1, key))
+		}
+		if percent < 0 || percent > 100 {
+			return errors.NewValidationError("PERIOD_EXPOSURE_LIMITS values must be between 0 and 100")
+		}
+	}
+
+	// 驗證風險控管參數
+	if c.EnableRiskGuard {
+		if c.RiskMaxRateFRRMultiplier < 0 {
+			return errors.NewValidationError("RISK_MAX_RATE_FRR_MULTIPLIER cannot be negative")
+		}
+		if c.RiskMaxRateFRRMultiplier > 0 && c.RiskMaxRateFRRMultiplier < 1 {
+			return errors.NewValidationError("RISK_MAX_RATE_FRR_MULTIPLIER must be at least 1 (or 0 to disable)")
+		}
+		if c.RiskCandleBandPercent < 0 || c.RiskCandleBandPercent > 100 {
+			return errors.NewValidationError("RISK_CANDLE_BAND_PERCENT must be between 0 and 100")
+		}
+		if c.RiskMaxNotionalPerWindow < 0 {
+			return errors.NewValidationError("RISK_MAX_NOTIONAL_PER_WINDOW cannot be negative")
+		}
+		if c.RiskMaxOrdersPerWindow < 0 {
+			return errors.NewValidationError("RISK_MAX_ORDERS_PER_WINDOW cannot be negative")
+		}
+		if c.RiskWindowMinutes <= 0 {
+			return errors.NewValidationError("RISK_WINDOW_MINUTES must be positive")
+		}
+	}
+
+	// 驗證季節性模型參數
+	if c.EnableSeasonality {
+		if c.SeasonalityLookbackDays < 7 || c.SeasonalityLookbackDays*24 > constants.MaxCandlesPerRequest {
+			return errors.NewValidationError(fmt.Sprintf("SEASONALITY_LOOKBACK_DAYS must be between 7 and %d", constants.MaxCandlesPerRequest/24))
+		}
+		if c.SeasonalityMaxAdjustPct <= 0 || c.SeasonalityMaxAdjustPct > 100 {
+			return errors.NewValidationError("SEASONALITY_MAX_ADJUST_PERCENT must be between 0 and 100")
+		}
+		if lead := c.GetSeasonalityLeadHours(); lead < 0 || lead > 24 {
+			return errors.NewValidationError("SEASONALITY_LEAD_HOURS must be between 0 and 24")
+		}
+		if c.SeasonalityRefreshHours <= 0 {
+			return errors.NewValidationError("SEASONALITY_REFRESH_HOURS must be positive")
+		}
+	}
+
+	// 驗證利率飆升狙擊參數
+	if c.EnableSpikeSniper {
+		if c.SpikePollSeconds <= 0 {
+			return errors.NewValidationError("SPIKE_POLL_SECONDS must be positive")
+		}
+		if c.SpikeEMAPeriod <= 1 {
+			return errors.NewValidationError("SPIKE_EMA_PERIOD must be greater than 1")
+		}
+		if c.SpikeThresholdPercent <= 0 {
+			return errors.NewValidationError("SPIKE_THRESHOLD_PERCENT must be positive")
+		}
+		if c.SpikeCapital < c.MinLoan {
+			return errors.NewValidationError("SPIKE_CAPITAL must be at least MIN_LOAN")
+		}
+		if c.SpikeOrders <= 0 {
+			return errors.NewValidationError("SPIKE_ORDERS must be positive")
+		}
+		if c.SpikePeriodDays < constants.DefaultPeriodDays || c.SpikePeriodDays > constants.Period120Days {
+			return errors.NewValidationError(fmt.Sprintf("SPIKE_PERIOD_DAYS must be between %d and %d", constants.DefaultPeriodDays, constants.Period120Days))
+		}
+		if c.SpikeCooldownMinutes < 0 || c.SpikeOfferTTLMinutes < 0 {
+			return errors.NewValidationError("SPIKE_COOLDOWN_MINUTES and SPIKE_OFFER_TTL_MINUTES cannot be negative")
+		}
+	}
+
+	// 驗證再融資參數
+	if c.EnableRefinancing {
+		if c.RefinanceMode != constants.RefinanceModeConfirm && c.RefinanceMode != constants.RefinanceModeAuto {
+			return errors.NewValidationError("REFINANCE_MODE must be one of: confirm, auto")
+		}
+		if c.RefinanceMinImprovementPercent <= 0 {
+			return errors.NewValidationError("REFINANCE_MIN_IMPROVEMENT_PERCENT must be positive")
+		}
+		if c.RefinanceMinGain < 0 || c.RefinanceMinRemainingDays < 0 || c.RefinanceRefillHours < 0 {
+			return errors.NewValidationError("REFINANCE_MIN_GAIN, REFINANCE_MIN_REMAINING_DAYS and REFINANCE_REFILL_HOURS cannot be negative")
+		}
+		if c.RefinanceMaxClosesPerDay <= 0 {
+			return errors.NewValidationError("REFINANCE_MAX_CLOSES_PER_DAY must be positive")
+		}
+		if c.RefinanceMinIntervalMinutes < 0 {
+			return errors.NewValidationError("REFINANCE_MIN_INTERVAL_MINUTES cannot be negative")
+		}
+	}
+
+	// 驗證訂單歸屬策略
+	switch c.OrphanPolicy {
+	case "", constants.OrphanPolicyManual, constants.OrphanPolicyAdoptAll, constants.OrphanPolicySignature:
+	default:
+		return errors.NewValidationError("ORPHAN_POLICY must be one of: manual, adopt_all, signature")
+	}
+
+	// 驗證回測參數
+	if c.BacktestFillModel != "" && !IsValidFillModel(c.BacktestFillModel) {
+		return errors.NewValidationError("BACKTEST_FILL_MODEL must be one of: touch, close")
+	}
+	if c.BacktestFillRatio < 0 || c.BacktestFillRatio > 1 {
+		return errors.NewValidationError("BACKTEST_FILL_RATIO must be between 0 and 1")
+	}
+	if c.BacktestInitialBalance < 0 {
+		return errors.NewValidationError("BACKTEST_INITIAL_BALANCE cannot be negative")
+	}
+
+	// 驗證模擬交易參數
+	if c.PaperTrading {
+		if c.PaperMarketSource != constants.PaperMarketLive && c.PaperMarketSource != constants.PaperMarketReplay {
+			return errors.NewValidationError("PAPER_MARKET_SOURCE must be one of: live, replay")
+		}
+		if c.PaperInitialBalance <= 0 {
+			return errors.NewValidationError("PAPER_INITIAL_BALANCE must be positive")
+		}
+		if c.PaperReplayStart != "" {
+			if _, err := time.Parse(constants.DateLayout, c.PaperReplayStart); err != nil {
+				return errors.NewValidationError("PAPER_REPLAY_START must use YYYY-MM-DD format")
+			}
 		}
 	}
 
@@ -183,6 +603,13 @@ func (c *Config) Validate() error {
 		return errors.NewValidationError("LENDING_CHECK_MINUTES must be positive")
 	}
 
+	// 驗證多幣種設定（各幣種套用覆寫後需各自通過驗證）
+	if len(c.Currencies) > 0 {
+		if _, err := c.CurrencyConfigs(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -191,6 +618,30 @@ func (c *Config) GetFundingSymbol() string {
 	return constants.FundingSymbolPrefix + strings.ToUpper(c.Currency)
 }
 
+// GetAnalyzerStatePath 獲取市場分析器快照的保存路徑
+func (c *Config) GetAnalyzerStatePath() string {
+	if c.AnalyzerStateFile != "" {
+		return c.AnalyzerStateFile
+	}
+	return filepath.Join(c.DataDir, fmt.Sprintf("analyzer_%s.json", c.GetFundingSymbol()))
+}
+
+// GetRateBonusStatePath 獲取自適應利率加成記錄的保存路徑
+func (c *Config) GetRateBonusStatePath() string {
+	if c.RateBonusStateFile != "" {
+		return c.RateBonusStateFile
+	}
+	return filepath.Join(c.DataDir, fmt.Sprintf("rate_bonus_%s.json", c.GetFundingSymbol()))
+}
+
+// GetOrderTrackerStatePath 獲取程式訂單追蹤記錄的保存路徑
+func (c *Config) GetOrderTrackerStatePath() string {
+	if c.OrderTrackerStateFile != "" {
+		return c.OrderTrackerStateFile
+	}
+	return filepath.Join(c.DataDir, fmt.Sprintf("order_tracker_%s.json", c.GetFundingSymbol()))
+}
+
 // GetMinDailyRateDecimal 獲取最低日利率（小數格式）
 func (c *Config) GetMinDailyRateDecimal() float64 {
 	minDailyRate, useFRR, err := c.parseMinDailyLendRate()
@@ -270,6 +721,72 @@ func (c *Config) parseMinDailyLendRate() (float64, bool, error) {
 	}
 }
 
+// UsesStrategy 檢查策略是否會被使用（單一策略模式為目前啟用的策略，組合模式為權重大於 0 的策略）
+func (c *Config) UsesStrategy(name string) bool {
+	if c.EnableStrategyBlend {
+		for key, weight := range c.StrategyWeights {
+			if strings.EqualFold(key, name) && weight > 0 {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch name {
+	case constants.StrategyKline:
+		return c.EnableKlineStrategy
+	case constants.StrategySmart:
+		return c.EnableSmartStrategy
+	default:
+		return !c.EnableKlineStrategy && !c.EnableSmartStrategy
+	}
+}
+
+// GetAllocationProfile 返回分散單金額分配方式（未設定為平均分配）
+func (c *Config) GetAllocationProfile() string {
+	if c.AllocationProfile == "" {
+		return constants.AllocationProfileEqual
+	}
+	return strings.ToLower(c.AllocationProfile)
+}
+
+// IsVolumeGapMode 檢查 GAP_BOTTOM/GAP_TOP 是否以累計掛單金額表示
+func (c *Config) IsVolumeGapMode() bool {
+	return strings.EqualFold(c.GapMode, constants.GapModeVolume)
+}
+
+// HasHiddenOfferPolicy 檢查是否啟用任何隱藏掛單規則
+func (c *Config) HasHiddenOfferPolicy() bool {
+	return c.HideHighHoldOffers || c.HiddenOfferMinAmount > 0 || c.HiddenOfferRandomPercent > 0
+}
+
+// PeriodExposureLimit 期間曝險上限：剩餘期間超過 AboveDays 天的資金不超過總資金的 MaxPercent%
+type PeriodExposureLimit struct {
+	AboveDays  int
+	MaxPercent float64
+}
+
+// GetPeriodExposureLimits 獲取依天數由短到長排列的期間曝險上限
+func (c *Config) GetPeriodExposureLimits() []PeriodExposureLimit {
+	limits := make([]PeriodExposureLimit, 0, len(c.PeriodExposureLimits))
+	for key, percent := range c.PeriodExposureLimits {
+		days, err := strconv.Atoi(strings.TrimSpace(key))
+		if err != nil {
+			continue
+		}
+		limits = append(limits, PeriodExposureLimit{AboveDays: days, MaxPercent: percent})
+	}
+	sort.Slice(limits, func(i, j int) bool {
+		return limits[i].AboveDays < limits[j].AboveDays
+	})
+	return limits
+}
+
+// HasPeriodExposureLimits 檢查是否設置期間曝險限制
+func (c *Config) HasPeriodExposureLimits() bool {
+	return len(c.PeriodExposureLimits) > 0
+}
+
 // GetHighHoldRateDecimal 獲取高額持有利率（小數格式）
 func (c *Config) GetHighHoldRateDecimal() float64 {
 	return c.HighHoldRate / constants.PercentageToDecimal
@@ -285,6 +802,51 @@ func (c *Config) GetOneTwentyDayThresholdDecimal() float64 {
 	return c.OneTwentyDayLendRateThreshold / constants.PercentageToDecimal
 }
 
+// GetFundingFeePercent 獲取一般掛單利息手續費（%），未設置時為標準帳戶費率
+func (c *Config) GetFundingFeePercent() float64 {
+	if c.FundingFeePercent == nil {
+		return constants.FundingFeeRate * constants.PercentageToDecimal
+	}
+	return *c.FundingFeePercent
+}
+
+// GetHiddenFundingFeePercent 獲取隱藏掛單利息手續費（%），未設置時為標準帳戶費率
+func (c *Config) GetHiddenFundingFeePercent() float64 {
+	if c.HiddenFundingFeePercent == nil {
+		return constants.HiddenFundingFeeRate * constants.PercentageToDecimal
+	}
+	return *c.HiddenFundingFeePercent
+}
+
+// GetMaturityPeriodFlex 獲取到期分散規劃的期間可調整幅度，未設置時為預設值
+func (c *Config) GetMaturityPeriodFlex() float64 {
+	if c.MaturityPeriodFlex == nil {
+		return constants.DefaultMaturityPeriodFlex
+	}
+	return *c.MaturityPeriodFlex
+}
+
+// GetSeasonalityLeadHours 獲取季節性模型提前反應的小時數，未設置時為預設值
+func (c *Config) GetSeasonalityLeadHours() int {
+	if c.SeasonalityLeadHours == nil {
+		return constants.DefaultSeasonalityLeadHours
+	}
+	return *c.SeasonalityLeadHours
+}
+
+// GetFeeModel 獲取利息手續費模型，未設置時使用標準帳戶費率
+func (c *Config) GetFeeModel() rates.FeeModel {
+	fees := rates.DefaultFeeModel()
+	fees.Rate = c.GetFundingFeePercent() / constants.PercentageToDecimal
+	fees.HiddenRate = c.GetHiddenFundingFeePercent() / constants.PercentageToDecimal
+	return fees
+}
+
+// IsNetRateThreshold 檢查 30/120 天閾值是否以扣除手續費後的淨利率比較
+func (c *Config) IsNetRateThreshold() bool {
+	return strings.EqualFold(c.RateThresholdMode, constants.RateThresholdNet)
+}
+
 // setSmartStrategyDefaults 設置智能策略參數的預設值
 func (c *Config) setSmartStrategyDefaults() {
 	// 如果智能策略啟用但參數為零，設置建議的預設值
@@ -316,12 +878,21 @@ func (c *Config) setSmartStrategyDefaults() {
 			c.RateRangeIncreasePercent = constants.RateRangeIncreasePercent
 		}
 	}
+
+	if c.SmartTrendEstimator == "" {
+		c.SmartTrendEstimator = constants.TrendEstimatorCount
+	}
+	c.SmartTrendEstimator = strings.ToLower(c.SmartTrendEstimator)
+	if c.SmartVolatilityEstimator == "" {
+		c.SmartVolatilityEstimator = constants.VolatilityEstimatorStdDev
+	}
+	c.SmartVolatilityEstimator = strings.ToLower(c.SmartVolatilityEstimator)
 }
 
 // setKlineStrategyDefaults 設置K線策略參數的預設值
 func (c *Config) setKlineStrategyDefaults() {
-	// 如果K線策略啟用但參數為空，設置預設值
-	if c.EnableKlineStrategy {
+	// 如果K線策略啟用（或在策略組合中有權重）但參數為空，設置預設值
+	if c.UsesStrategy(constants.StrategyKline) {
 		if c.KlineTimeFrame == "" {
 			c.KlineTimeFrame = "15m"
 		}
@@ -334,9 +905,88 @@ func (c *Config) setKlineStrategyDefaults() {
 		if c.KlineSmoothMethod == "" {
 			c.KlineSmoothMethod = "ema" // 預設使用指數移動平均
 		}
+		if c.KlineAggregation == "" {
+			c.KlineAggregation = constants.KlineAggregationWeighted
+		}
 	}
 }
 
+// KlineWindow K線策略的單一時間框架視窗
+type KlineWindow struct {
+	TimeFrame    string
+	Period       int
+	Weight       float64 // 加權平均時的權重，預設 1
+	SmoothMethod string  // 空字串代表使用 KLINE_SMOOTH_METHOD
+}
+
+// String 以設定格式顯示視窗，例如 5m×12
+func (w KlineWindow) String() string {
+	return fmt.Sprintf("%s×%d", w.TimeFrame, w.Period)
+}
+
+// ParseKlineWindow 解析 時間框架:週期數[:權重[:平滑方法]] 格式的K線視窗
+func ParseKlineWindow(text string) (KlineWindow, error) {
+	parts := strings.Split(strings.TrimSpace(text), ":")
+	invalid := func(reason string) (KlineWindow, error) {
+		return KlineWindow{}, errors.NewValidationError(fmt.Sprintf("KLINE_WINDOWS entry %q %s, expected timeframe:period[:weight[:smooth]]", text, reason))
+	}
+	if len(parts) < 2 || len(parts) > 4 || strings.TrimSpace(parts[0]) == "" {
+		return invalid("is malformed")
+	}
+
+	window := KlineWindow{TimeFrame: strings.TrimSpace(parts[0]), Weight: 1}
+	period, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err != nil || period <= 0 {
+		return invalid("has an invalid period")
+	}
+	window.Period = period
+
+	if len(parts) >= 3 && strings.TrimSpace(parts[2]) != "" {
+		weight, err := strconv.ParseFloat(strings.TrimSpace(parts[2]), 64)
+		if err != nil || weight < 0 {
+			return invalid("has an invalid weight")
+		}
+		window.Weight = weight
+	}
+	if len(parts) == 4 {
+		window.SmoothMethod = strings.ToLower(strings.TrimSpace(parts[3]))
+		if window.SmoothMethod != "" && !isValidKlineSmoothMethod(window.SmoothMethod) {
+			return invalid("has an invalid smooth method")
+		}
+	}
+	return window, nil
+}
+
+// GetKlineWindows 獲取K線策略的時間框架視窗，未指定平滑方法的視窗使用 KLINE_SMOOTH_METHOD
+// 未設定 KLINE_WINDOWS 時以 KLINE_TIME_FRAME/KLINE_PERIOD 作為唯一視窗
+func (c *Config) GetKlineWindows() []KlineWindow {
+	if len(c.KlineWindows) == 0 {
+		return []KlineWindow{{TimeFrame: c.KlineTimeFrame, Period: c.KlinePeriod, Weight: 1, SmoothMethod: c.KlineSmoothMethod}}
+	}
+
+	windows := make([]KlineWindow, 0, len(c.KlineWindows))
+	for _, text := range c.KlineWindows {
+		window, err := ParseKlineWindow(text)
+		if err != nil {
+			continue
+		}
+		if window.SmoothMethod == "" {
+			window.SmoothMethod = c.KlineSmoothMethod
+		}
+		windows = append(windows, window)
+	}
+	return windows
+}
+
+// isValidKlineSmoothMethod 檢查K線利率平滑方法是否有效
+func isValidKlineSmoothMethod(method string) bool {
+	switch method {
+	case "max", "sma", "ema", "hla", "p90", "vwap", "vwp90", "median", "bbu", "bbm":
+		return true
+	}
+	return false
+}
+
 // setLendingCheckDefaults 設置借貸檢查間隔的預設值
 func (c *Config) setLendingCheckDefaults() {
 	// 如果未設置借貸檢查間隔，預設為 10 分鐘
@@ -344,3 +994,241 @@ func (c *Config) setLendingCheckDefaults() {
 		c.LendingCheckMinutes = 10
 	}
 }
+
+// GetMaturityBucketCount 獲取到期分桶數量（涵蓋 0 到 120 天）
+func (c *Config) GetMaturityBucketCount() int {
+	bucketDays := c.MaturityBucketDays
+	if bucketDays <= 0 {
+		bucketDays = constants.DefaultMaturityBucketDays
+	}
+	return constants.MaturityHorizonDays/bucketDays + 1
+}
+
+// setMaturityPlannerDefaults 設置到期分散規劃的預設值
+func (c *Config) setMaturityPlannerDefaults() {
+	if c.MaturityBucketDays == 0 {
+		c.MaturityBucketDays = constants.DefaultMaturityBucketDays
+	}
+	// 明確設為 0 時保留（只記錄到期分布，不調整期間），只補上未設定的欄位
+	if c.MaturityPeriodFlex == nil {
+		flex := c.GetMaturityPeriodFlex()
+		c.MaturityPeriodFlex = &flex
+	}
+}
+
+// setRiskGuardDefaults 設置風險控管的預設值
+func (c *Config) setRiskGuardDefaults() {
+	if c.RiskMaxRateFRRMultiplier == 0 {
+		c.RiskMaxRateFRRMultiplier = constants.DefaultRiskMaxRateFRRMultiplier
+	}
+	if c.RiskCandleBandPercent == 0 {
+		c.RiskCandleBandPercent = constants.DefaultRiskCandleBandPercent
+	}
+	if c.RiskWindowMinutes == 0 {
+		c.RiskWindowMinutes = constants.DefaultRiskWindowMinutes
+	}
+}
+
+// setSeasonalityDefaults 設置季節性模型的預設值
+func (c *Config) setSeasonalityDefaults() {
+	if c.SeasonalityLookbackDays == 0 {
+		c.SeasonalityLookbackDays = constants.DefaultSeasonalityLookbackDays
+	}
+	if c.SeasonalityMaxAdjustPct == 0 {
+		c.SeasonalityMaxAdjustPct = constants.DefaultSeasonalityMaxAdjustPct
+	}
+	// 明確設為 0 時保留（只看目前時段），只補上未設定的欄位
+	if c.SeasonalityLeadHours == nil {
+		lead := c.GetSeasonalityLeadHours()
+		c.SeasonalityLeadHours = &lead
+	}
+	if c.SeasonalityRefreshHours == 0 {
+		c.SeasonalityRefreshHours = constants.DefaultSeasonalityRefreshHours
+	}
+}
+
+// setAllocationDefaults 設置資金分配方式的預設值
+func (c *Config) setAllocationDefaults() {
+	if c.AllocationRatio == 0 {
+		c.AllocationRatio = constants.DefaultAllocationRatio
+	}
+}
+
+// setRateBonusDefaults 設置自適應利率加成的預設值
+func (c *Config) setRateBonusDefaults() {
+	if c.RateBonusTargetFillPercent == 0 {
+		c.RateBonusTargetFillPercent = constants.DefaultRateBonusTargetFillPct
+	}
+	if c.RateBonusGain == 0 {
+		c.RateBonusGain = constants.DefaultRateBonusGain
+	}
+	if c.RateBonusMax == 0 && c.RateBonusMin >= 0 {
+		c.RateBonusMax = constants.DefaultRateBonusMax
+	}
+	if c.RateBonusSmoothing == 0 {
+		c.RateBonusSmoothing = constants.DefaultRateBonusSmoothing
+	}
+}
+
+// setUtilizationDefaults 設置資金使用率的預設值
+func (c *Config) setUtilizationDefaults() {
+	if c.UtilizationTargetPercent == 0 {
+		c.UtilizationTargetPercent = constants.DefaultUtilizationTargetPct
+	}
+	if c.IdleGraceMinutes == 0 {
+		c.IdleGraceMinutes = constants.DefaultIdleGraceMinutes
+	}
+	if c.UtilizationWindowHours == 0 {
+		c.UtilizationWindowHours = constants.DefaultUtilizationWindowHours
+	}
+	if c.UtilizationFloorStepPercent == 0 {
+		c.UtilizationFloorStepPercent = constants.DefaultUtilizationFloorStepPct
+	}
+}
+
+// GetIdleCashThreshold 返回觸發掛單的閒置資金門檻（未設定時為 MIN_LOAN）
+func (c *Config) GetIdleCashThreshold() float64 {
+	if c.IdleCashThreshold > 0 {
+		return c.IdleCashThreshold
+	}
+	return c.MinLoan
+}
+
+// setFeeDefaults 設置手續費的預設值（Bitfinex 標準帳戶費率）
+func (c *Config) setFeeDefaults() {
+	// 明確設為 0 時保留（零手續費等級），只補上未設定的欄位
+	if c.FundingFeePercent == nil {
+		fee := c.GetFundingFeePercent()
+		c.FundingFeePercent = &fee
+	}
+	if c.HiddenFundingFeePercent == nil {
+		fee := c.GetHiddenFundingFeePercent()
+		c.HiddenFundingFeePercent = &fee
+	}
+	if c.RateThresholdMode == "" {
+		c.RateThresholdMode = constants.RateThresholdGross
+	}
+}
+
+// setStrategyBlendDefaults 設置策略組合的預設值
+func (c *Config) setStrategyBlendDefaults() {
+	if !c.EnableStrategyBlend {
+		return
+	}
+	if len(c.StrategyWeights) == 0 {
+		// 未設定權重時三種策略平均分配
+		c.StrategyWeights = map[string]float64{
+			constants.StrategyTraditional: 1,
+			constants.StrategySmart:       1,
+			constants.StrategyKline:       1,
+		}
+	}
+	weights := make(map[string]float64, len(c.StrategyWeights))
+	for name, weight := range c.StrategyWeights {
+		weights[strings.ToLower(name)] += weight
+	}
+	c.StrategyWeights = weights
+	if c.BlendDedupePercent == 0 {
+		c.BlendDedupePercent = constants.DefaultBlendDedupePercent
+	}
+}
+
+// setSpikeSniperDefaults 設置利率飆升狙擊的預設值
+func (c *Config) setSpikeSniperDefaults() {
+	if c.SpikePollSeconds == 0 {
+		c.SpikePollSeconds = constants.DefaultSpikePollSeconds
+	}
+	if c.SpikeEMAPeriod == 0 {
+		c.SpikeEMAPeriod = constants.DefaultSpikeEMAPeriod
+	}
+	if c.SpikeThresholdPercent == 0 {
+		c.SpikeThresholdPercent = constants.DefaultSpikeThresholdPercent
+	}
+	if c.SpikeOrders == 0 {
+		c.SpikeOrders = constants.DefaultSpikeOrders
+	}
+	if c.SpikePeriodDays == 0 {
+		c.SpikePeriodDays = constants.DefaultSpikePeriodDays
+	}
+	if c.SpikeCooldownMinutes == 0 {
+		c.SpikeCooldownMinutes = constants.DefaultSpikeCooldownMinutes
+	}
+	if c.SpikeOfferTTLMinutes == 0 {
+		c.SpikeOfferTTLMinutes = constants.DefaultSpikeOfferTTLMinutes
+	}
+}
+
+// setOwnershipDefaults 設置訂單歸屬的預設值
+func (c *Config) setOwnershipDefaults() {
+	if c.OrphanPolicy == "" {
+		c.OrphanPolicy = constants.OrphanPolicyManual
+	}
+	c.OrphanPolicy = strings.ToLower(c.OrphanPolicy)
+}
+
+// setRefinanceDefaults 設置再融資的預設值
+func (c *Config) setRefinanceDefaults() {
+	if c.RefinanceMode == "" {
+		c.RefinanceMode = constants.RefinanceModeConfirm
+	}
+	c.RefinanceMode = strings.ToLower(c.RefinanceMode)
+	if c.RefinanceMinImprovementPercent == 0 {
+		c.RefinanceMinImprovementPercent = constants.DefaultRefinanceMinImprovementPct
+	}
+	if c.RefinanceMinRemainingDays == 0 {
+		c.RefinanceMinRemainingDays = constants.DefaultRefinanceMinRemainingDays
+	}
+	if c.RefinanceRefillHours == 0 {
+		c.RefinanceRefillHours = constants.DefaultRefinanceRefillHours
+	}
+	if c.RefinanceMaxClosesPerDay == 0 {
+		c.RefinanceMaxClosesPerDay = constants.DefaultRefinanceMaxClosesPerDay
+	}
+	if c.RefinanceMinIntervalMinutes == 0 {
+		c.RefinanceMinIntervalMinutes = constants.DefaultRefinanceMinIntervalMinutes
+	}
+}
+
+// IsDryRun 是否只記錄而不下單（測試模式且未啟用模擬交易）
+func (c *Config) IsDryRun() bool {
+	return c.TestMode && !c.PaperTrading
+}
+
+// setPaperTradingDefaults 設置模擬交易的預設值
+func (c *Config) setPaperTradingDefaults() {
+	if c.PaperMarketSource == "" {
+		c.PaperMarketSource = constants.PaperMarketLive
+	}
+	c.PaperMarketSource = strings.ToLower(c.PaperMarketSource)
+	if c.PaperInitialBalance == 0 {
+		c.PaperInitialBalance = constants.DefaultBacktestBalance
+	}
+}
+
+// IsValidFillModel 檢查回測成交模型是否有效
+func IsValidFillModel(model string) bool {
+	switch strings.ToLower(model) {
+	case constants.FillModelTouch, constants.FillModelClose:
+		return true
+	}
+	return false
+}
+
+// setBacktestDefaults 設置歷史數據與回測的預設值
+func (c *Config) setBacktestDefaults() {
+	if c.DataDir == "" {
+		c.DataDir = constants.DefaultDataDir
+	}
+	if c.BacktestTimeFrame == "" {
+		c.BacktestTimeFrame = constants.DefaultBacktestTimeFrame
+	}
+	if c.BacktestFillModel == "" {
+		c.BacktestFillModel = constants.FillModelTouch
+	}
+	if c.BacktestFillRatio == 0 {
+		c.BacktestFillRatio = 1
+	}
+	if c.BacktestInitialBalance == 0 {
+		c.BacktestInitialBalance = constants.DefaultBacktestBalance
+	}
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"reflect"
 	"testing"
 )
 
@@ -133,6 +134,39 @@ func TestConfig_Validate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "invalid hidden offer random percent",
+			config: Config{
+				BitfinexApiKey:           "test_api_key",
+				BitfinexSecretKey:        "test_secret_key",
+				Currency:                 "USD",
+				MinLoan:                  150.0,
+				MinDailyLendRate:         0.02,
+				SpreadLend:               30,
+				GapBottom:                10,
+				GapTop:                   5000,
+				HiddenOfferRandomPercent: 120,
+				LendingCheckMinutes:      10,
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid risk guard window",
+			config: Config{
+				BitfinexApiKey:      "test_api_key",
+				BitfinexSecretKey:   "test_secret_key",
+				Currency:            "USD",
+				MinLoan:             150.0,
+				MinDailyLendRate:    0.02,
+				SpreadLend:          30,
+				GapBottom:           10,
+				GapTop:              5000,
+				EnableRiskGuard:     true,
+				RiskWindowMinutes:   0,
+				LendingCheckMinutes: 10,
+			},
+			wantErr: true,
+		},
 		{
 			name: "invalid min daily rate string",
 			config: Config{
@@ -148,6 +182,76 @@ func TestConfig_Validate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "invalid smart trend estimator",
+			config: Config{
+				BitfinexApiKey:           "test_api_key",
+				BitfinexSecretKey:        "test_secret_key",
+				Currency:                 "USD",
+				MinLoan:                  150.0,
+				MinDailyLendRate:         0.02,
+				SpreadLend:               30,
+				GapBottom:                10,
+				GapTop:                   5000,
+				EnableSmartStrategy:      true,
+				VolatilityThreshold:      0.002,
+				MaxRateMultiplier:        2.0,
+				MinRateMultiplier:        0.8,
+				RateRangeIncreasePercent: 0.2,
+				SmartTrendEstimator:      "macd",
+				LendingCheckMinutes:      10,
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid allocation profile",
+			config: Config{
+				BitfinexApiKey:      "test_api_key",
+				BitfinexSecretKey:   "test_secret_key",
+				Currency:            "USD",
+				MinLoan:             150.0,
+				MinDailyLendRate:    0.02,
+				SpreadLend:          30,
+				GapBottom:           10,
+				GapTop:              5000,
+				AllocationProfile:   "pyramid",
+				LendingCheckMinutes: 10,
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid allocation ratio",
+			config: Config{
+				BitfinexApiKey:      "test_api_key",
+				BitfinexSecretKey:   "test_secret_key",
+				Currency:            "USD",
+				MinLoan:             150.0,
+				MinDailyLendRate:    0.02,
+				SpreadLend:          30,
+				GapBottom:           10,
+				GapTop:              5000,
+				AllocationProfile:   "geometric",
+				AllocationRatio:     1.5,
+				LendingCheckMinutes: 10,
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid orphan policy",
+			config: Config{
+				BitfinexApiKey:      "test_api_key",
+				BitfinexSecretKey:   "test_secret_key",
+				Currency:            "USD",
+				MinLoan:             150.0,
+				MinDailyLendRate:    0.02,
+				SpreadLend:          30,
+				GapBottom:           10,
+				GapTop:              5000,
+				OrphanPolicy:        "cancel_all",
+				LendingCheckMinutes: 10,
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -238,6 +342,273 @@ LENDING_CHECK_MINUTES: 10
 	}
 }
 
+func TestLoadConfigExplicitZero(t *testing.T) {
+	testConfigContent := `
+BITFINEX_API_KEY: "test_api_key"
+BITFINEX_SECRET_KEY: "test_secret_key"
+CURRENCY: "USD"
+MIN_LOAN: 150.0
+MIN_DAILY_LEND_RATE: 0.02
+SPREAD_LEND: 30
+GAP_BOTTOM: 10
+GAP_TOP: 5000
+LENDING_CHECK_MINUTES: 10
+FUNDING_FEE_PERCENT: 0
+HIDDEN_FUNDING_FEE_PERCENT: 0
+ENABLE_SEASONALITY: true
+SEASONALITY_LEAD_HOURS: 0
+ENABLE_MATURITY_PLANNER: true
+MATURITY_PERIOD_FLEX: 0
+`
+
+	tmpFile, err := os.CreateTemp("", "test_config_zero_*.yaml")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := tmpFile.WriteString(testConfigContent); err != nil {
+		t.Fatalf("Failed to write to temp file: %v", err)
+	}
+	tmpFile.Close()
+
+	config, err := LoadConfig(tmpFile.Name())
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	// 明確設為 0 的參數不被預設值覆蓋
+	if fees := config.GetFeeModel(); fees.Rate != 0 || fees.HiddenRate != 0 {
+		t.Errorf("zero fee tier should be kept, got %+v", fees)
+	}
+	if lead := config.GetSeasonalityLeadHours(); lead != 0 {
+		t.Errorf("SEASONALITY_LEAD_HOURS = %d, want 0", lead)
+	}
+	if flex := config.GetMaturityPeriodFlex(); flex != 0 {
+		t.Errorf("MATURITY_PERIOD_FLEX = %v, want 0", flex)
+	}
+
+	// 未設定時使用預設值；以 Set 覆寫為 0 同樣保留
+	defaults := &Config{}
+	defaults.ApplyDefaults()
+	if defaults.GetFundingFeePercent() != 15 || defaults.GetHiddenFundingFeePercent() != 18 {
+		t.Errorf("default fees = %v/%v, want 15/18", defaults.GetFundingFeePercent(), defaults.GetHiddenFundingFeePercent())
+	}
+	if lead := defaults.GetSeasonalityLeadHours(); lead != 1 {
+		t.Errorf("default SEASONALITY_LEAD_HOURS = %d, want 1", lead)
+	}
+	if flex := defaults.GetMaturityPeriodFlex(); flex != 0.5 {
+		t.Errorf("default MATURITY_PERIOD_FLEX = %v, want 0.5", flex)
+	}
+	if err := defaults.Set("FUNDING_FEE_PERCENT", "0"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	defaults.ApplyDefaults()
+	if defaults.GetFundingFeePercent() != 0 {
+		t.Errorf("FUNDING_FEE_PERCENT after Set(0) = %v, want 0", defaults.GetFundingFeePercent())
+	}
+}
+
+func TestLoadConfigWithCurrencies(t *testing.T) {
+	testConfigContent := `
+BITFINEX_API_KEY: "test_api_key"
+BITFINEX_SECRET_KEY: "test_secret_key"
+MIN_LOAN: 150.0
+MIN_DAILY_LEND_RATE: 0.02
+SPREAD_LEND: 30
+GAP_BOTTOM: 10
+GAP_TOP: 5000
+LENDING_CHECK_MINUTES: 10
+CURRENCY: "USD"
+CURRENCIES:
+  ust:
+    MIN_LOAN: 200
+  btc:
+    MIN_LOAN: 0.005
+    MAX_LOAN: 0.5
+    GAP_TOP: 20
+    MINUTES_RUN: 30
+    ENABLE_KLINE_STRATEGY: true
+`
+
+	tmpFile, err := os.CreateTemp("", "test_config_currencies_*.yaml")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := tmpFile.WriteString(testConfigContent); err != nil {
+		t.Fatalf("Failed to write to temp file: %v", err)
+	}
+	tmpFile.Close()
+
+	config, err := LoadConfig(tmpFile.Name())
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	configs, err := config.CurrencyConfigs()
+	if err != nil {
+		t.Fatalf("CurrencyConfigs() error = %v", err)
+	}
+	if len(configs) != 3 || configs[0].Currency != "USD" || configs[1].Currency != "BTC" || configs[2].Currency != "UST" {
+		t.Fatalf("CurrencyConfigs() currencies = %v, want [USD BTC UST]", config.GetCurrencies())
+	}
+
+	btc := configs[1]
+	if btc.MinLoan != 0.005 || btc.MaxLoan != 0.5 || btc.GapTop != 20 || btc.MinutesRun != 30 {
+		t.Errorf("BTC overrides not applied: %+v", btc)
+	}
+	if !btc.EnableKlineStrategy || btc.KlineTimeFrame == "" {
+		t.Error("BTC should enable the kline strategy with defaults")
+	}
+	if btc.GetFundingSymbol() != "fBTC" || btc.Currencies != nil {
+		t.Errorf("BTC config symbol = %s, currencies = %v", btc.GetFundingSymbol(), btc.Currencies)
+	}
+
+	usd := configs[0]
+	if usd.MinLoan != 150 || usd.GapTop != 5000 || usd.EnableKlineStrategy {
+		t.Errorf("USD should keep the base settings: %+v", usd)
+	}
+	if configs[2].MinLoan != 200 {
+		t.Errorf("UST MinLoan = %v, want 200", configs[2].MinLoan)
+	}
+}
+
+func TestCurrencyConfigs_Invalid(t *testing.T) {
+	base := Config{
+		BitfinexApiKey:      "test_api_key",
+		BitfinexSecretKey:   "test_secret_key",
+		MinLoan:             150.0,
+		MinDailyLendRate:    0.02,
+		SpreadLend:          30,
+		GapBottom:           10,
+		GapTop:              5000,
+		LendingCheckMinutes: 10,
+	}
+
+	shared := base
+	shared.Currency = "USD"
+	shared.Currencies = map[string]map[string]interface{}{"usd": {"bitfinex_api_key": "other"}}
+	if _, err := shared.CurrencyConfigs(); err == nil {
+		t.Error("overriding an account-level key should fail")
+	}
+
+	sharedState := base
+	sharedState.Currency = "USD"
+	sharedState.OrderTrackerStateFile = "orders.json"
+	sharedState.Currencies = map[string]map[string]interface{}{"ust": {}}
+	if _, err := sharedState.CurrencyConfigs(); err == nil {
+		t.Error("a top-level state file shared by all currencies should fail")
+	}
+
+	samePath := base
+	samePath.Currency = "USD"
+	samePath.Currencies = map[string]map[string]interface{}{
+		"usd": {"rate_bonus_state_file": "bonus.json"},
+		"ust": {"rate_bonus_state_file": "bonus.json"},
+	}
+	if _, err := samePath.CurrencyConfigs(); err == nil {
+		t.Error("two currencies writing the same state file should fail")
+	}
+
+	separate := base
+	separate.Currency = "USD"
+	separate.Currencies = map[string]map[string]interface{}{"ust": {"order_tracker_state_file": "ust_orders.json"}}
+	separate.ApplyDefaults()
+	configs, err := separate.CurrencyConfigs()
+	if err != nil {
+		t.Fatalf("CurrencyConfigs() error = %v", err)
+	}
+	paths := map[string]bool{}
+	for _, cfg := range configs {
+		for _, path := range []string{cfg.GetAnalyzerStatePath(), cfg.GetRateBonusStatePath(), cfg.GetOrderTrackerStatePath()} {
+			if paths[path] {
+				t.Errorf("state file %s is shared between currencies", path)
+			}
+			paths[path] = true
+		}
+	}
+	if configs[1].GetOrderTrackerStatePath() != "ust_orders.json" {
+		t.Errorf("UST order tracker path = %s, want ust_orders.json", configs[1].GetOrderTrackerStatePath())
+	}
+
+	invalid := base
+	invalid.Currencies = map[string]map[string]interface{}{"btc": {"gap_top": 5}}
+	invalid.ApplyDefaults()
+	if err := invalid.Validate(); err == nil {
+		t.Error("Validate() should reject a currency whose overrides are invalid")
+	}
+}
+
+func TestLoadConfigWithPeriodExposureLimits(t *testing.T) {
+	testConfigContent := `
+BITFINEX_API_KEY: "test_api_key"
+BITFINEX_SECRET_KEY: "test_secret_key"
+CURRENCY: "USD"
+MIN_LOAN: 150.0
+MIN_DAILY_LEND_RATE: 0.02
+SPREAD_LEND: 30
+GAP_BOTTOM: 10
+GAP_TOP: 5000
+PERIOD_EXPOSURE_LIMITS:
+  60: 20
+  30: 40
+`
+
+	tmpFile, err := os.CreateTemp("", "test_config_period_limits_*.yaml")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := tmpFile.WriteString(testConfigContent); err != nil {
+		t.Fatalf("Failed to write to temp file: %v", err)
+	}
+	tmpFile.Close()
+
+	config, err := LoadConfig(tmpFile.Name())
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	expected := []PeriodExposureLimit{{AboveDays: 30, MaxPercent: 40}, {AboveDays: 60, MaxPercent: 20}}
+	if limits := config.GetPeriodExposureLimits(); !reflect.DeepEqual(limits, expected) {
+		t.Errorf("GetPeriodExposureLimits() = %v, want %v", limits, expected)
+	}
+
+	config.PeriodExposureLimits["120"] = 10
+	if err := config.Validate(); err == nil {
+		t.Error("Validate() should reject a period limit at or above 120 days")
+	}
+}
+
+func TestGetKlineWindows(t *testing.T) {
+	config := &Config{KlineTimeFrame: "15m", KlinePeriod: 24, KlineSmoothMethod: "ema"}
+	expected := []KlineWindow{{TimeFrame: "15m", Period: 24, Weight: 1, SmoothMethod: "ema"}}
+	if windows := config.GetKlineWindows(); !reflect.DeepEqual(windows, expected) {
+		t.Errorf("GetKlineWindows() without KLINE_WINDOWS = %v, want %v", windows, expected)
+	}
+
+	if err := config.Set("KLINE_WINDOWS", "5m:12:1:max, 1h:24:2,1D:7"); err != nil {
+		t.Fatalf("Set(KLINE_WINDOWS) error = %v", err)
+	}
+	expected = []KlineWindow{
+		{TimeFrame: "5m", Period: 12, Weight: 1, SmoothMethod: "max"},
+		{TimeFrame: "1h", Period: 24, Weight: 2, SmoothMethod: "ema"},
+		{TimeFrame: "1D", Period: 7, Weight: 1, SmoothMethod: "ema"},
+	}
+	if windows := config.GetKlineWindows(); !reflect.DeepEqual(windows, expected) {
+		t.Errorf("GetKlineWindows() = %v, want %v", windows, expected)
+	}
+
+	for _, text := range []string{"5m", "5m:0", "5m:12:-1", "5m:12:1:avg", ":12"} {
+		if _, err := ParseKlineWindow(text); err == nil {
+			t.Errorf("ParseKlineWindow(%q) should fail", text)
+		}
+	}
+}
+
 func TestGetFundingSymbol(t *testing.T) {
 	config := &Config{Currency: "USD"}
 	expected := "fUSD"
@@ -267,3 +638,35 @@ func TestGetMinDailyRateDecimal_FRR(t *testing.T) {
 		t.Errorf("Expected IsMinDailyLendRateFRR() to be true")
 	}
 }
+
+func TestConfig_Set(t *testing.T) {
+	config := &Config{}
+
+	overrides, err := ParseOverrides("GAP_TOP=5000; kline_smooth_method=p90;ENABLE_KLINE_STRATEGY=true;MIN_DAILY_LEND_RATE=FRR;MATURITY_TARGET_WEIGHTS=1,2,3;STRATEGY_WEIGHTS=Kline:50,smart:30")
+	if err != nil {
+		t.Fatalf("ParseOverrides() error = %v", err)
+	}
+	if err := config.ApplyOverrides(overrides); err != nil {
+		t.Fatalf("ApplyOverrides() error = %v", err)
+	}
+
+	if config.GapTop != 5000 || config.KlineSmoothMethod != "p90" || !config.EnableKlineStrategy {
+		t.Errorf("unexpected config after overrides: %+v", config)
+	}
+	if !config.IsMinDailyLendRateFRR() {
+		t.Errorf("Expected MIN_DAILY_LEND_RATE override to enable FRR mode")
+	}
+	if len(config.MaturityTargetWeights) != 3 || config.MaturityTargetWeights[2] != 3 {
+		t.Errorf("Expected MATURITY_TARGET_WEIGHTS [1 2 3], got %v", config.MaturityTargetWeights)
+	}
+	if len(config.StrategyWeights) != 2 || config.StrategyWeights["kline"] != 50 || config.StrategyWeights["smart"] != 30 {
+		t.Errorf("Expected STRATEGY_WEIGHTS kline:50 smart:30, got %v", config.StrategyWeights)
+	}
+
+	if err := config.Set("UNKNOWN_KEY", "1"); err == nil {
+		t.Errorf("Expected error for unknown key")
+	}
+	if err := config.Set("GAP_TOP", "abc"); err == nil {
+		t.Errorf("Expected error for invalid number")
+	}
+}
@@ -0,0 +1,160 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/kfrico/BitfinexLendingBot/internal/errors"
+)
+
+// accountLevelKeys 帳戶層級的設定，所有幣種共用，不可在 CURRENCIES 中覆寫
+var accountLevelKeys = map[string]bool{
+	"BITFINEX_API_KEY":    true,
+	"BITFINEX_SECRET_KEY": true,
+	"TELEGRAM_BOT_TOKEN":  true,
+	"TELEGRAM_AUTH_TOKEN": true,
+	"CURRENCY":            true,
+	"CURRENCIES":          true,
+	"PAPER_TRADING":       true,
+	"DATA_DIR":            true,
+}
+
+// laneStateFiles 各幣種獨立的狀態檔設定，多幣種時不可在頂層共用同一路徑
+var laneStateFiles = []struct {
+	key  string
+	path func(*Config) string
+	set  func(*Config) string
+}{
+	{"ANALYZER_STATE_FILE", (*Config).GetAnalyzerStatePath, func(c *Config) string { return c.AnalyzerStateFile }},
+	{"RATE_BONUS_STATE_FILE", (*Config).GetRateBonusStatePath, func(c *Config) string { return c.RateBonusStateFile }},
+	{"ORDER_TRACKER_STATE_FILE", (*Config).GetOrderTrackerStatePath, func(c *Config) string { return c.OrderTrackerStateFile }},
+}
+
+// GetCurrencies 獲取要貸出的幣種：CURRENCY 在前，其餘為 CURRENCIES 中的幣種（依名稱排序）
+func (c *Config) GetCurrencies() []string {
+	main := strings.ToUpper(c.Currency)
+
+	var others []string
+	for name := range c.Currencies {
+		if currency := strings.ToUpper(name); currency != main {
+			others = append(others, currency)
+		}
+	}
+	sort.Strings(others)
+
+	if main == "" {
+		return others
+	}
+	return append([]string{main}, others...)
+}
+
+// IsMultiCurrency 是否同時貸出多個幣種
+func (c *Config) IsMultiCurrency() bool {
+	return len(c.GetCurrencies()) > 1
+}
+
+// CurrencyConfigs 依 CURRENCIES 產生各幣種的配置
+// 每個幣種以主配置為基礎，套用該幣種的覆寫設定後補齊預設值並驗證；未設定 CURRENCIES 時只返回自身
+func (c *Config) CurrencyConfigs() ([]*Config, error) {
+	if len(c.Currencies) == 0 {
+		return []*Config{c}, nil
+	}
+
+	// viper 會將鍵名轉為小寫，依大寫幣種名稱對應
+	overridesByCurrency := make(map[string]map[string]interface{}, len(c.Currencies))
+	for name, overrides := range c.Currencies {
+		overridesByCurrency[strings.ToUpper(name)] = overrides
+	}
+
+	// 狀態檔以幣種區分，頂層明確指定的路徑會被所有幣種共用而互相覆寫
+	if c.IsMultiCurrency() {
+		for _, file := range laneStateFiles {
+			if file.set(c) != "" {
+				return nil, errors.NewValidationError(fmt.Sprintf("%s is per-currency state and must be set under CURRENCIES when lending multiple currencies", file.key))
+			}
+		}
+	}
+
+	configs := make([]*Config, 0, len(overridesByCurrency))
+	for _, currency := range c.GetCurrencies() {
+		if currency == "" {
+			return nil, errors.NewValidationError("CURRENCIES contains an empty currency name")
+		}
+
+		currencyConfig := c.Clone()
+		currencyConfig.Currencies = nil
+		currencyConfig.Currency = currency
+
+		for key, value := range overridesByCurrency[currency] {
+			key = strings.ToUpper(key)
+			if accountLevelKeys[key] {
+				return nil, errors.NewValidationError(fmt.Sprintf("CURRENCIES.%s: %s is shared by all currencies and cannot be overridden", currency, key))
+			}
+			if err := currencyConfig.Set(key, formatOverrideValue(value)); err != nil {
+				return nil, errors.NewValidationError(fmt.Sprintf("CURRENCIES.%s: %v", currency, err))
+			}
+		}
+
+		currencyConfig.ApplyDefaults()
+		if err := currencyConfig.Validate(); err != nil {
+			return nil, errors.NewValidationError(fmt.Sprintf("CURRENCIES.%s: %v", currency, err))
+		}
+		configs = append(configs, currencyConfig)
+	}
+
+	for _, file := range laneStateFiles {
+		owners := make(map[string]string, len(configs))
+		for _, currencyConfig := range configs {
+			path := filepath.Clean(file.path(currencyConfig))
+			if owner, exists := owners[path]; exists {
+				return nil, errors.NewValidationError(fmt.Sprintf("CURRENCIES.%s: %s %s is already used by %s", currencyConfig.Currency, file.key, path, owner))
+			}
+			owners[path] = currencyConfig.Currency
+		}
+	}
+
+	return configs, nil
+}
+
+// formatOverrideValue 將 YAML 解析出的值轉為 Set 接受的字串格式
+// 列表轉為 a,b,c；對照表轉為 name:value,name:value
+func formatOverrideValue(value interface{}) string {
+	switch v := value.(type) {
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			items = append(items, fmt.Sprint(item))
+		}
+		return strings.Join(items, ",")
+	case map[string]interface{}:
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		items := make([]string, 0, len(v))
+		for _, name := range names {
+			items = append(items, fmt.Sprintf("%s:%v", name, v[name]))
+		}
+		return strings.Join(items, ",")
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(v))
+		for name, item := range v {
+			converted[fmt.Sprint(name)] = item
+		}
+		return formatOverrideValue(converted)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// setCurrencyDefaults 設置多幣種的預設值
+// 設定 CURRENCIES 但未設定 CURRENCY 時，以排序後第一個幣種作為主配置的幣種（回測等單幣種指令使用）
+func (c *Config) setCurrencyDefaults() {
+	if c.Currency == "" && len(c.Currencies) > 0 {
+		c.Currency = c.GetCurrencies()[0]
+	}
+	c.Currency = strings.ToUpper(c.Currency)
+}
@@ -0,0 +1,180 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/kfrico/BitfinexLendingBot/internal/errors"
+)
+
+// Clone 複製配置（切片欄位一併複製，避免多個回測互相影響）
+func (c *Config) Clone() *Config {
+	clone := *c
+	if c.MaturityTargetWeights != nil {
+		clone.MaturityTargetWeights = append([]float64(nil), c.MaturityTargetWeights...)
+	}
+	if c.KlineWindows != nil {
+		clone.KlineWindows = append([]string(nil), c.KlineWindows...)
+	}
+	if c.StrategyWeights != nil {
+		clone.StrategyWeights = make(map[string]float64, len(c.StrategyWeights))
+		for name, weight := range c.StrategyWeights {
+			clone.StrategyWeights[name] = weight
+		}
+	}
+	if c.PeriodExposureLimits != nil {
+		clone.PeriodExposureLimits = make(map[string]float64, len(c.PeriodExposureLimits))
+		for days, percent := range c.PeriodExposureLimits {
+			clone.PeriodExposureLimits[days] = percent
+		}
+	}
+	if c.Currencies != nil {
+		clone.Currencies = make(map[string]map[string]interface{}, len(c.Currencies))
+		for currency, overrides := range c.Currencies {
+			clone.Currencies[currency] = overrides
+		}
+	}
+	return &clone
+}
+
+// Set 依配置鍵名（mapstructure 標籤，如 GAP_BOTTOM）設置配置值
+func (c *Config) Set(key string, value string) error {
+	key = strings.ToUpper(strings.TrimSpace(key))
+	value = strings.TrimSpace(value)
+
+	rv := reflect.ValueOf(c).Elem()
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		if rt.Field(i).Tag.Get("mapstructure") != key {
+			continue
+		}
+		if err := setFieldValue(rv.Field(i), value); err != nil {
+			return errors.NewValidationError(fmt.Sprintf("invalid value %q for %s: %v", value, key, err))
+		}
+		return nil
+	}
+
+	return errors.NewValidationError(fmt.Sprintf("unknown config key: %s", key))
+}
+
+// ApplyOverrides 依序套用多個 KEY=VALUE 設定
+func (c *Config) ApplyOverrides(overrides map[string]string) error {
+	for key, value := range overrides {
+		if err := c.Set(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParseOverrides 解析 "KEY=VALUE;KEY2=VALUE2" 格式的設定字串
+func ParseOverrides(text string) (map[string]string, error) {
+	overrides := make(map[string]string)
+	for _, part := range strings.Split(text, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			return nil, errors.NewValidationError(fmt.Sprintf("invalid override %q, expected KEY=VALUE", part))
+		}
+		overrides[strings.ToUpper(strings.TrimSpace(kv[0]))] = strings.TrimSpace(kv[1])
+	}
+	return overrides, nil
+}
+
+// setFieldValue 依欄位型別解析並設置值
+func setFieldValue(field reflect.Value, value string) error {
+	switch field.Kind() {
+	case reflect.Ptr:
+		// 可明確設為零值的選填參數，設置新的值而不修改共用的指標
+		target := reflect.New(field.Type().Elem())
+		if err := setFieldValue(target.Elem(), value); err != nil {
+			return err
+		}
+		field.Set(target)
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		v, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(v)
+	case reflect.Int, reflect.Int64:
+		v, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		field.SetInt(v)
+	case reflect.Float64:
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(v)
+	case reflect.Slice:
+		if field.Type().Elem().Kind() == reflect.String {
+			// KLINE_WINDOWS 等字串列表，格式為 a,b,c
+			values := make([]string, 0)
+			for _, item := range strings.Split(value, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					values = append(values, item)
+				}
+			}
+			field.Set(reflect.ValueOf(values))
+			return nil
+		}
+		if field.Type().Elem().Kind() != reflect.Float64 {
+			return fmt.Errorf("unsupported slice type %s", field.Type())
+		}
+		values := make([]float64, 0)
+		for _, item := range strings.Split(value, ",") {
+			item = strings.TrimSpace(item)
+			if item == "" {
+				continue
+			}
+			v, err := strconv.ParseFloat(item, 64)
+			if err != nil {
+				return err
+			}
+			values = append(values, v)
+		}
+		field.Set(reflect.ValueOf(values))
+	case reflect.Map:
+		// STRATEGY_WEIGHTS 等權重表，格式為 name:value,name:value
+		if field.Type().Key().Kind() != reflect.String || field.Type().Elem().Kind() != reflect.Float64 {
+			return fmt.Errorf("unsupported map type %s", field.Type())
+		}
+		values := make(map[string]float64)
+		for _, item := range strings.Split(value, ",") {
+			item = strings.TrimSpace(item)
+			if item == "" {
+				continue
+			}
+			name, raw, ok := strings.Cut(item, ":")
+			if !ok {
+				return fmt.Errorf("expected name:value, got %q", item)
+			}
+			v, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+			if err != nil {
+				return err
+			}
+			values[strings.ToLower(strings.TrimSpace(name))] = v
+		}
+		field.Set(reflect.ValueOf(values))
+	case reflect.Interface:
+		// MIN_DAILY_LEND_RATE 等可為數值或字串的欄位
+		if v, err := strconv.ParseFloat(value, 64); err == nil {
+			field.Set(reflect.ValueOf(v))
+		} else {
+			field.Set(reflect.ValueOf(value))
+		}
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+	return nil
+}
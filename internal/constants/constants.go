@@ -21,6 +21,73 @@ const (
 	MinDailyRateModeFRR = "FRR"
 )
 
+// 深度模式常量
+const (
+	GapModeIndex  = "index"  // GAP_BOTTOM/GAP_TOP 為訂單簿檔位索引
+	GapModeVolume = "volume" // GAP_BOTTOM/GAP_TOP 為前方累計掛單金額
+)
+
+// 資金分配方式常量
+const (
+	AllocationProfileEqual      = "equal"      // 平均分配
+	AllocationProfileGeometric  = "geometric"  // 等比遞減，低利率（較易成交）分配較多
+	AllocationProfileInverse    = "inverse"    // 等比遞增，高利率分配較多
+	AllocationProfileBook       = "book"       // 依訂單簿相鄰 ask 檔位間距分配
+	AllocationProfileAbsorption = "absorption" // 依各利率區間一個週期內可吸收的金額分配
+	DefaultAllocationRatio      = 0.7          // 等比分配相鄰兩筆的金額比例
+)
+
+// 資金使用率控制相關常量
+const (
+	DefaultUtilizationTargetPct    = 90.0 // 目標借出比例 90%
+	DefaultIdleGraceMinutes        = 30   // 閒置資金寬限時間（分鐘）
+	DefaultUtilizationWindowHours  = 24   // 平均使用率時間窗口（小時）
+	DefaultUtilizationFloorStepPct = 5.0  // 每個窗口調整最低日利率 5%
+	IdleSweepMinReduction          = 0.01 // 觸發掛單後閒置資金減少不足 1% 視為無效
+	MaxIdleSweepBackoff            = 16   // 無效觸發後寬限時間最多延長為 16 倍
+)
+
+// 訂單歸屬策略（未被程式追蹤的掛單）
+const (
+	OrphanPolicyManual    = "manual"    // 視為手動掛單，不取消
+	OrphanPolicyAdoptAll  = "adopt_all" // 啟動時接管所有掛單
+	OrphanPolicySignature = "signature" // 啟動時接管符合程式金額與利率特徵的掛單
+)
+
+// 訂單追蹤記錄相關常量
+const (
+	OrderHistorySize      = 500                // 保留的已結束訂單記錄數
+	OrderHistoryRetention = 7 * 24 * time.Hour // 已結束訂單記錄保留時間
+	CreditMatchSlack      = time.Minute        // 比對借貸開始時間與掛單建立時間的容許誤差
+)
+
+// 訂單簿吸收量模型相關常量
+const (
+	MaxFundingTradesLimit     = 1000             // 每次查詢公開成交的最大筆數
+	AbsorptionTradeLookback   = 6 * time.Hour    // 計算成交流量使用的公開成交時間範圍
+	AbsorptionFillHistorySize = 200              // 保留的自有成交速度樣本數
+	AbsorptionMaxOrders       = 50               // ORDER_LIMIT 不限制時，依吸收量加掛後的分散單筆數上限
+	AbsorptionMinFillHours    = 1.0 / 60         // 成交時間下限（小時），避免速度無限大
+	AbsorptionMinTradeWindow  = 10 * time.Minute // 公開成交涵蓋時間下限，避免少量成交換算出過大的流量
+)
+
+// 手續費相關常量
+const (
+	FundingFeeRate       = 0.15    // 一般放貸利息手續費 15%
+	HiddenFundingFeeRate = 0.18    // 隱藏掛單成交的利息手續費 18%
+	RateThresholdGross   = "gross" // 30/120 天閾值以毛利率比較
+	RateThresholdNet     = "net"   // 30/120 天閾值以扣除手續費後的淨利率比較
+)
+
+// 幣種金額規格
+const (
+	MinOfferUSD            = 150.0            // Bitfinex 資金掛單最低金額（美元等值）
+	DefaultAmountPrecision = 2                // 依美元價格計算時的最少金額小數位數
+	MaxAmountPrecision     = 8                // Bitfinex 金額最多 8 位小數
+	CurrencyPriceCacheTTL  = 15 * time.Minute // 幣種美元價格快取時間
+	QuoteCurrencyUSD       = "USD"
+)
+
 // 默認配置值
 const (
 	DefaultPriceLevels = 25
@@ -61,6 +128,141 @@ const (
 	RecommendedMinRateMax    = 0.9   // 保守用戶建議值
 )
 
+// 智能策略趨勢與波動率估計器
+const (
+	TrendEstimatorCount       = "count"      // 最近數點的漲跌次數（預設）
+	TrendEstimatorRegression  = "regression" // 線性迴歸斜率與顯著性
+	TrendEstimatorEMA         = "ema"        // 快慢 EMA 交叉
+	VolatilityEstimatorStdDev = "stddev"     // 利率母體標準差（預設）
+	VolatilityEstimatorEWMA   = "ewma"       // 利率變動的指數加權波動率
+	VolatilityEstimatorATR    = "atr"        // K線高低點的平均真實區間
+
+	TrendCountWindow        = 6      // 漲跌次數使用最近 6 點
+	TrendCountThreshold     = 0.0001 // 視為漲跌的最小變化
+	TrendCountMinMoves      = 4      // 至少 4 次同向變化才判定趨勢
+	TrendRegressionWindow   = 12     // 迴歸使用最近 12 點
+	TrendRegressionMinTStat = 2.0    // 斜率 t 值絕對值達此值才視為顯著
+	TrendEMAFastSpan        = 6      // 快速 EMA 點數
+	TrendEMASlowSpan        = 24     // 慢速 EMA 點數
+	TrendEMACrossPercent    = 2.0    // 快線高於/低於慢線此百分比才判定趨勢
+	VolatilityEWMALambda    = 0.94   // EWMA 衰減係數
+	VolatilityATRPeriod     = 14     // ATR 平均的區間數
+)
+
+// 到期分散規劃預設值
+const (
+	MaturityHorizonDays       = 120 // 到期分布涵蓋天數
+	DefaultMaturityBucketDays = 7   // 預設每週一個分桶
+	DefaultMaturityPeriodFlex = 0.5 // 期間可在原期間 ±50% 內調整
+)
+
+// 風險控管預設值
+const (
+	MaxFundingDailyRate             = 0.07 // Bitfinex 允許的最高日利率 7%
+	DefaultRiskMaxRateFRRMultiplier = 5.0  // 訂單利率不得超過 FRR 的倍數
+	DefaultRiskCandleBandPercent    = 50.0 // K線高低點外允許的偏離百分比
+	DefaultRiskWindowMinutes        = 60   // 下單限額時間窗口
+	RiskCandleTimeFrame             = "1h" // 風險檢查使用的K線時間框架
+	RiskCandleLimit                 = 24   // 風險檢查使用的K線數量（近24小時）
+)
+
+// 季節性模型預設值
+const (
+	DefaultSeasonalityLookbackDays = 28   // 學習最近四週的小時K線
+	DefaultSeasonalityMaxAdjustPct = 20.0 // 季節性係數最多調整 ±20%
+	DefaultSeasonalityLeadHours    = 1    // 提前反應的小時數
+	DefaultSeasonalityRefreshHours = 24   // 重新學習間隔
+	SeasonalityTimeFrame           = "1h" // 季節性模型使用的K線時間框架
+	SeasonalityBaselineCandles     = 24   // 計算溢價的基準（前24小時平均收盤利率）
+	SeasonalityMinSamples          = 2    // 每個時段至少需要的樣本數
+	SeasonalityMinStoredCoverage   = 0.8  // 已保存的K線需涵蓋回看區間的比例，不足時改用即時K線
+	HoursPerWeek                   = 7 * 24
+)
+
+// 利率飆升狙擊預設值
+const (
+	DefaultSpikePollSeconds      = 10   // 訂單簿輪詢間隔（秒）
+	DefaultSpikeEMAPeriod        = 30   // 最佳 ask 利率 EMA 的樣本數
+	DefaultSpikeThresholdPercent = 30.0 // 最佳 ask 高於 EMA 多少百分比視為飆升
+	DefaultSpikeOrders           = 1    // 飆升資金拆分筆數
+	DefaultSpikePeriodDays       = 120  // 飆升掛單期間
+	DefaultSpikeCooldownMinutes  = 30   // 兩次觸發之間的冷卻時間
+	DefaultSpikeOfferTTLMinutes  = 30   // 飆升掛單保留時間，逾時才由主策略取消
+	SpikeBookDepth               = 25   // 輪詢訂單簿的檔位數
+)
+
+// 再融資預設值
+const (
+	RefinanceModeConfirm               = "confirm" // 透過 Telegram 提議，確認後才關閉
+	RefinanceModeAuto                  = "auto"    // 自動關閉並通知
+	DefaultRefinanceMinImprovementPct  = 50.0      // 市場利率至少高於原利率 50%
+	DefaultRefinanceMinRemainingDays   = 2.0       // 剩餘天數不足時不值得關閉
+	DefaultRefinanceRefillHours        = 1.0       // 預估關閉後重新貸出所需時間
+	DefaultRefinanceMaxClosesPerDay    = 3
+	DefaultRefinanceMinIntervalMinutes = 60
+	RefinanceProposalTTL               = 30 * time.Minute // 提議有效時間
+	RefinanceCandleTimeFrame           = "1h"             // 參考市場利率使用的K線
+)
+
+// 自適應利率加成預設值
+const (
+	DefaultRateBonusTargetFillPct = 70.0  // 目標成交比例 70%
+	DefaultRateBonusGain          = 0.002 // 成交比例偏離目標 100% 時每次調整 0.002%
+	DefaultRateBonusMax           = 0.01  // 加成上限 0.01%
+	DefaultRateBonusSmoothing     = 0.3   // 成交比例 EMA 平滑係數
+	RateBonusHistorySize          = 96    // 保留的加成調整記錄數
+)
+
+// K線多時間框架合併方式
+const (
+	KlineAggregationWeighted = "weighted" // 依權重加權平均
+	KlineAggregationMin      = "min"      // 取最低（保守）
+	KlineAggregationMedian   = "median"   // 取中位數（排除單一視窗極值）
+
+	KlineBollingerStdDevs = 2.0 // 布林通道上軌使用的標準差倍數
+)
+
+// 策略名稱
+const (
+	StrategyTraditional = "traditional"
+	StrategySmart       = "smart"
+	StrategyKline       = "kline"
+	StrategyBlend       = "blend"    // 依 STRATEGY_WEIGHTS 同時使用多個策略
+	StrategyHighHold    = "highhold" // 高額持有單（組合模式中不屬於任何策略）
+	StrategySpike       = "spike"    // 利率飆升狙擊掛單
+	StrategyAdopted     = "adopted"  // 啟動或手動接管的既有掛單
+
+	DefaultBlendDedupePercent = 1.0 // 組合模式中利率相差 1% 內的訂單合併
+)
+
+// 歷史數據與回測
+const (
+	DefaultDataDir           = "data"
+	DefaultBacktestTimeFrame = "15m"
+	DefaultBacktestBalance   = 10000.0
+	FillModelTouch           = "touch"        // K線高點觸及訂單利率即成交
+	FillModelClose           = "close"        // K線收盤利率達到訂單利率才成交
+	MaxCandlesPerRequest     = 10000          // Bitfinex K線 API 單次最多返回筆數
+	DateLayout               = "2006-01-02"   // 日期參數格式
+	PaperMarketLive          = "live"         // 模擬交易使用即時公開數據
+	PaperMarketReplay        = "replay"       // 模擬交易回放歷史數據
+	PaperFillTimeFrame       = "1m"           // 即時模擬交易撮合使用的K線時間框架
+	PaperAdvanceInterval     = time.Minute    // 模擬交易撮合週期
+	BacktestWarmup           = 24 * time.Hour // 回測開始前保留給策略計算指標的歷史區間
+)
+
+// 參數最佳化
+const (
+	SearchModeGrid         = "grid"                 // 窮舉所有參數組合
+	SearchModeRandom       = "random"               // 隨機抽樣參數組合
+	ObjectiveAPR           = "apr"                  // 最大化年化報酬率
+	ObjectiveAPRPerIdle    = "apr_per_idle"         // 最大化每單位閒置時間的報酬
+	ObjectiveUtilDrawdown  = "utilization_drawdown" // 最小化資金利用率回落
+	DefaultOptimizeSamples = 50
+	DefaultOptimizeTop     = 10
+	MaxGridCombinations    = 5000 // 超過此數量請改用隨機搜尋
+)
+
 // 顯示和處理限制
 const (
 	MaxDisplayOrders         = 5    // 最多顯示的訂單數量
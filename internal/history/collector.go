@@ -0,0 +1,74 @@
+package history
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/kfrico/BitfinexLendingBot/internal/bitfinex"
+	"github.com/kfrico/BitfinexLendingBot/internal/constants"
+)
+
+// MarketDataAPI 收集歷史數據需要的公開市場接口
+type MarketDataAPI interface {
+	GetFundingBook(symbol string, limit int) ([]*bitfinex.FundingBookEntry, error)
+	GetCurrentFundingRate(symbol string) (float64, error)
+	GetFundingCandles(symbol string, timeFrame string, limit int) ([]*bitfinex.Candle, error)
+}
+
+// Collector 從交易所收集K線與訂單簿快照並寫入儲存
+type Collector struct {
+	api   MarketDataAPI
+	store *Store
+}
+
+// NewCollector 創建歷史數據收集器
+func NewCollector(api MarketDataAPI, store *Store) *Collector {
+	return &Collector{api: api, store: store}
+}
+
+// CollectCandles 下載最近的K線並合併至儲存，返回保存後的總筆數
+func (c *Collector) CollectCandles(symbol, timeFrame string) (int, error) {
+	if _, err := TimeFrameDuration(timeFrame); err != nil {
+		return 0, err
+	}
+
+	candles, err := c.api.GetFundingCandles(symbol, timeFrame, constants.MaxCandlesPerRequest)
+	if err != nil {
+		return 0, fmt.Errorf("下載 %s K線失敗: %w", timeFrame, err)
+	}
+
+	total, err := c.store.SaveCandles(symbol, timeFrame, candles)
+	if err != nil {
+		return 0, err
+	}
+
+	log.Printf("已保存 %s %s K線: 下載 %d 筆，累計 %d 筆", symbol, timeFrame, len(candles), total)
+	return total, nil
+}
+
+// SnapshotBook 保存一筆目前的訂單簿快照
+func (c *Collector) SnapshotBook(symbol string, now time.Time) error {
+	book, err := c.api.GetFundingBook(symbol, constants.MaxPriceLevels)
+	if err != nil {
+		return fmt.Errorf("下載訂單簿失敗: %w", err)
+	}
+
+	snapshot := &BookSnapshot{
+		MTS:     TimeToMTS(now),
+		Entries: book,
+	}
+
+	if frr, err := c.api.GetCurrentFundingRate(symbol); err != nil {
+		log.Printf("取得 FRR 失敗，快照不含 FRR: %v", err)
+	} else {
+		snapshot.FRR = frr
+	}
+
+	if err := c.store.AppendBookSnapshot(symbol, snapshot); err != nil {
+		return err
+	}
+
+	log.Printf("已保存 %s 訂單簿快照: %d 檔, FRR: %.6f%%", symbol, len(book), snapshot.FRR*100)
+	return nil
+}
@@ -0,0 +1,196 @@
+package history
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kfrico/BitfinexLendingBot/internal/bitfinex"
+)
+
+// 訂單簿快照超過此時間視為過期，改以K線合成訂單簿
+const maxBookSnapshotAge = 30 * time.Minute
+
+// 合成訂單簿使用的K線數量
+const syntheticBookCandles = 24
+
+// Replay 以儲存的歷史數據回放市場，只返回指定時間點之前已收盤的K線，避免未來數據
+type Replay struct {
+	store         *Store
+	symbol        string
+	baseTimeFrame string
+
+	mu      sync.Mutex
+	candles map[string][]*bitfinex.Candle // 依時間由舊到新
+	books   []*BookSnapshot
+}
+
+// NewReplay 創建歷史回放，baseTimeFrame 為撮合與合成訂單簿使用的K線
+func NewReplay(store *Store, symbol, baseTimeFrame string) (*Replay, error) {
+	r := &Replay{
+		store:         store,
+		symbol:        symbol,
+		baseTimeFrame: baseTimeFrame,
+		candles:       make(map[string][]*bitfinex.Candle),
+	}
+
+	base, err := r.loadCandles(baseTimeFrame)
+	if err != nil {
+		return nil, err
+	}
+	if len(base) == 0 {
+		return nil, fmt.Errorf("沒有 %s %s 的歷史K線，請先執行 collect", symbol, baseTimeFrame)
+	}
+
+	books, err := store.LoadBookSnapshots(symbol)
+	if err != nil {
+		return nil, err
+	}
+	r.books = books
+
+	return r, nil
+}
+
+// Range 返回基礎K線涵蓋的時間範圍
+func (r *Replay) Range() (time.Time, time.Time) {
+	r.mu.Lock()
+	base := r.candles[r.baseTimeFrame]
+	r.mu.Unlock()
+
+	duration, _ := TimeFrameDuration(r.baseTimeFrame)
+	return MTSToTime(base[0].MTS), MTSToTime(base[len(base)-1].MTS).Add(duration)
+}
+
+// BookSnapshotCount 返回可用的訂單簿快照數量
+func (r *Replay) BookSnapshotCount() int {
+	return len(r.books)
+}
+
+// loadCandles 讀取並快取指定時間框架的K線
+func (r *Replay) loadCandles(timeFrame string) ([]*bitfinex.Candle, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if candles, ok := r.candles[timeFrame]; ok {
+		return candles, nil
+	}
+
+	candles, err := r.store.LoadCandles(r.symbol, timeFrame)
+	if err != nil {
+		return nil, err
+	}
+	r.candles[timeFrame] = candles
+	return candles, nil
+}
+
+// Candles 返回指定時間點前已收盤的K線（由新到舊，與 API 相同）
+func (r *Replay) Candles(symbol, timeFrame string, limit int, at time.Time) ([]*bitfinex.Candle, error) {
+	if symbol != r.symbol {
+		return nil, fmt.Errorf("回放數據不包含 %s", symbol)
+	}
+
+	duration, err := TimeFrameDuration(timeFrame)
+	if err != nil {
+		return nil, err
+	}
+
+	candles, err := r.loadCandles(timeFrame)
+	if err != nil {
+		return nil, err
+	}
+	if len(candles) == 0 {
+		return nil, fmt.Errorf("沒有 %s %s 的歷史K線", symbol, timeFrame)
+	}
+
+	// 找出第一根尚未收盤的K線
+	cutoff := TimeToMTS(at.Add(-duration))
+	end := sort.Search(len(candles), func(i int) bool {
+		return candles[i].MTS > cutoff
+	})
+
+	result := make([]*bitfinex.Candle, 0, limit)
+	for i := end - 1; i >= 0 && len(result) < limit; i-- {
+		result = append(result, candles[i])
+	}
+
+	return result, nil
+}
+
+// FundingBook 返回指定時間點的訂單簿；沒有新鮮快照時以近期K線合成
+func (r *Replay) FundingBook(symbol string, limit int, at time.Time) ([]*bitfinex.FundingBookEntry, error) {
+	if snapshot := r.snapshotAt(at); snapshot != nil {
+		entries := snapshot.Entries
+		if limit > 0 && len(entries) > limit*2 {
+			entries = entries[:limit*2]
+		}
+		return entries, nil
+	}
+
+	candles, err := r.Candles(symbol, r.baseTimeFrame, syntheticBookCandles, at)
+	if err != nil {
+		return nil, err
+	}
+	return SyntheticBook(candles), nil
+}
+
+// FundingRate 返回指定時間點的 FRR；快照沒有 FRR 時以最近K線收盤利率代替
+func (r *Replay) FundingRate(symbol string, at time.Time) (float64, error) {
+	if snapshot := r.snapshotAt(at); snapshot != nil && snapshot.FRR > 0 {
+		return snapshot.FRR, nil
+	}
+
+	candles, err := r.Candles(symbol, r.baseTimeFrame, 1, at)
+	if err != nil {
+		return 0, err
+	}
+	if len(candles) == 0 {
+		return 0, fmt.Errorf("%s 之前沒有K線數據", at.Format(time.RFC3339))
+	}
+	return candles[0].Close, nil
+}
+
+// snapshotAt 返回指定時間點前最近且未過期的快照
+func (r *Replay) snapshotAt(at time.Time) *BookSnapshot {
+	atMTS := TimeToMTS(at)
+	idx := sort.Search(len(r.books), func(i int) bool {
+		return r.books[i].MTS > atMTS
+	}) - 1
+	if idx < 0 {
+		return nil
+	}
+
+	snapshot := r.books[idx]
+	if at.Sub(MTSToTime(snapshot.MTS)) > maxBookSnapshotAge {
+		return nil
+	}
+	return snapshot
+}
+
+// SyntheticBook 以K線的收盤與高點利率合成 ask 檔位、低點利率合成 bid 檔位
+// 用於沒有訂單簿快照的歷史區間；ask 依利率由低到高排列，與原始訂單簿相同
+func SyntheticBook(candles []*bitfinex.Candle) []*bitfinex.FundingBookEntry {
+	asks := make([]*bitfinex.FundingBookEntry, 0, len(candles)*2)
+	bids := make([]*bitfinex.FundingBookEntry, 0, len(candles))
+
+	for _, candle := range candles {
+		volume := candle.Volume
+		if volume <= 0 {
+			volume = 1
+		}
+		if candle.Close > 0 {
+			asks = append(asks, &bitfinex.FundingBookEntry{Rate: candle.Close, Amount: volume / 2, Period: 2, Count: 1})
+		}
+		if candle.High > 0 {
+			asks = append(asks, &bitfinex.FundingBookEntry{Rate: candle.High, Amount: volume / 2, Period: 2, Count: 1})
+		}
+		if candle.Low > 0 {
+			bids = append(bids, &bitfinex.FundingBookEntry{Rate: candle.Low, Amount: -volume, Period: 2, Count: 1})
+		}
+	}
+
+	sort.SliceStable(asks, func(i, j int) bool { return asks[i].Rate < asks[j].Rate })
+	sort.SliceStable(bids, func(i, j int) bool { return bids[i].Rate > bids[j].Rate })
+
+	return append(asks, bids...)
+}
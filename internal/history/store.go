@@ -0,0 +1,188 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/kfrico/BitfinexLendingBot/internal/bitfinex"
+)
+
+// Store 歷史市場數據儲存（以 JSON 檔案保存於 DATA_DIR）
+type Store struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// BookSnapshot funding book 快照
+type BookSnapshot struct {
+	MTS     int64                        `json:"mts"` // 快照時間戳（毫秒）
+	FRR     float64                      `json:"frr"` // 快照當下的 FRR，0 代表未取得
+	Entries []*bitfinex.FundingBookEntry `json:"entries"`
+}
+
+// NewStore 創建歷史數據儲存
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// symbolDir 返回幣種的數據目錄
+func (s *Store) symbolDir(symbol string) string {
+	return filepath.Join(s.dir, symbol)
+}
+
+// candlePath 返回K線檔案路徑
+func (s *Store) candlePath(symbol, timeFrame string) string {
+	return filepath.Join(s.symbolDir(symbol), fmt.Sprintf("candles_%s.json", timeFrame))
+}
+
+// bookPath 返回訂單簿快照檔案路徑
+func (s *Store) bookPath(symbol string) string {
+	return filepath.Join(s.symbolDir(symbol), "books.jsonl")
+}
+
+// SaveCandles 合併並保存K線（同一時間戳以新數據覆蓋），返回保存後的總筆數
+func (s *Store) SaveCandles(symbol, timeFrame string, candles []*bitfinex.Candle) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, err := s.loadCandlesLocked(symbol, timeFrame)
+	if err != nil {
+		return 0, err
+	}
+
+	byMTS := make(map[int64]*bitfinex.Candle, len(existing)+len(candles))
+	for _, candle := range existing {
+		byMTS[candle.MTS] = candle
+	}
+	for _, candle := range candles {
+		if candle != nil {
+			byMTS[candle.MTS] = candle
+		}
+	}
+
+	merged := make([]*bitfinex.Candle, 0, len(byMTS))
+	for _, candle := range byMTS {
+		merged = append(merged, candle)
+	}
+	sortCandles(merged)
+
+	if err := os.MkdirAll(s.symbolDir(symbol), 0o755); err != nil {
+		return 0, fmt.Errorf("建立數據目錄失敗: %w", err)
+	}
+
+	data, err := json.Marshal(merged)
+	if err != nil {
+		return 0, fmt.Errorf("序列化K線失敗: %w", err)
+	}
+
+	// 先寫入暫存檔再改名，避免中斷時損壞既有數據
+	path := s.candlePath(symbol, timeFrame)
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o644); err != nil {
+		return 0, fmt.Errorf("寫入K線失敗: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return 0, fmt.Errorf("保存K線失敗: %w", err)
+	}
+
+	return len(merged), nil
+}
+
+// LoadCandles 讀取K線（依時間由舊到新排序），沒有數據時返回空切片
+func (s *Store) LoadCandles(symbol, timeFrame string) ([]*bitfinex.Candle, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.loadCandlesLocked(symbol, timeFrame)
+}
+
+func (s *Store) loadCandlesLocked(symbol, timeFrame string) ([]*bitfinex.Candle, error) {
+	data, err := os.ReadFile(s.candlePath(symbol, timeFrame))
+	if os.IsNotExist(err) {
+		return []*bitfinex.Candle{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("讀取K線失敗: %w", err)
+	}
+
+	var candles []*bitfinex.Candle
+	if err := json.Unmarshal(data, &candles); err != nil {
+		return nil, fmt.Errorf("解析K線失敗: %w", err)
+	}
+	sortCandles(candles)
+
+	return candles, nil
+}
+
+// AppendBookSnapshot 追加一筆訂單簿快照
+func (s *Store) AppendBookSnapshot(symbol string, snapshot *BookSnapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(s.symbolDir(symbol), 0o755); err != nil {
+		return fmt.Errorf("建立數據目錄失敗: %w", err)
+	}
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("序列化訂單簿快照失敗: %w", err)
+	}
+
+	file, err := os.OpenFile(s.bookPath(symbol), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("開啟訂單簿快照檔案失敗: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("寫入訂單簿快照失敗: %w", err)
+	}
+
+	return nil
+}
+
+// LoadBookSnapshots 讀取訂單簿快照（依時間由舊到新排序），損壞的行會被略過
+func (s *Store) LoadBookSnapshots(symbol string) ([]*BookSnapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.Open(s.bookPath(symbol))
+	if os.IsNotExist(err) {
+		return []*BookSnapshot{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("讀取訂單簿快照失敗: %w", err)
+	}
+	defer file.Close()
+
+	snapshots := make([]*BookSnapshot, 0)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var snapshot BookSnapshot
+		if err := json.Unmarshal(scanner.Bytes(), &snapshot); err != nil {
+			continue
+		}
+		snapshots = append(snapshots, &snapshot)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("讀取訂單簿快照失敗: %w", err)
+	}
+
+	sort.SliceStable(snapshots, func(i, j int) bool {
+		return snapshots[i].MTS < snapshots[j].MTS
+	})
+
+	return snapshots, nil
+}
+
+// sortCandles 依時間由舊到新排序
+func sortCandles(candles []*bitfinex.Candle) {
+	sort.Slice(candles, func(i, j int) bool {
+		return candles[i].MTS < candles[j].MTS
+	})
+}
@@ -0,0 +1,40 @@
+package history
+
+import (
+	"fmt"
+	"time"
+)
+
+// timeFrameDurations Bitfinex K線時間框架對應的長度
+var timeFrameDurations = map[string]time.Duration{
+	"1m":  time.Minute,
+	"5m":  5 * time.Minute,
+	"15m": 15 * time.Minute,
+	"30m": 30 * time.Minute,
+	"1h":  time.Hour,
+	"3h":  3 * time.Hour,
+	"6h":  6 * time.Hour,
+	"12h": 12 * time.Hour,
+	"1D":  24 * time.Hour,
+	"1W":  7 * 24 * time.Hour,
+	"14D": 14 * 24 * time.Hour,
+}
+
+// TimeFrameDuration 返回K線時間框架的長度
+func TimeFrameDuration(timeFrame string) (time.Duration, error) {
+	duration, ok := timeFrameDurations[timeFrame]
+	if !ok {
+		return 0, fmt.Errorf("不支援的K線時間框架: %s", timeFrame)
+	}
+	return duration, nil
+}
+
+// MTSToTime 將毫秒時間戳轉換為時間
+func MTSToTime(mts int64) time.Time {
+	return time.Unix(0, mts*int64(time.Millisecond))
+}
+
+// TimeToMTS 將時間轉換為毫秒時間戳
+func TimeToMTS(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
@@ -0,0 +1,36 @@
+package optimizer
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/kfrico/BitfinexLendingBot/internal/backtest"
+	"github.com/kfrico/BitfinexLendingBot/internal/constants"
+)
+
+// 計算每單位閒置時間報酬時的閒置比例下限（%），避免幾乎無閒置時分數失真
+const minIdleSharePercent = 1.0
+
+// ValidateObjective 檢查最佳化目標名稱
+func ValidateObjective(name string) error {
+	switch name {
+	case constants.ObjectiveAPR, constants.ObjectiveAPRPerIdle, constants.ObjectiveUtilDrawdown:
+		return nil
+	default:
+		return fmt.Errorf("未知的最佳化目標: %s（可用: %s, %s, %s）", name,
+			constants.ObjectiveAPR, constants.ObjectiveAPRPerIdle, constants.ObjectiveUtilDrawdown)
+	}
+}
+
+// Score 依最佳化目標計算分數，分數越高越好
+func Score(objective string, m *backtest.Metrics) float64 {
+	switch objective {
+	case constants.ObjectiveAPRPerIdle:
+		return m.APR / math.Max(m.IdleShare, minIdleSharePercent)
+	case constants.ObjectiveUtilDrawdown:
+		// 回落越小越好；回落相同時以 APR 區分
+		return -m.UtilDrawdown + m.APR/1000
+	default:
+		return m.APR
+	}
+}
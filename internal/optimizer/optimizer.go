@@ -0,0 +1,187 @@
+package optimizer
+
+import (
+	"fmt"
+	"math/rand"
+	"runtime"
+	"sort"
+	"sync"
+
+	"github.com/kfrico/BitfinexLendingBot/internal/backtest"
+	"github.com/kfrico/BitfinexLendingBot/internal/constants"
+)
+
+// Options 參數最佳化設定
+type Options struct {
+	Strategy  string        // 要最佳化的策略
+	Ranges    []*ParamRange // 搜尋的參數範圍
+	Mode      string        // grid 或 random
+	Samples   int           // 隨機搜尋的抽樣次數
+	Objective string        // 排名使用的最佳化目標
+	Workers   int           // 平行回測數量，0 代表 CPU 數量
+	Backtest  backtest.Options
+
+	// Progress 每完成一組回測時呼叫（可為 nil）
+	Progress func(done, total int)
+}
+
+// Trial 一組參數的回測結果
+type Trial struct {
+	Params map[string]string
+	Result *backtest.Result
+	Score  float64
+}
+
+// Run 依搜尋模式產生參數組合，平行回測後依目標分數由高到低排序
+// 回測失敗（例如參數未通過配置驗證）的組合排在最後
+func Run(engine *backtest.Engine, opts Options) ([]*Trial, error) {
+	if err := ValidateObjective(opts.Objective); err != nil {
+		return nil, err
+	}
+	if len(opts.Ranges) == 0 {
+		return nil, fmt.Errorf("至少需要一個參數範圍")
+	}
+
+	paramSets, err := generate(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers > len(paramSets) {
+		workers = len(paramSets)
+	}
+
+	trials := make([]*Trial, len(paramSets))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	done := 0
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				trials[idx] = runTrial(engine, opts, paramSets[idx])
+
+				if opts.Progress != nil {
+					mu.Lock()
+					done++
+					opts.Progress(done, len(paramSets))
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for idx := range paramSets {
+		jobs <- idx
+	}
+	close(jobs)
+	wg.Wait()
+
+	sort.SliceStable(trials, func(i, j int) bool {
+		a, b := trials[i], trials[j]
+		if (a.Result.Err == nil) != (b.Result.Err == nil) {
+			return a.Result.Err == nil
+		}
+		return a.Score > b.Score
+	})
+
+	return trials, nil
+}
+
+// runTrial 回測單一參數組合
+func runTrial(engine *backtest.Engine, opts Options, params map[string]string) *Trial {
+	trial := &Trial{
+		Params: params,
+		Result: &backtest.Result{Strategy: opts.Strategy, Params: backtest.FormatParams(params)},
+	}
+
+	cfg, err := engine.PrepareConfig(opts.Strategy, params)
+	if err != nil {
+		trial.Result.Err = err
+		return trial
+	}
+
+	trial.Result.Metrics, trial.Result.Err = engine.RunConfig(cfg, opts.Backtest)
+	if trial.Result.Err == nil {
+		trial.Score = Score(opts.Objective, trial.Result.Metrics)
+	}
+	return trial
+}
+
+// generate 依搜尋模式產生參數組合
+func generate(opts Options) ([]map[string]string, error) {
+	switch opts.Mode {
+	case constants.SearchModeGrid, "":
+		return gridSets(opts.Ranges)
+	case constants.SearchModeRandom:
+		samples := opts.Samples
+		if samples <= 0 {
+			samples = constants.DefaultOptimizeSamples
+		}
+		return randomSets(opts.Ranges, samples, rand.New(rand.NewSource(opts.Backtest.Seed))), nil
+	default:
+		return nil, fmt.Errorf("未知的搜尋模式: %s（可用: %s, %s）", opts.Mode, constants.SearchModeGrid, constants.SearchModeRandom)
+	}
+}
+
+// gridSets 展開所有參數組合
+func gridSets(ranges []*ParamRange) ([]map[string]string, error) {
+	total := 1
+	for _, r := range ranges {
+		if r.Continuous {
+			return nil, fmt.Errorf("%s 為連續範圍，網格搜尋需指定步長 (min:max:step)", r.Key)
+		}
+		total *= len(r.Values)
+		if total > constants.MaxGridCombinations {
+			return nil, fmt.Errorf("參數組合超過 %d 組，請縮小範圍或改用隨機搜尋", constants.MaxGridCombinations)
+		}
+	}
+
+	sets := []map[string]string{{}}
+	for _, r := range ranges {
+		next := make([]map[string]string, 0, len(sets)*len(r.Values))
+		for _, set := range sets {
+			for _, value := range r.Values {
+				params := make(map[string]string, len(set)+1)
+				for k, v := range set {
+					params[k] = v
+				}
+				params[r.Key] = value
+				next = append(next, params)
+			}
+		}
+		sets = next
+	}
+
+	return sets, nil
+}
+
+// randomSets 隨機抽樣參數組合，重複的組合只保留一次
+func randomSets(ranges []*ParamRange, samples int, rng *rand.Rand) []map[string]string {
+	seen := make(map[string]bool)
+	sets := make([]map[string]string, 0, samples)
+
+	// 離散空間可能小於抽樣次數，限制嘗試次數避免無窮迴圈
+	for attempts := 0; len(sets) < samples && attempts < samples*20; attempts++ {
+		params := make(map[string]string, len(ranges))
+		for _, r := range ranges {
+			params[r.Key] = r.Sample(rng)
+		}
+
+		key := backtest.FormatParams(params)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		sets = append(sets, params)
+	}
+
+	return sets
+}
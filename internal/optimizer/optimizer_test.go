@@ -0,0 +1,163 @@
+package optimizer
+
+import (
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kfrico/BitfinexLendingBot/internal/backtest"
+	"github.com/kfrico/BitfinexLendingBot/internal/bitfinex"
+	"github.com/kfrico/BitfinexLendingBot/internal/config"
+	"github.com/kfrico/BitfinexLendingBot/internal/constants"
+	"github.com/kfrico/BitfinexLendingBot/internal/history"
+)
+
+func TestParseParamRange(t *testing.T) {
+	tests := []struct {
+		spec       string
+		wantValues []string
+		continuous bool
+		wantErr    bool
+	}{
+		{spec: "SPREAD_LEND=5:15:5", wantValues: []string{"5", "10", "15"}},
+		{spec: "kline_spread_percent=0:0.3:0.1", wantValues: []string{"0.0", "0.1", "0.2", "0.3"}},
+		{spec: "KLINE_SMOOTH_METHOD=max, ema", wantValues: []string{"max", "ema"}},
+		{spec: "VOLATILITY_THRESHOLD=0.001:0.005", continuous: true},
+		{spec: "GAP_TOP=100:10:10", wantErr: true},
+		{spec: "GAP_TOP=1:10:0", wantErr: true},
+		{spec: "GAP_TOP", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			p, err := ParseParamRange(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseParamRange() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if p.Continuous != tt.continuous {
+				t.Errorf("Continuous = %v, want %v", p.Continuous, tt.continuous)
+			}
+			if strings.Join(p.Values, ",") != strings.Join(tt.wantValues, ",") {
+				t.Errorf("Values = %v, want %v", p.Values, tt.wantValues)
+			}
+		})
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	spread, _ := ParseParamRange("SPREAD_LEND=1:3:1")
+	method, _ := ParseParamRange("KLINE_SMOOTH_METHOD=max,ema")
+	threshold, _ := ParseParamRange("VOLATILITY_THRESHOLD=0.001:0.005")
+
+	sets, err := generate(Options{Mode: constants.SearchModeGrid, Ranges: []*ParamRange{spread, method}})
+	if err != nil {
+		t.Fatalf("grid error = %v", err)
+	}
+	if len(sets) != 6 {
+		t.Errorf("grid sets = %d, want 6", len(sets))
+	}
+
+	if _, err := generate(Options{Mode: constants.SearchModeGrid, Ranges: []*ParamRange{threshold}}); err == nil {
+		t.Error("grid search over a continuous range should fail")
+	}
+
+	// 離散空間只有 6 組，隨機抽樣不應產生重複組合
+	sets = randomSets([]*ParamRange{spread, method}, 20, rand.New(rand.NewSource(1)))
+	if len(sets) != 6 {
+		t.Errorf("random sets = %d, want 6 unique", len(sets))
+	}
+
+	sets = randomSets([]*ParamRange{threshold}, 5, rand.New(rand.NewSource(1)))
+	for _, set := range sets {
+		v, err := strconv.ParseFloat(set["VOLATILITY_THRESHOLD"], 64)
+		if err != nil || v < 0.001 || v > 0.005 {
+			t.Errorf("sample %s out of range", set["VOLATILITY_THRESHOLD"])
+		}
+	}
+}
+
+func TestRun_RanksByObjectiveAndEmitsYAML(t *testing.T) {
+	dir := t.TempDir()
+	store := history.NewStore(dir)
+	seedCandles(t, store, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), 5)
+
+	cfg := &config.Config{
+		BitfinexApiKey:    "test_api_key",
+		BitfinexSecretKey: "test_secret_key",
+		Currency:          "USD",
+		MinLoan:           150,
+		MinDailyLendRate:  0.01,
+		SpreadLend:        3,
+		GapBottom:         1,
+		GapTop:            20,
+		MinutesRun:        60,
+		DataDir:           dir,
+	}
+	cfg.ApplyDefaults()
+
+	gapTop, _ := ParseParamRange("GAP_TOP=0:40:20")
+	trials, err := Run(backtest.NewEngine(cfg, store), Options{
+		Strategy:  constants.StrategyKline,
+		Ranges:    []*ParamRange{gapTop},
+		Objective: constants.ObjectiveAPR,
+		Workers:   2,
+	})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if len(trials) != 3 {
+		t.Fatalf("trials = %d, want 3", len(trials))
+	}
+
+	// GAP_TOP=0 未通過配置驗證，應排在最後
+	if trials[2].Result.Err == nil || trials[2].Params["GAP_TOP"] != "0" {
+		t.Errorf("last trial = %+v, want the invalid GAP_TOP=0 set", trials[2].Params)
+	}
+	if trials[0].Result.Err != nil || trials[0].Score < trials[1].Score {
+		t.Errorf("trials not ranked by score: %v, %v", trials[0].Score, trials[1].Score)
+	}
+
+	fragment := FormatYAML(constants.StrategyKline, constants.ObjectiveAPR, trials[0])
+	for _, want := range []string{"ENABLE_KLINE_STRATEGY: true", "ENABLE_SMART_STRATEGY: false", "GAP_TOP: " + trials[0].Params["GAP_TOP"]} {
+		if !strings.Contains(fragment, want) {
+			t.Errorf("fragment missing %q:\n%s", want, fragment)
+		}
+	}
+}
+
+func TestYAMLValue(t *testing.T) {
+	tests := map[string]string{"10": "10", "0.0005": "0.0005", "TRUE": "true", "ema": `"ema"`}
+	for in, want := range tests {
+		if got := yamlValue(in); got != want {
+			t.Errorf("yamlValue(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+func seedCandles(t *testing.T, store *history.Store, start time.Time, days int) {
+	t.Helper()
+
+	candles := make([]*bitfinex.Candle, 0, days*96)
+	for i := 0; i < days*96; i++ {
+		wave := math.Sin(float64(i) / 96 * 2 * math.Pi)
+		close := 0.0004 + 0.0001*wave
+		candles = append(candles, &bitfinex.Candle{
+			MTS:    start.Add(time.Duration(i)*15*time.Minute).UnixNano() / int64(time.Millisecond),
+			Open:   close,
+			Close:  close,
+			High:   close * 1.5,
+			Low:    close * 0.8,
+			Volume: 50000,
+		})
+	}
+
+	if _, err := store.SaveCandles("fUSD", "15m", candles); err != nil {
+		t.Fatalf("SaveCandles() error = %v", err)
+	}
+}
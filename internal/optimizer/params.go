@@ -0,0 +1,117 @@
+package optimizer
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// MaxRangeValues 單一參數範圍最多展開的值數量
+const MaxRangeValues = 1000
+
+// ParamRange 單一配置鍵的搜尋範圍
+//
+// 支援三種寫法：
+//   - KEY=a,b,c        列舉候選值（可為字串，如 KLINE_SMOOTH_METHOD=max,ema）
+//   - KEY=min:max:step 依步長展開數值
+//   - KEY=min:max      連續範圍，只能用於隨機搜尋
+type ParamRange struct {
+	Key        string
+	Values     []string // 離散候選值
+	Min        float64
+	Max        float64
+	Continuous bool // 未指定步長的連續範圍
+	Integer    bool // 範圍端點皆為整數，抽樣結果取整
+	decimals   int
+}
+
+// ParseParamRange 解析參數範圍設定
+func ParseParamRange(spec string) (*ParamRange, error) {
+	kv := strings.SplitN(spec, "=", 2)
+	if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" || strings.TrimSpace(kv[1]) == "" {
+		return nil, fmt.Errorf("參數範圍格式錯誤 %q，應為 KEY=min:max:step 或 KEY=a,b,c", spec)
+	}
+
+	p := &ParamRange{Key: strings.ToUpper(strings.TrimSpace(kv[0]))}
+	value := strings.TrimSpace(kv[1])
+
+	if !strings.Contains(value, ":") {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				p.Values = append(p.Values, item)
+			}
+		}
+		if len(p.Values) == 0 {
+			return nil, fmt.Errorf("%s 沒有候選值", p.Key)
+		}
+		return p, nil
+	}
+
+	parts := strings.Split(value, ":")
+	if len(parts) != 2 && len(parts) != 3 {
+		return nil, fmt.Errorf("%s 範圍格式錯誤 %q，應為 min:max 或 min:max:step", p.Key, value)
+	}
+
+	numbers := make([]float64, len(parts))
+	p.Integer = true
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		n, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s 範圍數值錯誤 %q", p.Key, part)
+		}
+		numbers[i] = n
+		if idx := strings.Index(part, "."); idx >= 0 {
+			p.Integer = false
+			if d := len(part) - idx - 1; d > p.decimals {
+				p.decimals = d
+			}
+		}
+	}
+
+	p.Min, p.Max = numbers[0], numbers[1]
+	if p.Max < p.Min {
+		return nil, fmt.Errorf("%s 範圍上限 %v 小於下限 %v", p.Key, p.Max, p.Min)
+	}
+
+	if len(parts) == 2 {
+		p.Continuous = true
+		return p, nil
+	}
+
+	step := numbers[2]
+	if step <= 0 {
+		return nil, fmt.Errorf("%s 步長必須大於 0", p.Key)
+	}
+	count := int(math.Floor((p.Max-p.Min)/step+1e-9)) + 1
+	if count > MaxRangeValues {
+		return nil, fmt.Errorf("%s 範圍展開後有 %d 個值，超過上限 %d", p.Key, count, MaxRangeValues)
+	}
+	for i := 0; i < count; i++ {
+		p.Values = append(p.Values, p.format(p.Min+float64(i)*step))
+	}
+
+	return p, nil
+}
+
+// Sample 隨機抽取一個值
+func (p *ParamRange) Sample(rng *rand.Rand) string {
+	if !p.Continuous {
+		return p.Values[rng.Intn(len(p.Values))]
+	}
+	return p.format(p.Min + rng.Float64()*(p.Max-p.Min))
+}
+
+// format 依範圍精度格式化數值，避免浮點累加誤差
+func (p *ParamRange) format(v float64) string {
+	if p.Integer {
+		return strconv.FormatInt(int64(math.Round(v)), 10)
+	}
+	decimals := p.decimals
+	if p.Continuous && decimals < 6 {
+		decimals = 6
+	}
+	return strconv.FormatFloat(v, 'f', decimals, 64)
+}
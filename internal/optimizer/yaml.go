@@ -0,0 +1,50 @@
+package optimizer
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kfrico/BitfinexLendingBot/internal/constants"
+)
+
+// FormatYAML 將最佳參數組合輸出為可直接貼入 config.yaml 的片段
+func FormatYAML(strategy, objective string, trial *Trial) string {
+	var buf bytes.Buffer
+	m := trial.Result.Metrics
+
+	fmt.Fprintf(&buf, "# 最佳化目標: %s, 分數: %.4f\n", objective, trial.Score)
+	fmt.Fprintf(&buf, "# 回測區間: %s - %s\n", m.Start.Format(constants.DateLayout), m.End.Format(constants.DateLayout))
+	fmt.Fprintf(&buf, "# APR: %.2f%%, 利用率: %.1f%%, 利用率回落: %.1f, 閒置: %.1f%%\n",
+		m.APR, m.Utilization, m.UtilDrawdown, m.IdleShare)
+
+	fmt.Fprintf(&buf, "ENABLE_SMART_STRATEGY: %t\n", strategy == constants.StrategySmart)
+	fmt.Fprintf(&buf, "ENABLE_KLINE_STRATEGY: %t\n", strategy == constants.StrategyKline)
+	fmt.Fprintf(&buf, "ENABLE_STRATEGY_BLEND: %t\n", strategy == constants.StrategyBlend)
+
+	keys := make([]string, 0, len(trial.Params))
+	for key := range trial.Params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		fmt.Fprintf(&buf, "%s: %s\n", key, yamlValue(trial.Params[key]))
+	}
+
+	return buf.String()
+}
+
+// yamlValue 數值與布林值原樣輸出，其餘以字串輸出
+func yamlValue(value string) string {
+	if _, err := strconv.ParseFloat(value, 64); err == nil {
+		return value
+	}
+	switch strings.ToLower(value) {
+	case "true", "false":
+		return strings.ToLower(value)
+	}
+	return strconv.Quote(value)
+}
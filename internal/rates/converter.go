@@ -1,15 +1,85 @@
 package rates
 
 import (
+	"fmt"
+
 	"github.com/kfrico/BitfinexLendingBot/internal/constants"
 )
 
+// FeeModel 利息手續費模型（依帳戶等級設定）
+type FeeModel struct {
+	Rate       float64 // 一般掛單成交的利息手續費比例 (0-1)
+	HiddenRate float64 // 隱藏掛單成交的利息手續費比例 (0-1)
+}
+
+// DefaultFeeModel 返回 Bitfinex 標準帳戶的手續費模型
+func DefaultFeeModel() FeeModel {
+	return FeeModel{Rate: constants.FundingFeeRate, HiddenRate: constants.HiddenFundingFeeRate}
+}
+
+// Yield 利率或收益的手續費拆分
+type Yield struct {
+	Gross float64 // 扣除手續費前
+	Fee   float64 // 手續費
+	Net   float64 // 扣除手續費後
+}
+
+// FormatRate 以百分比利率格式顯示毛利率、手續費與淨利率
+func (y Yield) FormatRate() string {
+	return fmt.Sprintf("%.4f%% (手續費 %.4f%%，淨 %.4f%%)", y.Gross, y.Fee, y.Net)
+}
+
+// FormatAmount 以金額格式顯示毛收益、手續費與淨收益
+func (y Yield) FormatAmount(currency string) string {
+	return fmt.Sprintf("%.4f %s (手續費 %.4f，淨 %.4f)", y.Gross, currency, y.Fee, y.Net)
+}
+
 // Converter 利率轉換器
-type Converter struct{}
+type Converter struct {
+	fees FeeModel
+}
 
-// NewConverter 創建新的利率轉換器
+// NewConverter 創建使用標準手續費的利率轉換器
 func NewConverter() *Converter {
-	return &Converter{}
+	return NewConverterWithFees(DefaultFeeModel())
+}
+
+// NewConverterWithFees 創建使用指定手續費模型的利率轉換器
+func NewConverterWithFees(fees FeeModel) *Converter {
+	return &Converter{fees: fees}
+}
+
+// Fees 返回手續費模型
+func (c *Converter) Fees() FeeModel {
+	return c.fees
+}
+
+// FeeRate 返回利息手續費比例
+func (c *Converter) FeeRate(hidden bool) float64 {
+	if hidden {
+		return c.fees.HiddenRate
+	}
+	return c.fees.Rate
+}
+
+// NetRate 將毛利率（或毛收益）扣除手續費後轉換為淨值
+func (c *Converter) NetRate(gross float64, hidden bool) float64 {
+	return gross * (1 - c.FeeRate(hidden))
+}
+
+// GrossRate 將淨利率換算回扣除手續費前的毛利率
+func (c *Converter) GrossRate(net float64, hidden bool) float64 {
+	factor := 1 - c.FeeRate(hidden)
+	if factor <= 0 {
+		return 0
+	}
+	return net / factor
+}
+
+// Split 將毛利率（或毛收益）拆分為手續費與淨值
+func (c *Converter) Split(gross float64, hidden bool) Yield {
+	net := c.NetRate(gross, hidden)
+	return Yield{Gross: gross, Fee: gross - net, Net: net}
 }
 
 // PercentageToDecimal 將百分比轉換為小數
@@ -183,3 +183,40 @@ func TestConverter_Consistency(t *testing.T) {
 		})
 	}
 }
+
+func TestConverter_FeeModel(t *testing.T) {
+	converter := NewConverterWithFees(FeeModel{Rate: 0.15, HiddenRate: 0.18})
+
+	tests := []struct {
+		name     string
+		gross    float64
+		hidden   bool
+		expected Yield
+	}{
+		{
+			name:     "public offer",
+			gross:    0.0004,
+			expected: Yield{Gross: 0.0004, Fee: 0.00006, Net: 0.00034},
+		},
+		{
+			name:     "hidden offer",
+			gross:    0.0005,
+			hidden:   true,
+			expected: Yield{Gross: 0.0005, Fee: 0.00009, Net: 0.00041},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := converter.Split(tt.gross, tt.hidden)
+			if math.Abs(result.Fee-tt.expected.Fee) > 1e-12 || math.Abs(result.Net-tt.expected.Net) > 1e-12 {
+				t.Errorf("Split(%f, %v) = %+v, expected %+v", tt.gross, tt.hidden, result, tt.expected)
+			}
+
+			backToGross := converter.GrossRate(result.Net, tt.hidden)
+			if math.Abs(backToGross-tt.gross) > 1e-12 {
+				t.Errorf("GrossRate(%f) = %f, expected %f", result.Net, backToGross, tt.gross)
+			}
+		})
+	}
+}
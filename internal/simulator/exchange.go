@@ -0,0 +1,549 @@
+package simulator
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kfrico/BitfinexLendingBot/internal/bitfinex"
+	"github.com/kfrico/BitfinexLendingBot/internal/constants"
+	"github.com/kfrico/BitfinexLendingBot/internal/errors"
+	"github.com/kfrico/BitfinexLendingBot/internal/rates"
+)
+
+const day = 24 * time.Hour
+
+// MarketSource 模擬交易所使用的市場數據來源（歷史回放或即時公開數據）
+type MarketSource interface {
+	FundingBook(symbol string, limit int, at time.Time) ([]*bitfinex.FundingBookEntry, error)
+	FundingRate(symbol string, at time.Time) (float64, error)
+	// Candles 返回 at 之前已收盤的K線，由新到舊
+	Candles(symbol, timeFrame string, limit int, at time.Time) ([]*bitfinex.Candle, error)
+}
+
+// Offer 模擬掛單
+type Offer struct {
+	ID        int64
+	Amount    float64
+	Rate      float64 // 日利率（小數格式），FRR 單為 0
+	Period    int
+	Hidden    bool
+	FRR       bool
+	CreatedAt time.Time
+}
+
+// Credit 模擬成交的借貸
+type Credit struct {
+	ID       int64
+	Amount   float64
+	Rate     float64 // 日利率（小數格式）
+	Period   int
+	Hidden   bool
+	FRR      bool
+	OpenedAt time.Time
+}
+
+// MaturesAt 返回借貸到期時間
+func (c *Credit) MaturesAt() time.Time {
+	return c.OpenedAt.Add(time.Duration(c.Period) * day)
+}
+
+// Stats 模擬帳戶統計
+type Stats struct {
+	InitialBalance         float64
+	InterestEarned         float64       // 扣除手續費後的利息
+	FeesPaid               float64       // 利息手續費
+	FilledAmount           float64       // 累計成交金額
+	FilledCount            int           // 成交筆數
+	FilledRateSum          float64       // 成交金額加權利率總和（用於平均成交利率）
+	MaturedCount           int           // 到期筆數
+	ClosedCount            int           // 提前關閉筆數
+	CapitalDays            float64       // 帳戶資金 × 天數
+	LentDays               float64       // 借出金額 × 天數
+	IdleDuration           time.Duration // 未借出金額達閒置門檻的時間
+	Elapsed                time.Duration // 已模擬時間
+	PeakUtilization        float64       // 最高資金利用率 (0-1)
+	MaxUtilizationDrawdown float64       // 資金利用率自高點的最大回落 (0-1)
+}
+
+// Options 模擬交易所設定
+type Options struct {
+	Currency       string
+	InitialBalance float64
+	FillModel      FillModel
+	FillTimeFrame  string        // 撮合使用的K線時間框架
+	FillInterval   time.Duration // FillTimeFrame 對應的長度
+	IdleThreshold  float64       // 未借出金額達此值視為閒置（通常為 MIN_LOAN）
+	Start          time.Time
+	Clock          func() time.Time // 模擬交易使用的時鐘；nil 代表由 Advance 推進（回測）
+	Fees           rates.FeeModel   // 利息手續費；零值代表標準帳戶費率
+}
+
+// Exchange 模擬資金市場，實作 bitfinex.FundingAPI
+type Exchange struct {
+	opts   Options
+	symbol string
+	market MarketSource
+
+	mu      sync.Mutex
+	now     time.Time // 機器人看到的目前時間
+	cursor  time.Time // 已完成撮合與計息的時間
+	balance float64   // 資金錢包總額（含掛單與借出）
+	offers  map[int64]*Offer
+	credits map[int64]*Credit
+	nextID  int64
+	stats   Stats
+}
+
+var _ bitfinex.FundingAPI = (*Exchange)(nil)
+
+// NewExchange 創建模擬交易所
+func NewExchange(market MarketSource, opts Options) *Exchange {
+	currency := strings.ToUpper(opts.Currency)
+	opts.Currency = currency
+	if opts.Fees == (rates.FeeModel{}) {
+		opts.Fees = rates.DefaultFeeModel()
+	}
+
+	return &Exchange{
+		opts:    opts,
+		symbol:  constants.FundingSymbolPrefix + currency,
+		market:  market,
+		now:     opts.Start,
+		cursor:  opts.Start,
+		balance: opts.InitialBalance,
+		offers:  make(map[int64]*Offer),
+		credits: make(map[int64]*Credit),
+		nextID:  1,
+		stats:   Stats{InitialBalance: opts.InitialBalance},
+	}
+}
+
+// Now 返回模擬時間
+func (e *Exchange) Now() time.Time {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.currentTimeLocked()
+}
+
+// currentTimeLocked 返回目前時間（需持有鎖）
+func (e *Exchange) currentTimeLocked() time.Time {
+	if e.opts.Clock != nil {
+		return e.opts.Clock()
+	}
+	return e.now
+}
+
+// Stats 返回目前統計
+func (e *Exchange) Stats() Stats {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.stats
+}
+
+// Balance 返回資金錢包總額
+func (e *Exchange) Balance() float64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.balance
+}
+
+// Advance 推進模擬時間，依期間內已收盤的K線撮合掛單、計算利息與到期
+func (e *Exchange) Advance(to time.Time) error {
+	e.mu.Lock()
+	if to.Before(e.now) {
+		e.mu.Unlock()
+		return fmt.Errorf("模擬時間不可倒退: %s -> %s", e.now.Format(time.RFC3339), to.Format(time.RFC3339))
+	}
+	cursor := e.cursor
+	withFRR := e.hasFRROffersLocked()
+	e.mu.Unlock()
+
+	// 市場數據可能來自即時 API，先在不持有鎖的情況下取得，避免阻塞其他查詢與下單
+	var candles []*bitfinex.Candle
+	var frrRates map[int64]float64
+	var fetchErr error
+	if to.After(cursor) {
+		candles, frrRates, fetchErr = e.fetchFillData(cursor, to, withFRR)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if to.Before(e.now) {
+		return fmt.Errorf("模擬時間不可倒退: %s -> %s", e.now.Format(time.RFC3339), to.Format(time.RFC3339))
+	}
+	e.now = to
+	if fetchErr != nil {
+		return fetchErr
+	}
+
+	// K線由新到舊，反向處理
+	for i := len(candles) - 1; i >= 0; i-- {
+		candle := candles[i]
+		start := time.Unix(0, candle.MTS*int64(time.Millisecond))
+		end := start.Add(e.opts.FillInterval)
+		if !end.After(e.cursor) || end.After(to) {
+			continue
+		}
+
+		e.settleUntil(end)
+		e.fillOffers(candle, start, end, frrRates)
+	}
+
+	return nil
+}
+
+// fetchFillData 取得 cursor 至 to 之間的撮合K線，有 FRR 掛單時一併取得各K線結束時的 FRR（以K線時間為鍵，不需持有鎖）
+func (e *Exchange) fetchFillData(cursor, to time.Time, withFRR bool) ([]*bitfinex.Candle, map[int64]float64, error) {
+	limit := int(to.Sub(cursor)/e.opts.FillInterval) + 2
+	if limit > constants.MaxCandlesPerRequest {
+		limit = constants.MaxCandlesPerRequest
+	}
+
+	candles, err := e.market.Candles(e.symbol, e.opts.FillTimeFrame, limit, to)
+	if err != nil {
+		return nil, nil, fmt.Errorf("取得撮合K線失敗: %w", err)
+	}
+	if !withFRR {
+		return candles, nil, nil
+	}
+
+	frrRates := make(map[int64]float64, len(candles))
+	for _, candle := range candles {
+		end := time.Unix(0, candle.MTS*int64(time.Millisecond)).Add(e.opts.FillInterval)
+		if !end.After(cursor) || end.After(to) {
+			continue
+		}
+		if frr, err := e.market.FundingRate(e.symbol, end); err == nil && frr > 0 {
+			frrRates[candle.MTS] = frr
+		}
+	}
+	return candles, frrRates, nil
+}
+
+// hasFRROffersLocked 檢查是否有 FRR 掛單（需持有鎖）
+func (e *Exchange) hasFRROffersLocked() bool {
+	for _, offer := range e.offers {
+		if offer.FRR {
+			return true
+		}
+	}
+	return false
+}
+
+// settleUntil 計算 cursor 至指定時間的利息、到期與統計（需持有鎖）
+func (e *Exchange) settleUntil(until time.Time) {
+	from := e.cursor
+	if !until.After(from) {
+		return
+	}
+	segmentDays := until.Sub(from).Hours() / 24
+
+	lent := 0.0
+	for id, credit := range e.credits {
+		lent += credit.Amount
+
+		accrualEnd := until
+		maturity := credit.MaturesAt()
+		if maturity.Before(accrualEnd) {
+			accrualEnd = maturity
+		}
+		accrualStart := from
+		if credit.OpenedAt.After(accrualStart) {
+			accrualStart = credit.OpenedAt
+		}
+
+		if accrualEnd.After(accrualStart) {
+			days := accrualEnd.Sub(accrualStart).Hours() / 24
+			gross := credit.Amount * credit.Rate * days
+			feeRate := e.opts.Fees.Rate
+			if credit.Hidden {
+				feeRate = e.opts.Fees.HiddenRate
+			}
+			fee := gross * feeRate
+
+			e.balance += gross - fee
+			e.stats.InterestEarned += gross - fee
+			e.stats.FeesPaid += fee
+			e.stats.LentDays += credit.Amount * days
+		}
+
+		if !maturity.After(until) {
+			delete(e.credits, id)
+			e.stats.MaturedCount++
+		}
+	}
+
+	if e.balance > 0 {
+		utilization := lent / e.balance
+		if utilization > e.stats.PeakUtilization {
+			e.stats.PeakUtilization = utilization
+		}
+		if drawdown := e.stats.PeakUtilization - utilization; drawdown > e.stats.MaxUtilizationDrawdown {
+			e.stats.MaxUtilizationDrawdown = drawdown
+		}
+	}
+
+	if e.balance-lent >= e.opts.IdleThreshold && e.opts.IdleThreshold > 0 {
+		e.stats.IdleDuration += until.Sub(from)
+	}
+	e.stats.CapitalDays += e.balance * segmentDays
+	e.stats.Elapsed += until.Sub(from)
+	e.cursor = until
+}
+
+// fillOffers 依成交模型撮合在K線開始前已存在的掛單（需持有鎖）
+// frrRates 為預先取得的各K線 FRR，沒有對應利率時 FRR 掛單本根K線不成交
+func (e *Exchange) fillOffers(candle *bitfinex.Candle, start, end time.Time, frrRates map[int64]float64) {
+	for _, id := range e.sortedOfferIDs() {
+		offer := e.offers[id]
+		if offer.CreatedAt.After(start) {
+			continue
+		}
+
+		fraction := e.opts.FillModel.FillFraction(offer, candle)
+		if fraction <= 0 {
+			continue
+		}
+
+		rate := offer.Rate
+		if offer.FRR {
+			frr, ok := frrRates[candle.MTS]
+			if !ok {
+				continue
+			}
+			rate = frr
+		}
+
+		filled := offer.Amount * fraction
+		if offer.Amount-filled < 1e-6 {
+			filled = offer.Amount
+		}
+
+		credit := &Credit{
+			ID:       e.nextID,
+			Amount:   filled,
+			Rate:     rate,
+			Period:   offer.Period,
+			Hidden:   offer.Hidden,
+			FRR:      offer.FRR,
+			OpenedAt: end,
+		}
+		e.nextID++
+		e.credits[credit.ID] = credit
+
+		e.stats.FilledAmount += filled
+		e.stats.FilledCount++
+		e.stats.FilledRateSum += filled * rate
+
+		offer.Amount -= filled
+		if offer.Amount <= 1e-6 {
+			delete(e.offers, id)
+		}
+	}
+}
+
+// sortedOfferIDs 依掛單編號排序（確保撮合順序可重現）
+func (e *Exchange) sortedOfferIDs() []int64 {
+	ids := make([]int64, 0, len(e.offers))
+	for id := range e.offers {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// availableLocked 返回未掛單也未借出的金額（需持有鎖）
+func (e *Exchange) availableLocked() float64 {
+	available := e.balance
+	for _, offer := range e.offers {
+		available -= offer.Amount
+	}
+	for _, credit := range e.credits {
+		available -= credit.Amount
+	}
+	return math.Max(0, available)
+}
+
+// LentAmount 返回目前借出金額
+func (e *Exchange) LentAmount() float64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	lent := 0.0
+	for _, credit := range e.credits {
+		lent += credit.Amount
+	}
+	return lent
+}
+
+// checkSymbol 檢查交易對
+func (e *Exchange) checkSymbol(symbol string) error {
+	if symbol != e.symbol {
+		return errors.NewValidationError(fmt.Sprintf("simulated exchange only supports %s, got %s", e.symbol, symbol))
+	}
+	return nil
+}
+
+// GetFundingOffers 獲取模擬掛單
+func (e *Exchange) GetFundingOffers(symbol string) ([]*bitfinex.FundingOffer, error) {
+	if err := e.checkSymbol(symbol); err != nil {
+		return nil, err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	result := make([]*bitfinex.FundingOffer, 0, len(e.offers))
+	for _, id := range e.sortedOfferIDs() {
+		offer := e.offers[id]
+		result = append(result, &bitfinex.FundingOffer{
+			ID:     offer.ID,
+			Amount: offer.Amount,
+			Rate:   offer.Rate,
+			Period: offer.Period,
+			Hidden: offer.Hidden,
+		})
+	}
+
+	return result, nil
+}
+
+// CancelFundingOffer 取消模擬掛單
+func (e *Exchange) CancelFundingOffer(offerID int64) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if _, ok := e.offers[offerID]; !ok {
+		return errors.NewOrderError(fmt.Sprintf("offer %d not found", offerID), nil)
+	}
+	delete(e.offers, offerID)
+	return nil
+}
+
+// SubmitFundingOffer 提交模擬固定利率掛單
+func (e *Exchange) SubmitFundingOffer(symbol string, amount float64, dailyRate float64, period int, hidden bool) (int64, error) {
+	if dailyRate <= 0 {
+		return 0, errors.NewOrderError(fmt.Sprintf("invalid rate %v", dailyRate), nil)
+	}
+	return e.submit(symbol, amount, dailyRate, period, hidden, false)
+}
+
+// SubmitFundingOfferFRR 提交模擬 FRR 掛單
+func (e *Exchange) SubmitFundingOfferFRR(symbol string, amount float64, period int, hidden bool) (int64, error) {
+	return e.submit(symbol, amount, 0, period, hidden, true)
+}
+
+func (e *Exchange) submit(symbol string, amount float64, dailyRate float64, period int, hidden bool, frr bool) (int64, error) {
+	if err := e.checkSymbol(symbol); err != nil {
+		return 0, err
+	}
+	if period < constants.DefaultPeriodDays || period > constants.Period120Days {
+		return 0, errors.NewOrderError(fmt.Sprintf("invalid period %d", period), nil)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if amount <= 0 || amount > e.availableLocked()+1e-9 {
+		return 0, errors.NewOrderError(fmt.Sprintf("insufficient funds for offer amount %.2f", amount), nil)
+	}
+
+	offer := &Offer{
+		ID:        e.nextID,
+		Amount:    amount,
+		Rate:      dailyRate,
+		Period:    period,
+		Hidden:    hidden,
+		FRR:       frr,
+		CreatedAt: e.currentTimeLocked(),
+	}
+	e.nextID++
+	e.offers[offer.ID] = offer
+
+	return offer.ID, nil
+}
+
+// GetFundingBalance 獲取模擬資金錢包可用餘額
+func (e *Exchange) GetFundingBalance(currency string) (float64, error) {
+	if !strings.EqualFold(currency, e.opts.Currency) {
+		return 0, nil
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.availableLocked(), nil
+}
+
+// GetFundingBook 獲取模擬時間點的訂單簿
+func (e *Exchange) GetFundingBook(symbol string, limit int) ([]*bitfinex.FundingBookEntry, error) {
+	return e.market.FundingBook(symbol, limit, e.Now())
+}
+
+// GetCurrentFundingRate 獲取模擬時間點的 FRR
+func (e *Exchange) GetCurrentFundingRate(symbol string) (float64, error) {
+	return e.market.FundingRate(symbol, e.Now())
+}
+
+// GetFundingCandles 獲取模擬時間點前的K線
+func (e *Exchange) GetFundingCandles(symbol string, timeFrame string, limit int) ([]*bitfinex.Candle, error) {
+	return e.market.Candles(symbol, timeFrame, limit, e.Now())
+}
+
+// CloseFundingCredit 提前關閉模擬借貸，上次撮合後尚未計入的利息不再計算
+func (e *Exchange) CloseFundingCredit(creditID int64) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if _, ok := e.credits[creditID]; !ok {
+		return errors.NewOrderError(fmt.Sprintf("credit %d not found", creditID), nil)
+	}
+	delete(e.credits, creditID)
+	e.stats.ClosedCount++
+	return nil
+}
+
+// GetFundingCredits 獲取模擬借貸
+func (e *Exchange) GetFundingCredits(symbol string) ([]*bitfinex.FundingCredit, error) {
+	if err := e.checkSymbol(symbol); err != nil {
+		return nil, err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	ids := make([]int64, 0, len(e.credits))
+	for id := range e.credits {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	result := make([]*bitfinex.FundingCredit, 0, len(ids))
+	for _, id := range ids {
+		credit := e.credits[id]
+		opened := credit.OpenedAt.UnixNano() / int64(time.Millisecond)
+		fc := &bitfinex.FundingCredit{
+			ID:         credit.ID,
+			Symbol:     e.symbol,
+			Amount:     credit.Amount,
+			RateType:   "FIXED",
+			Rate:       credit.Rate,
+			Period:     int64(credit.Period),
+			MTSCreated: opened,
+			MTSOpened:  opened,
+			Status:     "ACTIVE",
+		}
+		if credit.FRR {
+			fc.RateType = "FRR"
+			fc.Rate = 0
+			fc.RateReal = credit.Rate
+		}
+		result = append(result, fc)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,223 @@
+package simulator
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/kfrico/BitfinexLendingBot/internal/bitfinex"
+)
+
+// staticMarket 固定K線的測試市場
+type staticMarket struct {
+	candles []*bitfinex.Candle // 由舊到新
+	frr     float64
+}
+
+func (m *staticMarket) FundingBook(symbol string, limit int, at time.Time) ([]*bitfinex.FundingBookEntry, error) {
+	return []*bitfinex.FundingBookEntry{{Rate: 0.0002, Amount: 1000}}, nil
+}
+
+func (m *staticMarket) FundingRate(symbol string, at time.Time) (float64, error) {
+	return m.frr, nil
+}
+
+func (m *staticMarket) Candles(symbol, timeFrame string, limit int, at time.Time) ([]*bitfinex.Candle, error) {
+	result := make([]*bitfinex.Candle, 0, limit)
+	for i := len(m.candles) - 1; i >= 0 && len(result) < limit; i-- {
+		end := time.Unix(0, m.candles[i].MTS*int64(time.Millisecond)).Add(time.Hour)
+		if !end.After(at) {
+			result = append(result, m.candles[i])
+		}
+	}
+	return result, nil
+}
+
+func newTestExchange(t *testing.T, model string, candles []*bitfinex.Candle, start time.Time) *Exchange {
+	t.Helper()
+	fill, err := NewFillModel(model, 1)
+	if err != nil {
+		t.Fatalf("NewFillModel() error = %v", err)
+	}
+	return NewExchange(&staticMarket{candles: candles, frr: 0.0003}, Options{
+		Currency:       "USD",
+		InitialBalance: 1000,
+		FillModel:      fill,
+		FillTimeFrame:  "1h",
+		FillInterval:   time.Hour,
+		IdleThreshold:  150,
+		Start:          start,
+	})
+}
+
+func hourlyCandles(start time.Time, hours int, high, close float64) []*bitfinex.Candle {
+	candles := make([]*bitfinex.Candle, 0, hours)
+	for i := 0; i < hours; i++ {
+		mts := start.Add(time.Duration(i)*time.Hour).UnixNano() / int64(time.Millisecond)
+		candles = append(candles, &bitfinex.Candle{MTS: mts, High: high, Close: close, Low: close})
+	}
+	return candles
+}
+
+func TestExchange_FillAccrueAndMature(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	ex := newTestExchange(t, "touch", hourlyCandles(start, 24*4, 0.0005, 0.0002), start)
+
+	if _, err := ex.SubmitFundingOffer("fUSD", 1000, 0.0004, 2, false); err != nil {
+		t.Fatalf("SubmitFundingOffer() error = %v", err)
+	}
+	if available, _ := ex.GetFundingBalance("USD"); available != 0 {
+		t.Errorf("available after offer = %.2f, want 0", available)
+	}
+
+	if err := ex.Advance(start.Add(4 * 24 * time.Hour)); err != nil {
+		t.Fatalf("Advance() error = %v", err)
+	}
+
+	stats := ex.Stats()
+	if stats.FilledCount != 1 || stats.MaturedCount != 1 {
+		t.Fatalf("fills = %d, matured = %d; want 1, 1", stats.FilledCount, stats.MaturedCount)
+	}
+
+	// 2 天 × 0.04% × 1000 = 0.8，扣 15% 手續費後 0.68
+	if math.Abs(stats.InterestEarned-0.68) > 1e-9 {
+		t.Errorf("InterestEarned = %.6f, want 0.68", stats.InterestEarned)
+	}
+	if credits, _ := ex.GetFundingCredits("fUSD"); len(credits) != 0 {
+		t.Errorf("credits after maturity = %d, want 0", len(credits))
+	}
+	if math.Abs(ex.Balance()-1000.68) > 1e-9 {
+		t.Errorf("Balance() = %.6f, want 1000.68", ex.Balance())
+	}
+}
+
+func TestExchange_CloseModelIsStricter(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	candles := hourlyCandles(start, 24, 0.0005, 0.0002)
+
+	touch := newTestExchange(t, "touch", candles, start)
+	closeModel := newTestExchange(t, "close", candles, start)
+	for _, ex := range []*Exchange{touch, closeModel} {
+		if _, err := ex.SubmitFundingOffer("fUSD", 500, 0.0004, 2, false); err != nil {
+			t.Fatalf("SubmitFundingOffer() error = %v", err)
+		}
+		if err := ex.Advance(start.Add(24 * time.Hour)); err != nil {
+			t.Fatalf("Advance() error = %v", err)
+		}
+	}
+
+	if touch.Stats().FilledCount != 1 {
+		t.Errorf("touch model fills = %d, want 1", touch.Stats().FilledCount)
+	}
+	if closeModel.Stats().FilledCount != 0 {
+		t.Errorf("close model fills = %d, want 0", closeModel.Stats().FilledCount)
+	}
+}
+
+// blockingMarket 取得K線時等待放行，模擬緩慢的即時 API
+type blockingMarket struct {
+	staticMarket
+	fetching chan struct{}
+	release  chan struct{}
+}
+
+func (m *blockingMarket) Candles(symbol, timeFrame string, limit int, at time.Time) ([]*bitfinex.Candle, error) {
+	close(m.fetching)
+	<-m.release
+	return m.staticMarket.Candles(symbol, timeFrame, limit, at)
+}
+
+func TestExchange_AdvanceFetchesWithoutLock(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	fill, _ := NewFillModel("touch", 1)
+	market := &blockingMarket{
+		staticMarket: staticMarket{candles: hourlyCandles(start, 3, 0.0005, 0.0002), frr: 0.0003},
+		fetching:     make(chan struct{}),
+		release:      make(chan struct{}),
+	}
+	ex := NewExchange(market, Options{
+		Currency:       "USD",
+		InitialBalance: 1000,
+		FillModel:      fill,
+		FillTimeFrame:  "1h",
+		FillInterval:   time.Hour,
+		Start:          start,
+	})
+	if _, err := ex.SubmitFundingOfferFRR("fUSD", 1000, 2, false); err != nil {
+		t.Fatalf("SubmitFundingOfferFRR() error = %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- ex.Advance(start.Add(2 * time.Hour)) }()
+	<-market.fetching
+
+	// 取得K線期間其他查詢不被阻塞
+	queried := make(chan struct{})
+	go func() {
+		ex.GetFundingBalance("USD")
+		ex.GetFundingOffers("fUSD")
+		close(queried)
+	}()
+	select {
+	case <-queried:
+	case <-time.After(time.Second):
+		t.Fatal("queries blocked while Advance was fetching candles")
+	}
+
+	close(market.release)
+	if err := <-done; err != nil {
+		t.Fatalf("Advance() error = %v", err)
+	}
+	credits, _ := ex.GetFundingCredits("fUSD")
+	if len(credits) != 1 || credits[0].RateReal != 0.0003 {
+		t.Fatalf("FRR offer should fill at the prefetched FRR, got %+v", credits)
+	}
+}
+
+func TestExchange_RejectsOverspend(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	ex := newTestExchange(t, "touch", nil, start)
+
+	if _, err := ex.SubmitFundingOffer("fUSD", 1500, 0.0004, 2, false); err == nil {
+		t.Error("expected error when offer exceeds available balance")
+	}
+}
+
+// fakeMarketAPI 固定回應的公開市場接口
+type fakeMarketAPI struct {
+	candles []*bitfinex.Candle // 由新到舊
+}
+
+func (f *fakeMarketAPI) GetFundingBook(symbol string, limit int) ([]*bitfinex.FundingBookEntry, error) {
+	return nil, nil
+}
+
+func (f *fakeMarketAPI) GetCurrentFundingRate(symbol string) (float64, error) {
+	return 0.0003, nil
+}
+
+func (f *fakeMarketAPI) GetFundingCandles(symbol string, timeFrame string, limit int) ([]*bitfinex.Candle, error) {
+	if len(f.candles) > limit {
+		return f.candles[:limit], nil
+	}
+	return f.candles, nil
+}
+
+func TestLiveMarket_SkipsOpenCandle(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 30, 0, time.UTC)
+	mts := func(t time.Time) int64 { return t.UnixNano() / int64(time.Millisecond) }
+
+	market := NewLiveMarket(&fakeMarketAPI{candles: []*bitfinex.Candle{
+		{MTS: mts(now.Truncate(time.Minute))},                   // 尚未收盤
+		{MTS: mts(now.Truncate(time.Minute).Add(-time.Minute))}, // 已收盤
+		{MTS: mts(now.Truncate(time.Minute).Add(-2 * time.Minute))},
+	}})
+
+	candles, err := market.Candles("fUSD", "1m", 2, now)
+	if err != nil {
+		t.Fatalf("Candles() error = %v", err)
+	}
+	if len(candles) != 2 || candles[0].MTS != mts(now.Truncate(time.Minute).Add(-time.Minute)) {
+		t.Errorf("unexpected candles: %+v", candles)
+	}
+}
@@ -0,0 +1,60 @@
+package simulator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kfrico/BitfinexLendingBot/internal/bitfinex"
+	"github.com/kfrico/BitfinexLendingBot/internal/constants"
+)
+
+// FillModel 決定掛單在一根K線內的成交比例
+type FillModel interface {
+	Name() string
+	// FillFraction 返回掛單剩餘金額在此K線內成交的比例 (0-1)
+	FillFraction(offer *Offer, candle *bitfinex.Candle) float64
+}
+
+// touchFillModel K線高點觸及掛單利率即成交（較樂觀）
+type touchFillModel struct {
+	ratio float64
+}
+
+func (m *touchFillModel) Name() string { return constants.FillModelTouch }
+
+func (m *touchFillModel) FillFraction(offer *Offer, candle *bitfinex.Candle) float64 {
+	if offer.FRR || candle.High >= offer.Rate {
+		return m.ratio
+	}
+	return 0
+}
+
+// closeFillModel K線收盤利率達到掛單利率才成交（較保守）
+type closeFillModel struct {
+	ratio float64
+}
+
+func (m *closeFillModel) Name() string { return constants.FillModelClose }
+
+func (m *closeFillModel) FillFraction(offer *Offer, candle *bitfinex.Candle) float64 {
+	if offer.FRR || candle.Close >= offer.Rate {
+		return m.ratio
+	}
+	return 0
+}
+
+// NewFillModel 依名稱創建成交模型，ratio 為每根符合條件K線的成交比例
+func NewFillModel(name string, ratio float64) (FillModel, error) {
+	if ratio <= 0 || ratio > 1 {
+		return nil, fmt.Errorf("成交比例必須介於 0 與 1 之間: %v", ratio)
+	}
+
+	switch strings.ToLower(name) {
+	case constants.FillModelTouch:
+		return &touchFillModel{ratio: ratio}, nil
+	case constants.FillModelClose:
+		return &closeFillModel{ratio: ratio}, nil
+	default:
+		return nil, fmt.Errorf("未知的成交模型: %s", name)
+	}
+}
@@ -0,0 +1,53 @@
+package simulator
+
+import (
+	"time"
+
+	"github.com/kfrico/BitfinexLendingBot/internal/bitfinex"
+	"github.com/kfrico/BitfinexLendingBot/internal/history"
+)
+
+// LiveMarket 以交易所即時公開數據作為模擬交易的市場來源
+type LiveMarket struct {
+	api history.MarketDataAPI
+}
+
+// NewLiveMarket 創建即時市場來源
+func NewLiveMarket(api history.MarketDataAPI) *LiveMarket {
+	return &LiveMarket{api: api}
+}
+
+// FundingBook 返回目前的訂單簿（即時數據忽略時間參數）
+func (m *LiveMarket) FundingBook(symbol string, limit int, at time.Time) ([]*bitfinex.FundingBookEntry, error) {
+	return m.api.GetFundingBook(symbol, limit)
+}
+
+// FundingRate 返回目前的 FRR
+func (m *LiveMarket) FundingRate(symbol string, at time.Time) (float64, error) {
+	return m.api.GetCurrentFundingRate(symbol)
+}
+
+// Candles 返回 at 之前已收盤的K線（排除尚未收盤的最新一根）
+func (m *LiveMarket) Candles(symbol, timeFrame string, limit int, at time.Time) ([]*bitfinex.Candle, error) {
+	duration, err := history.TimeFrameDuration(timeFrame)
+	if err != nil {
+		return nil, err
+	}
+
+	candles, err := m.api.GetFundingCandles(symbol, timeFrame, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	closed := make([]*bitfinex.Candle, 0, len(candles))
+	for _, candle := range candles {
+		if !history.MTSToTime(candle.MTS).Add(duration).After(at) {
+			closed = append(closed, candle)
+		}
+	}
+	if len(closed) > limit {
+		closed = closed[:limit]
+	}
+
+	return closed, nil
+}
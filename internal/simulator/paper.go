@@ -0,0 +1,68 @@
+package simulator
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/kfrico/BitfinexLendingBot/internal/config"
+	"github.com/kfrico/BitfinexLendingBot/internal/constants"
+	"github.com/kfrico/BitfinexLendingBot/internal/history"
+)
+
+// NewPaperExchange 依配置創建模擬交易帳戶
+// live 模式使用即時公開數據；replay 模式從 PAPER_REPLAY_START 起以實際時間速度回放 DATA_DIR 的歷史數據
+func NewPaperExchange(cfg *config.Config, api history.MarketDataAPI) (*Exchange, error) {
+	fillModel, err := NewFillModel(cfg.BacktestFillModel, cfg.BacktestFillRatio)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := Options{
+		Currency:       cfg.Currency,
+		InitialBalance: cfg.PaperInitialBalance,
+		FillModel:      fillModel,
+		IdleThreshold:  cfg.MinLoan,
+		Fees:           cfg.GetFeeModel(),
+	}
+
+	var market MarketSource
+	switch cfg.PaperMarketSource {
+	case constants.PaperMarketReplay:
+		replay, err := history.NewReplay(history.NewStore(cfg.DataDir), cfg.GetFundingSymbol(), cfg.BacktestTimeFrame)
+		if err != nil {
+			return nil, err
+		}
+
+		dataStart, dataEnd := replay.Range()
+		start := dataStart.Add(constants.BacktestWarmup)
+		if cfg.PaperReplayStart != "" {
+			requested, err := time.ParseInLocation(constants.DateLayout, cfg.PaperReplayStart, time.UTC)
+			if err != nil {
+				return nil, err
+			}
+			if requested.After(start) {
+				start = requested
+			}
+		}
+		if !start.Before(dataEnd) {
+			return nil, fmt.Errorf("回放起始時間 %s 超出歷史數據範圍（至 %s）",
+				start.Format(time.RFC3339), dataEnd.Format(time.RFC3339))
+		}
+
+		offset := start.Sub(time.Now())
+		opts.Clock = func() time.Time { return time.Now().Add(offset) }
+		opts.FillTimeFrame = cfg.BacktestTimeFrame
+		market = replay
+	default:
+		opts.Clock = time.Now
+		opts.FillTimeFrame = constants.PaperFillTimeFrame
+		market = NewLiveMarket(api)
+	}
+
+	if opts.FillInterval, err = history.TimeFrameDuration(opts.FillTimeFrame); err != nil {
+		return nil, err
+	}
+	opts.Start = opts.Clock()
+
+	return NewExchange(market, opts), nil
+}
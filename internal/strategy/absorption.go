@@ -0,0 +1,235 @@
+package strategy
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/kfrico/BitfinexLendingBot/internal/bitfinex"
+	"github.com/kfrico/BitfinexLendingBot/internal/constants"
+)
+
+// AbsorptionRung 單一分散單利率區間的吸收量估計
+// 區間為 [本筆利率, 下一筆利率)，最高一筆不設上限
+type AbsorptionRung struct {
+	Rate      float64 // 區間下限（訂單利率）
+	BidDepth  float64 // 區間內借款人掛單金額
+	TradeFlow float64 // 依公開成交推算的每週期成交量
+	FillFlow  float64 // 依自有成交速度推算的每週期成交量
+	Capacity  float64 // 估計一個週期內可吸收的金額
+}
+
+// absorptionFill 自有訂單的成交速度樣本
+type absorptionFill struct {
+	Rate   float64
+	Amount float64
+	Hours  float64 // 掛出到成交經過的小時數
+}
+
+// AbsorptionModel 訂單簿吸收量模型：依借款人掛單、近期公開成交與自有成交速度，
+// 估計各利率區間一個週期內可吸收的金額
+type AbsorptionModel struct {
+	mu        sync.Mutex
+	book      []*bitfinex.FundingBookEntry
+	trades    []*bitfinex.FundingTrade
+	fills     []absorptionFill
+	updatedAt time.Time
+	cycle     time.Duration
+	rungs     []AbsorptionRung // 最近一次估計結果
+}
+
+// NewAbsorptionModel 創建訂單簿吸收量模型
+func NewAbsorptionModel() *AbsorptionModel {
+	return &AbsorptionModel{}
+}
+
+// UpdateMarket 更新本週期的訂單簿與公開成交
+func (m *AbsorptionModel) UpdateMarket(book []*bitfinex.FundingBookEntry, trades []*bitfinex.FundingTrade, now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.book = book
+	m.trades = trades
+	m.updatedAt = now
+}
+
+// RecordFill 記錄自有訂單的成交金額與掛出到成交經過的時間
+func (m *AbsorptionModel) RecordFill(rate, amount float64, elapsed time.Duration) {
+	if amount <= 0 || rate <= 0 {
+		return
+	}
+	hours := math.Max(elapsed.Hours(), constants.AbsorptionMinFillHours)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.fills = append(m.fills, absorptionFill{Rate: rate, Amount: amount, Hours: hours})
+	if len(m.fills) > constants.AbsorptionFillHistorySize {
+		m.fills = m.fills[len(m.fills)-constants.AbsorptionFillHistorySize:]
+	}
+}
+
+// Estimate 估計各筆訂單利率區間一個週期內可吸收的金額，rates 需由低到高排列
+// 吸收量 = 區間內借款人掛單 + max(公開成交流量, 自有成交流量)
+func (m *AbsorptionModel) Estimate(rates []float64, cycle time.Duration) []AbsorptionRung {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rungs := make([]AbsorptionRung, len(rates))
+	for i, rate := range rates {
+		rungs[i].Rate = rate
+	}
+	if len(rates) == 0 {
+		return rungs
+	}
+	cycleHours := cycle.Hours()
+
+	// 借款人掛單（訂單簿中金額為負的一側）
+	for _, entry := range m.book {
+		if entry.Amount >= 0 {
+			continue
+		}
+		if i := rungIndex(rates, entry.Rate); i >= 0 {
+			rungs[i].BidDepth += -entry.Amount
+		}
+	}
+
+	// 近期公開成交換算為每週期流量
+	since := m.updatedAt.Add(-constants.AbsorptionTradeLookback).UnixNano() / int64(time.Millisecond)
+	lookbackHours := m.tradeWindow(since).Hours()
+	for _, trade := range m.trades {
+		if trade.MTS < since {
+			continue
+		}
+		if i := rungIndex(rates, trade.Rate); i >= 0 {
+			rungs[i].TradeFlow += math.Abs(trade.Amount) / lookbackHours * cycleHours
+		}
+	}
+
+	// 自有訂單平均成交速度換算為每週期流量
+	speedSums := make([]float64, len(rates))
+	counts := make([]int, len(rates))
+	for _, fill := range m.fills {
+		if i := rungIndex(rates, fill.Rate); i >= 0 {
+			speedSums[i] += fill.Amount / fill.Hours
+			counts[i]++
+		}
+	}
+	for i := range rungs {
+		if counts[i] > 0 {
+			rungs[i].FillFlow = speedSums[i] / float64(counts[i]) * cycleHours
+		}
+		rungs[i].Capacity = rungs[i].BidDepth + math.Max(rungs[i].TradeFlow, rungs[i].FillFlow)
+	}
+
+	m.rungs = rungs
+	m.cycle = cycle
+	return rungs
+}
+
+// tradeWindow 公開成交實際涵蓋的時間範圍（需持有鎖）
+// 查詢筆數達到上限時成交只涵蓋最舊一筆之後的時間，以此換算流量；未達上限時涵蓋完整的回溯時間
+func (m *AbsorptionModel) tradeWindow(since int64) time.Duration {
+	if len(m.trades) < constants.MaxFundingTradesLimit {
+		return constants.AbsorptionTradeLookback
+	}
+
+	oldest := int64(math.MaxInt64)
+	for _, trade := range m.trades {
+		if trade.MTS >= since && trade.MTS < oldest {
+			oldest = trade.MTS
+		}
+	}
+	if oldest == math.MaxInt64 {
+		return constants.AbsorptionTradeLookback
+	}
+
+	window := m.updatedAt.Sub(time.Unix(0, oldest*int64(time.Millisecond)))
+	if window < constants.AbsorptionMinTradeWindow {
+		window = constants.AbsorptionMinTradeWindow
+	}
+	if window > constants.AbsorptionTradeLookback {
+		window = constants.AbsorptionTradeLookback
+	}
+	return window
+}
+
+// rungIndex 返回利率所屬的區間索引，低於最低一筆利率時返回 -1
+func rungIndex(rates []float64, rate float64) int {
+	index := -1
+	for i, r := range rates {
+		if rate >= r {
+			index = i
+		}
+	}
+	return index
+}
+
+// absorptionCapacities 返回各區間的吸收量，各區間皆無吸收量數據時返回 nil
+func absorptionCapacities(rungs []AbsorptionRung) []float64 {
+	capacities := make([]float64, len(rungs))
+	total := 0.0
+	for i, rung := range rungs {
+		capacities[i] = rung.Capacity
+		total += rung.Capacity
+	}
+	if total <= 0 {
+		return nil
+	}
+	return capacities
+}
+
+// refreshAbsorption 以本週期訂單簿與公開成交更新吸收量模型（僅 absorption 分配方式使用）
+func (lb *LendingBot) refreshAbsorption(fundingBook []*bitfinex.FundingBookEntry) {
+	if lb.config.GetAllocationProfile() != constants.AllocationProfileAbsorption {
+		return
+	}
+
+	var trades []*bitfinex.FundingTrade
+	if api, ok := lb.client.(bitfinex.FundingTradeAPI); ok {
+		var err error
+		trades, err = api.GetFundingTrades(lb.config.GetFundingSymbol(), constants.MaxFundingTradesLimit)
+		if err != nil {
+			log.Printf("取得公開成交失敗，吸收量僅依訂單簿與自有成交估計: %v", err)
+		}
+	}
+	lb.absorption.UpdateMarket(fundingBook, trades, lb.now())
+}
+
+// recordAbsorptionFill 記錄追蹤訂單從掛出到借貸開始的成交速度
+func (lb *LendingBot) recordAbsorptionFill(rate, amount float64, createdAt, filledAt time.Time) {
+	lb.absorption.RecordFill(rate, amount, filledAt.Sub(createdAt))
+}
+
+// absorptionCycle 一個執行週期的時間長度
+func (lb *LendingBot) absorptionCycle() time.Duration {
+	minutes := lb.config.MinutesRun
+	if minutes <= 0 {
+		minutes = constants.DefaultMinutesRun
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// absorptionCapacities 估計各筆訂單利率區間一個週期內的吸收量（供 applyAllocationProfile 使用）
+func (lb *LendingBot) absorptionCapacities(rates []float64) []float64 {
+	return absorptionCapacities(lb.absorption.Estimate(rates, lb.absorptionCycle()))
+}
+
+// GetAbsorptionReport 獲取最近一次各利率區間吸收量估計（供 Telegram 指令使用）
+func (lb *LendingBot) GetAbsorptionReport() string {
+	m := lb.absorption
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.rungs) == 0 {
+		return fmt.Sprintf("尚未估計（自有成交樣本 %d 筆）", len(m.fills))
+	}
+
+	report := fmt.Sprintf("每週期 %.0f 分鐘，公開成交 %d 筆，自有成交樣本 %d 筆",
+		m.cycle.Minutes(), len(m.trades), len(m.fills))
+	for _, rung := range m.rungs {
+		report += fmt.Sprintf("\n≥ %.4f%%: 吸收量 %.2f (掛單 %.2f, 成交流量 %.2f, 自有成交 %.2f)",
+			lb.rateConverter.DecimalToPercentage(rung.Rate), rung.Capacity, rung.BidDepth, rung.TradeFlow, rung.FillFlow)
+	}
+	return report
+}
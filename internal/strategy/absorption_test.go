@@ -0,0 +1,144 @@
+package strategy
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/kfrico/BitfinexLendingBot/internal/bitfinex"
+	"github.com/kfrico/BitfinexLendingBot/internal/config"
+	"github.com/kfrico/BitfinexLendingBot/internal/constants"
+)
+
+func TestAbsorptionModel_Estimate(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	ms := func(d time.Duration) int64 { return now.Add(-d).UnixNano() / int64(time.Millisecond) }
+
+	model := NewAbsorptionModel()
+	model.UpdateMarket([]*bitfinex.FundingBookEntry{
+		{Rate: 0.00015, Amount: -3000}, // 借款人掛單，落在第一筆區間
+		{Rate: 0.00005, Amount: -500},  // 低於最低一筆利率，不納入
+		{Rate: 0.0002, Amount: 9999},   // 放貸人掛單，不納入
+	}, []*bitfinex.FundingTrade{
+		{MTS: ms(time.Hour), Amount: -600, Rate: 0.00025},
+		{MTS: ms(30 * time.Minute), Amount: 60, Rate: 0.0003},
+		{MTS: ms(7 * time.Hour), Amount: 6000, Rate: 0.00025}, // 超過回溯時間
+	}, now)
+	model.RecordFill(0.0003, 1000, 2*time.Hour)
+	model.RecordFill(0.00035, 200, time.Hour)
+
+	rungs := model.Estimate([]float64{0.0001, 0.0002, 0.0003}, time.Hour)
+	expected := []AbsorptionRung{
+		{Rate: 0.0001, BidDepth: 3000, Capacity: 3000},
+		{Rate: 0.0002, TradeFlow: 100, Capacity: 100},
+		{Rate: 0.0003, TradeFlow: 10, FillFlow: 350, Capacity: 350},
+	}
+	for i, rung := range rungs {
+		want := expected[i]
+		if math.Abs(rung.BidDepth-want.BidDepth) > floatTolerance || math.Abs(rung.TradeFlow-want.TradeFlow) > floatTolerance ||
+			math.Abs(rung.FillFlow-want.FillFlow) > floatTolerance || math.Abs(rung.Capacity-want.Capacity) > floatTolerance {
+			t.Errorf("rung %d = %+v, want %+v", i, rung, want)
+		}
+	}
+
+	capacities := absorptionCapacities(rungs)
+	for i, want := range []float64{3000, 100, 350} {
+		if math.Abs(capacities[i]-want) > floatTolerance {
+			t.Errorf("absorptionCapacities()[%d] = %v, want %v", i, capacities[i], want)
+		}
+	}
+	if absorptionCapacities(NewAbsorptionModel().Estimate([]float64{0.0001, 0.0002}, time.Hour)) != nil {
+		t.Error("absorptionCapacities() without data should return nil")
+	}
+}
+
+func TestAbsorptionModel_TruncatedTradeWindow(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	// 查詢筆數達到上限時，成交只涵蓋最近 30 分鐘，流量以 30 分鐘換算而非完整回溯時間
+	trades := make([]*bitfinex.FundingTrade, constants.MaxFundingTradesLimit)
+	for i := range trades {
+		at := now.Add(-time.Duration(i) * 30 * time.Minute / time.Duration(len(trades)))
+		trades[i] = &bitfinex.FundingTrade{MTS: at.UnixNano() / int64(time.Millisecond), Amount: 3, Rate: 0.0002}
+	}
+	model := NewAbsorptionModel()
+	model.UpdateMarket(nil, trades, now)
+
+	rungs := model.Estimate([]float64{0.0001, 0.0002}, time.Hour)
+	window := now.Sub(time.Unix(0, trades[len(trades)-1].MTS*int64(time.Millisecond))).Hours()
+	if want := 3000 / window; math.Abs(rungs[1].TradeFlow-want) > 1e-6 {
+		t.Errorf("TradeFlow = %v, want %v", rungs[1].TradeFlow, want)
+	}
+}
+
+func TestApplyAllocationProfile_Absorption(t *testing.T) {
+	cfg := &config.Config{MinLoan: 150, AllocationProfile: constants.AllocationProfileAbsorption}
+	newOffers := func() []*LoanOffer {
+		return []*LoanOffer{
+			{Amount: 1216.67, Rate: 0.0001},
+			{Amount: 1216.67, Rate: 0.0002},
+			{Amount: 1216.66, Rate: 0.0003},
+		}
+	}
+
+	offers := applyAllocationProfile(newOffers(), cfg, usdAmountRules, nil, 3, func(rates []float64) []float64 {
+		return []float64{3000, 300, 350}
+	})
+	for i, want := range []float64{3000, 300, 350} {
+		if offers[i].Amount != want {
+			t.Errorf("offer %d amount = %.2f, want %.2f", i, offers[i].Amount, want)
+		}
+	}
+
+	// 沒有吸收量數據時維持平均分配
+	offers = applyAllocationProfile(newOffers(), cfg, usdAmountRules, nil, 3, func(rates []float64) []float64 { return nil })
+	if offers[0].Amount != 1216.67 || offers[2].Amount != 1216.66 {
+		t.Errorf("absorption without data should keep amounts, got %.2f/%.2f/%.2f", offers[0].Amount, offers[1].Amount, offers[2].Amount)
+	}
+}
+
+func TestApplyAllocationProfile_AbsorptionCapsRungs(t *testing.T) {
+	cfg := &config.Config{MinLoan: 150, AllocationProfile: constants.AllocationProfileAbsorption}
+	offers := []*LoanOffer{
+		{Amount: 3333.34, Rate: 0.0001, Period: 2},
+		{Amount: 3333.33, Rate: 0.0002, Period: 2},
+		{Amount: 3333.33, Rate: 0.0003, Period: 30},
+	}
+	capacities := []float64{500, 200, 100}
+
+	// 總吸收量遠低於資金：每筆不超過吸收量（不足最小金額的以最小金額計），
+	// 多出的資金輪流加掛吸收量大小的訂單直到可用筆數，其餘本週期不掛出
+	sized := applyAllocationProfile(offers, cfg, usdAmountRules, nil, 8, func(rates []float64) []float64 { return capacities })
+	if len(sized) != 8 {
+		t.Fatalf("expected 8 offers, got %d", len(sized))
+	}
+
+	expected := []struct {
+		rate   float64
+		amount float64
+		period int
+	}{
+		{0.0001, 500, 2}, {0.0001, 500, 2}, {0.0001, 500, 2},
+		{0.0002, 200, 2}, {0.0002, 200, 2}, {0.0002, 200, 2},
+		{0.0003, 150, 30}, {0.0003, 150, 30},
+	}
+	total := 0.0
+	for i, offer := range sized {
+		want := expected[i]
+		if offer.Rate != want.rate || offer.Amount != want.amount || offer.Period != want.period {
+			t.Errorf("offer %d = %.4f%%/%.2f/%d, want %.4f%%/%.2f/%d",
+				i, offer.Rate*100, offer.Amount, offer.Period, want.rate*100, want.amount, want.period)
+		}
+		total += offer.Amount
+	}
+	if total != 2400 {
+		t.Errorf("placed %.2f, want 2400", total)
+	}
+
+	// 可用筆數不足加掛時只保留原本的訂單
+	offers = []*LoanOffer{{Amount: 5000, Rate: 0.0001}, {Amount: 5000, Rate: 0.0002}}
+	sized = applyAllocationProfile(offers, cfg, usdAmountRules, nil, 2, func(rates []float64) []float64 { return []float64{500, 200} })
+	if len(sized) != 2 || sized[0].Amount != 500 || sized[1].Amount != 200 {
+		t.Errorf("expected rungs capped at capacity without extra offers, got %+v, %+v", *sized[0], *sized[1])
+	}
+}
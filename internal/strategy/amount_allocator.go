@@ -1,13 +1,18 @@
 package strategy
 
-import "math"
+import (
+	"log"
+	"math"
+	"sort"
 
-func floorToCents(amount float64) float64 {
-	return math.Floor((amount+1e-9)*100) / 100
-}
+	"github.com/kfrico/BitfinexLendingBot/internal/bitfinex"
+	"github.com/kfrico/BitfinexLendingBot/internal/config"
+	"github.com/kfrico/BitfinexLendingBot/internal/constants"
+)
 
-// buildOrderAmounts 盡量平均分配全部資金，並確保每筆金額介於 MIN_LOAN 與 MAX_LOAN 之間。
-func buildOrderAmounts(totalFunds float64, requestedSplits int, minLoan float64, maxLoan float64) []float64 {
+// buildOrderAmounts 盡量平均分配全部資金，並確保每筆金額介於最小與最大貸出金額之間，金額以幣種最小單位計。
+func buildOrderAmounts(totalFunds float64, requestedSplits int, rules amountRules) []float64 {
+	minLoan, maxLoan := rules.minLoan, rules.maxLoan
 	if requestedSplits <= 0 || totalFunds < minLoan {
 		return nil
 	}
@@ -35,18 +40,19 @@ func buildOrderAmounts(totalFunds float64, requestedSplits int, minLoan float64,
 		return amounts
 	}
 
-	totalCents := int(math.Floor((totalFunds + 1e-9) * 100))
-	baseCents := totalCents / orderCount
-	remainderCents := totalCents % orderCount
+	scale := rules.scale()
+	totalUnits := int64(math.Floor((totalFunds + 1e-9) * scale))
+	baseUnits := totalUnits / int64(orderCount)
+	remainderUnits := totalUnits % int64(orderCount)
 
 	amounts := make([]float64, 0, orderCount)
 	for i := 0; i < orderCount; i++ {
-		cents := baseCents
-		if i < remainderCents {
-			cents++
+		units := baseUnits
+		if int64(i) < remainderUnits {
+			units++
 		}
 
-		amount := float64(cents) / 100
+		amount := float64(units) / scale
 		if amount < minLoan {
 			return nil
 		}
@@ -58,3 +64,278 @@ func buildOrderAmounts(totalFunds float64, requestedSplits int, minLoan float64,
 
 	return amounts
 }
+
+// allocationWeights 依分配方式計算各筆訂單的權重，rates 為由低到高排列的訂單利率，fundingBook 供 book 分配方式使用
+func allocationWeights(profile string, ratio float64, rates []float64, fundingBook []*bitfinex.FundingBookEntry) []float64 {
+	n := len(rates)
+	weights := make([]float64, n)
+
+	switch profile {
+	case constants.AllocationProfileGeometric:
+		// 第一筆（最低利率）權重最大，之後每筆乘上 ratio
+		for i := range weights {
+			weights[i] = math.Pow(ratio, float64(i))
+		}
+	case constants.AllocationProfileInverse:
+		for i := range weights {
+			weights[i] = math.Pow(ratio, float64(n-1-i))
+		}
+	case constants.AllocationProfileBook:
+		if bookWeights := bookGapWeights(rates, fundingBook); bookWeights != nil {
+			return bookWeights
+		}
+		log.Println("訂單簿檔位不足，分散單維持平均分配")
+		for i := range weights {
+			weights[i] = 1
+		}
+	default:
+		for i := range weights {
+			weights[i] = 1
+		}
+	}
+
+	return weights
+}
+
+// bookGapWeights 依訂單簿 ask 檔位的間距計算權重：每筆訂單的權重為其利率所在區間（下方最近檔位到上方最近檔位）的寬度，
+// 間距大代表該利率區間掛單稀疏，分配較多資金。低於最佳 ask 的訂單取到最佳 ask 的距離，高於所有檔位的訂單沿用最上方兩檔的間距；
+// ask 檔位少於兩檔或所有權重為 0 時返回 nil
+func bookGapWeights(rates []float64, fundingBook []*bitfinex.FundingBookEntry) []float64 {
+	var levels []float64
+	for _, ask := range askLevels(fundingBook) {
+		if len(levels) == 0 || ask.Rate > levels[len(levels)-1] {
+			levels = append(levels, ask.Rate)
+		}
+	}
+	if len(levels) < 2 {
+		return nil
+	}
+
+	weights := make([]float64, len(rates))
+	total := 0.0
+	for i, rate := range rates {
+		upper := sort.Search(len(levels), func(j int) bool { return levels[j] > rate })
+		switch {
+		case upper == 0:
+			weights[i] = levels[0] - rate
+		case upper == len(levels):
+			weights[i] = levels[len(levels)-1] - levels[len(levels)-2]
+		default:
+			weights[i] = levels[upper] - levels[upper-1]
+		}
+		total += weights[i]
+	}
+	if total <= 0 {
+		return nil
+	}
+	return weights
+}
+
+// buildWeightedOrderAmounts 依權重分配資金，每筆金額介於最小與最大貸出金額之間並以幣種最小單位計，
+// 總額不超過可用資金；低於最小金額的筆數提高至最小金額，超過最大金額的封頂，差額由其餘筆數依權重分攤。
+func buildWeightedOrderAmounts(totalFunds float64, weights []float64, rules amountRules) []float64 {
+	return buildCappedOrderAmounts(totalFunds, weights, nil, rules)
+}
+
+// buildCappedOrderAmounts 同 buildWeightedOrderAmounts，另以 caps 限制各筆金額上限（低於最小金額的上限以最小金額計）；
+// caps 為 nil 時只受最大貸出金額限制。所有筆數都達上限時，剩餘資金不分配
+func buildCappedOrderAmounts(totalFunds float64, weights, caps []float64, rules amountRules) []float64 {
+	n := len(weights)
+	if n == 0 {
+		return nil
+	}
+
+	// 以下 cents 均指幣種最小單位（兩位小數時即為分）
+	scale := rules.scale()
+	totalCents := math.Floor((totalFunds + 1e-9) * scale)
+	minCents := math.Ceil(rules.minLoan*scale - 1e-6)
+	if totalCents < minCents*float64(n) {
+		return nil
+	}
+	maxCents := make([]float64, n)
+	budget := 0.0
+	for i := range maxCents {
+		maxCents[i] = math.Inf(1)
+		if rules.maxLoan > 0 {
+			maxCents[i] = math.Floor(rules.maxLoan*scale + 1e-6)
+		}
+		if caps != nil {
+			maxCents[i] = math.Max(math.Min(maxCents[i], math.Floor(caps[i]*scale+1e-6)), minCents)
+		}
+		budget += maxCents[i]
+	}
+	budget = math.Min(totalCents, budget)
+
+	cents := make([]float64, n)
+	fixed := make([]bool, n)
+	for {
+		remaining := budget
+		weightSum := 0.0
+		free := 0
+		for i := range weights {
+			if fixed[i] {
+				remaining -= cents[i]
+			} else {
+				weightSum += weights[i]
+				free++
+			}
+		}
+		if free == 0 {
+			break
+		}
+
+		for i, w := range weights {
+			if fixed[i] {
+				continue
+			}
+			if weightSum > 0 {
+				cents[i] = remaining * w / weightSum
+			} else {
+				cents[i] = remaining / float64(free)
+			}
+		}
+
+		// 先處理低於最小金額的筆數，再處理超過最大金額的筆數
+		changed := false
+		for i := range weights {
+			if !fixed[i] && cents[i] < minCents {
+				cents[i], fixed[i], changed = minCents, true, true
+			}
+		}
+		if !changed {
+			for i := range weights {
+				if !fixed[i] && cents[i] > maxCents[i] {
+					cents[i], fixed[i], changed = maxCents[i], true, true
+				}
+			}
+		}
+		if !changed {
+			break
+		}
+	}
+
+	// 無條件捨去至最小單位，剩餘的單位依權重由大到小逐筆補回
+	allocated := 0.0
+	for i := range cents {
+		cents[i] = math.Floor(cents[i] + 1e-6)
+		allocated += cents[i]
+	}
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return weights[order[a]] > weights[order[b]]
+	})
+	for leftover := budget - allocated; leftover >= 1; {
+		progressed := false
+		for _, i := range order {
+			if leftover < 1 {
+				break
+			}
+			if cents[i]+1 <= maxCents[i] {
+				cents[i]++
+				leftover--
+				progressed = true
+			}
+		}
+		if !progressed {
+			break
+		}
+	}
+
+	amounts := make([]float64, n)
+	for i, c := range cents {
+		amounts[i] = c / scale
+	}
+	return amounts
+}
+
+// applyAllocationProfile 依 ALLOCATION_PROFILE 重新分配分散單金額並返回調整後的訂單，利率階梯維持不變
+// offers 需依利率由低到高排列；平均分配或只有一筆時不調整
+// fundingBook 為 book 分配方式使用的訂單簿；absorption 返回各筆訂單利率區間一個週期內的吸收量（absorption 分配方式使用），
+// 為 nil 或沒有吸收量數據時維持平均分配；maxOrders 為分散單可用的訂單數（負數表示不限制）
+func applyAllocationProfile(offers []*LoanOffer, cfg *config.Config, rules amountRules, fundingBook []*bitfinex.FundingBookEntry, maxOrders int, absorption func(rates []float64) []float64) []*LoanOffer {
+	profile := cfg.GetAllocationProfile()
+	if profile == constants.AllocationProfileEqual || len(offers) < 2 {
+		return offers
+	}
+
+	total := 0.0
+	rates := make([]float64, len(offers))
+	for i, offer := range offers {
+		total += offer.Amount
+		rates[i] = offer.Rate
+	}
+
+	if profile == constants.AllocationProfileAbsorption {
+		var capacities []float64
+		if absorption != nil {
+			capacities = absorption(rates)
+		}
+		if len(capacities) != len(offers) {
+			log.Println("沒有吸收量數據，分散單維持平均分配")
+			return offers
+		}
+		return sizeOffersByCapacity(offers, total, capacities, rules, maxOrders)
+	}
+
+	amounts := buildWeightedOrderAmounts(total, allocationWeights(profile, cfg.AllocationRatio, rates, fundingBook), rules)
+	if len(amounts) != len(offers) {
+		return offers
+	}
+	for i, offer := range offers {
+		offer.Amount = amounts[i]
+	}
+	return offers
+}
+
+// sizeOffersByCapacity 依吸收量決定各筆訂單金額：每筆不超過其區間一個週期內的吸收量（至少為最小貸出金額），
+// 資金少於總吸收量時依吸收量比例分配；超出總吸收量的資金由低利率起輪流在各區間加掛吸收量大小的訂單，
+// 直到用完可用訂單數，仍剩餘的資金本週期不掛出，避免單筆大額訂單長時間停在稀薄的區間
+func sizeOffersByCapacity(offers []*LoanOffer, total float64, capacities []float64, rules amountRules, maxOrders int) []*LoanOffer {
+	amounts := buildCappedOrderAmounts(total, capacities, capacities, rules)
+	if len(amounts) != len(offers) {
+		return offers
+	}
+
+	remaining := total
+	for i, offer := range offers {
+		offer.Amount = amounts[i]
+		remaining -= amounts[i]
+	}
+
+	if maxOrders < 0 {
+		maxOrders = constants.AbsorptionMaxOrders
+	}
+	sized := offers
+	for added := true; added && len(sized) < maxOrders; {
+		added = false
+		for i, offer := range offers {
+			if len(sized) >= maxOrders {
+				break
+			}
+			piece := rules.floor(math.Min(remaining, math.Max(capacities[i], rules.minLoan)))
+			if rules.maxLoan > 0 {
+				piece = math.Min(piece, rules.maxLoan)
+			}
+			if piece < rules.minLoan {
+				continue
+			}
+			extra := *offer
+			extra.Amount = piece
+			sized = append(sized, &extra)
+			remaining -= piece
+			added = true
+		}
+	}
+
+	sort.SliceStable(sized, func(i, j int) bool { return sized[i].Rate < sized[j].Rate })
+	for i, offer := range sized {
+		log.Printf("吸收量分配 #%d - 利率: %.6f%%, 金額: %.2f", i+1, offer.Rate*100, offer.Amount)
+	}
+	if remaining >= rules.minLoan {
+		log.Printf("分散單已達吸收量與訂單數上限，%.2f 本週期不掛出", remaining)
+	}
+	return sized
+}
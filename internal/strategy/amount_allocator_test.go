@@ -3,6 +3,10 @@ package strategy
 import (
 	"reflect"
 	"testing"
+
+	"github.com/kfrico/BitfinexLendingBot/internal/bitfinex"
+	"github.com/kfrico/BitfinexLendingBot/internal/config"
+	"github.com/kfrico/BitfinexLendingBot/internal/constants"
 )
 
 func TestBuildOrderAmounts(t *testing.T) {
@@ -58,10 +62,164 @@ func TestBuildOrderAmounts(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			actual := buildOrderAmounts(tt.totalFunds, tt.requestedSplits, tt.minLoan, tt.maxLoan)
+			actual := buildOrderAmounts(tt.totalFunds, tt.requestedSplits, amountRules{precision: 2, minLoan: tt.minLoan, maxLoan: tt.maxLoan})
 			if !reflect.DeepEqual(actual, tt.expected) {
 				t.Fatalf("expected %v, got %v", tt.expected, actual)
 			}
 		})
 	}
 }
+
+func TestBuildWeightedOrderAmounts(t *testing.T) {
+	tests := []struct {
+		name       string
+		profile    string
+		ratio      float64
+		rates      []float64
+		book       []*bitfinex.FundingBookEntry
+		totalFunds float64
+		minLoan    float64
+		maxLoan    float64
+		expected   []float64
+	}{
+		{
+			name:       "geometric puts more capital at lower rates",
+			profile:    constants.AllocationProfileGeometric,
+			ratio:      0.5,
+			rates:      []float64{0.0001, 0.0002, 0.0003},
+			totalFunds: 1000,
+			minLoan:    100,
+			expected:   []float64{571.43, 285.72, 142.85},
+		},
+		{
+			name:       "inverse puts more capital at higher rates",
+			profile:    constants.AllocationProfileInverse,
+			ratio:      0.5,
+			rates:      []float64{0.0001, 0.0002, 0.0003},
+			totalFunds: 700,
+			minLoan:    50,
+			expected:   []float64{100, 200, 400},
+		},
+		{
+			name:    "book weights follow gaps between book levels",
+			profile: constants.AllocationProfileBook,
+			rates:   []float64{0.0001, 0.0002, 0.0006},
+			book: []*bitfinex.FundingBookEntry{
+				{Rate: 0.0001, Amount: 1000},
+				{Rate: 0.0002, Amount: 1000},
+				{Rate: 0.0005, Amount: 1000},
+				{Rate: 0.0006, Amount: 1000},
+				{Rate: 0.00009, Amount: -1000}, // bid 不列入
+			},
+			totalFunds: 700,
+			minLoan:    50,
+			expected:   []float64{140, 420, 140},
+		},
+		{
+			name:    "book weights ignore gaps between ladder rates",
+			profile: constants.AllocationProfileBook,
+			rates:   []float64{0.0001, 0.00015, 0.0009},
+			book: []*bitfinex.FundingBookEntry{
+				{Rate: 0.0002, Amount: 1000},
+				{Rate: 0.0003, Amount: 1000},
+			},
+			totalFunds: 900,
+			minLoan:    50,
+			expected:   []float64{360, 180, 360},
+		},
+		{
+			name:       "book falls back to equal without book levels",
+			profile:    constants.AllocationProfileBook,
+			rates:      []float64{0.0001, 0.0002, 0.0005},
+			book:       []*bitfinex.FundingBookEntry{{Rate: 0.0002, Amount: 1000}},
+			totalFunds: 900,
+			minLoan:    50,
+			expected:   []float64{300, 300, 300},
+		},
+		{
+			name:       "raises small orders to min loan",
+			profile:    constants.AllocationProfileGeometric,
+			ratio:      0.5,
+			rates:      []float64{0.0001, 0.0002, 0.0003},
+			totalFunds: 1000,
+			minLoan:    150,
+			expected:   []float64{566.67, 283.33, 150},
+		},
+		{
+			name:       "caps orders at max loan and redistributes",
+			profile:    constants.AllocationProfileGeometric,
+			ratio:      0.5,
+			rates:      []float64{0.0001, 0.0002, 0.0003},
+			totalFunds: 1000,
+			minLoan:    100,
+			maxLoan:    400,
+			expected:   []float64{400, 400, 200},
+		},
+		{
+			name:       "leaves remainder when every order is capped",
+			profile:    constants.AllocationProfileInverse,
+			ratio:      0.5,
+			rates:      []float64{0.0001, 0.0002},
+			totalFunds: 5000,
+			minLoan:    150,
+			maxLoan:    1000,
+			expected:   []float64{1000, 1000},
+		},
+		{
+			name:       "never rounds above available funds",
+			profile:    constants.AllocationProfileGeometric,
+			ratio:      0.7,
+			rates:      []float64{0.0001, 0.0002},
+			totalFunds: 397.539,
+			minLoan:    150,
+			expected:   []float64{233.85, 163.68},
+		},
+		{
+			name:       "returns nil when min loan cannot be met",
+			profile:    constants.AllocationProfileGeometric,
+			ratio:      0.5,
+			rates:      []float64{0.0001, 0.0002},
+			totalFunds: 250,
+			minLoan:    150,
+			expected:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			weights := allocationWeights(tt.profile, tt.ratio, tt.rates, tt.book)
+			actual := buildWeightedOrderAmounts(tt.totalFunds, weights, amountRules{precision: 2, minLoan: tt.minLoan, maxLoan: tt.maxLoan})
+			if !reflect.DeepEqual(actual, tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestApplyAllocationProfile(t *testing.T) {
+	newOffers := func() []*LoanOffer {
+		return []*LoanOffer{
+			{Amount: 333.34, Rate: 0.0001},
+			{Amount: 333.33, Rate: 0.0002},
+			{Amount: 333.33, Rate: 0.0003},
+		}
+	}
+
+	cfg := &config.Config{MinLoan: 150, AllocationRatio: 0.5}
+	offers := applyAllocationProfile(newOffers(), cfg, usdAmountRules, nil, 3, nil)
+	if offers[0].Amount != 333.34 || offers[2].Amount != 333.33 {
+		t.Fatalf("equal profile should keep amounts, got %.2f/%.2f/%.2f", offers[0].Amount, offers[1].Amount, offers[2].Amount)
+	}
+
+	cfg.AllocationProfile = "Geometric"
+	offers = applyAllocationProfile(newOffers(), cfg, usdAmountRules, nil, 3, nil)
+	expected := []float64{566.67, 283.33, 150}
+	for i, offer := range offers {
+		if offer.Amount != expected[i] {
+			t.Errorf("offer %d amount = %.2f, want %.2f", i, offer.Amount, expected[i])
+		}
+		if offer.Rate != newOffers()[i].Rate {
+			t.Errorf("offer %d rate changed to %v", i, offer.Rate)
+		}
+	}
+}
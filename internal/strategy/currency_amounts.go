@@ -0,0 +1,80 @@
+package strategy
+
+import (
+	"math"
+
+	"github.com/kfrico/BitfinexLendingBot/internal/bitfinex"
+	"github.com/kfrico/BitfinexLendingBot/internal/config"
+)
+
+// CurrencyInfoProvider 幣種金額規格來源（正式環境為 bitfinex.CurrencyMetadata）
+type CurrencyInfoProvider interface {
+	Info(currency string) bitfinex.CurrencyInfo
+}
+
+// amountRules 下單金額規則：金額小數位數與每筆金額上下限
+type amountRules struct {
+	precision int
+	minLoan   float64 // MIN_LOAN 與交易所最低掛單金額取大者
+	maxLoan   float64 // 0 表示不限制
+}
+
+// newAmountRules 依配置與幣種規格建立下單金額規則
+func newAmountRules(cfg *config.Config, info bitfinex.CurrencyInfo) amountRules {
+	rules := amountRules{
+		precision: info.Precision,
+		minLoan:   math.Max(cfg.MinLoan, info.MinOfferAmount),
+		maxLoan:   cfg.MaxLoan,
+	}
+	// MIN_LOAN 或交易所最低金額不在最小單位上時無條件進位，確保不低於限制
+	rules.minLoan = math.Ceil(rules.minLoan*rules.scale()-1e-6) / rules.scale()
+	// 交易所最低金額高於 MAX_LOAN 時以交易所為準，否則無法下單
+	if rules.maxLoan > 0 && rules.maxLoan < rules.minLoan {
+		rules.maxLoan = rules.minLoan
+	}
+	return rules
+}
+
+// scale 每單位金額的最小單位數量（兩位小數為 100）
+func (r amountRules) scale() float64 {
+	return math.Pow10(r.precision)
+}
+
+// floor 無條件捨去至最小單位
+func (r amountRules) floor(amount float64) float64 {
+	return math.Floor((amount+1e-9)*r.scale()) / r.scale()
+}
+
+// currencyInfo 返回目前幣種的金額規格，未設置幣種資訊來源時使用預設規格
+func (lb *LendingBot) currencyInfo() bitfinex.CurrencyInfo {
+	if lb.currencyMeta == nil {
+		return bitfinex.DefaultCurrencyInfo(lb.config.Currency)
+	}
+	return lb.currencyMeta.Info(lb.config.Currency)
+}
+
+// minOfferUnknown 判斷實盤是否無法取得交易所最低掛單金額（價格查詢失敗且沒有快取）
+// 未設置幣種資訊來源時（模擬交易與回測）不受交易所最低金額限制
+func (lb *LendingBot) minOfferUnknown() bool {
+	return lb.currencyMeta != nil && lb.currencyInfo().MinOfferAmount <= 0
+}
+
+// amountRules 返回本次下單使用的金額規則
+func (lb *LendingBot) amountRules() amountRules {
+	return newAmountRules(lb.config, lb.currencyInfo())
+}
+
+// SetCurrencyInfoProvider 設置幣種金額規格來源
+func (lb *LendingBot) SetCurrencyInfoProvider(provider CurrencyInfoProvider) {
+	lb.currencyMeta = provider
+}
+
+// GetCurrencyInfo 返回目前幣種的金額規格（Telegram 顯示用）
+func (lb *LendingBot) GetCurrencyInfo() bitfinex.CurrencyInfo {
+	return lb.currencyInfo()
+}
+
+// GetEffectiveMinLoan 返回實際生效的最小貸出金額
+func (lb *LendingBot) GetEffectiveMinLoan() float64 {
+	return lb.amountRules().minLoan
+}
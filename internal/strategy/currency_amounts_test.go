@@ -0,0 +1,133 @@
+package strategy
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kfrico/BitfinexLendingBot/internal/bitfinex"
+	"github.com/kfrico/BitfinexLendingBot/internal/config"
+	"github.com/kfrico/BitfinexLendingBot/internal/simulator"
+)
+
+// usdAmountRules USD 幣種在 MIN_LOAN 150 時的下單金額規則
+var usdAmountRules = amountRules{precision: 2, minLoan: 150}
+
+type staticCurrencyInfo map[string]float64
+
+func (s staticCurrencyInfo) Info(currency string) bitfinex.CurrencyInfo {
+	return bitfinex.NewCurrencyInfo(currency, s[currency])
+}
+
+func TestNewAmountRules(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      *config.Config
+		info     bitfinex.CurrencyInfo
+		expected amountRules
+	}{
+		{
+			name:     "usd keeps cents and exchange minimum",
+			cfg:      &config.Config{MinLoan: 50, MaxLoan: 1000},
+			info:     bitfinex.DefaultCurrencyInfo("USD"),
+			expected: amountRules{precision: 2, minLoan: 150, maxLoan: 1000},
+		},
+		{
+			name:     "btc raises min loan to exchange minimum",
+			cfg:      &config.Config{MinLoan: 0.001},
+			info:     bitfinex.NewCurrencyInfo("BTC", 60000),
+			expected: amountRules{precision: 7, minLoan: 0.0025},
+		},
+		{
+			name:     "btc keeps larger min loan and lifts max loan below exchange minimum",
+			cfg:      &config.Config{MinLoan: 0.01, MaxLoan: 0.002},
+			info:     bitfinex.NewCurrencyInfo("BTC", 60000),
+			expected: amountRules{precision: 7, minLoan: 0.01, maxLoan: 0.01},
+		},
+		{
+			name:     "unknown price falls back to max precision without exchange minimum",
+			cfg:      &config.Config{MinLoan: 0.005},
+			info:     bitfinex.DefaultCurrencyInfo("ETH"),
+			expected: amountRules{precision: 8, minLoan: 0.005},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := newAmountRules(tt.cfg, tt.info); actual != tt.expected {
+				t.Errorf("newAmountRules() = %+v, want %+v", actual, tt.expected)
+			}
+		})
+	}
+}
+
+func TestBuildOrderAmounts_CurrencyPrecision(t *testing.T) {
+	rules := newAmountRules(&config.Config{MinLoan: 0.001}, bitfinex.NewCurrencyInfo("BTC", 60000))
+
+	// 0.0123456 BTC 分三筆，以 0.0000001 為單位平均分配
+	expected := []float64{0.0041152, 0.0041152, 0.0041152}
+	if actual := buildOrderAmounts(0.01234569, 3, rules); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("buildOrderAmounts() = %v, want %v", actual, expected)
+	}
+
+	// 每筆不足交易所最低金額時減少筆數
+	expected = []float64{0.003, 0.003}
+	if actual := buildOrderAmounts(0.006, 3, rules); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("buildOrderAmounts() below exchange minimum = %v, want %v", actual, expected)
+	}
+}
+
+func TestLendingBot_CurrencyInfoProvider(t *testing.T) {
+	cfg := &config.Config{Currency: "BTC", MinLoan: 0.001, SpreadLend: 2, GapTop: 1}
+	bot := NewLendingBot(cfg, nil)
+	bot.SetCurrencyInfoProvider(staticCurrencyInfo{"BTC": 50000})
+
+	if got := bot.GetEffectiveMinLoan(); got != 0.003 {
+		t.Errorf("GetEffectiveMinLoan() = %v, want 0.003", got)
+	}
+
+	offers := bot.calculateLoanOffers(0.00999999, nil)
+	if len(offers) != 2 || offers[0].Amount != 0.005 || offers[1].Amount != 0.0049999 {
+		t.Errorf("calculateLoanOffers() amounts = %+v, want 0.005 and 0.0049999", offers)
+	}
+}
+
+func TestLendingBot_SkipsPlacementWithoutExchangeMinimum(t *testing.T) {
+	now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	cfg := &config.Config{Currency: "ETH", MinLoan: 0.01, MinDailyLendRate: 0.02, SpreadLend: 2, GapTop: 2}
+	cfg.ApplyDefaults()
+
+	exchange := simulator.NewExchange(&bookMarket{bestAsk: 0.0003}, simulator.Options{
+		Currency:       "ETH",
+		InitialBalance: 1,
+		FillTimeFrame:  "1h",
+		FillInterval:   time.Hour,
+		Start:          now,
+	})
+	bot := NewLendingBot(cfg, exchange)
+	bot.SetClock(exchange.Now, func(time.Duration) {})
+
+	// 價格查詢失敗時最低掛單金額未知，本週期不掛單
+	bot.SetCurrencyInfoProvider(staticCurrencyInfo{})
+	if err := bot.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if offers, _ := exchange.GetFundingOffers(cfg.GetFundingSymbol()); len(offers) != 0 {
+		t.Fatalf("expected no offers without exchange minimum, got %d", len(offers))
+	}
+
+	// 取得價格後恢復掛單，每筆不低於 150 美元等值
+	bot.SetCurrencyInfoProvider(staticCurrencyInfo{"ETH": 3000})
+	if err := bot.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	offers, _ := exchange.GetFundingOffers(cfg.GetFundingSymbol())
+	if len(offers) == 0 {
+		t.Fatal("expected offers once the exchange minimum is known")
+	}
+	for _, offer := range offers {
+		if offer.Amount < 0.05 {
+			t.Errorf("offer amount %v below the exchange minimum 0.05", offer.Amount)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package strategy
+
+import (
+	"sort"
+
+	"github.com/kfrico/BitfinexLendingBot/internal/bitfinex"
+)
+
+// askLevels 取出 funding book 中的 ask（放貸方掛單，Amount > 0），依利率由低到高排序
+func askLevels(fundingBook []*bitfinex.FundingBookEntry) []*bitfinex.FundingBookEntry {
+	asks := make([]*bitfinex.FundingBookEntry, 0, len(fundingBook))
+	for _, entry := range fundingBook {
+		if entry == nil || entry.Amount <= 0 || entry.Rate <= 0 {
+			continue
+		}
+		asks = append(asks, entry)
+	}
+
+	sort.SliceStable(asks, func(i, j int) bool {
+		return asks[i].Rate < asks[j].Rate
+	})
+
+	return asks
+}
+
+// totalAskVolume 計算 ask 總掛單量
+func totalAskVolume(asks []*bitfinex.FundingBookEntry) float64 {
+	total := 0.0
+	for _, entry := range asks {
+		total += entry.Amount
+	}
+	return total
+}
+
+// findRateAtCumulativeVolume 找出前方累計 ask 量達到目標金額的檔位利率
+// 目標超過訂單簿總量時使用最深一檔的利率；沒有 ask 數據時返回 false
+func findRateAtCumulativeVolume(asks []*bitfinex.FundingBookEntry, targetVolume float64) (float64, bool) {
+	if len(asks) == 0 {
+		return 0, false
+	}
+
+	cumulative := 0.0
+	for _, entry := range asks {
+		cumulative += entry.Amount
+		if cumulative >= targetVolume {
+			return entry.Rate, true
+		}
+	}
+
+	return asks[len(asks)-1].Rate, true
+}
@@ -0,0 +1,61 @@
+package strategy
+
+import (
+	"testing"
+
+	"github.com/kfrico/BitfinexLendingBot/internal/bitfinex"
+)
+
+func TestAskLevels(t *testing.T) {
+	book := []*bitfinex.FundingBookEntry{
+		{Rate: 0.0003, Amount: 1000},
+		{Rate: 0.0001, Amount: -5000}, // bid
+		{Rate: 0.0002, Amount: 2000},
+		{Rate: 0, Amount: 100}, // 無效利率
+	}
+
+	asks := askLevels(book)
+	if len(asks) != 2 {
+		t.Fatalf("expected 2 ask levels, got %d", len(asks))
+	}
+	if asks[0].Rate != 0.0002 || asks[1].Rate != 0.0003 {
+		t.Fatalf("expected asks sorted by rate, got %v and %v", asks[0].Rate, asks[1].Rate)
+	}
+}
+
+func TestFindRateAtCumulativeVolume(t *testing.T) {
+	asks := []*bitfinex.FundingBookEntry{
+		{Rate: 0.0001, Amount: 30000},
+		{Rate: 0.0002, Amount: 100000},
+		{Rate: 0.0003, Amount: 500000},
+		{Rate: 0.0004, Amount: 1000000},
+	}
+
+	tests := []struct {
+		name         string
+		targetVolume float64
+		expectedRate float64
+	}{
+		{name: "inside first level", targetVolume: 10000, expectedRate: 0.0001},
+		{name: "exactly at level boundary", targetVolume: 30000, expectedRate: 0.0001},
+		{name: "second level", targetVolume: 50000, expectedRate: 0.0002},
+		{name: "deep level", targetVolume: 1000000, expectedRate: 0.0004},
+		{name: "beyond book uses deepest level", targetVolume: 5000000, expectedRate: 0.0004},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rate, ok := findRateAtCumulativeVolume(asks, tt.targetVolume)
+			if !ok {
+				t.Fatalf("expected rate to be found")
+			}
+			if rate != tt.expectedRate {
+				t.Fatalf("expected %v, got %v", tt.expectedRate, rate)
+			}
+		})
+	}
+
+	if _, ok := findRateAtCumulativeVolume(nil, 1000); ok {
+		t.Fatalf("expected no rate for empty book")
+	}
+}
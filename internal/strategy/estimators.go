@@ -0,0 +1,301 @@
+package strategy
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/kfrico/BitfinexLendingBot/internal/constants"
+)
+
+// TrendReading 趨勢估計結果
+type TrendReading struct {
+	Trend  string  // "rising", "falling", "stable"
+	Score  float64 // 估計器的原始讀數（漲跌次數差、t 值或 EMA 差距百分比）
+	Detail string  // 給使用者看的讀數說明
+}
+
+// TrendEstimator 趨勢估計器
+type TrendEstimator interface {
+	Name() string
+	MinPoints() int // 少於此數量的快照時一律視為 stable
+	Estimate(history []RateSnapshot) TrendReading
+}
+
+// VolatilityEstimator 波動率估計器，結果與 VOLATILITY_THRESHOLD 同為日利率（小數）單位
+type VolatilityEstimator interface {
+	Name() string
+	Estimate(history []RateSnapshot) float64
+}
+
+// TrendEstimatorNames 可用的趨勢估計器
+var TrendEstimatorNames = []string{
+	constants.TrendEstimatorCount,
+	constants.TrendEstimatorRegression,
+	constants.TrendEstimatorEMA,
+}
+
+// VolatilityEstimatorNames 可用的波動率估計器
+var VolatilityEstimatorNames = []string{
+	constants.VolatilityEstimatorStdDev,
+	constants.VolatilityEstimatorEWMA,
+	constants.VolatilityEstimatorATR,
+}
+
+// NewTrendEstimator 依名稱創建趨勢估計器，未知名稱使用漲跌次數
+func NewTrendEstimator(name string) TrendEstimator {
+	switch strings.ToLower(name) {
+	case constants.TrendEstimatorRegression:
+		return regressionTrend{window: constants.TrendRegressionWindow, minTStat: constants.TrendRegressionMinTStat}
+	case constants.TrendEstimatorEMA:
+		return emaCrossTrend{fast: constants.TrendEMAFastSpan, slow: constants.TrendEMASlowSpan, crossPercent: constants.TrendEMACrossPercent}
+	default:
+		return countTrend{window: constants.TrendCountWindow, threshold: constants.TrendCountThreshold, minMoves: constants.TrendCountMinMoves}
+	}
+}
+
+// NewVolatilityEstimator 依名稱創建波動率估計器，未知名稱使用標準差
+func NewVolatilityEstimator(name string) VolatilityEstimator {
+	switch strings.ToLower(name) {
+	case constants.VolatilityEstimatorEWMA:
+		return ewmaVolatility{lambda: constants.VolatilityEWMALambda}
+	case constants.VolatilityEstimatorATR:
+		return atrVolatility{period: constants.VolatilityATRPeriod}
+	default:
+		return stdDevVolatility{}
+	}
+}
+
+// countTrend 計算最近數點中超過閾值的上漲與下跌次數
+type countTrend struct {
+	window    int
+	threshold float64
+	minMoves  int
+}
+
+func (e countTrend) Name() string   { return constants.TrendEstimatorCount }
+func (e countTrend) MinPoints() int { return e.window }
+
+func (e countTrend) Estimate(history []RateSnapshot) TrendReading {
+	recent := history[len(history)-e.window:]
+
+	var upCount, downCount int
+	for i := 1; i < len(recent); i++ {
+		diff := recent[i].Rate - recent[i-1].Rate
+		if diff > e.threshold {
+			upCount++
+		} else if diff < -e.threshold {
+			downCount++
+		}
+	}
+
+	trend := "stable"
+	if upCount >= e.minMoves {
+		trend = "rising"
+	} else if downCount >= e.minMoves {
+		trend = "falling"
+	}
+	return TrendReading{
+		Trend:  trend,
+		Score:  float64(upCount - downCount),
+		Detail: fmt.Sprintf("上漲 %d 次, 下跌 %d 次", upCount, downCount),
+	}
+}
+
+// regressionTrend 以最近數點的線性迴歸斜率判斷趨勢，斜率 t 值需達顯著水準
+type regressionTrend struct {
+	window   int
+	minTStat float64
+}
+
+func (e regressionTrend) Name() string   { return constants.TrendEstimatorRegression }
+func (e regressionTrend) MinPoints() int { return 3 }
+
+func (e regressionTrend) Estimate(history []RateSnapshot) TrendReading {
+	recent := history
+	if len(recent) > e.window {
+		recent = recent[len(recent)-e.window:]
+	}
+	n := float64(len(recent))
+
+	var meanX, meanY float64
+	for i, snapshot := range recent {
+		meanX += float64(i)
+		meanY += snapshot.Rate
+	}
+	meanX /= n
+	meanY /= n
+
+	var sxx, sxy float64
+	for i, snapshot := range recent {
+		dx := float64(i) - meanX
+		sxx += dx * dx
+		sxy += dx * (snapshot.Rate - meanY)
+	}
+	slope := sxy / sxx
+
+	var sse float64
+	for i, snapshot := range recent {
+		residual := snapshot.Rate - (meanY + slope*(float64(i)-meanX))
+		sse += residual * residual
+	}
+
+	// 殘差為零時（完全落在直線上）只要有斜率即視為顯著
+	tStat := 0.0
+	if standardError := math.Sqrt(sse/(n-2)) / math.Sqrt(sxx); standardError > 0 {
+		tStat = slope / standardError
+	} else if slope != 0 {
+		tStat = math.Copysign(math.Inf(1), slope)
+	}
+
+	trend := "stable"
+	if tStat >= e.minTStat {
+		trend = "rising"
+	} else if tStat <= -e.minTStat {
+		trend = "falling"
+	}
+	return TrendReading{
+		Trend:  trend,
+		Score:  tStat,
+		Detail: fmt.Sprintf("斜率 %+.6f%%/點, t=%.2f", slope*constants.PercentageToDecimal, tStat),
+	}
+}
+
+// emaCrossTrend 比較快慢 EMA，快線明顯高於慢線視為上升
+type emaCrossTrend struct {
+	fast         int
+	slow         int
+	crossPercent float64
+}
+
+func (e emaCrossTrend) Name() string   { return constants.TrendEstimatorEMA }
+func (e emaCrossTrend) MinPoints() int { return e.fast }
+
+func (e emaCrossTrend) Estimate(history []RateSnapshot) TrendReading {
+	fast := snapshotEMA(history, e.fast)
+	slow := snapshotEMA(history, e.slow)
+
+	gap := 0.0
+	if slow > 0 {
+		gap = (fast/slow - 1) * 100
+	}
+
+	trend := "stable"
+	if gap >= e.crossPercent {
+		trend = "rising"
+	} else if gap <= -e.crossPercent {
+		trend = "falling"
+	}
+	return TrendReading{
+		Trend:  trend,
+		Score:  gap,
+		Detail: fmt.Sprintf("快線 %.6f%%, 慢線 %.6f%%, 差距 %+.2f%%", fast*constants.PercentageToDecimal, slow*constants.PercentageToDecimal, gap),
+	}
+}
+
+// snapshotEMA 以快照利率計算 EMA，起始值為前 span 點（不足時為全部）的簡單平均，避免歷史較短時偏向第一點
+func snapshotEMA(history []RateSnapshot, span int) float64 {
+	seed := span
+	if seed > len(history) {
+		seed = len(history)
+	}
+
+	var ema float64
+	for _, snapshot := range history[:seed] {
+		ema += snapshot.Rate
+	}
+	ema /= float64(seed)
+
+	alpha := 2.0 / float64(span+1)
+	for _, snapshot := range history[seed:] {
+		ema = alpha*snapshot.Rate + (1-alpha)*ema
+	}
+	return ema
+}
+
+// stdDevVolatility 利率的母體標準差
+type stdDevVolatility struct{}
+
+func (stdDevVolatility) Name() string { return constants.VolatilityEstimatorStdDev }
+
+func (stdDevVolatility) Estimate(history []RateSnapshot) float64 {
+	if len(history) < 2 {
+		return 0.0
+	}
+
+	var sum float64
+	for _, snapshot := range history {
+		sum += snapshot.Rate
+	}
+	avgRate := sum / float64(len(history))
+
+	var sumSquaredDiff float64
+	for _, snapshot := range history {
+		diff := snapshot.Rate - avgRate
+		sumSquaredDiff += diff * diff
+	}
+	return math.Sqrt(sumSquaredDiff / float64(len(history)))
+}
+
+// ewmaVolatility 利率逐點變動的指數加權波動率，近期變動權重較高
+type ewmaVolatility struct {
+	lambda float64
+}
+
+func (ewmaVolatility) Name() string { return constants.VolatilityEstimatorEWMA }
+
+func (e ewmaVolatility) Estimate(history []RateSnapshot) float64 {
+	if len(history) < 2 {
+		return 0.0
+	}
+
+	first := history[1].Rate - history[0].Rate
+	variance := first * first
+	for i := 2; i < len(history); i++ {
+		diff := history[i].Rate - history[i-1].Rate
+		variance = e.lambda*variance + (1-e.lambda)*diff*diff
+	}
+	return math.Sqrt(variance)
+}
+
+// atrVolatility 平均真實區間：K線補入的快照使用高低點，訂單簿快照的高低點即為利率本身
+type atrVolatility struct {
+	period int
+}
+
+func (atrVolatility) Name() string { return constants.VolatilityEstimatorATR }
+
+func (e atrVolatility) Estimate(history []RateSnapshot) float64 {
+	if len(history) < 2 {
+		return 0.0
+	}
+
+	start := 1
+	if len(history)-1 > e.period {
+		start = len(history) - e.period
+	}
+
+	var sum float64
+	for i := start; i < len(history); i++ {
+		high, low := history[i].rangeHigh(), history[i].rangeLow()
+		prevClose := history[i-1].Rate
+		sum += math.Max(high, prevClose) - math.Min(low, prevClose)
+	}
+	return sum / float64(len(history)-start)
+}
+
+// rangeHigh 快照區間高點（未記錄時使用利率）
+func (s RateSnapshot) rangeHigh() float64 {
+	if s.High > 0 {
+		return s.High
+	}
+	return s.Rate
+}
+
+// rangeLow 快照區間低點（未記錄時使用利率）
+func (s RateSnapshot) rangeLow() float64 {
+	if s.Low > 0 {
+		return s.Low
+	}
+	return s.Rate
+}
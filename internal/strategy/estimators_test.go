@@ -0,0 +1,94 @@
+package strategy
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/kfrico/BitfinexLendingBot/internal/constants"
+)
+
+func snapshotsFromRates(rates ...float64) []RateSnapshot {
+	history := make([]RateSnapshot, len(rates))
+	for i, rate := range rates {
+		history[i] = RateSnapshot{Rate: rate}
+	}
+	return history
+}
+
+func TestTrendEstimators(t *testing.T) {
+	rising := snapshotsFromRates(0.0010, 0.0012, 0.0011, 0.0013, 0.0015, 0.0014, 0.0016, 0.0018, 0.0017, 0.0019, 0.0021, 0.0022)
+	choppy := snapshotsFromRates(0.0010, 0.0013, 0.0010, 0.0013, 0.0010, 0.0013, 0.0010, 0.0013, 0.0010, 0.0013, 0.0010, 0.0013)
+
+	tests := []struct {
+		estimator string
+		history   []RateSnapshot
+		expected  string
+	}{
+		{constants.TrendEstimatorCount, rising, "rising"},
+		{constants.TrendEstimatorCount, choppy, "stable"},
+		{constants.TrendEstimatorRegression, rising, "rising"},
+		{constants.TrendEstimatorRegression, choppy, "stable"},
+		{constants.TrendEstimatorRegression, snapshotsFromRates(0.003, 0.002, 0.001), "falling"},
+		{constants.TrendEstimatorEMA, rising, "rising"},
+		{constants.TrendEstimatorEMA, snapshotsFromRates(0.0010, 0.00101, 0.0010, 0.00101, 0.0010, 0.00101, 0.0010, 0.00101), "stable"},
+	}
+
+	for _, tt := range tests {
+		estimator := NewTrendEstimator(tt.estimator)
+		if estimator.Name() != tt.estimator {
+			t.Fatalf("NewTrendEstimator(%s).Name() = %s", tt.estimator, estimator.Name())
+		}
+		if reading := estimator.Estimate(tt.history); reading.Trend != tt.expected {
+			t.Errorf("%s estimator trend = %s (%s), want %s", tt.estimator, reading.Trend, reading.Detail, tt.expected)
+		}
+	}
+}
+
+func TestVolatilityEstimators(t *testing.T) {
+	history := snapshotsFromRates(0.0010, 0.0012, 0.0010, 0.0012)
+
+	if got := NewVolatilityEstimator(constants.VolatilityEstimatorStdDev).Estimate(history); math.Abs(got-0.0001) > floatTolerance {
+		t.Errorf("stddev = %v, want 0.0001", got)
+	}
+	// 每次變動 0.0002，EWMA 波動率即為 0.0002
+	if got := NewVolatilityEstimator(constants.VolatilityEstimatorEWMA).Estimate(history); math.Abs(got-0.0002) > floatTolerance {
+		t.Errorf("ewma = %v, want 0.0002", got)
+	}
+	if got := NewVolatilityEstimator(constants.VolatilityEstimatorATR).Estimate(history); math.Abs(got-0.0002) > floatTolerance {
+		t.Errorf("atr without high/low = %v, want 0.0002", got)
+	}
+
+	// K線補入的快照使用高低點計算真實區間
+	history[3].High, history[3].Low = 0.0015, 0.0011
+	want := (0.0002 + 0.0002 + 0.0005) / 3
+	if got := NewVolatilityEstimator(constants.VolatilityEstimatorATR).Estimate(history); math.Abs(got-want) > floatTolerance {
+		t.Errorf("atr with high/low = %v, want %v", got, want)
+	}
+
+	if got := NewVolatilityEstimator(constants.VolatilityEstimatorEWMA).Estimate(history[:1]); got != 0 {
+		t.Errorf("ewma with one point = %v, want 0", got)
+	}
+}
+
+func TestMarketAnalyzer_UseEstimators(t *testing.T) {
+	analyzer := NewMarketAnalyzer()
+	for _, rate := range []float64{0.0010, 0.0011, 0.0012, 0.0013} {
+		analyzer.AddRateSnapshot(rate, 0)
+	}
+
+	// 預設漲跌次數需要 6 點，迴歸 3 點即可判斷
+	if condition := analyzer.AnalyzeMarket(nil); condition.Trend != "stable" {
+		t.Errorf("count estimator with 4 points = %s, want stable", condition.Trend)
+	}
+	analyzer.UseEstimators(constants.TrendEstimatorRegression, constants.VolatilityEstimatorEWMA)
+	condition := analyzer.AnalyzeMarket(nil)
+	if condition.Trend != "rising" || math.Abs(condition.Volatility-0.0001) > floatTolerance {
+		t.Errorf("regression/ewma condition = %+v, want rising with 0.0001 volatility", condition)
+	}
+
+	report := analyzer.Report()
+	if !strings.Contains(report, "➤ regression: rising") || !strings.Contains(report, "➤ ewma") || !strings.Contains(report, "• count: stable") {
+		t.Errorf("Report() missing estimator comparison:\n%s", report)
+	}
+}
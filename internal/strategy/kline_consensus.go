@@ -0,0 +1,143 @@
+package strategy
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kfrico/BitfinexLendingBot/internal/config"
+	"github.com/kfrico/BitfinexLendingBot/internal/constants"
+)
+
+// KlineComponent 單一K線視窗的平滑利率
+type KlineComponent struct {
+	Window  config.KlineWindow
+	Rate    float64 // 平滑後日利率（小數格式），取得K線失敗時為 0
+	Candles int     // 實際取得的K線數量
+	Err     error
+}
+
+// valid 視窗是否有可用的利率
+func (c KlineComponent) valid() bool {
+	return c.Err == nil && c.Candles > 0 && c.Rate > 0
+}
+
+// klineConsensus 最近一次K線多時間框架計算結果（供 Telegram 顯示）
+type klineConsensus struct {
+	mu          sync.Mutex
+	components  []KlineComponent
+	aggregation string
+	consensus   float64 // 合併後利率（加成前）
+	target      float64 // 目標利率（加成並套用最低利率後）
+	updatedAt   time.Time
+}
+
+// aggregateKlineRates 依合併方式合併各視窗利率，沒有可用視窗時返回 false
+func aggregateKlineRates(components []KlineComponent, aggregation string) (float64, bool) {
+	var rates []float64
+	weightedSum, totalWeight := 0.0, 0.0
+	for _, component := range components {
+		if !component.valid() {
+			continue
+		}
+		rates = append(rates, component.Rate)
+		weightedSum += component.Rate * component.Window.Weight
+		totalWeight += component.Window.Weight
+	}
+	if len(rates) == 0 {
+		return 0, false
+	}
+
+	switch aggregation {
+	case constants.KlineAggregationMin:
+		minRate := rates[0]
+		for _, rate := range rates[1:] {
+			minRate = math.Min(minRate, rate)
+		}
+		return minRate, true
+	case constants.KlineAggregationMedian:
+		sort.Float64s(rates)
+		mid := len(rates) / 2
+		if len(rates)%2 == 0 {
+			return (rates[mid-1] + rates[mid]) / 2, true
+		}
+		return rates[mid], true
+	default:
+		// 可用視窗權重皆為 0 時退回簡單平均
+		if totalWeight <= 0 {
+			sum := 0.0
+			for _, rate := range rates {
+				sum += rate
+			}
+			return sum / float64(len(rates)), true
+		}
+		return weightedSum / totalWeight, true
+	}
+}
+
+// calculateKlineComponents 取得各視窗的K線並計算平滑利率
+func (lb *LendingBot) calculateKlineComponents() []KlineComponent {
+	windows := lb.config.GetKlineWindows()
+	components := make([]KlineComponent, 0, len(windows))
+	for _, window := range windows {
+		candles, err := lb.client.GetFundingCandles(lb.config.GetFundingSymbol(), window.TimeFrame, window.Period)
+		component := KlineComponent{Window: window, Candles: len(candles), Err: err}
+		if err != nil {
+			log.Printf("取得 %s K線失敗，此視窗不納入計算: %v", window, err)
+		} else if len(candles) > 0 {
+			component.Rate = lb.findHighestRateFromCandles(candles, window.SmoothMethod)
+			log.Printf("K線視窗 %s (%s, 權重 %g)：%.6f%%",
+				window, window.SmoothMethod, window.Weight, lb.rateConverter.DecimalToPercentage(component.Rate))
+		}
+		components = append(components, component)
+	}
+	return components
+}
+
+// recordKlineConsensus 保存本次K線多時間框架計算結果
+func (lb *LendingBot) recordKlineConsensus(components []KlineComponent, consensus, target float64) {
+	lb.klineConsensus.mu.Lock()
+	defer lb.klineConsensus.mu.Unlock()
+	lb.klineConsensus.components = components
+	lb.klineConsensus.aggregation = lb.config.KlineAggregation
+	lb.klineConsensus.consensus = consensus
+	lb.klineConsensus.target = target
+	lb.klineConsensus.updatedAt = lb.now()
+}
+
+// GetKlineConsensusReport 獲取K線各時間框架視窗的利率與合併結果（供 Telegram 指令使用）
+func (lb *LendingBot) GetKlineConsensusReport() string {
+	lb.klineConsensus.mu.Lock()
+	defer lb.klineConsensus.mu.Unlock()
+
+	state := &lb.klineConsensus
+	if state.updatedAt.IsZero() {
+		report := "尚未計算，設定的視窗:"
+		for _, window := range lb.config.GetKlineWindows() {
+			report += fmt.Sprintf("\n%s (%s, 權重 %g)", window, window.SmoothMethod, window.Weight)
+		}
+		return report
+	}
+
+	aggregation := state.aggregation
+	if aggregation == "" {
+		aggregation = constants.KlineAggregationWeighted
+	}
+	report := fmt.Sprintf("合併方式: %s (%s 更新)", aggregation, state.updatedAt.Format("15:04"))
+	for _, component := range state.components {
+		reading := fmt.Sprintf("%.4f%%", lb.rateConverter.DecimalToPercentage(component.Rate))
+		if !component.valid() {
+			reading = "無數據"
+			if component.Err != nil {
+				reading = "獲取失敗"
+			}
+		}
+		report += fmt.Sprintf("\n%s (%s, 權重 %g): %s", component.Window, component.Window.SmoothMethod, component.Window.Weight, reading)
+	}
+	report += fmt.Sprintf("\n共識利率: %.4f%% → 目標利率: %.4f%%",
+		lb.rateConverter.DecimalToPercentage(state.consensus), lb.rateConverter.DecimalToPercentage(state.target))
+	return report
+}
@@ -0,0 +1,93 @@
+package strategy
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kfrico/BitfinexLendingBot/internal/bitfinex"
+	"github.com/kfrico/BitfinexLendingBot/internal/config"
+	"github.com/kfrico/BitfinexLendingBot/internal/constants"
+	"github.com/kfrico/BitfinexLendingBot/internal/simulator"
+)
+
+// timeFrameMarket 依時間框架返回固定高點K線的測試市場
+type timeFrameMarket struct {
+	bookMarket
+	highs map[string]float64
+}
+
+func (m *timeFrameMarket) Candles(symbol, timeFrame string, limit int, at time.Time) ([]*bitfinex.Candle, error) {
+	high, ok := m.highs[timeFrame]
+	if !ok {
+		return nil, nil
+	}
+	candles := make([]*bitfinex.Candle, limit)
+	for i := range candles {
+		candles[i] = &bitfinex.Candle{Open: high, Close: high, High: high, Low: high}
+	}
+	return candles, nil
+}
+
+func TestAggregateKlineRates(t *testing.T) {
+	components := []KlineComponent{
+		{Window: config.KlineWindow{Weight: 1}, Rate: 0.0006, Candles: 12},
+		{Window: config.KlineWindow{Weight: 2}, Rate: 0.0003, Candles: 24},
+		{Window: config.KlineWindow{Weight: 1}, Rate: 0.0009, Candles: 7},
+		{Window: config.KlineWindow{Weight: 5}}, // 無數據不納入
+	}
+
+	tests := []struct {
+		aggregation string
+		expected    float64
+	}{
+		{constants.KlineAggregationWeighted, 0.000525},
+		{"", 0.000525},
+		{constants.KlineAggregationMin, 0.0003},
+		{constants.KlineAggregationMedian, 0.0006},
+	}
+	for _, tt := range tests {
+		actual, ok := aggregateKlineRates(components, tt.aggregation)
+		if !ok || math.Abs(actual-tt.expected) > floatTolerance {
+			t.Errorf("aggregateKlineRates(%q) = %v, %v, want %v", tt.aggregation, actual, ok, tt.expected)
+		}
+	}
+
+	if _, ok := aggregateKlineRates(components[3:], constants.KlineAggregationWeighted); ok {
+		t.Error("aggregateKlineRates() without data should report no rate")
+	}
+}
+
+func TestLendingBot_KlineTargetRateMultiTimeFrame(t *testing.T) {
+	cfg := &config.Config{
+		Currency:            "USD",
+		MinLoan:             150,
+		MinDailyLendRate:    0.01,
+		EnableKlineStrategy: true,
+		KlineSmoothMethod:   "ema",
+		KlineWindows:        []string{"5m:12:1:max", "1h:24:2", "1D:7:1"},
+	}
+	cfg.ApplyDefaults()
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	market := &timeFrameMarket{highs: map[string]float64{"5m": 0.0006, "1h": 0.0003}}
+	exchange := simulator.NewExchange(market, simulator.Options{Currency: "USD", Start: now})
+	bot := NewLendingBot(cfg, exchange)
+	bot.SetClock(exchange.Now, func(time.Duration) {})
+
+	// 1D 視窗沒有數據，5m 與 1h 依權重 1:2 平均
+	if rate := bot.calculateKlineTargetRate(); math.Abs(rate-0.0004) > floatTolerance {
+		t.Errorf("calculateKlineTargetRate() weighted = %v, want 0.0004", rate)
+	}
+	report := bot.GetKlineConsensusReport()
+	if !strings.Contains(report, "5m×12 (max, 權重 1): 0.0600%") || !strings.Contains(report, "1h×24 (ema, 權重 2): 0.0300%") ||
+		!strings.Contains(report, "1D×7 (ema, 權重 1): 無數據") {
+		t.Errorf("GetKlineConsensusReport() = %s", report)
+	}
+
+	cfg.KlineAggregation = constants.KlineAggregationMin
+	if rate := bot.calculateKlineTargetRate(); math.Abs(rate-0.0003) > floatTolerance {
+		t.Errorf("calculateKlineTargetRate() min = %v, want 0.0003", rate)
+	}
+}
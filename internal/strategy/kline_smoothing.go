@@ -0,0 +1,107 @@
+package strategy
+
+import (
+	"math"
+	"sort"
+
+	"github.com/kfrico/BitfinexLendingBot/internal/bitfinex"
+	"github.com/kfrico/BitfinexLendingBot/internal/constants"
+)
+
+// typicalRate K線的典型利率（高、低、收盤平均）
+func typicalRate(candle *bitfinex.Candle) float64 {
+	return (candle.High + candle.Low + candle.Close) / 3
+}
+
+// calculateVWAP 計算典型利率的成交量加權平均，成交量稀少的K線影響較小
+// 所有K線都沒有成交量時退回簡單平均
+func (lb *LendingBot) calculateVWAP(candles []*bitfinex.Candle) float64 {
+	if len(candles) == 0 {
+		return lb.config.GetMinDailyRateDecimal()
+	}
+
+	weightedSum, totalVolume, sum := 0.0, 0.0, 0.0
+	for _, candle := range candles {
+		rate := typicalRate(candle)
+		volume := math.Abs(candle.Volume)
+		weightedSum += rate * volume
+		totalVolume += volume
+		sum += rate
+	}
+	if totalVolume <= 0 {
+		return sum / float64(len(candles))
+	}
+	return weightedSum / totalVolume
+}
+
+// calculateVolumeWeightedP90 計算高點的成交量加權 90 百分位數
+// 累計成交量達 90% 的高點，成交量稀少的異常高點不會被選中；沒有成交量時退回一般 90 百分位數
+func (lb *LendingBot) calculateVolumeWeightedP90(candles []*bitfinex.Candle) float64 {
+	if len(candles) == 0 {
+		return lb.config.GetMinDailyRateDecimal()
+	}
+
+	sorted := make([]*bitfinex.Candle, len(candles))
+	copy(sorted, candles)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].High < sorted[j].High })
+
+	totalVolume := 0.0
+	for _, candle := range sorted {
+		totalVolume += math.Abs(candle.Volume)
+	}
+	if totalVolume <= 0 {
+		return lb.calculate90Percentile(candles)
+	}
+
+	threshold := totalVolume * 0.9
+	cumulative := 0.0
+	for _, candle := range sorted {
+		cumulative += math.Abs(candle.Volume)
+		if cumulative >= threshold-1e-12 {
+			return candle.High
+		}
+	}
+	return sorted[len(sorted)-1].High
+}
+
+// calculateMedianHigh 計算高點的中位數
+func (lb *LendingBot) calculateMedianHigh(candles []*bitfinex.Candle) float64 {
+	if len(candles) == 0 {
+		return lb.config.GetMinDailyRateDecimal()
+	}
+
+	highs := make([]float64, len(candles))
+	for i, candle := range candles {
+		highs[i] = candle.High
+	}
+	sort.Float64s(highs)
+
+	mid := len(highs) / 2
+	if len(highs)%2 == 0 {
+		return (highs[mid-1] + highs[mid]) / 2
+	}
+	return highs[mid]
+}
+
+// calculateBollinger 計算典型利率的布林通道，返回中軌（平均）與上軌（平均 + 2 倍標準差）
+func (lb *LendingBot) calculateBollinger(candles []*bitfinex.Candle) (mid float64, upper float64) {
+	if len(candles) == 0 {
+		minRate := lb.config.GetMinDailyRateDecimal()
+		return minRate, minRate
+	}
+
+	sum := 0.0
+	for _, candle := range candles {
+		sum += typicalRate(candle)
+	}
+	mid = sum / float64(len(candles))
+
+	variance := 0.0
+	for _, candle := range candles {
+		diff := typicalRate(candle) - mid
+		variance += diff * diff
+	}
+	stdDev := math.Sqrt(variance / float64(len(candles)))
+
+	return mid, mid + constants.KlineBollingerStdDevs*stdDev
+}
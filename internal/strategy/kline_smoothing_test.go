@@ -0,0 +1,56 @@
+package strategy
+
+import (
+	"math"
+	"testing"
+
+	"github.com/kfrico/BitfinexLendingBot/internal/bitfinex"
+	"github.com/kfrico/BitfinexLendingBot/internal/config"
+)
+
+func TestFindHighestRateFromCandles_VolumeAware(t *testing.T) {
+	bot := NewLendingBot(&config.Config{MinDailyLendRate: 0.01}, nil)
+
+	// 四根正常成交量的K線，外加一根成交量極低但高點異常的K線
+	candles := []*bitfinex.Candle{
+		{High: 0.0004, Low: 0.0002, Close: 0.0003, Volume: 1000},
+		{High: 0.0005, Low: 0.0003, Close: 0.0004, Volume: 1000},
+		{High: 0.0004, Low: 0.0002, Close: 0.0003, Volume: 1000},
+		{High: 0.0005, Low: 0.0003, Close: 0.0004, Volume: 1000},
+		{High: 0.0100, Low: 0.0003, Close: 0.0003, Volume: 1},
+	}
+
+	tests := []struct {
+		method   string
+		expected float64
+	}{
+		{"max", 0.01},
+		{"p90", 0.01},
+		{"vwp90", 0.0005},
+		{"median", 0.0005},
+		{"vwap", (0.0003*2000 + 0.0004*2000 + (0.0106/3)*1) / 4001},
+		{"bbm", (0.0003*2 + 0.0004*2 + 0.0106/3) / 5},
+	}
+	for _, tt := range tests {
+		if actual := bot.findHighestRateFromCandles(candles, tt.method); math.Abs(actual-tt.expected) > floatTolerance {
+			t.Errorf("findHighestRateFromCandles(%s) = %v, want %v", tt.method, actual, tt.expected)
+		}
+	}
+
+	mid, upper := bot.calculateBollinger(candles[:4])
+	if math.Abs(mid-0.00035) > floatTolerance || math.Abs(upper-0.00045) > floatTolerance {
+		t.Errorf("calculateBollinger() = %v, %v, want 0.00035 and 0.00045", mid, upper)
+	}
+	if actual := bot.findHighestRateFromCandles(candles[:4], "bbu"); math.Abs(actual-upper) > floatTolerance {
+		t.Errorf("findHighestRateFromCandles(bbu) = %v, want %v", actual, upper)
+	}
+
+	// 沒有成交量時退回不加權的計算
+	noVolume := []*bitfinex.Candle{{High: 0.0004, Low: 0.0002, Close: 0.0003}, {High: 0.0006, Low: 0.0004, Close: 0.0005}}
+	if actual := bot.calculateVWAP(noVolume); math.Abs(actual-0.0004) > floatTolerance {
+		t.Errorf("calculateVWAP() without volume = %v, want 0.0004", actual)
+	}
+	if actual := bot.calculateVolumeWeightedP90(noVolume); actual != 0.0006 {
+		t.Errorf("calculateVolumeWeightedP90() without volume = %v, want 0.0006", actual)
+	}
+}
@@ -4,12 +4,15 @@ import (
 	"fmt"
 	"log"
 	"math"
+	"math/rand"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/kfrico/BitfinexLendingBot/internal/bitfinex"
 	"github.com/kfrico/BitfinexLendingBot/internal/config"
 	"github.com/kfrico/BitfinexLendingBot/internal/constants"
+	"github.com/kfrico/BitfinexLendingBot/internal/history"
 	"github.com/kfrico/BitfinexLendingBot/internal/rates"
 	"github.com/kfrico/BitfinexLendingBot/internal/tracker"
 )
@@ -17,49 +20,112 @@ import (
 // LendingBot 貸出機器人
 type LendingBot struct {
 	config         *config.Config
-	client         *bitfinex.Client
+	client         bitfinex.FundingAPI
 	rateConverter  *rates.Converter
 	smartStrategy  *SmartStrategy
 	orderTracker   *tracker.BotOrderTracker
-	notifyCallback func(string) error // Telegram 通知回調函數
+	riskGuard      *RiskGuard
+	seasonality    *SeasonalityModel
+	spikeDetector  *SpikeDetector
+	refinancer     *Refinancer
+	strategyStats  *strategyStatsBook
+	klineConsensus klineConsensus         // 最近一次K線多時間框架計算結果
+	rateBonus      *RateBonusController   // 自適應利率加成
+	rateBonusPath  string                 // 利率加成記錄保存路徑，空字串表示不保存
+	absorption     *AbsorptionModel       // 訂單簿吸收量模型（absorption 分配方式）
+	utilization    *UtilizationController // 資金使用率控制
+	currencyMeta   CurrencyInfoProvider   // 幣種金額規格來源，nil 時使用預設規格
+	execMu         sync.Mutex             // 避免主策略與飆升掛單同時動用資金
+	rng            *rand.Rand             // 隨機隱藏掛單使用
+	now            func() time.Time       // 時鐘（回測時使用模擬時間）
+	sleep          func(time.Duration)    // 等待函數（回測時不實際等待）
+	notifyCallback func(string) error     // Telegram 通知回調函數
 }
 
 // NewLendingBot 創建新的貸出機器人
-func NewLendingBot(cfg *config.Config, client *bitfinex.Client) *LendingBot {
-	return &LendingBot{
+func NewLendingBot(cfg *config.Config, client bitfinex.FundingAPI) *LendingBot {
+	lb := &LendingBot{
 		config:        cfg,
 		client:        client,
-		rateConverter: rates.NewConverter(),
+		rateConverter: rates.NewConverterWithFees(cfg.GetFeeModel()),
 		orderTracker:  tracker.NewBotOrderTracker(),
+		riskGuard:     NewRiskGuard(cfg),
+		seasonality:   NewSeasonalityModel(cfg.SeasonalityMaxAdjustPct),
+		spikeDetector: NewSpikeDetector(cfg),
+		refinancer:    NewRefinancer(cfg),
+		strategyStats: newStrategyStatsBook(),
+		rateBonus:     NewRateBonusController(cfg.RateBonus),
+		absorption:    NewAbsorptionModel(),
+		utilization:   NewUtilizationController(),
 		smartStrategy: NewSmartStrategy(cfg),
+		rng:           rand.New(rand.NewSource(time.Now().UnixNano())),
+		now:           time.Now,
+		sleep:         time.Sleep,
 	}
+	lb.smartStrategy.amounts = lb.amountRules
+	lb.smartStrategy.absorption = lb.absorptionCapacities
+	lb.smartStrategy.floor = lb.ladderFloor
+	return lb
+}
+
+// SetClock 設置時鐘與等待函數（回測時以模擬時間驅動）
+func (lb *LendingBot) SetClock(now func() time.Time, sleep func(time.Duration)) {
+	lb.now = now
+	lb.sleep = sleep
+	lb.smartStrategy.analyzer.SetClock(now)
+}
+
+// SetRandSource 設置隨機數來源（回測時使用固定種子以便重現）
+func (lb *LendingBot) SetRandSource(rng *rand.Rand) {
+	lb.rng = rng
 }
 
 // LoanOffer 代表一個貸出訂單
 type LoanOffer struct {
-	Amount float64
-	Rate   float64 // 日利率（小數格式）
-	Period int
-	UseFRR bool // 是否使用 FRR 掛單模式
+	Amount   float64
+	Rate     float64 // 日利率（小數格式）
+	Period   int
+	UseFRR   bool   // 是否使用 FRR 掛單模式
+	Hidden   bool   // 是否使用隱藏掛單
+	HighHold bool   // 是否為高額持有單
+	Strategy string // 產生此訂單的策略
+	// PeriodCapped 期間已因期間曝險限制縮短（FRR 單改以 Period 掛出，不再固定 120 天）
+	PeriodCapped bool
 }
 
 // Execute 執行機器人主要邏輯
 func (lb *LendingBot) Execute() error {
+	lb.execMu.Lock()
+	defer lb.execMu.Unlock()
+
 	log.Println("開始執行貸出機器人...")
 
-	// 清理舊的訂單記錄（避免記憶體洩漏）
-	lb.orderTracker.CleanOldOrders(24 * time.Hour)
+	// 熔斷狀態下不進行任何操作，等待手動 /resume
+	if lb.config.EnableRiskGuard {
+		if halted, reason, _ := lb.riskGuard.IsHalted(); halted {
+			log.Printf("⛔ 風險控管熔斷中，跳過本次執行: %s", reason)
+			return nil
+		}
+	}
+
+	// 清理已結束的舊訂單記錄（避免記錄無限增長）
+	lb.orderTracker.CleanOldOrders(lb.now(), constants.OrderHistoryRetention)
 
 	// 取消程式創建的未完成訂單
 	log.Println("取消程式創建的未完成訂單...")
-	hasPendingOrders, err := lb.cancelAllOffers()
+	hasPendingOrders, spikeOnBook, err := lb.cancelAllOffers()
 	if err != nil {
 		log.Printf("取消訂單失敗: %v", err)
 		return err
 	}
 
 	// 等待訂單取消完成
-	time.Sleep(constants.RetryDelay)
+	lb.sleep(constants.RetryDelay)
+
+	// 市場利率大幅高於既有借貸時提前關閉，釋出的本金由本次或下次執行重新貸出
+	if lb.config.EnableRefinancing {
+		lb.runRefinancing()
+	}
 
 	// 獲取可用資金
 	log.Println("取得可用額度...")
@@ -76,63 +142,122 @@ func (lb *LendingBot) Execute() error {
 		log.Printf("扣除保留金額後可用: %f", fundsAvailable)
 	}
 
-	// 檢查可用資金
-	if fundsAvailable < lb.config.MinLoan {
+	// 扣除利率飆升狙擊的保留資金
+	if spikeReserve := lb.spikeReserve(spikeOnBook); spikeReserve > 0 {
+		fundsAvailable = math.Max(0, fundsAvailable-spikeReserve)
+		log.Printf("扣除飆升保留資金 %.2f 後可用: %f", spikeReserve, fundsAvailable)
+	}
+
+	// 無法得知交易所最低掛單金額時本週期不掛單，避免掛出低於交易所限制的訂單
+	if lb.minOfferUnknown() {
+		log.Printf("無法取得 %s 的交易所最低掛單金額，本週期不掛單", lb.config.Currency)
+		return nil
+	}
+
+	// 檢查可用資金（MIN_LOAN 低於交易所最低掛單金額時以後者為準）
+	rules := lb.amountRules()
+	if fundsAvailable < rules.minLoan {
 		log.Println("可用資金小於最小貸出額，不進行操作")
 		return nil
 	}
 
 	// 獲取市場數據
-	fundingBook, err := lb.client.GetFundingBook(lb.config.GetFundingSymbol(), constants.MaxPriceLevels)
-	if err != nil {
-		log.Printf("取得 Funding Book 錯誤: %v", err)
+	fundingBook, bookErr := lb.client.GetFundingBook(lb.config.GetFundingSymbol(), constants.MaxPriceLevels)
+	if bookErr != nil {
+		log.Printf("取得 Funding Book 錯誤: %v", bookErr)
 		log.Println("使用fallback模式，僅使用最小利率策略")
 		// 使用空的funding book，策略會自動使用最小利率
 		fundingBook = []*bitfinex.FundingBookEntry{}
 	}
+	lb.refreshAbsorption(fundingBook)
 
 	// 根據配置選擇策略
-	var loanOffers []*LoanOffer
-	if lb.config.EnableKlineStrategy {
-		log.Println("使用K線策略計算貸出訂單...")
-		loanOffers = lb.calculateKlineOffers(fundsAvailable)
-	} else if lb.config.EnableSmartStrategy {
-		log.Println("使用智能策略計算貸出訂單...")
-		loanOffers = lb.smartStrategy.CalculateSmartOffers(fundsAvailable, fundingBook)
-	} else {
-		log.Println("使用傳統策略計算貸出訂單...")
-		loanOffers = lb.calculateLoanOffers(fundsAvailable, fundingBook)
+	loanOffers := lb.calculateStrategyOffers(fundsAvailable, fundingBook)
+	labelOffers(loanOffers, ActiveStrategyName(lb.config))
+
+	// 依歷史時段溢價調整掛單利率
+	if lb.config.EnableSeasonality {
+		lb.applySeasonality(loanOffers)
+	}
+
+	// 依到期分布調整訂單期間
+	if lb.config.EnableMaturityPlanner {
+		lb.applyMaturityPlan(loanOffers)
+	}
+
+	// 依期間曝險上限縮短新訂單期間
+	if lb.config.HasPeriodExposureLimits() {
+		if clamped := lb.applyPeriodLimits(loanOffers); clamped > 0 {
+			log.Printf("期間曝險限制：縮短 %d/%d 筆訂單期間", clamped, len(loanOffers))
+		}
+	}
+
+	// 套用隱藏掛單策略
+	if lb.config.HasHiddenOfferPolicy() {
+		hiddenCount := applyVisibilityPolicy(loanOffers, lb.config, lb.rng)
+		log.Printf("隱藏掛單策略：%d/%d 筆訂單使用隱藏掛單", hiddenCount, len(loanOffers))
+	}
+
+	// 添加利率加成（風險檢查以實際掛出的利率進行）
+	lb.applyRateBonus(loanOffers, hasPendingOrders)
+
+	// 風險檢查
+	if lb.config.EnableRiskGuard {
+		var ok bool
+		loanOffers, ok = lb.applyRiskGuard(loanOffers, fundingBook, bookErr)
+		if !ok {
+			return nil
+		}
 	}
 
 	// 下單
-	return lb.placeLoanOffers(loanOffers, hasPendingOrders)
+	return lb.placeLoanOffers(loanOffers)
 }
 
 // cancelAllOffers 取消程式創建的未完成訂單
-func (lb *LendingBot) cancelAllOffers() (bool, error) {
+// 仍在保留時間內的飆升掛單不取消，返回其金額供扣除飆升保留資金
+func (lb *LendingBot) cancelAllOffers() (bool, float64, error) {
 	offers, err := lb.client.GetFundingOffers(lb.config.GetFundingSymbol())
 	if err != nil {
-		return false, err
+		return false, 0, err
 	}
 
+	// 追蹤中但已不在訂單簿上的訂單視為成交，計入策略統計
+	cycle := lb.recordFilledOrders(offers)
+	defer func() { lb.observeRateBonus(cycle) }()
+
 	if len(offers) == 0 {
 		log.Println("目前沒有未完成的訂單")
-		return false, nil
+		return false, 0, nil
 	}
 
 	cancelledCount := 0
+	spikeOnBook := 0.0
 	for _, offer := range offers {
 		// 只取消程式追蹤的訂單
-		if !lb.orderTracker.IsTrackedOrder(offer.ID) {
+		info, tracked := lb.orderTracker.GetOrderInfo(offer.ID)
+		if !tracked {
 			log.Printf("跳過手動創建的訂單 ID: %d", offer.ID)
 			continue
 		}
+		if lb.isLiveSpikeOffer(info) {
+			log.Printf("保留飆升訂單 ID: %d", offer.ID)
+			spikeOnBook += offer.Amount
+			continue
+		}
 
 		if err := lb.client.CancelFundingOffer(offer.ID); err != nil {
 			log.Printf("取消程式訂單失敗: %v", err)
 		} else {
 			log.Printf("成功取消程式訂單 ID: %d", offer.ID)
-			lb.orderTracker.RemoveOrder(offer.ID) // 從追蹤中移除
+			lb.releasePlacement(offer.Amount)
+			lb.recordPartialFill(info, offer.Amount)
+			cycle.record(info.Strategy, info.Amount, info.Amount-offer.Amount)
+			outcome := tracker.OutcomeCancelled
+			if info.Amount-offer.Amount > 1e-9 {
+				outcome = tracker.OutcomePartiallyFilled
+			}
+			lb.orderTracker.ResolveOrder(offer.ID, outcome, math.Max(0, info.Amount-offer.Amount), lb.now())
 			cancelledCount++
 		}
 	}
@@ -141,7 +266,7 @@ func (lb *LendingBot) cancelAllOffers() (bool, error) {
 		log.Println("沒有程式創建的訂單需要取消")
 	}
 
-	return cancelledCount > 0, nil
+	return cancelledCount > 0, spikeOnBook, nil
 }
 
 // getAvailableFunds 獲取可用資金
@@ -149,25 +274,42 @@ func (lb *LendingBot) getAvailableFunds() (float64, error) {
 	return lb.client.GetFundingBalance(strings.ToUpper(lb.config.Currency))
 }
 
+// calculateStrategyOffers 依配置選擇的策略計算貸出訂單
+func (lb *LendingBot) calculateStrategyOffers(fundsAvailable float64, fundingBook []*bitfinex.FundingBookEntry) []*LoanOffer {
+	if lb.config.EnableStrategyBlend {
+		log.Println("使用策略組合計算貸出訂單...")
+		return lb.calculateBlendOffers(fundsAvailable, fundingBook)
+	} else if lb.config.EnableKlineStrategy {
+		log.Println("使用K線策略計算貸出訂單...")
+		return lb.calculateKlineOffers(fundsAvailable, fundingBook)
+	} else if lb.config.EnableSmartStrategy {
+		log.Println("使用智能策略計算貸出訂單...")
+		return lb.smartStrategy.CalculateSmartOffers(fundsAvailable, fundingBook)
+	}
+	log.Println("使用傳統策略計算貸出訂單...")
+	return lb.calculateLoanOffers(fundsAvailable, fundingBook)
+}
+
 // calculateLoanOffers 計算貸出訂單
 func (lb *LendingBot) calculateLoanOffers(fundsAvailable float64, fundingBook []*bitfinex.FundingBookEntry) []*LoanOffer {
 	var loanOffers []*LoanOffer
+	rules := lb.amountRules()
 
 	// 檢查可用資金
-	if fundsAvailable < lb.config.MinLoan {
+	if fundsAvailable < rules.minLoan {
 		return loanOffers
 	}
 
 	splitFundsAvailable := fundsAvailable
 
 	// 高額持有策略
-	if lb.config.HighHoldAmount > lb.config.MinLoan {
+	if lb.config.HighHoldAmount > rules.minLoan {
 		highHoldOffers := lb.calculateHighHoldOffers(&splitFundsAvailable)
 		loanOffers = append(loanOffers, highHoldOffers...)
 	}
 
 	// 分散貸出策略
-	if splitFundsAvailable >= lb.config.MinLoan {
+	if splitFundsAvailable >= rules.minLoan {
 		remainingSlots := lb.getRemainingOrderSlots(len(loanOffers))
 		if remainingSlots != 0 {
 			spreadOffers := lb.calculateSpreadOffers(splitFundsAvailable, fundingBook, remainingSlots)
@@ -187,11 +329,12 @@ func (lb *LendingBot) calculateHighHoldOffers(splitFundsAvailable *float64) []*L
 		ordersCount = 1
 	}
 
+	rules := lb.amountRules()
 	highHold := lb.config.HighHoldAmount
 	if lb.config.MaxLoan > 0 && highHold > lb.config.MaxLoan {
 		highHold = lb.config.MaxLoan
 	}
-	highHold = floorToCents(highHold)
+	highHold = rules.floor(highHold)
 
 	possibleOrders := int(*splitFundsAvailable / highHold)
 	actualOrders := int(math.Min(float64(ordersCount), float64(possibleOrders)))
@@ -202,10 +345,11 @@ func (lb *LendingBot) calculateHighHoldOffers(splitFundsAvailable *float64) []*L
 		}
 
 		offer := &LoanOffer{
-			Amount: highHold,
-			Rate:   lb.config.GetHighHoldRateDecimal(),
-			Period: constants.Period120Days,
-			UseFRR: false, // 高額持有單固定走一般利率單
+			Amount:   highHold,
+			Rate:     lb.config.GetHighHoldRateDecimal(),
+			Period:   constants.Period120Days,
+			UseFRR:   false, // 高額持有單固定走一般利率單
+			HighHold: true,
 		}
 		offers = append(offers, offer)
 		*splitFundsAvailable -= highHold
@@ -219,15 +363,16 @@ func (lb *LendingBot) calculateSpreadOffers(splitFundsAvailable float64, funding
 	var offers []*LoanOffer
 	useFRR := lb.config.IsMinDailyLendRateFRR()
 
+	rules := lb.amountRules()
 	numSplits := lb.config.SpreadLend
 	if maxOrders > 0 && numSplits > maxOrders {
 		numSplits = maxOrders
 	}
-	if numSplits <= 0 || splitFundsAvailable < lb.config.MinLoan {
+	if numSplits <= 0 || splitFundsAvailable < rules.minLoan {
 		return offers
 	}
 
-	orderAmounts := buildOrderAmounts(splitFundsAvailable, numSplits, lb.config.MinLoan, lb.config.MaxLoan)
+	orderAmounts := buildOrderAmounts(splitFundsAvailable, numSplits, rules)
 	if len(orderAmounts) == 0 {
 		return offers
 	}
@@ -237,24 +382,41 @@ func (lb *LendingBot) calculateSpreadOffers(splitFundsAvailable float64, funding
 	nextLend := lb.config.GapBottom
 
 	depthIndex := 0
-	minDailyRate := lb.config.GetMinDailyRateDecimal()
+	minDailyRate := lb.ladderFloor()
+
+	// 累計量模式下 GAP_BOTTOM/GAP_TOP 代表訂單前方的 ask 累計金額
+	volumeMode := lb.config.IsVolumeGapMode()
+	var asks []*bitfinex.FundingBookEntry
+	if volumeMode {
+		asks = askLevels(fundingBook)
+		log.Printf("累計量深度模式 - 目標前方掛單量: %.2f-%.2f %s, ask 檔位: %d, 總量: %.2f",
+			lb.config.GapBottom, lb.config.GapTop, lb.config.Currency, len(asks), totalAskVolume(asks))
+	}
 
 	for _, allocAmount := range orderAmounts {
 		// 累計市場量至指定利率區間（僅在有funding book數據時）
-		if len(fundingBook) > 0 {
+		if !volumeMode && len(fundingBook) > 0 {
 			for float64(depthIndex) < nextLend && depthIndex < len(fundingBook)-1 {
 				depthIndex++
 			}
 		}
 
-		if allocAmount < lb.config.MinLoan {
+		if allocAmount < rules.minLoan {
 			break
 		}
 
+		// 取得目標深度的市場利率
+		var marketRate float64
+		hasMarketRate := false
+		if volumeMode {
+			marketRate, hasMarketRate = findRateAtCumulativeVolume(asks, nextLend)
+		} else if len(fundingBook) > 0 && depthIndex < len(fundingBook) {
+			marketRate, hasMarketRate = fundingBook[depthIndex].Rate, true
+		}
+
 		// 計算利率
 		var rate float64
-		if len(fundingBook) > 0 && depthIndex < len(fundingBook) {
-			marketRate := fundingBook[depthIndex].Rate
+		if hasMarketRate {
 			if marketRate < minDailyRate {
 				rate = minDailyRate
 			} else {
@@ -279,26 +441,287 @@ func (lb *LendingBot) calculateSpreadOffers(splitFundsAvailable float64, funding
 		nextLend += gapClimb
 	}
 
+	offers = applyAllocationProfile(offers, lb.config, rules, fundingBook, maxOrders, lb.absorptionCapacities)
+
 	return offers
 }
 
+// applyMaturityPlan 依活躍借貸的到期分布調整新訂單期間
+func (lb *LendingBot) applyMaturityPlan(loanOffers []*LoanOffer) {
+	credits, err := lb.client.GetFundingCredits(lb.config.GetFundingSymbol())
+	if err != nil {
+		log.Printf("取得借貸訂單失敗，略過到期分散規劃: %v", err)
+		return
+	}
+
+	planner := NewMaturityPlanner(lb.config)
+	profile := planner.BuildProfile(credits, lb.now())
+	adjusted := planner.PlanPeriods(loanOffers, profile)
+	log.Printf("到期分散規劃：%d 個活躍借貸，調整 %d 筆新訂單期間", len(credits), adjusted)
+}
+
+// GetMaturityReport 獲取目前活躍借貸的到期分布報告（供 Telegram 指令使用）
+func (lb *LendingBot) GetMaturityReport() (string, error) {
+	credits, err := lb.client.GetFundingCredits(lb.config.GetFundingSymbol())
+	if err != nil {
+		return "", err
+	}
+
+	planner := NewMaturityPlanner(lb.config)
+	return planner.Report(planner.BuildProfile(credits, lb.now()), lb.config.Currency), nil
+}
+
+// refreshSeasonality 季節性模型未學習或已超過更新間隔時重新學習
+func (lb *LendingBot) refreshSeasonality() {
+	now := lb.now()
+	refresh := time.Duration(lb.config.SeasonalityRefreshHours) * time.Hour
+	if lb.seasonality.IsTrained() && now.Sub(lb.seasonality.TrainedAt()) < refresh {
+		return
+	}
+
+	candles, source, err := lb.seasonalityCandles(now)
+	if err != nil {
+		log.Printf("取得季節性K線失敗，沿用現有係數: %v", err)
+		return
+	}
+	if err := lb.seasonality.Train(candles, now); err != nil {
+		log.Printf("季節性模型學習失敗: %v", err)
+		return
+	}
+	log.Printf("季節性模型已更新，使用 %d 根%s小時K線", len(candles), source)
+}
+
+// seasonalityCandles 取得季節性模型的學習K線
+// 優先使用 collect 保存在 DATA_DIR 的小時K線（只取 now 之前的回看區間），
+// 保存的數據涵蓋不足回看區間時改向交易所取得
+func (lb *LendingBot) seasonalityCandles(now time.Time) ([]*bitfinex.Candle, string, error) {
+	symbol := lb.config.GetFundingSymbol()
+	lookback := lb.config.SeasonalityLookbackDays * 24
+
+	stored, err := history.NewStore(lb.config.DataDir).LoadCandles(symbol, constants.SeasonalityTimeFrame)
+	if err != nil {
+		log.Printf("讀取已保存的季節性K線失敗，改用即時K線: %v", err)
+	} else {
+		start := now.Add(-time.Duration(lookback) * time.Hour)
+		window := make([]*bitfinex.Candle, 0, lookback)
+		for _, candle := range stored {
+			if t := history.MTSToTime(candle.MTS); !t.Before(start) && t.Before(now) {
+				window = append(window, candle)
+			}
+		}
+		if float64(len(window)) >= float64(lookback)*constants.SeasonalityMinStoredCoverage {
+			return window, "已保存的", nil
+		}
+	}
+
+	candles, err := lb.client.GetFundingCandles(symbol, constants.SeasonalityTimeFrame, lookback)
+	if err != nil {
+		return nil, "", err
+	}
+	return candles, "即時", nil
+}
+
+// BootstrapMarketAnalyzer 啟動時恢復智能策略的市場快照
+// persist 為 true 時先載入上次保存的快照，之後每次記錄都寫回檔案（回測不保存）；
+// 快照不足時以執行間隔對應的K線收盤利率補足，讓趨勢與波動率從第一次執行就有意義
+func (lb *LendingBot) BootstrapMarketAnalyzer(persist bool) {
+	if !lb.config.UsesStrategy(constants.StrategySmart) {
+		return
+	}
+
+	analyzer := lb.smartStrategy.analyzer
+	if persist {
+		path := lb.config.GetAnalyzerStatePath()
+		if loaded, err := analyzer.Load(path); err != nil {
+			log.Printf("載入市場快照失敗，改由K線補足: %v", err)
+		} else if loaded > 0 {
+			log.Printf("已載入 %d 筆市場快照: %s", loaded, path)
+		}
+		lb.smartStrategy.statePath = path
+	}
+
+	now := lb.now()
+	timeFrame := AnalyzerTimeFrame(lb.config.MinutesRun)
+	interval, _ := history.TimeFrameDuration(timeFrame)
+
+	// 現有快照仍涵蓋完整區間時不需呼叫 API
+	snapshots := analyzer.Snapshots()
+	if len(snapshots) >= analyzer.HistorySize() && now.Sub(snapshots[0].Timestamp) <= time.Duration(analyzer.HistorySize())*interval {
+		return
+	}
+
+	candles, err := lb.client.GetFundingCandles(lb.config.GetFundingSymbol(), timeFrame, analyzer.HistorySize()+1)
+	if err != nil {
+		log.Printf("取得市場快照K線失敗，沿用現有快照: %v", err)
+		return
+	}
+	added := analyzer.Bootstrap(candles, interval, now)
+	log.Printf("以 %s K線補入 %d 筆市場快照，目前共 %d 筆", timeFrame, added, len(analyzer.Snapshots()))
+
+	if persist && added > 0 {
+		if err := analyzer.Save(lb.smartStrategy.statePath); err != nil {
+			log.Printf("保存市場快照失敗: %v", err)
+		}
+	}
+}
+
+// applySeasonality 依季節性係數調整分散單利率（高額持有單與 FRR 單不調整）
+func (lb *LendingBot) applySeasonality(loanOffers []*LoanOffer) {
+	lb.refreshSeasonality()
+
+	factor := lb.seasonality.Factor(lb.now(), lb.config.GetSeasonalityLeadHours())
+	if factor == 1 {
+		return
+	}
+
+	minDailyRate := lb.ladderFloor()
+	adjusted := 0
+	for _, offer := range loanOffers {
+		if offer.HighHold || offer.UseFRR {
+			continue
+		}
+		offer.Rate = math.Min(math.Max(offer.Rate*factor, minDailyRate), constants.MaxFundingDailyRate)
+		adjusted++
+	}
+
+	log.Printf("季節性調整：係數 %.3f (%+.1f%%)，調整 %d 筆訂單利率", factor, (factor-1)*100, adjusted)
+}
+
+// GetSeasonalityReport 獲取季節性模型摘要
+func (lb *LendingBot) GetSeasonalityReport() string {
+	return lb.seasonality.Report(lb.now(), lb.config.GetSeasonalityLeadHours())
+}
+
+// GetMarketAnalysisReport 返回智能策略市場分析器的估計器讀數
+func (lb *LendingBot) GetMarketAnalysisReport() string {
+	return lb.smartStrategy.MarketReport()
+}
+
+// applyRiskGuard 檢查市場數據與訂單利率，並依時間窗口限額裁減訂單
+// 市場數據或利率異常時進入熔斷狀態並發送通知，返回 false 代表不應下單
+func (lb *LendingBot) applyRiskGuard(loanOffers []*LoanOffer, fundingBook []*bitfinex.FundingBookEntry, bookErr error) ([]*LoanOffer, bool) {
+	fundingSymbol := lb.config.GetFundingSymbol()
+	ref := &MarketReference{
+		FundingBook: fundingBook,
+		BookErr:     bookErr,
+	}
+
+	if frr, err := lb.client.GetCurrentFundingRate(fundingSymbol); err != nil {
+		log.Printf("風險檢查：取得 FRR 失敗，略過 FRR 檢查: %v", err)
+	} else {
+		ref.FRR = frr
+	}
+
+	if candles, err := lb.client.GetFundingCandles(fundingSymbol, constants.RiskCandleTimeFrame, constants.RiskCandleLimit); err != nil {
+		log.Printf("風險檢查：取得K線失敗，略過K線區間檢查: %v", err)
+	} else {
+		ref.Candles = candles
+	}
+
+	if err := lb.riskGuard.CheckMarketData(ref); err != nil {
+		lb.tripRiskGuard(fmt.Sprintf("市場數據異常: %v", err))
+		return nil, false
+	}
+
+	if err := lb.riskGuard.CheckOfferRates(loanOffers, ref); err != nil {
+		lb.tripRiskGuard(fmt.Sprintf("訂單利率異常: %v", err))
+		return nil, false
+	}
+
+	allowed, note := lb.riskGuard.ApplyPlacementLimits(loanOffers, lb.now())
+	if note != "" {
+		log.Printf("⚠️ 風險控管: %s", note)
+	}
+
+	return allowed, true
+}
+
+// tripRiskGuard 進入熔斷狀態並發送 Telegram 警報
+func (lb *LendingBot) tripRiskGuard(reason string) {
+	log.Printf("⛔ 風險控管觸發熔斷: %s", reason)
+
+	if !lb.riskGuard.Trip(reason, lb.now()) || lb.notifyCallback == nil {
+		return
+	}
+
+	message := fmt.Sprintf("⛔ 風險控管熔斷\n\n幣種: %s\n原因: %s\n\n已停止下單，確認市場狀況後使用 /resume 恢復",
+		lb.config.Currency, reason)
+	if err := lb.notifyCallback(message); err != nil {
+		log.Printf("發送熔斷通知失敗: %v", err)
+	}
+}
+
+// ResumeRiskGuard 解除熔斷狀態（供 Telegram 指令使用），返回解除前是否處於熔斷
+func (lb *LendingBot) ResumeRiskGuard() bool {
+	resumed := lb.riskGuard.Resume()
+	if resumed {
+		log.Println("✅ 風險控管熔斷已手動解除")
+	}
+	return resumed
+}
+
+// GetRiskGuardStatus 獲取風險控管狀態描述（供 Telegram 指令使用）
+func (lb *LendingBot) GetRiskGuardStatus() string {
+	if !lb.config.EnableRiskGuard {
+		return "未啟用"
+	}
+
+	status := "運作中"
+	if halted, reason, haltedAt := lb.riskGuard.IsHalted(); halted {
+		status = fmt.Sprintf("⛔ 熔斷中 (%s)\n原因: %s", haltedAt.Format("2006-01-02 15:04:05"), reason)
+	}
+
+	orders, notional := lb.riskGuard.WindowUsage(lb.now())
+	status += fmt.Sprintf("\n近 %d 分鐘已下單: %d 筆 / %.2f %s", lb.config.RiskWindowMinutes, orders, notional, lb.config.Currency)
+	if lb.config.RiskMaxOrdersPerWindow > 0 {
+		status += fmt.Sprintf("\n筆數上限: %d", lb.config.RiskMaxOrdersPerWindow)
+	}
+	if lb.config.RiskMaxNotionalPerWindow > 0 {
+		status += fmt.Sprintf("\n金額上限: %.2f", lb.config.RiskMaxNotionalPerWindow)
+	}
+
+	return status
+}
+
+// recordPlacement 記錄下單金額供風險控管限額與策略統計使用
+func (lb *LendingBot) recordPlacement(offer *LoanOffer) {
+	if lb.config.EnableRiskGuard {
+		lb.riskGuard.RecordPlacement(offer.Amount, lb.now())
+	}
+	lb.strategyStats.RecordPlaced(offer.Strategy, offer.Amount)
+}
+
+// releasePlacement 撤單後扣回未成交金額的下單紀錄，重新掛出同一筆資金不重複計入風險控管限額
+func (lb *LendingBot) releasePlacement(amount float64) {
+	if lb.config.EnableRiskGuard {
+		lb.riskGuard.ReleasePlacement(amount, lb.now())
+	}
+}
+
 // calculatePeriod 根據利率計算貸出期間
 func (lb *LendingBot) calculatePeriod(dailyRate float64) int {
-	oneTwentyThreshold := lb.config.GetOneTwentyDayThresholdDecimal()
-	thirtyThreshold := lb.config.GetThirtyDayThresholdDecimal()
+	return thresholdPeriod(lb.config, lb.rateConverter, dailyRate)
+}
 
-	if lb.config.OneTwentyDayLendRateThreshold > 0 && dailyRate >= oneTwentyThreshold {
+// thresholdPeriod 依 30/120 天利率閾值決定期間
+// RATE_THRESHOLD_MODE=net 時閾值為淨利率，以扣除一般掛單手續費後的利率比較
+func thresholdPeriod(cfg *config.Config, converter *rates.Converter, dailyRate float64) int {
+	if cfg.IsNetRateThreshold() {
+		dailyRate = converter.NetRate(dailyRate, false)
+	}
+
+	if cfg.OneTwentyDayLendRateThreshold > 0 && dailyRate >= cfg.GetOneTwentyDayThresholdDecimal() {
 		return constants.Period120Days
-	} else if lb.config.ThirtyDayLendRateThreshold > 0 && dailyRate >= thirtyThreshold {
+	} else if cfg.ThirtyDayLendRateThreshold > 0 && dailyRate >= cfg.GetThirtyDayThresholdDecimal() {
 		return constants.Period30Days
-	} else {
-		return constants.DefaultPeriodDays
 	}
+	return constants.DefaultPeriodDays
 }
 
 // placeLoanOffers 下單
-func (lb *LendingBot) placeLoanOffers(loanOffers []*LoanOffer, hasPendingOrders bool) error {
+func (lb *LendingBot) placeLoanOffers(loanOffers []*LoanOffer) error {
 	orderCount := 0
+	rules := lb.amountRules()
 	fundingSymbol := lb.config.GetFundingSymbol()
 	if lb.config.IsMinDailyLendRateFRR() {
 		log.Printf("MIN_DAILY_LEND_RATE=%s，分散單使用 FRR 模式；高額持有單維持固定利率", constants.MinDailyRateModeFRR)
@@ -309,38 +732,49 @@ func (lb *LendingBot) placeLoanOffers(loanOffers []*LoanOffer, hasPendingOrders
 			break
 		}
 
-		offer.Amount = floorToCents(offer.Amount)
-		if offer.Amount < lb.config.MinLoan {
+		offer.Amount = rules.floor(offer.Amount)
+		if offer.Amount < rules.minLoan {
 			log.Printf("跳過無效金額: %.4f", offer.Amount)
 			continue
 		}
 
 		if offer.UseFRR {
-			frrPeriod := constants.Period120Days
+			frrPeriod := offerPeriod(offer)
 
-			if lb.config.TestMode {
-				log.Printf("🧪 [測試模式] 模擬下單 => Type: %s, Amount: %.4f, Period: %d (參考Rate: %.6f%%)",
+			if lb.config.IsDryRun() {
+				log.Printf("🧪 [測試模式] 模擬下單 => Type: %s, Amount: %.4f, Period: %d, Hidden: %v (參考Rate: %.6f%%)",
 					constants.OfferTypeFRRDeltaVar,
 					offer.Amount,
 					frrPeriod,
+					offer.Hidden,
 					lb.rateConverter.DecimalToPercentage(offer.Rate),
 				)
+				lb.recordPlacement(offer)
 				orderCount++
 			} else {
-				log.Printf("下單 => Type: %s, Amount: %.4f, Period: %d (參考Rate: %.6f%%)",
+				log.Printf("下單 => Type: %s, Amount: %.4f, Period: %d, Hidden: %v (參考Rate: %.6f%%)",
 					constants.OfferTypeFRRDeltaVar,
 					offer.Amount,
 					frrPeriod,
+					offer.Hidden,
 					lb.rateConverter.DecimalToPercentage(offer.Rate),
 				)
 
-				orderID, err := lb.client.SubmitFundingOfferFRR(fundingSymbol, offer.Amount, frrPeriod, false)
+				orderID, err := lb.client.SubmitFundingOfferFRR(fundingSymbol, offer.Amount, frrPeriod, offer.Hidden)
 				if err != nil {
 					log.Printf("下訂單失敗: %v", err)
 				} else {
 					// 追蹤程式創建的訂單
-					lb.orderTracker.TrackOrder(orderID)
+					lb.orderTracker.TrackOrder(orderID, tracker.OrderInfo{
+						CreatedAt: lb.now(),
+						Hidden:    offer.Hidden,
+						Strategy:  offer.Strategy,
+						Amount:    offer.Amount,
+						Rate:      offer.Rate,
+						Period:    frrPeriod,
+					})
 					log.Printf("成功創建訂單 ID: %d，已加入追蹤", orderID)
+					lb.recordPlacement(offer)
 					orderCount++
 				}
 			}
@@ -348,35 +782,39 @@ func (lb *LendingBot) placeLoanOffers(loanOffers []*LoanOffer, hasPendingOrders
 			continue
 		}
 
-		rate := offer.Rate
-		if !hasPendingOrders {
-			// 添加利率加成
-			rate += lb.rateConverter.PercentageToDecimal(lb.config.RateBonus)
-		}
-
 		// 驗證利率
+		rate := offer.Rate
 		if !lb.rateConverter.ValidateDailyRate(rate) {
 			log.Printf("跳過無效利率: %.6f", rate)
 			continue
 		}
 
-		if lb.config.TestMode {
+		if lb.config.IsDryRun() {
 			// 測試模式：只記錄不真的下單
-			log.Printf("🧪 [測試模式] 模擬下單 => Rate: %.6f%%, Amount: %.4f, Period: %d",
-				lb.rateConverter.DecimalToPercentage(rate), offer.Amount, offer.Period)
+			log.Printf("🧪 [測試模式] 模擬下單 => Rate: %.6f%%, Amount: %.4f, Period: %d, Hidden: %v",
+				lb.rateConverter.DecimalToPercentage(rate), offer.Amount, offer.Period, offer.Hidden)
+			lb.recordPlacement(offer)
 			orderCount++
 		} else {
 			// 正式模式：真的下單
-			log.Printf("下單 => Rate: %.6f%%, Amount: %.4f, Period: %d",
-				lb.rateConverter.DecimalToPercentage(rate), offer.Amount, offer.Period)
+			log.Printf("下單 => Rate: %.6f%%, Amount: %.4f, Period: %d, Hidden: %v",
+				lb.rateConverter.DecimalToPercentage(rate), offer.Amount, offer.Period, offer.Hidden)
 
-			orderID, err := lb.client.SubmitFundingOffer(fundingSymbol, offer.Amount, rate, offer.Period, false)
+			orderID, err := lb.client.SubmitFundingOffer(fundingSymbol, offer.Amount, rate, offer.Period, offer.Hidden)
 			if err != nil {
 				log.Printf("下訂單失敗: %v", err)
 			} else {
 				// 追蹤程式創建的訂單
-				lb.orderTracker.TrackOrder(orderID)
+				lb.orderTracker.TrackOrder(orderID, tracker.OrderInfo{
+					CreatedAt: lb.now(),
+					Hidden:    offer.Hidden,
+					Strategy:  offer.Strategy,
+					Amount:    offer.Amount,
+					Rate:      rate,
+					Period:    offer.Period,
+				})
 				log.Printf("成功創建訂單 ID: %d，已加入追蹤", orderID)
+				lb.recordPlacement(offer)
 				orderCount++
 			}
 		}
@@ -432,7 +870,7 @@ func (lb *LendingBot) CheckNewLendingCredits() (bool, error) {
 	}
 
 	// 獲取當前時間戳（毫秒）
-	currentTime := time.Now().UnixNano() / int64(time.Millisecond)
+	currentTime := lb.now().UnixNano() / int64(time.Millisecond)
 
 	// 如果這是第一次檢查，初始化時間戳和餘額但不觸發執行
 	if lb.config.LastLendingCheckTime == 0 {
@@ -515,19 +953,20 @@ func (lb *LendingBot) sendLendingNotification(credits []*bitfinex.FundingCredit)
 		}
 	}
 
-	// 先計算所有訂單的統計信息
+	// 先計算所有訂單的統計信息（借貸訂單無法得知是否為隱藏掛單，以一般手續費估算）
 	totalAmount := 0.0
-	totalEarnings := 0.0
+	totalEarnings := rates.Yield{}
 
 	for _, credit := range credits {
 		effectiveRate := credit.EffectiveDailyRate()
 		if effectiveRate == 0 && frrFallbackRate > 0 {
 			effectiveRate = frrFallbackRate
 		}
-		dailyEarnings := credit.Amount * effectiveRate
-		periodEarnings := dailyEarnings * float64(credit.Period)
+		earnings := lb.rateConverter.Split(credit.Amount*effectiveRate*float64(credit.Period), false)
 		totalAmount += credit.Amount
-		totalEarnings += periodEarnings
+		totalEarnings.Gross += earnings.Gross
+		totalEarnings.Fee += earnings.Fee
+		totalEarnings.Net += earnings.Net
 	}
 
 	// 顯示詳細信息（最多顯示配置數量的訂單）
@@ -538,23 +977,23 @@ func (lb *LendingBot) sendLendingNotification(credits []*bitfinex.FundingCredit)
 			break
 		}
 
-		// 計算預期收益（日利率 * 金額 * 期間）
+		// 計算預期收益（日利率 * 金額 * 期間），並拆分手續費
 		effectiveRate := credit.EffectiveDailyRate()
 		if effectiveRate == 0 && frrFallbackRate > 0 {
 			effectiveRate = frrFallbackRate
 		}
-		dailyEarnings := credit.Amount * effectiveRate
-		periodEarnings := dailyEarnings * float64(credit.Period)
+		rate := lb.rateConverter.Split(lb.rateConverter.DecimalToPercentage(effectiveRate), false)
+		earnings := lb.rateConverter.Split(credit.Amount*effectiveRate*float64(credit.Period), false)
 
 		// 格式化開始時間
 		openTime := time.Unix(credit.MTSOpened/1000, 0)
 
 		message += fmt.Sprintf("📊 訂單 #%d\n", i+1)
 		message += fmt.Sprintf("💵 金額: %.2f %s\n", credit.Amount, lb.config.Currency)
-		message += fmt.Sprintf("📈 日利率: %.4f%%\n", lb.rateConverter.DecimalToPercentage(effectiveRate))
-		message += fmt.Sprintf("📈 年利率: %.4f%%\n", lb.rateConverter.DecimalToPercentage(effectiveRate)*constants.DaysPerYear)
+		message += fmt.Sprintf("📈 日利率: %s\n", rate.FormatRate())
+		message += fmt.Sprintf("📈 年利率: %.4f%% (淨 %.4f%%)\n", rate.Gross*constants.DaysPerYear, rate.Net*constants.DaysPerYear)
 		message += fmt.Sprintf("⏰ 期間: %d 天\n", credit.Period)
-		message += fmt.Sprintf("💰 預期收益: %.4f %s\n", periodEarnings, lb.config.Currency)
+		message += fmt.Sprintf("💰 預期收益: %s\n", earnings.FormatAmount(lb.config.Currency))
 		message += fmt.Sprintf("🕐 開始時間: %s\n", openTime.Format("2006-01-02 15:04:05"))
 		message += "\n"
 	}
@@ -563,7 +1002,7 @@ func (lb *LendingBot) sendLendingNotification(credits []*bitfinex.FundingCredit)
 	message += fmt.Sprintf("📊 統計信息:\n")
 	message += fmt.Sprintf("📦 總數量: %d 個訂單\n", len(credits))
 	message += fmt.Sprintf("💵 總金額: %.2f %s\n", totalAmount, lb.config.Currency)
-	message += fmt.Sprintf("💰 總預期收益: %.4f %s\n", totalEarnings, lb.config.Currency)
+	message += fmt.Sprintf("💰 總預期收益: %s\n", totalEarnings.FormatAmount(lb.config.Currency))
 
 	// 嘗試發送通知，如果失敗（例如 Telegram 未認證）只記錄日誌但不返回錯誤
 	if err := lb.notifyCallback(message); err != nil {
@@ -577,59 +1016,41 @@ func (lb *LendingBot) sendLendingNotification(credits []*bitfinex.FundingCredit)
 	return nil
 }
 
+// GetTrackedOrderStats 獲取追蹤訂單數量與其中隱藏掛單數量（供 Telegram 指令使用）
+func (lb *LendingBot) GetTrackedOrderStats() (int, int) {
+	return lb.orderTracker.GetOrderCount(), lb.orderTracker.GetHiddenOrderCount()
+}
+
 // GetActiveLendingCredits 獲取活躍借貸訂單（供 Telegram 指令使用）
 func (lb *LendingBot) GetActiveLendingCredits() ([]*bitfinex.FundingCredit, error) {
 	return lb.client.GetFundingCredits(lb.config.GetFundingSymbol())
 }
 
 // calculateKlineOffers 基於K線數據計算貸出訂單
-func (lb *LendingBot) calculateKlineOffers(fundsAvailable float64) []*LoanOffer {
+func (lb *LendingBot) calculateKlineOffers(fundsAvailable float64, fundingBook []*bitfinex.FundingBookEntry) []*LoanOffer {
 	var loanOffers []*LoanOffer
+	rules := lb.amountRules()
 
 	// 檢查可用資金
-	if fundsAvailable < lb.config.MinLoan {
+	if fundsAvailable < rules.minLoan {
 		return loanOffers
 	}
 
-	// 獲取K線數據
-	candles, _ := lb.client.GetFundingCandles(
-		lb.config.GetFundingSymbol(),
-		lb.config.KlineTimeFrame,
-		lb.config.KlinePeriod,
-	)
-
-	// 找到最近期間內的最高利率
-	highestRate := lb.findHighestRateFromCandles(candles)
-	log.Printf("K線數據分析：最高利率 %.6f%%", lb.rateConverter.DecimalToPercentage(highestRate))
-
-	// 計算目標利率（最高利率 + 加成）
-	spreadMultiplier := 1.0 + (lb.config.KlineSpreadPercent / 100.0)
-	targetRate := highestRate * spreadMultiplier
-
-	// 確保不低於最小利率
-	minDailyRate := lb.config.GetMinDailyRateDecimal()
-	if targetRate < minDailyRate {
-		targetRate = minDailyRate
-		log.Printf("目標利率低於最小利率，使用最小利率: %.6f%%", lb.rateConverter.DecimalToPercentage(targetRate))
-	}
-
-	log.Printf("K線策略目標利率: %.6f%% (加成: %.1f%%)",
-		lb.rateConverter.DecimalToPercentage(targetRate),
-		lb.config.KlineSpreadPercent)
+	targetRate := lb.calculateKlineTargetRate()
 
 	splitFundsAvailable := fundsAvailable
 
 	// 高額持有策略
-	if lb.config.HighHoldAmount > lb.config.MinLoan {
+	if lb.config.HighHoldAmount > rules.minLoan {
 		highHoldOffers := lb.calculateHighHoldOffers(&splitFundsAvailable)
 		loanOffers = append(loanOffers, highHoldOffers...)
 	}
 
 	// 使用目標利率創建分散訂單
-	if splitFundsAvailable >= lb.config.MinLoan {
+	if splitFundsAvailable >= rules.minLoan {
 		remainingSlots := lb.getRemainingOrderSlots(len(loanOffers))
 		if remainingSlots != 0 {
-			klineOffers := lb.calculateKlineSpreadOffers(splitFundsAvailable, targetRate, remainingSlots)
+			klineOffers := lb.calculateKlineSpreadOffers(splitFundsAvailable, targetRate, fundingBook, remainingSlots)
 			loanOffers = append(loanOffers, klineOffers...)
 		}
 	}
@@ -637,14 +1058,44 @@ func (lb *LendingBot) calculateKlineOffers(fundsAvailable float64) []*LoanOffer
 	return loanOffers
 }
 
-// findHighestRateFromCandles 從K線數據中找到最高利率
-func (lb *LendingBot) findHighestRateFromCandles(candles []*bitfinex.Candle) float64 {
+// calculateKlineTargetRate 合併各時間框架視窗的平滑利率，加上加成計算目標利率（不低於最小利率）
+func (lb *LendingBot) calculateKlineTargetRate() float64 {
+	minDailyRate := lb.ladderFloor()
+
+	// 合併各視窗的平滑利率，皆無數據時以最小利率為基準
+	components := lb.calculateKlineComponents()
+	consensusRate, ok := aggregateKlineRates(components, lb.config.KlineAggregation)
+	if !ok {
+		consensusRate = minDailyRate
+	}
+	log.Printf("K線數據分析：%d 個視窗合併利率 %.6f%%", len(components), lb.rateConverter.DecimalToPercentage(consensusRate))
+
+	// 計算目標利率（合併利率 + 加成）
+	spreadMultiplier := 1.0 + (lb.config.KlineSpreadPercent / 100.0)
+	targetRate := consensusRate * spreadMultiplier
+
+	// 確保不低於最小利率
+	if targetRate < minDailyRate {
+		targetRate = minDailyRate
+		log.Printf("目標利率低於最小利率，使用最小利率: %.6f%%", lb.rateConverter.DecimalToPercentage(targetRate))
+	}
+
+	log.Printf("K線策略目標利率: %.6f%% (加成: %.1f%%)",
+		lb.rateConverter.DecimalToPercentage(targetRate),
+		lb.config.KlineSpreadPercent)
+
+	lb.recordKlineConsensus(components, consensusRate, targetRate)
+	return targetRate
+}
+
+// findHighestRateFromCandles 以指定的平滑方法從K線數據計算利率
+func (lb *LendingBot) findHighestRateFromCandles(candles []*bitfinex.Candle, method string) float64 {
 	if len(candles) == 0 {
 		return lb.config.GetMinDailyRateDecimal()
 	}
 
 	// 根據配置選擇平滑方法
-	switch lb.config.KlineSmoothMethod {
+	switch method {
 	case "max":
 		return lb.findMaxRate(candles)
 	case "sma":
@@ -655,8 +1106,20 @@ func (lb *LendingBot) findHighestRateFromCandles(candles []*bitfinex.Candle) flo
 		return lb.calculateHighLowAverage(candles)
 	case "p90":
 		return lb.calculate90Percentile(candles)
+	case "vwap":
+		return lb.calculateVWAP(candles)
+	case "vwp90":
+		return lb.calculateVolumeWeightedP90(candles)
+	case "median":
+		return lb.calculateMedianHigh(candles)
+	case "bbu":
+		_, upper := lb.calculateBollinger(candles)
+		return upper
+	case "bbm":
+		mid, _ := lb.calculateBollinger(candles)
+		return mid
 	default:
-		log.Printf("未知的平滑方法: %s，使用預設的 EMA", lb.config.KlineSmoothMethod)
+		log.Printf("未知的平滑方法: %s，使用預設的 EMA", method)
 		return lb.calculateEMAHigh(candles)
 	}
 }
@@ -753,33 +1216,34 @@ func (lb *LendingBot) calculate90Percentile(candles []*bitfinex.Candle) float64
 }
 
 // calculateKlineSpreadOffers 基於K線目標利率計算分散訂單
-func (lb *LendingBot) calculateKlineSpreadOffers(fundsAvailable float64, targetRate float64, maxOrders int) []*LoanOffer {
+func (lb *LendingBot) calculateKlineSpreadOffers(fundsAvailable float64, targetRate float64, fundingBook []*bitfinex.FundingBookEntry, maxOrders int) []*LoanOffer {
 	var offers []*LoanOffer
 	useFRR := lb.config.IsMinDailyLendRateFRR()
 
+	rules := lb.amountRules()
 	numSplits := lb.config.SpreadLend
 	if maxOrders > 0 && numSplits > maxOrders {
 		numSplits = maxOrders
 	}
-	if numSplits <= 0 || fundsAvailable < lb.config.MinLoan {
+	if numSplits <= 0 || fundsAvailable < rules.minLoan {
 		return offers
 	}
 
-	orderAmounts := buildOrderAmounts(fundsAvailable, numSplits, lb.config.MinLoan, lb.config.MaxLoan)
+	orderAmounts := buildOrderAmounts(fundsAvailable, numSplits, rules)
 	if len(orderAmounts) == 0 {
 		return offers
 	}
 
 	// 創建訂單，使用目標利率為基準，微調以分散風險
 	for i, allocAmount := range orderAmounts {
-		if allocAmount < lb.config.MinLoan {
+		if allocAmount < rules.minLoan {
 			break
 		}
 
 		rate := targetRate * (1 + (float64(i) * lb.config.RateRangeIncreasePercent))
 
 		// 確保利率不低於最小利率
-		minDailyRate := lb.config.GetMinDailyRateDecimal()
+		minDailyRate := lb.ladderFloor()
 		if rate < minDailyRate {
 			rate = minDailyRate
 		}
@@ -796,6 +1260,8 @@ func (lb *LendingBot) calculateKlineSpreadOffers(fundsAvailable float64, targetR
 		offers = append(offers, offer)
 	}
 
+	offers = applyAllocationProfile(offers, lb.config, rules, fundingBook, maxOrders, lb.absorptionCapacities)
+
 	return offers
 }
 
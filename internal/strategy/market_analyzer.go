@@ -1,23 +1,38 @@
 package strategy
 
 import (
-	"math"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/kfrico/BitfinexLendingBot/internal/bitfinex"
+	"github.com/kfrico/BitfinexLendingBot/internal/constants"
+	"github.com/kfrico/BitfinexLendingBot/internal/history"
 )
 
 // MarketAnalyzer 市場分析器
 type MarketAnalyzer struct {
+	mu             sync.Mutex
 	rateHistory    []RateSnapshot
 	maxHistorySize int
+	now            func() time.Time // 時鐘（回測時使用模擬時間）
+	trend          TrendEstimator
+	volatility     VolatilityEstimator
+	lastCondition  *MarketCondition // 最近一次分析結果
 }
 
 // RateSnapshot 利率快照
 type RateSnapshot struct {
-	Rate      float64
-	Timestamp time.Time
-	Volume    float64
+	Rate      float64   `json:"rate"`
+	Timestamp time.Time `json:"timestamp"`
+	Volume    float64   `json:"volume"`
+	High      float64   `json:"high,omitempty"` // 區間高點（僅K線補入的快照）
+	Low       float64   `json:"low,omitempty"`  // 區間低點（僅K線補入的快照）
 }
 
 // MarketCondition 市場狀況
@@ -27,6 +42,8 @@ type MarketCondition struct {
 	LiquidityDepth int     // 流動性深度
 	AvgRate        float64 // 平均利率
 	RateRatio      float64 // 當前利率/平均利率
+	TrendReading   TrendReading
+	Points         int // 分析使用的快照數量
 }
 
 // NewMarketAnalyzer 創建市場分析器
@@ -34,14 +51,39 @@ func NewMarketAnalyzer() *MarketAnalyzer {
 	return &MarketAnalyzer{
 		rateHistory:    make([]RateSnapshot, 0),
 		maxHistorySize: 48, // 保留48個數據點 (12小時，每15分鐘一次)
+		now:            time.Now,
+		trend:          NewTrendEstimator(constants.TrendEstimatorCount),
+		volatility:     NewVolatilityEstimator(constants.VolatilityEstimatorStdDev),
 	}
 }
 
+// UseEstimators 切換趨勢與波動率估計器（名稱相同時保留現有實例）
+func (ma *MarketAnalyzer) UseEstimators(trendName, volatilityName string) {
+	ma.mu.Lock()
+	defer ma.mu.Unlock()
+	if trendName != "" && ma.trend.Name() != strings.ToLower(trendName) {
+		ma.trend = NewTrendEstimator(trendName)
+	}
+	if volatilityName != "" && ma.volatility.Name() != strings.ToLower(volatilityName) {
+		ma.volatility = NewVolatilityEstimator(volatilityName)
+	}
+}
+
+// SetClock 設置時鐘（回測時以模擬時間記錄快照）
+func (ma *MarketAnalyzer) SetClock(now func() time.Time) {
+	ma.mu.Lock()
+	defer ma.mu.Unlock()
+	ma.now = now
+}
+
 // AddRateSnapshot 添加利率快照
 func (ma *MarketAnalyzer) AddRateSnapshot(rate float64, volume float64) {
+	ma.mu.Lock()
+	defer ma.mu.Unlock()
+
 	snapshot := RateSnapshot{
 		Rate:      rate,
-		Timestamp: time.Now(),
+		Timestamp: ma.now(),
 		Volume:    volume,
 	}
 
@@ -53,8 +95,114 @@ func (ma *MarketAnalyzer) AddRateSnapshot(rate float64, volume float64) {
 	}
 }
 
+// HistorySize 返回分析器可保留的快照數量
+func (ma *MarketAnalyzer) HistorySize() int {
+	return ma.maxHistorySize
+}
+
+// Snapshots 返回目前保存的快照（由舊到新）
+func (ma *MarketAnalyzer) Snapshots() []RateSnapshot {
+	ma.mu.Lock()
+	defer ma.mu.Unlock()
+	return append([]RateSnapshot(nil), ma.rateHistory...)
+}
+
+// Bootstrap 以歷史K線補足快照
+// 早於 now 往前 maxHistorySize 個間隔的舊快照視為過期捨棄；
+// K線收盤利率只補在現有最早快照之前，不覆蓋實際記錄的訂單簿快照。返回補入的數量
+func (ma *MarketAnalyzer) Bootstrap(candles []*bitfinex.Candle, interval time.Duration, now time.Time) int {
+	ma.mu.Lock()
+	defer ma.mu.Unlock()
+
+	cutoff := now.Add(-time.Duration(ma.maxHistorySize) * interval)
+	kept := make([]RateSnapshot, 0, len(ma.rateHistory))
+	for _, snapshot := range ma.rateHistory {
+		if snapshot.Timestamp.After(cutoff) && !snapshot.Timestamp.After(now) {
+			kept = append(kept, snapshot)
+		}
+	}
+
+	// K線時間戳為開盤時間，收盤利率對應 MTS + interval
+	var filled []RateSnapshot
+	for _, candle := range candles {
+		if candle == nil || candle.Close <= 0 {
+			continue
+		}
+		closedAt := history.MTSToTime(candle.MTS).Add(interval)
+		if !closedAt.After(cutoff) || closedAt.After(now) {
+			continue
+		}
+		if len(kept) > 0 && !closedAt.Before(kept[0].Timestamp) {
+			continue
+		}
+		filled = append(filled, RateSnapshot{Rate: candle.Close, Timestamp: closedAt, Volume: candle.Volume, High: candle.High, Low: candle.Low})
+	}
+	sort.Slice(filled, func(i, j int) bool { return filled[i].Timestamp.Before(filled[j].Timestamp) })
+
+	merged := append(filled, kept...)
+	if len(merged) > ma.maxHistorySize {
+		merged = merged[len(merged)-ma.maxHistorySize:]
+	}
+	added := len(merged) - len(kept)
+	if added < 0 {
+		added = 0
+	}
+	ma.rateHistory = merged
+	return added
+}
+
+// Save 將快照寫入 JSON 檔案（先寫暫存檔再改名，避免中途中斷留下不完整檔案）
+func (ma *MarketAnalyzer) Save(path string) error {
+	data, err := json.Marshal(ma.Snapshots())
+	if err != nil {
+		return fmt.Errorf("序列化市場快照失敗: %w", err)
+	}
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("建立目錄失敗: %w", err)
+		}
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o644); err != nil {
+		return fmt.Errorf("寫入市場快照失敗: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("保存市場快照失敗: %w", err)
+	}
+	return nil
+}
+
+// Load 從 JSON 檔案載入快照，檔案不存在時不視為錯誤。返回載入的數量
+func (ma *MarketAnalyzer) Load(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("讀取市場快照失敗: %w", err)
+	}
+
+	var snapshots []RateSnapshot
+	if err := json.Unmarshal(data, &snapshots); err != nil {
+		return 0, fmt.Errorf("解析市場快照失敗: %w", err)
+	}
+	sort.SliceStable(snapshots, func(i, j int) bool { return snapshots[i].Timestamp.Before(snapshots[j].Timestamp) })
+	if len(snapshots) > ma.maxHistorySize {
+		snapshots = snapshots[len(snapshots)-ma.maxHistorySize:]
+	}
+
+	ma.mu.Lock()
+	defer ma.mu.Unlock()
+	ma.rateHistory = snapshots
+	return len(snapshots), nil
+}
+
 // AnalyzeMarket 分析市場狀況
 func (ma *MarketAnalyzer) AnalyzeMarket(fundingBook []*bitfinex.FundingBookEntry) *MarketCondition {
+	ma.mu.Lock()
+	defer ma.mu.Unlock()
+
 	if len(ma.rateHistory) < 3 {
 		// 數據不足，返回默認狀況
 		return &MarketCondition{
@@ -63,25 +211,31 @@ func (ma *MarketAnalyzer) AnalyzeMarket(fundingBook []*bitfinex.FundingBookEntry
 			LiquidityDepth: len(fundingBook),
 			AvgRate:        0.0,
 			RateRatio:      1.0,
+			TrendReading:   TrendReading{Trend: "stable", Detail: "數據不足"},
+			Points:         len(ma.rateHistory),
 		}
 	}
 
 	avgRate := ma.calculateAverageRate()
-	volatility := ma.calculateVolatility()
-	trend := ma.determineTrend()
+	volatility := ma.volatility.Estimate(ma.rateHistory)
+	reading := ma.determineTrend()
 	currentRate := ma.rateHistory[len(ma.rateHistory)-1].Rate
 	rateRatio := 1.0
 	if avgRate > 0 {
 		rateRatio = currentRate / avgRate
 	}
 
-	return &MarketCondition{
-		Trend:          trend,
+	condition := &MarketCondition{
+		Trend:          reading.Trend,
 		Volatility:     volatility,
 		LiquidityDepth: len(fundingBook),
 		AvgRate:        avgRate,
 		RateRatio:      rateRatio,
+		TrendReading:   reading,
+		Points:         len(ma.rateHistory),
 	}
+	ma.lastCondition = condition
+	return condition
 }
 
 // calculateAverageRate 計算平均利率
@@ -98,52 +252,53 @@ func (ma *MarketAnalyzer) calculateAverageRate() float64 {
 	return sum / float64(len(ma.rateHistory))
 }
 
-// calculateVolatility 計算波動率
-func (ma *MarketAnalyzer) calculateVolatility() float64 {
-	if len(ma.rateHistory) < 2 {
-		return 0.0
+// determineTrend 以目前的趨勢估計器判斷趨勢
+func (ma *MarketAnalyzer) determineTrend() TrendReading {
+	if len(ma.rateHistory) < ma.trend.MinPoints() {
+		return TrendReading{Trend: "stable", Detail: fmt.Sprintf("數據不足（需 %d 筆）", ma.trend.MinPoints())}
 	}
+	return ma.trend.Estimate(ma.rateHistory)
+}
 
-	avgRate := ma.calculateAverageRate()
-	var sumSquaredDiff float64
+// Report 返回目前估計器的讀數，並列出其他估計器在相同快照上的結果供比較
+func (ma *MarketAnalyzer) Report() string {
+	ma.mu.Lock()
+	defer ma.mu.Unlock()
 
-	for _, snapshot := range ma.rateHistory {
-		diff := snapshot.Rate - avgRate
-		sumSquaredDiff += diff * diff
+	var b strings.Builder
+	fmt.Fprintf(&b, "趨勢估計器: %s, 波動率估計器: %s", ma.trend.Name(), ma.volatility.Name())
+	if len(ma.rateHistory) == 0 {
+		b.WriteString("\n尚無市場快照")
+		return b.String()
 	}
-
-	variance := sumSquaredDiff / float64(len(ma.rateHistory))
-	return math.Sqrt(variance)
-}
-
-// determineTrend 判斷趨勢
-func (ma *MarketAnalyzer) determineTrend() string {
-	if len(ma.rateHistory) < 6 {
-		return "stable"
+	latest := ma.rateHistory[len(ma.rateHistory)-1]
+	fmt.Fprintf(&b, "\n快照: %d 筆, 最新 %s (%.6f%%)", len(ma.rateHistory), latest.Timestamp.Format("01-02 15:04"), latest.Rate*constants.PercentageToDecimal)
+	if ma.lastCondition != nil {
+		fmt.Fprintf(&b, "\n最近分析: %s (%s), 波動率 %.6f", ma.lastCondition.Trend, ma.lastCondition.TrendReading.Detail, ma.lastCondition.Volatility)
 	}
 
-	// 取最近6個點進行趨勢分析
-	recentHistory := ma.rateHistory[len(ma.rateHistory)-6:]
-
-	var upCount, downCount int
-	for i := 1; i < len(recentHistory); i++ {
-		diff := recentHistory[i].Rate - recentHistory[i-1].Rate
-		threshold := 0.0001 // 0.01%的變化閾值
-
-		if diff > threshold {
-			upCount++
-		} else if diff < -threshold {
-			downCount++
+	b.WriteString("\n趨勢比較:")
+	for _, name := range TrendEstimatorNames {
+		estimator := NewTrendEstimator(name)
+		reading := TrendReading{Trend: "stable", Detail: "數據不足"}
+		if len(ma.rateHistory) >= estimator.MinPoints() {
+			reading = estimator.Estimate(ma.rateHistory)
 		}
+		fmt.Fprintf(&b, "\n%s %s: %s (%s)", estimatorMarker(name == ma.trend.Name()), name, reading.Trend, reading.Detail)
 	}
-
-	if upCount >= 4 {
-		return "rising"
-	} else if downCount >= 4 {
-		return "falling"
+	b.WriteString("\n波動率比較:")
+	for _, name := range VolatilityEstimatorNames {
+		fmt.Fprintf(&b, "\n%s %s: %.6f", estimatorMarker(name == ma.volatility.Name()), name, NewVolatilityEstimator(name).Estimate(ma.rateHistory))
 	}
+	return b.String()
+}
 
-	return "stable"
+// estimatorMarker 標示目前使用的估計器
+func estimatorMarker(active bool) string {
+	if active {
+		return "➤"
+	}
+	return "•"
 }
 
 // AnalyzeCompetition 分析競爭對手
@@ -0,0 +1,110 @@
+package strategy
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kfrico/BitfinexLendingBot/internal/bitfinex"
+	"github.com/kfrico/BitfinexLendingBot/internal/history"
+)
+
+// risingCandles 產生由新到舊、利率逐根上升的K線（與 Bitfinex API 回傳順序相同）
+func risingCandles(now time.Time, interval time.Duration, count int) []*bitfinex.Candle {
+	candles := make([]*bitfinex.Candle, 0, count)
+	for i := 0; i < count; i++ {
+		open := now.Add(-time.Duration(i+1) * interval)
+		candles = append(candles, &bitfinex.Candle{
+			MTS:    history.TimeToMTS(open),
+			Close:  0.02 - float64(i)*0.0002,
+			Volume: 100,
+		})
+	}
+	return candles
+}
+
+func TestMarketAnalyzer_Bootstrap(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	interval := 15 * time.Minute
+
+	analyzer := NewMarketAnalyzer()
+	if added := analyzer.Bootstrap(risingCandles(now, interval, 10), interval, now); added != 10 {
+		t.Fatalf("Bootstrap() added %d snapshots, want 10", added)
+	}
+
+	snapshots := analyzer.Snapshots()
+	if !snapshots[len(snapshots)-1].Timestamp.Equal(now) {
+		t.Errorf("latest snapshot at %v, want candle close time %v", snapshots[len(snapshots)-1].Timestamp, now)
+	}
+	if condition := analyzer.AnalyzeMarket(nil); condition.Trend != "rising" || condition.Volatility == 0 {
+		t.Errorf("AnalyzeMarket() after bootstrap = %+v, want rising trend with volatility", condition)
+	}
+}
+
+func TestMarketAnalyzer_BootstrapKeepsRecordedSnapshots(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	interval := 15 * time.Minute
+	recorded := now.Add(-20 * time.Minute)
+
+	analyzer := NewMarketAnalyzer()
+	analyzer.SetClock(func() time.Time { return now.Add(-48 * time.Hour) })
+	analyzer.AddRateSnapshot(0.0009, 1) // 過期快照
+	analyzer.SetClock(func() time.Time { return recorded })
+	analyzer.AddRateSnapshot(0.0005, 1)
+
+	// 12 小時區間內早於實際快照的K線：收盤於 11:30 至 00:15 共 46 根
+	added := analyzer.Bootstrap(risingCandles(now, interval, 60), interval, now)
+	snapshots := analyzer.Snapshots()
+	if added != 46 || len(snapshots) != 47 {
+		t.Fatalf("Bootstrap() added %d, history %d; want 46 and 47", added, len(snapshots))
+	}
+
+	last := snapshots[len(snapshots)-1]
+	if !last.Timestamp.Equal(recorded) || last.Rate != 0.0005 {
+		t.Errorf("latest snapshot = %+v, want the recorded book snapshot", last)
+	}
+	for _, snapshot := range snapshots[:len(snapshots)-1] {
+		if !snapshot.Timestamp.Before(recorded) {
+			t.Errorf("candle snapshot at %v should be older than recorded snapshot", snapshot.Timestamp)
+		}
+		if snapshot.Rate == 0.0009 {
+			t.Error("stale snapshot should be dropped")
+		}
+	}
+}
+
+func TestMarketAnalyzer_SaveLoad(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	path := filepath.Join(t.TempDir(), "state", "analyzer_fUSD.json")
+
+	analyzer := NewMarketAnalyzer()
+	analyzer.SetClock(func() time.Time { return now })
+	analyzer.AddRateSnapshot(0.0003, 1000)
+	analyzer.AddRateSnapshot(0.0004, 2000)
+	if err := analyzer.Save(path); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	restored := NewMarketAnalyzer()
+	loaded, err := restored.Load(path)
+	if err != nil || loaded != 2 {
+		t.Fatalf("Load() = %d, %v; want 2, nil", loaded, err)
+	}
+	snapshots := restored.Snapshots()
+	if snapshots[1].Rate != 0.0004 || snapshots[1].Volume != 2000 || !snapshots[1].Timestamp.Equal(now) {
+		t.Errorf("restored snapshot = %+v", snapshots[1])
+	}
+
+	if loaded, err := NewMarketAnalyzer().Load(filepath.Join(t.TempDir(), "missing.json")); err != nil || loaded != 0 {
+		t.Errorf("Load() of missing file = %d, %v; want 0, nil", loaded, err)
+	}
+}
+
+func TestAnalyzerTimeFrame(t *testing.T) {
+	tests := map[int]string{0: "1m", 1: "1m", 10: "5m", 15: "15m", 45: "30m", 60: "1h", 240: "3h", 2000: "1D"}
+	for minutes, want := range tests {
+		if got := AnalyzerTimeFrame(minutes); got != want {
+			t.Errorf("AnalyzerTimeFrame(%d) = %s, want %s", minutes, got, want)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package strategy
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/kfrico/BitfinexLendingBot/internal/bitfinex"
+	"github.com/kfrico/BitfinexLendingBot/internal/config"
+	"github.com/kfrico/BitfinexLendingBot/internal/constants"
+)
+
+// MaturityPlanner 到期分散規劃器，調整新訂單期間以填補到期分布的空缺
+type MaturityPlanner struct {
+	bucketDays int
+	targets    []float64 // 各分桶目標占比（總和為 1）
+	flex       float64   // 期間可調整幅度
+}
+
+// MaturityProfile 未來到期金額分布
+type MaturityProfile struct {
+	BucketDays int
+	Amounts    []float64 // 各分桶到期金額，第 i 桶涵蓋 [i*BucketDays, (i+1)*BucketDays) 天
+}
+
+// NewMaturityPlanner 創建到期分散規劃器
+func NewMaturityPlanner(cfg *config.Config) *MaturityPlanner {
+	bucketDays := cfg.MaturityBucketDays
+	if bucketDays <= 0 {
+		bucketDays = constants.DefaultMaturityBucketDays
+	}
+
+	return &MaturityPlanner{
+		bucketDays: bucketDays,
+		targets:    normalizeMaturityTargets(cfg.MaturityTargetWeights, cfg.GetMaturityBucketCount()),
+		flex:       cfg.GetMaturityPeriodFlex(),
+	}
+}
+
+// normalizeMaturityTargets 將目標權重正規化為占比，未設定時平均分布
+func normalizeMaturityTargets(weights []float64, bucketCount int) []float64 {
+	targets := make([]float64, bucketCount)
+
+	sum := 0.0
+	if len(weights) == bucketCount {
+		for _, w := range weights {
+			sum += w
+		}
+	}
+
+	for i := range targets {
+		if sum > 0 {
+			targets[i] = weights[i] / sum
+		} else {
+			targets[i] = 1.0 / float64(bucketCount)
+		}
+	}
+
+	return targets
+}
+
+// bucketFor 返回指定剩餘天數所屬的分桶
+func (mp *MaturityPlanner) bucketFor(days float64) int {
+	if days < 0 {
+		days = 0
+	}
+	bucket := int(days) / mp.bucketDays
+	if bucket >= len(mp.targets) {
+		bucket = len(mp.targets) - 1
+	}
+	return bucket
+}
+
+// BuildProfile 依活躍借貸的 MTSOpened + Period 計算未來到期分布
+func (mp *MaturityPlanner) BuildProfile(credits []*bitfinex.FundingCredit, now time.Time) *MaturityProfile {
+	profile := &MaturityProfile{
+		BucketDays: mp.bucketDays,
+		Amounts:    make([]float64, len(mp.targets)),
+	}
+
+	nowMs := now.UnixNano() / int64(time.Millisecond)
+	for _, credit := range credits {
+		if credit == nil || credit.Amount <= 0 {
+			continue
+		}
+		maturityMs := credit.MTSOpened + credit.Period*int64(24*time.Hour/time.Millisecond)
+		daysLeft := float64(maturityMs-nowMs) / float64(24*time.Hour/time.Millisecond)
+		profile.Amounts[mp.bucketFor(daysLeft)] += credit.Amount
+	}
+
+	return profile
+}
+
+// periodRange 返回基礎期間可調整的範圍
+func (mp *MaturityPlanner) periodRange(basePeriod int) (int, int) {
+	low := int(math.Round(float64(basePeriod) * (1 - mp.flex)))
+	high := int(math.Round(float64(basePeriod) * (1 + mp.flex)))
+
+	if low < constants.DefaultPeriodDays {
+		low = constants.DefaultPeriodDays
+	}
+	if high > constants.Period120Days {
+		high = constants.Period120Days
+	}
+	if high < low {
+		high = low
+	}
+
+	return low, high
+}
+
+// plannable 判斷訂單是否參與到期分散規劃（期間長於預設 2 天的一般利率單）
+func plannable(offer *LoanOffer) bool {
+	return !offer.UseFRR && offer.Period > constants.DefaultPeriodDays
+}
+
+// PlanPeriods 調整新訂單期間，使到期分布接近目標，返回被調整的訂單數
+// 只規劃期間長於預設 2 天的一般利率單：目標金額以活躍借貸加上這些訂單計算，
+// 短期單與 FRR 單維持原期間，也不計入目標與分布，避免虛增近期分桶的缺口
+func (mp *MaturityPlanner) PlanPeriods(offers []*LoanOffer, profile *MaturityProfile) int {
+	total := 0.0
+	for _, amount := range profile.Amounts {
+		total += amount
+	}
+	for _, offer := range offers {
+		if plannable(offer) {
+			total += offer.Amount
+		}
+	}
+	if total <= 0 {
+		return 0
+	}
+
+	adjusted := 0
+	for _, offer := range offers {
+		if !plannable(offer) {
+			continue
+		}
+
+		low, high := mp.periodRange(offer.Period)
+		bestPeriod := offer.Period
+		bestDeficit := math.Inf(-1)
+
+		for period := low; period <= high; period++ {
+			bucket := mp.bucketFor(float64(period))
+			deficit := mp.targets[bucket]*total - profile.Amounts[bucket]
+
+			// 缺口相同時選擇最接近原期間者
+			if deficit > bestDeficit+1e-9 ||
+				(math.Abs(deficit-bestDeficit) <= 1e-9 && absInt(period-offer.Period) < absInt(bestPeriod-offer.Period)) {
+				bestDeficit = deficit
+				bestPeriod = period
+			}
+		}
+
+		profile.Amounts[mp.bucketFor(float64(bestPeriod))] += offer.Amount
+		if bestPeriod != offer.Period {
+			offer.Period = bestPeriod
+			adjusted++
+		}
+	}
+
+	return adjusted
+}
+
+// Report 產生到期分布報告
+func (mp *MaturityPlanner) Report(profile *MaturityProfile, currency string) string {
+	total := 0.0
+	for _, amount := range profile.Amounts {
+		total += amount
+	}
+
+	report := fmt.Sprintf("分桶: 每 %d 天, 總到期金額: %.2f %s", profile.BucketDays, total, currency)
+	for i, amount := range profile.Amounts {
+		share := 0.0
+		if total > 0 {
+			share = amount / total * 100
+		}
+		report += fmt.Sprintf("\n%3d-%3d天: %10.2f (%.1f%% / 目標 %.1f%%)",
+			i*profile.BucketDays, (i+1)*profile.BucketDays-1, amount, share, mp.targets[i]*100)
+	}
+
+	return report
+}
+
+func absInt(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,126 @@
+package strategy
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/kfrico/BitfinexLendingBot/internal/bitfinex"
+	"github.com/kfrico/BitfinexLendingBot/internal/config"
+)
+
+func TestMaturityPlanner_BuildProfile(t *testing.T) {
+	planner := NewMaturityPlanner(&config.Config{MaturityBucketDays: 30})
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	nowMs := now.UnixNano() / int64(time.Millisecond)
+	dayMs := int64(24 * time.Hour / time.Millisecond)
+
+	credits := []*bitfinex.FundingCredit{
+		{Amount: 100, MTSOpened: nowMs - 28*dayMs, Period: 30},  // 2 天後到期
+		{Amount: 200, MTSOpened: nowMs, Period: 45},             // 45 天後到期
+		{Amount: 300, MTSOpened: nowMs, Period: 120},            // 120 天後到期
+		{Amount: 400, MTSOpened: nowMs - 10*dayMs, Period: 2},   // 已到期
+		{Amount: 500, MTSOpened: nowMs - 10*dayMs, Period: 100}, // 90 天後到期
+	}
+
+	profile := planner.BuildProfile(credits, now)
+	expected := []float64{500, 200, 0, 500, 300}
+	if len(profile.Amounts) != len(expected) {
+		t.Fatalf("expected %d buckets, got %d", len(expected), len(profile.Amounts))
+	}
+	for i, amount := range expected {
+		if math.Abs(profile.Amounts[i]-amount) > floatTolerance {
+			t.Fatalf("bucket %d: expected %.2f, got %.2f (profile %v)", i, amount, profile.Amounts[i], profile.Amounts)
+		}
+	}
+}
+
+func TestMaturityPlanner_PlanPeriods(t *testing.T) {
+	planner := NewMaturityPlanner(&config.Config{MaturityBucketDays: 30})
+
+	// 30-59 天分桶已滿，0-29 天分桶空缺最大
+	profile := &MaturityProfile{BucketDays: 30, Amounts: []float64{0, 1000, 1000, 1000, 1000}}
+	offers := []*LoanOffer{
+		{Amount: 500, Rate: 0.0004, Period: 30},
+		{Amount: 500, Rate: 0.0002, Period: 2},
+		{Amount: 500, Rate: 0.0004, Period: 30, UseFRR: true},
+	}
+
+	adjusted := planner.PlanPeriods(offers, profile)
+	if adjusted != 1 {
+		t.Fatalf("expected 1 adjusted offer, got %d", adjusted)
+	}
+	if offers[0].Period < 15 || offers[0].Period > 29 {
+		t.Fatalf("expected period moved into the 15-29 day window, got %d", offers[0].Period)
+	}
+	if offers[0].Period != 29 {
+		t.Fatalf("expected the closest period to the original (29), got %d", offers[0].Period)
+	}
+	if offers[1].Period != 2 || offers[2].Period != 30 {
+		t.Fatalf("short and FRR offers must keep their periods, got %d and %d", offers[1].Period, offers[2].Period)
+	}
+}
+
+func TestMaturityPlanner_PlanPeriodsMixedLadder(t *testing.T) {
+	planner := NewMaturityPlanner(&config.Config{MaturityBucketDays: 30, MaturityTargetWeights: []float64{3, 1, 1, 1, 1}})
+
+	// 0-29 天分桶已超過目標占比；2 天單不參與規劃，不應拉高目標金額而把 30 天單推向近期分桶
+	profile := &MaturityProfile{BucketDays: 30, Amounts: []float64{600, 0, 0, 0, 0}}
+	offers := []*LoanOffer{
+		{Amount: 1000, Rate: 0.0001, Period: 2},
+		{Amount: 1000, Rate: 0.0002, Period: 2},
+		{Amount: 1000, Rate: 0.0003, Period: 2},
+		{Amount: 1000, Rate: 0.0004, Period: 2},
+		{Amount: 500, Rate: 0.0005, Period: 30},
+	}
+
+	if adjusted := planner.PlanPeriods(offers, profile); adjusted != 0 {
+		t.Fatalf("expected no adjusted offers, got %d", adjusted)
+	}
+	for i, offer := range offers[:4] {
+		if offer.Period != 2 {
+			t.Fatalf("offer %d: short offers must keep their periods, got %d", i, offer.Period)
+		}
+	}
+	if offers[4].Period != 30 {
+		t.Fatalf("expected the 30-day offer to stay out of the filled near-term bucket, got %d", offers[4].Period)
+	}
+	expected := []float64{600, 500, 0, 0, 0}
+	for i, amount := range expected {
+		if math.Abs(profile.Amounts[i]-amount) > floatTolerance {
+			t.Fatalf("bucket %d: expected %.2f, got %.2f (profile %v)", i, amount, profile.Amounts[i], profile.Amounts)
+		}
+	}
+}
+
+func TestMaturityPlanner_ZeroFlexKeepsPeriods(t *testing.T) {
+	flex := 0.0
+	planner := NewMaturityPlanner(&config.Config{MaturityBucketDays: 30, MaturityPeriodFlex: &flex})
+
+	profile := &MaturityProfile{BucketDays: 30, Amounts: []float64{0, 1000, 1000, 1000, 1000}}
+	offers := []*LoanOffer{{Amount: 500, Rate: 0.0004, Period: 30}}
+
+	if adjusted := planner.PlanPeriods(offers, profile); adjusted != 0 {
+		t.Fatalf("expected no adjusted offers with zero flex, got %d", adjusted)
+	}
+	if offers[0].Period != 30 {
+		t.Fatalf("expected period to stay 30, got %d", offers[0].Period)
+	}
+	if math.Abs(profile.Amounts[1]-1500) > floatTolerance {
+		t.Fatalf("expected the offer to be counted in its own bucket, got %v", profile.Amounts)
+	}
+}
+
+func TestNormalizeMaturityTargets(t *testing.T) {
+	even := normalizeMaturityTargets(nil, 4)
+	for _, target := range even {
+		if math.Abs(target-0.25) > floatTolerance {
+			t.Fatalf("expected even targets, got %v", even)
+		}
+	}
+
+	weighted := normalizeMaturityTargets([]float64{1, 3}, 2)
+	if math.Abs(weighted[0]-0.25) > floatTolerance || math.Abs(weighted[1]-0.75) > floatTolerance {
+		t.Fatalf("expected weighted targets [0.25 0.75], got %v", weighted)
+	}
+}
@@ -0,0 +1,48 @@
+package strategy
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/kfrico/BitfinexLendingBot/internal/config"
+)
+
+// applyVisibilityPolicy 依隱藏掛單策略設定每筆訂單的 Hidden 旗標，返回隱藏的筆數
+func applyVisibilityPolicy(offers []*LoanOffer, cfg *config.Config, rng *rand.Rand) int {
+	var ladder []*LoanOffer
+
+	for _, offer := range offers {
+		offer.Hidden = false
+
+		// 高額持有單
+		if offer.HighHold {
+			offer.Hidden = cfg.HideHighHoldOffers
+			continue
+		}
+
+		// 大額訂單
+		if cfg.HiddenOfferMinAmount > 0 && offer.Amount >= cfg.HiddenOfferMinAmount {
+			offer.Hidden = true
+			continue
+		}
+
+		ladder = append(ladder, offer)
+	}
+
+	// 分散單隨機隱藏一定比例
+	if cfg.HiddenOfferRandomPercent > 0 && len(ladder) > 0 && rng != nil {
+		hideCount := int(math.Round(float64(len(ladder)) * cfg.HiddenOfferRandomPercent / 100.0))
+		for _, index := range rng.Perm(len(ladder))[:hideCount] {
+			ladder[index].Hidden = true
+		}
+	}
+
+	hiddenCount := 0
+	for _, offer := range offers {
+		if offer.Hidden {
+			hiddenCount++
+		}
+	}
+
+	return hiddenCount
+}
@@ -0,0 +1,72 @@
+package strategy
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/kfrico/BitfinexLendingBot/internal/config"
+)
+
+func TestApplyVisibilityPolicy(t *testing.T) {
+	newOffers := func() []*LoanOffer {
+		return []*LoanOffer{
+			{Amount: 5000, Rate: 0.001, Period: 120, HighHold: true},
+			{Amount: 300, Rate: 0.0002, Period: 2},
+			{Amount: 300, Rate: 0.0003, Period: 2},
+			{Amount: 2000, Rate: 0.0004, Period: 30},
+			{Amount: 300, Rate: 0.0005, Period: 2},
+		}
+	}
+
+	tests := []struct {
+		name           string
+		config         *config.Config
+		expectedHidden []bool
+	}{
+		{
+			name:           "no policy keeps every offer visible",
+			config:         &config.Config{},
+			expectedHidden: []bool{false, false, false, false, false},
+		},
+		{
+			name:           "hides high hold offers",
+			config:         &config.Config{HideHighHoldOffers: true},
+			expectedHidden: []bool{true, false, false, false, false},
+		},
+		{
+			name:           "hides offers above size threshold",
+			config:         &config.Config{HiddenOfferMinAmount: 1000},
+			expectedHidden: []bool{false, false, false, true, false},
+		},
+		{
+			name:           "hides whole ladder at 100 percent",
+			config:         &config.Config{HiddenOfferRandomPercent: 100},
+			expectedHidden: []bool{false, true, true, true, true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			offers := newOffers()
+			applyVisibilityPolicy(offers, tt.config, rand.New(rand.NewSource(1)))
+
+			for i, offer := range offers {
+				if offer.Hidden != tt.expectedHidden[i] {
+					t.Fatalf("offer #%d: expected hidden=%v, got %v", i, tt.expectedHidden[i], offer.Hidden)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyVisibilityPolicy_RandomShare(t *testing.T) {
+	offers := make([]*LoanOffer, 10)
+	for i := range offers {
+		offers[i] = &LoanOffer{Amount: 200, Rate: 0.0002 + float64(i)*0.00001, Period: 2}
+	}
+
+	hidden := applyVisibilityPolicy(offers, &config.Config{HiddenOfferRandomPercent: 30}, rand.New(rand.NewSource(42)))
+	if hidden != 3 {
+		t.Fatalf("expected 3 hidden offers, got %d", hidden)
+	}
+}
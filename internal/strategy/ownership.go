@@ -0,0 +1,343 @@
+package strategy
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+
+	"github.com/kfrico/BitfinexLendingBot/internal/bitfinex"
+	"github.com/kfrico/BitfinexLendingBot/internal/constants"
+	"github.com/kfrico/BitfinexLendingBot/internal/tracker"
+)
+
+// OrphanOffer 未被程式追蹤的掛單（手動掛單，或重啟前程式留下的掛單）
+type OrphanOffer struct {
+	Offer     *bitfinex.FundingOffer
+	Signature bool // 是否符合程式下單的金額與利率特徵
+}
+
+// RestoreOrderTracker 啟動時載入程式訂單追蹤記錄，之後每次變更都寫回檔案（回測與模擬交易不保存）
+// 載入後與訂單簿比對，程式停止期間已消失的訂單標記為 closed_offline，仍在訂單簿上的訂單繼續由程式管理
+func (lb *LendingBot) RestoreOrderTracker() {
+	path := lb.config.GetOrderTrackerStatePath()
+	loaded, err := lb.orderTracker.Open(path)
+	if err != nil {
+		log.Printf("載入訂單追蹤記錄失敗，重新開始追蹤: %v", err)
+		return
+	}
+	if loaded == 0 {
+		return
+	}
+
+	offers, err := lb.client.GetFundingOffers(lb.config.GetFundingSymbol())
+	if err != nil {
+		log.Printf("取得既有掛單失敗，略過訂單追蹤記錄比對: %v", err)
+		return
+	}
+	kept, closed := lb.reconcileTrackedOrders(offers)
+	log.Printf("已載入 %d 筆追蹤訂單: %d 筆仍在訂單簿上，%d 筆已於停止期間結束: %s", loaded, kept, closed, path)
+}
+
+// reconcileTrackedOrders 比對追蹤中的訂單與訂單簿，不在訂單簿上的訂單標記為 closed_offline
+// 停止期間無法分辨成交或被手動取消，因此不計入策略成交統計
+func (lb *LendingBot) reconcileTrackedOrders(offers []*bitfinex.FundingOffer) (kept, closed int) {
+	onBook := make(map[int64]bool, len(offers))
+	for _, offer := range offers {
+		onBook[offer.ID] = true
+	}
+
+	for _, orderID := range lb.orderTracker.GetTrackedOrders() {
+		if onBook[orderID] {
+			kept++
+			continue
+		}
+		if lb.orderTracker.ResolveOrder(orderID, tracker.OutcomeClosedOffline, 0, lb.now()) {
+			closed++
+		}
+	}
+	return kept, closed
+}
+
+// offerSignature 程式依目前配置與市場會產生的掛單特徵
+type offerSignature struct {
+	periods map[int]bool // 期間閾值、高額持有、到期分散、期間曝險與飆升狙擊可能產生的期間
+	minRate float64      // 目前分散單利率範圍（含利率加成與季節性調整）
+	maxRate float64
+	amounts []float64 // 目前策略的分散單與高額持有單金額
+	rules   amountRules
+}
+
+// signaturePeriods 程式可能掛出的期間
+func (lb *LendingBot) signaturePeriods() map[int]bool {
+	periods := map[int]bool{
+		constants.DefaultPeriodDays: true,
+		constants.Period30Days:      true,
+		constants.Period120Days:     true,
+	}
+	if lb.config.EnableSpikeSniper {
+		periods[lb.config.SpikePeriodDays] = true
+	}
+	for _, limit := range lb.config.GetPeriodExposureLimits() {
+		periods[limit.AboveDays] = true
+	}
+	if lb.config.EnableMaturityPlanner {
+		planner := NewMaturityPlanner(lb.config)
+		for _, base := range []int{constants.Period30Days, constants.Period120Days} {
+			low, high := planner.periodRange(base)
+			for period := low; period <= high; period++ {
+				periods[period] = true
+			}
+		}
+	}
+	return periods
+}
+
+// buildOfferSignature 以目前的可用資金（含訂單簿上的掛單）與訂單簿重新計算策略訂單，作為比對舊掛單的特徵
+func (lb *LendingBot) buildOfferSignature(offers []*bitfinex.FundingOffer) (*offerSignature, error) {
+	balance, err := lb.getAvailableFunds()
+	if err != nil {
+		return nil, err
+	}
+	funds := math.Max(0, balance-lb.config.ReserveAmount)
+	for _, offer := range offers {
+		funds += offer.Amount
+	}
+
+	fundingBook, err := lb.client.GetFundingBook(lb.config.GetFundingSymbol(), constants.MaxPriceLevels)
+	if err != nil {
+		return nil, err
+	}
+
+	signature := &offerSignature{periods: lb.signaturePeriods(), rules: lb.amountRules()}
+	ladder := lb.calculateStrategyOffers(funds, fundingBook)
+	if len(ladder) == 0 {
+		return signature, nil
+	}
+
+	signature.minRate, signature.maxRate = math.Inf(1), 0
+	for _, offer := range ladder {
+		signature.amounts = append(signature.amounts, offer.Amount)
+		signature.minRate = math.Min(signature.minRate, offer.Rate)
+		signature.maxRate = math.Max(signature.maxRate, offer.Rate)
+	}
+
+	// 掛單時加上的利率加成與季節性調整
+	bonusLow, bonusHigh := 0.0, lb.config.RateBonus
+	if lb.config.EnableAdaptiveRateBonus {
+		bonusLow, bonusHigh = math.Min(0, lb.config.RateBonusMin), lb.config.RateBonusMax
+	}
+	signature.minRate += lb.rateConverter.PercentageToDecimal(bonusLow)
+	signature.maxRate += lb.rateConverter.PercentageToDecimal(bonusHigh)
+	if lb.config.EnableSeasonality {
+		adjust := lb.config.SeasonalityMaxAdjustPct / 100
+		signature.minRate *= 1 - adjust
+		signature.maxRate *= 1 + adjust
+	}
+	signature.minRate = math.Max(signature.minRate, math.Min(lb.ladderFloor(), lb.config.GetMinDailyRateDecimal()))
+	return signature, nil
+}
+
+// matchesTerms 掛單的期間、利率與金額單位是否符合程式下單的特徵（FRR 模式不檢查利率）
+func (s *offerSignature) matchesTerms(offer *bitfinex.FundingOffer, frr bool) bool {
+	if !s.periods[offer.Period] {
+		return false
+	}
+	if offer.Amount < s.rules.minLoan-1e-9 || (s.rules.maxLoan > 0 && offer.Amount > s.rules.maxLoan+1e-9) {
+		return false
+	}
+	if math.Abs(s.rules.floor(offer.Amount)-offer.Amount) > 1e-9 {
+		return false
+	}
+	if frr {
+		return true
+	}
+	return offer.Rate >= s.minRate-1e-12 && offer.Rate <= s.maxRate+1e-12
+}
+
+// matchesAmount 掛單金額是否與目前策略的某一筆金額相差不超過一個最小單位
+func (s *offerSignature) matchesAmount(amount float64) bool {
+	unit := 1 / s.rules.scale()
+	for _, ladderAmount := range s.amounts {
+		if math.Abs(ladderAmount-amount) <= unit+1e-9 {
+			return true
+		}
+	}
+	return false
+}
+
+// matchSignatures 標示符合程式下單特徵的掛單：期間、利率與金額單位符合，且金額與目前策略的分散單相同，
+// 或與另一筆符合條件的掛單相差不超過一個最小單位（資金變動前平均分配的同一組分散單）
+func (lb *LendingBot) matchSignatures(orphans []OrphanOffer, signature *offerSignature) {
+	frr := lb.config.IsMinDailyLendRateFRR()
+	var candidates []int
+	for i, orphan := range orphans {
+		if signature.matchesTerms(orphan.Offer, frr) {
+			candidates = append(candidates, i)
+		}
+	}
+
+	unit := 1 / signature.rules.scale()
+	for _, i := range candidates {
+		amount := orphans[i].Offer.Amount
+		if signature.matchesAmount(amount) {
+			orphans[i].Signature = true
+			continue
+		}
+		for _, j := range candidates {
+			if i != j && math.Abs(orphans[j].Offer.Amount-amount) <= unit+1e-9 {
+				orphans[i].Signature = true
+				break
+			}
+		}
+	}
+}
+
+// GetOrphanedOffers 獲取訂單簿上未被程式追蹤的掛單（依訂單 ID 排序）
+// 無法計算程式下單特徵時（例如取得訂單簿失敗）不標示任何掛單，避免誤接管手動掛單
+func (lb *LendingBot) GetOrphanedOffers() ([]OrphanOffer, error) {
+	offers, err := lb.client.GetFundingOffers(lb.config.GetFundingSymbol())
+	if err != nil {
+		return nil, err
+	}
+
+	var orphans []OrphanOffer
+	for _, offer := range offers {
+		if lb.orderTracker.IsTrackedOrder(offer.ID) {
+			continue
+		}
+		orphans = append(orphans, OrphanOffer{Offer: offer})
+	}
+	sort.Slice(orphans, func(i, j int) bool { return orphans[i].Offer.ID < orphans[j].Offer.ID })
+	if len(orphans) == 0 {
+		return orphans, nil
+	}
+
+	signature, err := lb.buildOfferSignature(offers)
+	if err != nil {
+		log.Printf("無法計算程式下單特徵，未追蹤的掛單一律視為手動掛單: %v", err)
+		return orphans, nil
+	}
+	lb.matchSignatures(orphans, signature)
+	return orphans, nil
+}
+
+// adoptOffer 將掛單納入程式追蹤，下次執行時會與程式訂單一起取消並重新掛單
+func (lb *LendingBot) adoptOffer(offer *bitfinex.FundingOffer) {
+	lb.orderTracker.TrackOrder(offer.ID, tracker.OrderInfo{
+		CreatedAt: lb.now(),
+		Hidden:    offer.Hidden,
+		Strategy:  constants.StrategyAdopted,
+		Amount:    offer.Amount,
+		Rate:      offer.Rate,
+		Period:    offer.Period,
+	})
+	lb.strategyStats.RecordPlaced(constants.StrategyAdopted, offer.Amount)
+}
+
+// AdoptStartupOffers 啟動時依 ORPHAN_POLICY 處理未被追蹤的掛單，並通知仍未接管的掛單
+func (lb *LendingBot) AdoptStartupOffers() {
+	orphans, err := lb.GetOrphanedOffers()
+	if err != nil {
+		log.Printf("取得既有掛單失敗，略過訂單歸屬處理: %v", err)
+		return
+	}
+	if len(orphans) == 0 {
+		return
+	}
+
+	policy := lb.config.OrphanPolicy
+	adopted := 0
+	var remaining []OrphanOffer
+	for _, orphan := range orphans {
+		if policy == constants.OrphanPolicyAdoptAll || (policy == constants.OrphanPolicySignature && orphan.Signature) {
+			lb.adoptOffer(orphan.Offer)
+			log.Printf("接管既有掛單 ID: %d, 金額: %.2f, 利率: %.6f%%", orphan.Offer.ID, orphan.Offer.Amount, orphan.Offer.Rate*100)
+			adopted++
+			continue
+		}
+		remaining = append(remaining, orphan)
+	}
+
+	message := fmt.Sprintf("📋 啟動時發現 %d 筆未追蹤的掛單 (ORPHAN_POLICY=%s)", len(orphans), policy)
+	if adopted > 0 {
+		message += fmt.Sprintf("\n已接管 %d 筆，下次執行時將取消並依目前策略重新掛單", adopted)
+	}
+	if len(remaining) > 0 {
+		signature := 0
+		for _, orphan := range remaining {
+			if orphan.Signature {
+				signature++
+			}
+		}
+		message += fmt.Sprintf("\n%d 筆視為手動掛單不處理（其中 %d 筆符合程式下單特徵）\n使用 /orphans 查看，/adopt [訂單ID] 接管",
+			len(remaining), signature)
+	}
+	log.Println(message)
+	lb.notify(message)
+}
+
+// GetOrphanReport 獲取未追蹤掛單列表（供 Telegram 指令使用）
+func (lb *LendingBot) GetOrphanReport() (string, error) {
+	orphans, err := lb.GetOrphanedOffers()
+	if err != nil {
+		return "", err
+	}
+
+	report := fmt.Sprintf("訂單歸屬策略: %s, 程式追蹤中: %d 筆", lb.config.OrphanPolicy, lb.orderTracker.GetOrderCount())
+	if len(orphans) == 0 {
+		return report + "\n沒有未追蹤的掛單", nil
+	}
+	for _, orphan := range orphans {
+		offer := orphan.Offer
+		report += fmt.Sprintf("\nID %d: %.2f %s @ %.4f%%, %d天", offer.ID, offer.Amount, lb.config.Currency,
+			lb.rateConverter.DecimalToPercentage(offer.Rate), offer.Period)
+		if offer.Hidden {
+			report += " (隱藏)"
+		}
+		if orphan.Signature {
+			report += " ⚠️ 疑似程式舊單"
+		}
+	}
+	return report, nil
+}
+
+// AdoptOffer 接管指定的未追蹤掛單
+func (lb *LendingBot) AdoptOffer(offerID int64) (string, error) {
+	lb.execMu.Lock()
+	defer lb.execMu.Unlock()
+
+	if lb.orderTracker.IsTrackedOrder(offerID) {
+		return "", fmt.Errorf("訂單 %d 已由程式追蹤", offerID)
+	}
+
+	offers, err := lb.client.GetFundingOffers(lb.config.GetFundingSymbol())
+	if err != nil {
+		return "", err
+	}
+	for _, offer := range offers {
+		if offer.ID != offerID {
+			continue
+		}
+		lb.adoptOffer(offer)
+		log.Printf("手動接管掛單 ID: %d", offerID)
+		return fmt.Sprintf("✅ 已接管訂單 %d (%.2f %s @ %.4f%%)，下次執行時將取消並重新掛單",
+			offerID, offer.Amount, lb.config.Currency, lb.rateConverter.DecimalToPercentage(offer.Rate)), nil
+	}
+	return "", fmt.Errorf("訂單簿上找不到訂單 %d", offerID)
+}
+
+// ReleaseOffer 停止追蹤指定的掛單，之後視為手動掛單不再取消
+func (lb *LendingBot) ReleaseOffer(offerID int64) (string, error) {
+	lb.execMu.Lock()
+	defer lb.execMu.Unlock()
+
+	info, ok := lb.orderTracker.GetOrderInfo(offerID)
+	if !ok {
+		return "", fmt.Errorf("訂單 %d 未由程式追蹤", offerID)
+	}
+	lb.orderTracker.ResolveOrder(offerID, tracker.OutcomeReleased, 0, lb.now())
+	log.Printf("釋放掛單 ID: %d", offerID)
+	return fmt.Sprintf("✅ 已釋放訂單 %d (%.2f %s @ %.4f%%)，之後不會再取消此訂單",
+		offerID, info.Amount, lb.config.Currency, lb.rateConverter.DecimalToPercentage(info.Rate)), nil
+}
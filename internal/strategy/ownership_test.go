@@ -0,0 +1,174 @@
+package strategy
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kfrico/BitfinexLendingBot/internal/bitfinex"
+	"github.com/kfrico/BitfinexLendingBot/internal/config"
+	"github.com/kfrico/BitfinexLendingBot/internal/constants"
+	"github.com/kfrico/BitfinexLendingBot/internal/simulator"
+	"github.com/kfrico/BitfinexLendingBot/internal/tracker"
+)
+
+func TestLendingBot_OrphanPolicies(t *testing.T) {
+	now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	newBot := func(policy string) (*LendingBot, []int64) {
+		cfg := &config.Config{Currency: "USD", MinLoan: 150, MaxLoan: 1000, MinDailyLendRate: 0.02, SpreadLend: 5, GapTop: 2, OrphanPolicy: policy}
+		cfg.ApplyDefaults()
+		exchange := simulator.NewExchange(&bookMarket{bestAsk: 0.0003}, simulator.Options{Currency: "USD", InitialBalance: 20000, Start: now})
+
+		// 依序為：符合程式特徵（分散單金額封頂於 MAX_LOAN）、金額非最小單位、利率低於最低日利率、超過最大貸出金額、
+		// 手動常見的 7 天期間、與目前分散單金額不同的整數金額
+		var ids []int64
+		for _, offer := range []struct {
+			amount float64
+			rate   float64
+			period int
+		}{{1000, 0.0003, 2}, {333.333, 0.0003, 2}, {1000, 0.0001, 2}, {5000, 0.0003, 2}, {1000, 0.0003, 7}, {400, 0.0003, 2}} {
+			id, err := exchange.SubmitFundingOffer(cfg.GetFundingSymbol(), offer.amount, offer.rate, offer.period, false)
+			if err != nil {
+				t.Fatalf("SubmitFundingOffer() error = %v", err)
+			}
+			ids = append(ids, id)
+		}
+
+		bot := NewLendingBot(cfg, exchange)
+		bot.SetClock(exchange.Now, func(time.Duration) {})
+		return bot, ids
+	}
+
+	tests := []struct {
+		policy  string
+		adopted []bool
+	}{
+		{constants.OrphanPolicyManual, []bool{false, false, false, false, false, false}},
+		{constants.OrphanPolicySignature, []bool{true, false, false, false, false, false}},
+		{constants.OrphanPolicyAdoptAll, []bool{true, true, true, true, true, true}},
+	}
+	for _, tt := range tests {
+		bot, ids := newBot(tt.policy)
+		bot.AdoptStartupOffers()
+		for i, id := range ids {
+			if tracked := bot.orderTracker.IsTrackedOrder(id); tracked != tt.adopted[i] {
+				t.Errorf("%s: offer %d tracked = %v, want %v", tt.policy, i, tracked, tt.adopted[i])
+			}
+		}
+	}
+
+	// 手動接管與釋放
+	bot, ids := newBot(constants.OrphanPolicyManual)
+	report, err := bot.GetOrphanReport()
+	if err != nil || strings.Count(report, "疑似程式舊單") != 1 {
+		t.Errorf("GetOrphanReport() = %q, %v, want one signature match", report, err)
+	}
+	if _, err := bot.AdoptOffer(ids[1]); err != nil || !bot.orderTracker.IsTrackedOrder(ids[1]) {
+		t.Errorf("AdoptOffer() error = %v, tracked = %v", err, bot.orderTracker.IsTrackedOrder(ids[1]))
+	}
+	if _, err := bot.AdoptOffer(ids[1]); err == nil {
+		t.Error("AdoptOffer() on a tracked offer should fail")
+	}
+	if _, err := bot.AdoptOffer(999999); err == nil {
+		t.Error("AdoptOffer() on a missing offer should fail")
+	}
+	if _, err := bot.ReleaseOffer(ids[1]); err != nil || bot.orderTracker.IsTrackedOrder(ids[1]) {
+		t.Errorf("ReleaseOffer() error = %v, tracked = %v", err, bot.orderTracker.IsTrackedOrder(ids[1]))
+	}
+	if _, err := bot.ReleaseOffer(ids[1]); err == nil {
+		t.Error("ReleaseOffer() on an untracked offer should fail")
+	}
+}
+
+func TestLendingBot_RestoreOrderTracker(t *testing.T) {
+	now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	cfg := &config.Config{
+		Currency:              "USD",
+		MinLoan:               150,
+		MinDailyLendRate:      0.02,
+		OrderTrackerStateFile: filepath.Join(t.TempDir(), "orders.json"),
+	}
+	cfg.ApplyDefaults()
+	exchange := simulator.NewExchange(&bookMarket{bestAsk: 0.0003}, simulator.Options{Currency: "USD", InitialBalance: 10000, Start: now})
+
+	var ids []int64
+	for _, period := range []int{2, 30} {
+		id, err := exchange.SubmitFundingOffer(cfg.GetFundingSymbol(), 500, 0.0003, period, false)
+		if err != nil {
+			t.Fatalf("SubmitFundingOffer() error = %v", err)
+		}
+		ids = append(ids, id)
+	}
+
+	bot := NewLendingBot(cfg, exchange)
+	bot.SetClock(exchange.Now, func(time.Duration) {})
+	bot.RestoreOrderTracker()
+	for _, id := range ids {
+		if _, err := bot.AdoptOffer(id); err != nil {
+			t.Fatalf("AdoptOffer(%d) error = %v", id, err)
+		}
+	}
+
+	// 程式停止期間第二筆訂單從訂單簿消失
+	if err := exchange.CancelFundingOffer(ids[1]); err != nil {
+		t.Fatalf("CancelFundingOffer() error = %v", err)
+	}
+
+	restarted := NewLendingBot(cfg, exchange)
+	restarted.SetClock(exchange.Now, func(time.Duration) {})
+	restarted.RestoreOrderTracker()
+
+	info, ok := restarted.orderTracker.GetOrderInfo(ids[0])
+	if !ok || info.Strategy != constants.StrategyAdopted || info.Period != 2 || info.Amount != 500 {
+		t.Errorf("restored order = %+v, %v, want adopted 500 for 2 days", info, ok)
+	}
+	if restarted.orderTracker.IsTrackedOrder(ids[1]) {
+		t.Error("offer removed while offline should no longer be tracked")
+	}
+	history := restarted.orderTracker.GetHistory()
+	if len(history) != 1 || history[0].ID != ids[1] || history[0].Outcome != tracker.OutcomeClosedOffline || history[0].Period != 30 {
+		t.Errorf("history = %+v, want one closed_offline record for offer %d", history, ids[1])
+	}
+
+	// 釋放的訂單在下一次重啟後仍保留結束原因
+	if _, err := restarted.ReleaseOffer(ids[0]); err != nil {
+		t.Fatalf("ReleaseOffer() error = %v", err)
+	}
+	reloaded := NewLendingBot(cfg, exchange)
+	reloaded.RestoreOrderTracker()
+	history = reloaded.orderTracker.GetHistory()
+	if reloaded.orderTracker.GetOrderCount() != 0 || len(history) != 2 || history[1].Outcome != tracker.OutcomeReleased {
+		t.Errorf("after reload: tracked = %d, history = %+v", reloaded.orderTracker.GetOrderCount(), history)
+	}
+}
+
+func TestLendingBot_MatchSignaturesSiblingLadder(t *testing.T) {
+	cfg := &config.Config{Currency: "USD", MinLoan: 150, MinDailyLendRate: 0.02}
+	cfg.ApplyDefaults()
+	bot := NewLendingBot(cfg, nil)
+	signature := &offerSignature{
+		periods: map[int]bool{2: true, 30: true},
+		minRate: 0.0002,
+		maxRate: 0.0004,
+		amounts: []float64{800},
+		rules:   bot.amountRules(),
+	}
+
+	// 資金變動前平均分配的分散單（金額相差一個最小單位）仍視為程式舊單，單獨一筆的整數金額則否
+	orphans := []OrphanOffer{
+		{Offer: &bitfinex.FundingOffer{ID: 1, Amount: 1333.34, Rate: 0.0003, Period: 2}},
+		{Offer: &bitfinex.FundingOffer{ID: 2, Amount: 1333.33, Rate: 0.00035, Period: 30}},
+		{Offer: &bitfinex.FundingOffer{ID: 3, Amount: 800, Rate: 0.0003, Period: 2}},
+		{Offer: &bitfinex.FundingOffer{ID: 4, Amount: 500, Rate: 0.0003, Period: 2}},
+		{Offer: &bitfinex.FundingOffer{ID: 5, Amount: 1333.33, Rate: 0.0005, Period: 2}},
+	}
+	bot.matchSignatures(orphans, signature)
+
+	want := []bool{true, true, true, false, false}
+	for i, orphan := range orphans {
+		if orphan.Signature != want[i] {
+			t.Errorf("offer %d signature = %v, want %v", orphan.Offer.ID, orphan.Signature, want[i])
+		}
+	}
+}
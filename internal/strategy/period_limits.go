@@ -0,0 +1,162 @@
+package strategy
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/kfrico/BitfinexLendingBot/internal/bitfinex"
+	"github.com/kfrico/BitfinexLendingBot/internal/config"
+	"github.com/kfrico/BitfinexLendingBot/internal/constants"
+)
+
+// PeriodExposure 單一期間門檻的曝險
+type PeriodExposure struct {
+	Limit  config.PeriodExposureLimit
+	Amount float64 // 剩餘期間超過門檻天數的借出與掛單金額
+}
+
+// PeriodExposureBook 期間曝險帳本：以活躍借貸與掛單計算各門檻已占用的資金，並限制新訂單期間
+type PeriodExposureBook struct {
+	Total     float64 // 總資金（借出 + 掛單 + 可用餘額）
+	Exposures []PeriodExposure
+}
+
+// NewPeriodExposureBook 依活躍借貸（以剩餘天數計）、訂單簿上的掛單與可用餘額建立曝險帳本
+func NewPeriodExposureBook(limits []config.PeriodExposureLimit, credits []*bitfinex.FundingCredit, offers []*bitfinex.FundingOffer, available float64, now time.Time) *PeriodExposureBook {
+	book := &PeriodExposureBook{
+		Total:     available,
+		Exposures: make([]PeriodExposure, len(limits)),
+	}
+	for i, limit := range limits {
+		book.Exposures[i].Limit = limit
+	}
+
+	dayMs := float64(24 * time.Hour / time.Millisecond)
+	nowMs := now.UnixNano() / int64(time.Millisecond)
+	for _, credit := range credits {
+		if credit == nil || credit.Amount <= 0 {
+			continue
+		}
+		maturityMs := credit.MTSOpened + credit.Period*int64(dayMs)
+		book.Total += credit.Amount
+		book.add(float64(maturityMs-nowMs)/dayMs, credit.Amount)
+	}
+	for _, offer := range offers {
+		if offer == nil || offer.Amount <= 0 {
+			continue
+		}
+		book.Total += offer.Amount
+		book.add(float64(offer.Period), offer.Amount)
+	}
+
+	return book
+}
+
+// add 將金額計入剩餘期間超過的所有門檻
+func (b *PeriodExposureBook) add(days float64, amount float64) {
+	for i := range b.Exposures {
+		if days > float64(b.Exposures[i].Limit.AboveDays) {
+			b.Exposures[i].Amount += amount
+		}
+	}
+}
+
+// capacity 返回門檻的資金上限
+func (b *PeriodExposureBook) capacity(exposure PeriodExposure) float64 {
+	return b.Total * exposure.Limit.MaxPercent / 100
+}
+
+// Clamp 返回新訂單可使用的最長期間並計入帳本
+// 由短到長檢查門檻，加入訂單後超過上限時，期間縮短至該門檻天數（整筆縮短，不拆單）
+func (b *PeriodExposureBook) Clamp(period int, amount float64) int {
+	for _, exposure := range b.Exposures {
+		if period > exposure.Limit.AboveDays && exposure.Amount+amount > b.capacity(exposure)+1e-9 {
+			period = exposure.Limit.AboveDays
+			break
+		}
+	}
+	b.add(float64(period), amount)
+	return period
+}
+
+// Report 產生各門檻的曝險報告
+func (b *PeriodExposureBook) Report(currency string) string {
+	report := fmt.Sprintf("總資金: %.2f %s", b.Total, currency)
+	for _, exposure := range b.Exposures {
+		share := 0.0
+		if b.Total > 0 {
+			share = exposure.Amount / b.Total * 100
+		}
+		status := ""
+		if exposure.Amount >= b.capacity(exposure)-1e-9 {
+			status = " ⚠️ 已滿"
+		}
+		report += fmt.Sprintf("\n>%d天: %.2f (%.1f%% / 上限 %.0f%%)%s",
+			exposure.Limit.AboveDays, exposure.Amount, share, exposure.Limit.MaxPercent, status)
+	}
+	return report
+}
+
+// offerPeriod 訂單實際掛出的期間（FRR 單未受限制時固定掛 120 天）
+func offerPeriod(offer *LoanOffer) int {
+	if offer.UseFRR && !offer.PeriodCapped {
+		return constants.Period120Days
+	}
+	return offer.Period
+}
+
+// buildPeriodExposureBook 查詢活躍借貸、掛單與可用餘額建立期間曝險帳本
+func (lb *LendingBot) buildPeriodExposureBook() (*PeriodExposureBook, error) {
+	fundingSymbol := lb.config.GetFundingSymbol()
+	credits, err := lb.client.GetFundingCredits(fundingSymbol)
+	if err != nil {
+		return nil, err
+	}
+	offers, err := lb.client.GetFundingOffers(fundingSymbol)
+	if err != nil {
+		return nil, err
+	}
+	available, err := lb.getAvailableFunds()
+	if err != nil {
+		return nil, err
+	}
+	return NewPeriodExposureBook(lb.config.GetPeriodExposureLimits(), credits, offers, available, lb.now()), nil
+}
+
+// applyPeriodLimits 依期間曝險上限縮短新訂單期間，返回被縮短的訂單數
+// 無法取得帳戶資料時保守處理，所有訂單期間限制在最短的門檻內
+func (lb *LendingBot) applyPeriodLimits(loanOffers []*LoanOffer) int {
+	limits := lb.config.GetPeriodExposureLimits()
+	if len(limits) == 0 {
+		return 0
+	}
+
+	book, err := lb.buildPeriodExposureBook()
+	if err != nil {
+		log.Printf("無法計算期間曝險，本次訂單期間限制在 %d 天內: %v", limits[0].AboveDays, err)
+		book = &PeriodExposureBook{Exposures: []PeriodExposure{{Limit: config.PeriodExposureLimit{AboveDays: limits[0].AboveDays}}}}
+	}
+
+	clamped := 0
+	for _, offer := range loanOffers {
+		period := offerPeriod(offer)
+		if allowed := book.Clamp(period, offer.Amount); allowed < period {
+			log.Printf("期間曝險已滿，訂單 %.2f 期間由 %d 天縮短為 %d 天", offer.Amount, period, allowed)
+			offer.Period = allowed
+			offer.PeriodCapped = true
+			clamped++
+		}
+	}
+
+	return clamped
+}
+
+// GetPeriodExposureReport 獲取期間曝險報告（供 Telegram 指令使用）
+func (lb *LendingBot) GetPeriodExposureReport() (string, error) {
+	book, err := lb.buildPeriodExposureBook()
+	if err != nil {
+		return "", err
+	}
+	return book.Report(lb.config.Currency), nil
+}
@@ -0,0 +1,62 @@
+package strategy
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kfrico/BitfinexLendingBot/internal/bitfinex"
+	"github.com/kfrico/BitfinexLendingBot/internal/config"
+	"github.com/kfrico/BitfinexLendingBot/internal/constants"
+)
+
+func TestPeriodExposureBook(t *testing.T) {
+	now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	daysAgo := func(days int) int64 {
+		return now.AddDate(0, 0, -days).UnixNano() / int64(time.Millisecond)
+	}
+
+	limits := []config.PeriodExposureLimit{{AboveDays: 30, MaxPercent: 40}, {AboveDays: 60, MaxPercent: 20}}
+	credits := []*bitfinex.FundingCredit{
+		{Amount: 2000, Period: 120, MTSOpened: daysAgo(20)},  // 剩餘 100 天
+		{Amount: 1000, Period: 120, MTSOpened: daysAgo(110)}, // 剩餘 10 天
+	}
+	offers := []*bitfinex.FundingOffer{{Amount: 500, Period: 2}}
+
+	book := NewPeriodExposureBook(limits, credits, offers, 6500, now)
+	if book.Total != 10000 || book.Exposures[0].Amount != 2000 || book.Exposures[1].Amount != 2000 {
+		t.Fatalf("book = %+v, want total 10000 with 2000 above 30 and 60 days", book)
+	}
+
+	// >60 天已滿（2000 / 上限 2000），縮短為 60 天
+	if period := book.Clamp(120, 1000); period != 60 {
+		t.Errorf("Clamp() with full 60-day bucket = %d, want 60", period)
+	}
+	// >30 天已有 3000，再加 1500 超過上限 4000，縮短為 30 天
+	if period := book.Clamp(120, 1500); period != 30 {
+		t.Errorf("Clamp() with full 30-day bucket = %d, want 30", period)
+	}
+	if period := book.Clamp(2, 1000); period != 2 {
+		t.Errorf("Clamp() short offer = %d, want 2", period)
+	}
+	if math.Abs(book.Exposures[0].Amount-3000) > floatTolerance {
+		t.Errorf(">30 exposure = %v, want 3000", book.Exposures[0].Amount)
+	}
+
+	report := book.Report("USD")
+	if !strings.Contains(report, ">30天: 3000.00 (30.0% / 上限 40%)") || !strings.Contains(report, ">60天: 2000.00 (20.0% / 上限 20%) ⚠️ 已滿") {
+		t.Errorf("Report() = %s", report)
+	}
+}
+
+func TestOfferPeriod(t *testing.T) {
+	frr := &LoanOffer{Period: 2, UseFRR: true}
+	if got := offerPeriod(frr); got != constants.Period120Days {
+		t.Errorf("offerPeriod(FRR) = %d, want 120", got)
+	}
+	frr.Period, frr.PeriodCapped = 30, true
+	if got := offerPeriod(frr); got != 30 {
+		t.Errorf("offerPeriod(capped FRR) = %d, want 30", got)
+	}
+}
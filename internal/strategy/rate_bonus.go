@@ -0,0 +1,260 @@
+package strategy
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/kfrico/BitfinexLendingBot/internal/config"
+	"github.com/kfrico/BitfinexLendingBot/internal/constants"
+)
+
+// RateBonusRecord 單次加成調整記錄
+type RateBonusRecord struct {
+	Time      time.Time `json:"time"`
+	Placed    float64   `json:"placed"`     // 本週期結算的掛單金額（成交 + 取消）
+	Filled    float64   `json:"filled"`     // 其中成交的金額
+	FillRatio float64   `json:"fill_ratio"` // 本週期成交比例 (0-1)
+	Smoothed  float64   `json:"smoothed"`   // EMA 平滑後的成交比例 (0-1)
+	Offset    float64   `json:"offset"`     // 調整後的利率加成（%）
+}
+
+// rateBonusState 保存到檔案的控制器狀態
+type rateBonusState struct {
+	Offset   float64           `json:"offset"`
+	Smoothed float64           `json:"smoothed"`
+	Samples  int               `json:"samples"`
+	History  []RateBonusRecord `json:"history"`
+}
+
+// RateBonusController 自適應利率加成控制器
+// 每個週期以程式掛單的成交比例回饋調整加成：成交比例高於目標時提高利率，低於目標時降低利率
+type RateBonusController struct {
+	mu       sync.Mutex
+	offset   float64 // 目前加成（%）
+	smoothed float64 // 平滑後成交比例
+	samples  int
+	history  []RateBonusRecord
+}
+
+// NewRateBonusController 創建自適應利率加成控制器，initial 為起始加成（%）
+func NewRateBonusController(initial float64) *RateBonusController {
+	return &RateBonusController{offset: initial}
+}
+
+// Observe 記錄一個週期的掛單與成交金額並調整加成
+func (c *RateBonusController) Observe(now time.Time, placed, filled float64, cfg *config.Config) RateBonusRecord {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ratio := 0.0
+	if placed > 0 {
+		ratio = math.Min(math.Max(filled/placed, 0), 1)
+	}
+	if c.samples == 0 {
+		c.smoothed = ratio
+	} else {
+		c.smoothed = cfg.RateBonusSmoothing*ratio + (1-cfg.RateBonusSmoothing)*c.smoothed
+	}
+	c.samples++
+
+	offset := c.offset + cfg.RateBonusGain*(c.smoothed-cfg.RateBonusTargetFillPercent/100)
+	c.offset = math.Min(math.Max(offset, cfg.RateBonusMin), cfg.RateBonusMax)
+
+	record := RateBonusRecord{
+		Time:      now,
+		Placed:    placed,
+		Filled:    filled,
+		FillRatio: ratio,
+		Smoothed:  c.smoothed,
+		Offset:    c.offset,
+	}
+	c.history = append(c.history, record)
+	if len(c.history) > constants.RateBonusHistorySize {
+		c.history = c.history[len(c.history)-constants.RateBonusHistorySize:]
+	}
+	return record
+}
+
+// Offset 返回目前加成（%）
+func (c *RateBonusController) Offset() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.offset
+}
+
+// History 返回加成調整記錄的副本（由舊到新）
+func (c *RateBonusController) History() []RateBonusRecord {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	history := make([]RateBonusRecord, len(c.history))
+	copy(history, c.history)
+	return history
+}
+
+// Report 產生目前加成與最近 limit 筆調整記錄的報告
+func (c *RateBonusController) Report(cfg *config.Config, limit int) string {
+	history := c.History()
+	report := fmt.Sprintf("目前加成: %.4f%% (範圍 %.4f%% ~ %.4f%%)\n目標成交比例: %.0f%%",
+		c.Offset(), cfg.RateBonusMin, cfg.RateBonusMax, cfg.RateBonusTargetFillPercent)
+	if len(history) == 0 {
+		return report + "\n尚無成交記錄"
+	}
+
+	if len(history) > limit {
+		history = history[len(history)-limit:]
+	}
+	for i := len(history) - 1; i >= 0; i-- {
+		record := history[i]
+		report += fmt.Sprintf("\n%s 成交 %.2f/%.2f (%.0f%%, 平滑 %.0f%%) → %.4f%%",
+			record.Time.Format("01-02 15:04"), record.Filled, record.Placed,
+			record.FillRatio*100, record.Smoothed*100, record.Offset)
+	}
+	return report
+}
+
+// Save 將控制器狀態寫入 JSON 檔案（先寫暫存檔再改名）
+func (c *RateBonusController) Save(path string) error {
+	c.mu.Lock()
+	state := rateBonusState{Offset: c.offset, Smoothed: c.smoothed, Samples: c.samples, History: c.history}
+	data, err := json.Marshal(state)
+	c.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("序列化利率加成記錄失敗: %w", err)
+	}
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("建立目錄失敗: %w", err)
+		}
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o644); err != nil {
+		return fmt.Errorf("寫入利率加成記錄失敗: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("保存利率加成記錄失敗: %w", err)
+	}
+	return nil
+}
+
+// Load 從 JSON 檔案載入控制器狀態，檔案不存在時不視為錯誤。返回是否有載入
+func (c *RateBonusController) Load(path string) (bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("讀取利率加成記錄失敗: %w", err)
+	}
+
+	var state rateBonusState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return false, fmt.Errorf("解析利率加成記錄失敗: %w", err)
+	}
+	if len(state.History) > constants.RateBonusHistorySize {
+		state.History = state.History[len(state.History)-constants.RateBonusHistorySize:]
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.offset = state.Offset
+	c.smoothed = state.Smoothed
+	c.samples = state.Samples
+	c.history = state.History
+	return true, nil
+}
+
+// fillCycle 本週期已結算的程式掛單金額
+// 成交金額來自交易所資料：程式取消的掛單以取消前訂單簿上的剩餘金額推算，已離開訂單簿的掛單以對應的活躍借貸確認；
+// 離開訂單簿但沒有對應借貸的掛單（手動或交易所取消、成交後立即還款）無法確認結果，不納入成交比例
+type fillCycle struct {
+	placed float64
+	filled float64
+}
+
+// record 記錄一筆結算的掛單（飆升掛單有獨立的利率邏輯、接管的掛單並非依目前加成掛出，皆不納入）
+func (f *fillCycle) record(strategy string, amount, filled float64) {
+	if strategy == constants.StrategySpike || strategy == constants.StrategyAdopted || amount <= 0 {
+		return
+	}
+	f.placed += amount
+	f.filled += math.Min(math.Max(filled, 0), amount)
+}
+
+// observeRateBonus 以本週期的成交比例調整自適應利率加成
+func (lb *LendingBot) observeRateBonus(cycle fillCycle) {
+	if !lb.config.EnableAdaptiveRateBonus || cycle.placed <= 0 {
+		return
+	}
+
+	record := lb.rateBonus.Observe(lb.now(), cycle.placed, cycle.filled, lb.config)
+	log.Printf("🎯 成交比例 %.1f%% (平滑 %.1f%%)，利率加成調整為 %.4f%%",
+		record.FillRatio*100, record.Smoothed*100, record.Offset)
+
+	if lb.rateBonusPath != "" {
+		if err := lb.rateBonus.Save(lb.rateBonusPath); err != nil {
+			log.Printf("保存利率加成記錄失敗: %v", err)
+		}
+	}
+}
+
+// rateBonusDecimal 返回本次下單的利率加成（小數格式）
+// 自適應模式使用控制器的加成；否則沿用 RATE_BONUS，僅在沒有取消舊訂單時加上
+func (lb *LendingBot) rateBonusDecimal(hasPendingOrders bool) float64 {
+	if lb.config.EnableAdaptiveRateBonus {
+		// 載入的記錄可能超出目前配置的範圍
+		offset := math.Min(math.Max(lb.rateBonus.Offset(), lb.config.RateBonusMin), lb.config.RateBonusMax)
+		return lb.rateConverter.PercentageToDecimal(offset)
+	}
+	if hasPendingOrders {
+		return 0
+	}
+	return lb.rateConverter.PercentageToDecimal(lb.config.RateBonus)
+}
+
+// applyRateBonus 為一般利率單加上利率加成（FRR 單沒有固定利率，不加成）
+func (lb *LendingBot) applyRateBonus(loanOffers []*LoanOffer, hasPendingOrders bool) {
+	bonus := lb.rateBonusDecimal(hasPendingOrders)
+	if bonus == 0 {
+		return
+	}
+	for _, offer := range loanOffers {
+		if offer.UseFRR {
+			continue
+		}
+		rate := offer.Rate + bonus
+		if lb.config.EnableAdaptiveRateBonus {
+			// 自適應加成為負時不低於最低日利率
+			if floor := math.Min(offer.Rate, lb.ladderFloor()); rate < floor {
+				rate = floor
+			}
+		}
+		offer.Rate = rate
+	}
+}
+
+// RestoreRateBonus 啟動時載入上次保存的自適應利率加成，之後每次調整都寫回檔案（回測與模擬交易不保存）
+func (lb *LendingBot) RestoreRateBonus() {
+	if !lb.config.EnableAdaptiveRateBonus {
+		return
+	}
+
+	path := lb.config.GetRateBonusStatePath()
+	if loaded, err := lb.rateBonus.Load(path); err != nil {
+		log.Printf("載入利率加成記錄失敗，從 RATE_BONUS 開始: %v", err)
+	} else if loaded {
+		log.Printf("已載入利率加成記錄，目前加成 %.4f%%: %s", lb.rateBonus.Offset(), path)
+	}
+	lb.rateBonusPath = path
+}
+
+// GetRateBonusReport 獲取自適應利率加成報告（供 Telegram 指令使用）
+func (lb *LendingBot) GetRateBonusReport() string {
+	return lb.rateBonus.Report(lb.config, 5)
+}
@@ -0,0 +1,77 @@
+package strategy
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kfrico/BitfinexLendingBot/internal/config"
+	"github.com/kfrico/BitfinexLendingBot/internal/constants"
+)
+
+func TestRateBonusController_Observe(t *testing.T) {
+	cfg := &config.Config{EnableAdaptiveRateBonus: true, RateBonusMin: -0.005, RateBonusMax: 0.01}
+	cfg.ApplyDefaults()
+	now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	controller := NewRateBonusController(0.002)
+
+	// 全部成交：平滑比例 1，加成提高 0.002 × (1 − 0.7)
+	record := controller.Observe(now, 1000, 1000, cfg)
+	if math.Abs(record.Offset-0.0026) > floatTolerance || record.Smoothed != 1 {
+		t.Errorf("Observe() all filled = %+v, want offset 0.0026 and smoothed 1", record)
+	}
+
+	// 全部未成交：平滑比例 0.7，剛好等於目標，加成不變
+	record = controller.Observe(now.Add(time.Hour), 1000, 0, cfg)
+	if math.Abs(record.Smoothed-0.7) > floatTolerance || math.Abs(record.Offset-0.0026) > floatTolerance {
+		t.Errorf("Observe() none filled = %+v, want smoothed 0.7 and offset unchanged", record)
+	}
+
+	// 持續未成交時加成不低於下限
+	for i := 0; i < 200; i++ {
+		controller.Observe(now.Add(time.Duration(i+2)*time.Hour), 1000, 0, cfg)
+	}
+	if offset := controller.Offset(); offset != cfg.RateBonusMin {
+		t.Errorf("Offset() after repeated misses = %v, want %v", offset, cfg.RateBonusMin)
+	}
+	if history := controller.History(); len(history) != constants.RateBonusHistorySize {
+		t.Errorf("History() length = %d, want %d", len(history), constants.RateBonusHistorySize)
+	}
+}
+
+func TestRateBonusController_SaveLoad(t *testing.T) {
+	cfg := &config.Config{EnableAdaptiveRateBonus: true}
+	cfg.ApplyDefaults()
+	path := filepath.Join(t.TempDir(), "rate_bonus_fUSD.json")
+
+	controller := NewRateBonusController(0)
+	controller.Observe(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), 800, 600, cfg)
+	if err := controller.Save(path); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	restored := NewRateBonusController(0.005)
+	if loaded, err := restored.Load(path); err != nil || !loaded {
+		t.Fatalf("Load() = %v, %v", loaded, err)
+	}
+	if restored.Offset() != controller.Offset() || len(restored.History()) != 1 {
+		t.Errorf("restored offset %v with %d records, want %v with 1", restored.Offset(), len(restored.History()), controller.Offset())
+	}
+
+	if loaded, err := NewRateBonusController(0).Load(filepath.Join(t.TempDir(), "missing.json")); err != nil || loaded {
+		t.Errorf("Load() missing file = %v, %v, want false without error", loaded, err)
+	}
+}
+
+func TestFillCycle_SkipsSpikeOffers(t *testing.T) {
+	var cycle fillCycle
+	cycle.record(constants.StrategyTraditional, 500, 500)
+	cycle.record(constants.StrategySmart, 500, 200)
+	cycle.record(constants.StrategySpike, 1000, 1000)
+
+	if cycle.placed != 1000 || cycle.filled != 700 {
+		t.Errorf("fillCycle = %+v, want placed 1000 and filled 700", cycle)
+	}
+}
@@ -0,0 +1,389 @@
+package strategy
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kfrico/BitfinexLendingBot/internal/bitfinex"
+	"github.com/kfrico/BitfinexLendingBot/internal/config"
+	"github.com/kfrico/BitfinexLendingBot/internal/constants"
+)
+
+// RefinanceCandidate 值得提前關閉並以市場利率重新貸出的借貸
+type RefinanceCandidate struct {
+	Credit        *bitfinex.FundingCredit
+	CurrentRate   float64 // 原借貸日利率
+	MarketRate    float64 // 預估可重新貸出的日利率
+	RemainingDays float64 // 原借貸剩餘天數
+	KeepInterest  float64 // 持有至到期的預估淨利息
+	RefiInterest  float64 // 關閉後重新貸出至原到期日的預估淨利息
+	Gain          float64 // 預估淨利息增加
+	ProposedAt    time.Time
+}
+
+// Refinancer 比較活躍借貸與市場利率，並以頻率上限保護提前關閉
+type Refinancer struct {
+	config *config.Config
+
+	mu        sync.Mutex
+	closes    []time.Time                   // 近 24 小時的關閉時間
+	proposals map[int64]*RefinanceCandidate // 等待 Telegram 確認的提議
+}
+
+// NewRefinancer 創建再融資模組
+func NewRefinancer(cfg *config.Config) *Refinancer {
+	return &Refinancer{
+		config:    cfg,
+		proposals: make(map[int64]*RefinanceCandidate),
+	}
+}
+
+// Evaluate 找出關閉後重新貸出明顯較佳的借貸，依預估增益由高到低排序
+//
+// 持有：原利率 × (剩餘天數 + 自上次每日結息後已累計、關閉時會損失的部分)
+// 關閉：市場利率 × (剩餘天數 - 預估重新貸出前的閒置時間)
+// 兩者皆扣除利息手續費；FRR 借貸為浮動利率，不列入比較
+func (r *Refinancer) Evaluate(credits []*bitfinex.FundingCredit, marketRate float64, now time.Time) []*RefinanceCandidate {
+	if marketRate <= 0 {
+		return nil
+	}
+
+	netFactor := 1 - r.config.GetFeeModel().Rate
+	refillDays := r.config.RefinanceRefillHours / 24
+	minRate := 1 + r.config.RefinanceMinImprovementPercent/100
+
+	var candidates []*RefinanceCandidate
+	for _, credit := range credits {
+		if credit == nil || credit.Period <= 0 || strings.EqualFold(credit.RateType, "frr") {
+			continue
+		}
+
+		rate := credit.EffectiveDailyRate()
+		if rate <= 0 || marketRate < rate*minRate {
+			continue
+		}
+
+		opened := time.Unix(0, credit.MTSOpened*int64(time.Millisecond))
+		maturity := opened.Add(time.Duration(credit.Period) * 24 * time.Hour)
+		remaining := maturity.Sub(now).Hours() / 24
+		if remaining < r.config.RefinanceMinRemainingDays {
+			continue
+		}
+
+		elapsed := now.Sub(opened).Hours() / 24
+		accrued := elapsed - math.Floor(elapsed)
+
+		keep := credit.Amount * rate * (remaining + accrued) * netFactor
+		refi := credit.Amount * marketRate * math.Max(0, remaining-refillDays) * netFactor
+		gain := refi - keep
+		if gain <= 0 || gain < r.config.RefinanceMinGain {
+			continue
+		}
+
+		candidates = append(candidates, &RefinanceCandidate{
+			Credit:        credit,
+			CurrentRate:   rate,
+			MarketRate:    marketRate,
+			RemainingDays: remaining,
+			KeepInterest:  keep,
+			RefiInterest:  refi,
+			Gain:          gain,
+			ProposedAt:    now,
+		})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Gain > candidates[j].Gain
+	})
+
+	return candidates
+}
+
+// Allowance 返回目前還可以關閉的筆數（受最短間隔與每日上限限制）
+func (r *Refinancer) Allowance(now time.Time) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.pruneLocked(now)
+	if len(r.closes) > 0 {
+		interval := time.Duration(r.config.RefinanceMinIntervalMinutes) * time.Minute
+		if now.Sub(r.closes[len(r.closes)-1]) < interval {
+			return 0
+		}
+	}
+
+	remaining := r.config.RefinanceMaxClosesPerDay - len(r.closes)
+	if remaining < 0 {
+		return 0
+	}
+	// 同一次執行中連續關閉多筆仍受最短間隔限制
+	if r.config.RefinanceMinIntervalMinutes > 0 && remaining > 1 {
+		return 1
+	}
+	return remaining
+}
+
+// RecordClose 記錄一次關閉
+func (r *Refinancer) RecordClose(now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closes = append(r.closes, now)
+	r.pruneLocked(now)
+}
+
+// ClosesInLastDay 返回近 24 小時的關閉筆數
+func (r *Refinancer) ClosesInLastDay(now time.Time) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pruneLocked(now)
+	return len(r.closes)
+}
+
+// pruneLocked 移除超過 24 小時的關閉記錄（需持有鎖）
+func (r *Refinancer) pruneLocked(now time.Time) {
+	kept := r.closes[:0]
+	for _, t := range r.closes {
+		if now.Sub(t) < 24*time.Hour {
+			kept = append(kept, t)
+		}
+	}
+	r.closes = kept
+}
+
+// UpdateProposals 以最新評估結果取代提議，返回先前未提議過的借貸
+func (r *Refinancer) UpdateProposals(candidates []*RefinanceCandidate) []*RefinanceCandidate {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var fresh []*RefinanceCandidate
+	next := make(map[int64]*RefinanceCandidate, len(candidates))
+	for _, candidate := range candidates {
+		if previous, ok := r.proposals[candidate.Credit.ID]; ok {
+			candidate.ProposedAt = previous.ProposedAt
+		} else {
+			fresh = append(fresh, candidate)
+		}
+		next[candidate.Credit.ID] = candidate
+	}
+	r.proposals = next
+
+	return fresh
+}
+
+// TakeProposal 取出仍在有效時間內的提議
+func (r *Refinancer) TakeProposal(creditID int64, now time.Time) (*RefinanceCandidate, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	candidate, ok := r.proposals[creditID]
+	if !ok || now.Sub(candidate.ProposedAt) > constants.RefinanceProposalTTL {
+		return nil, false
+	}
+	delete(r.proposals, creditID)
+	return candidate, true
+}
+
+// Proposals 返回仍有效的提議，依增益由高到低排序
+func (r *Refinancer) Proposals(now time.Time) []*RefinanceCandidate {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var proposals []*RefinanceCandidate
+	for _, candidate := range r.proposals {
+		if now.Sub(candidate.ProposedAt) <= constants.RefinanceProposalTTL {
+			proposals = append(proposals, candidate)
+		}
+	}
+	sort.SliceStable(proposals, func(i, j int) bool {
+		return proposals[i].Gain > proposals[j].Gain
+	})
+	return proposals
+}
+
+// describe 返回提議的單行說明
+func (c *RefinanceCandidate) describe(currency string) string {
+	return fmt.Sprintf("ID %d: %.2f %s, %.4f%% → %.4f%%, 剩餘 %.1f 天, 預估多賺 %.4f %s",
+		c.Credit.ID, c.Credit.Amount, currency, c.CurrentRate*100, c.MarketRate*100, c.RemainingDays, c.Gain, currency)
+}
+
+// refinanceMarketRate 預估可重新貸出的利率：取最佳 ask 與最近1小時K線收盤的較低者，
+// 避免瞬間的訂單簿跳動造成誤判
+func (lb *LendingBot) refinanceMarketRate() (float64, error) {
+	fundingSymbol := lb.config.GetFundingSymbol()
+
+	fundingBook, err := lb.client.GetFundingBook(fundingSymbol, constants.SpikeBookDepth)
+	if err != nil {
+		return 0, err
+	}
+	rate := bestAskRate(fundingBook)
+	if rate <= 0 {
+		return 0, fmt.Errorf("訂單簿沒有 ask 掛單")
+	}
+
+	candles, err := lb.client.GetFundingCandles(fundingSymbol, constants.RefinanceCandleTimeFrame, 1)
+	if err != nil {
+		return 0, err
+	}
+	if len(candles) == 0 || candles[0].Close <= 0 {
+		return 0, fmt.Errorf("沒有最近的K線數據")
+	}
+
+	return math.Min(rate, candles[0].Close), nil
+}
+
+// runRefinancing 評估活躍借貸；auto 模式直接關閉，confirm 模式發送提議
+func (lb *LendingBot) runRefinancing() {
+	credits, err := lb.client.GetFundingCredits(lb.config.GetFundingSymbol())
+	if err != nil {
+		log.Printf("再融資：取得借貸訂單失敗: %v", err)
+		return
+	}
+	if len(credits) == 0 {
+		return
+	}
+
+	marketRate, err := lb.refinanceMarketRate()
+	if err != nil {
+		log.Printf("再融資：取得市場利率失敗: %v", err)
+		return
+	}
+
+	now := lb.now()
+	candidates := lb.refinancer.Evaluate(credits, marketRate, now)
+	if len(candidates) == 0 {
+		lb.refinancer.UpdateProposals(nil)
+		return
+	}
+
+	if lb.config.RefinanceMode == constants.RefinanceModeConfirm {
+		fresh := lb.refinancer.UpdateProposals(candidates)
+		if len(fresh) == 0 {
+			return
+		}
+
+		message := fmt.Sprintf("💱 再融資提議（市場利率 %.4f%%）", marketRate*100)
+		for _, candidate := range fresh {
+			message += "\n" + candidate.describe(lb.config.Currency)
+		}
+		message += fmt.Sprintf("\n\n使用 /refinance <ID> 確認關閉（%d 分鐘內有效）", int(constants.RefinanceProposalTTL.Minutes()))
+		lb.notify(message)
+		return
+	}
+
+	allowance := lb.refinancer.Allowance(now)
+	if allowance == 0 {
+		log.Printf("再融資：有 %d 筆借貸值得關閉，但已達頻率上限", len(candidates))
+		return
+	}
+
+	for _, candidate := range candidates {
+		if allowance == 0 {
+			break
+		}
+		if result, err := lb.closeCredit(candidate); err != nil {
+			log.Printf("再融資：關閉借貸 %d 失敗: %v", candidate.Credit.ID, err)
+		} else {
+			lb.notify(result)
+			allowance--
+		}
+	}
+}
+
+// closeCredit 關閉借貸並記錄
+func (lb *LendingBot) closeCredit(candidate *RefinanceCandidate) (string, error) {
+	description := candidate.describe(lb.config.Currency)
+
+	if lb.config.IsDryRun() {
+		log.Printf("🧪 [測試模式] 模擬關閉借貸 => %s", description)
+		return "🧪 [測試模式] 模擬再融資關閉\n" + description, nil
+	}
+
+	if err := lb.client.CloseFundingCredit(candidate.Credit.ID); err != nil {
+		return "", err
+	}
+	lb.refinancer.RecordClose(lb.now())
+
+	log.Printf("💱 已關閉借貸以再融資 => %s", description)
+	return "💱 已關閉借貸，本金將以市場利率重新貸出\n" + description, nil
+}
+
+// ConfirmRefinance 確認 Telegram 提議並關閉借貸
+func (lb *LendingBot) ConfirmRefinance(creditID int64) (string, error) {
+	now := lb.now()
+	if lb.refinancer.Allowance(now) == 0 {
+		return "", fmt.Errorf("已達再融資頻率上限（近24小時 %d 筆，最短間隔 %d 分鐘）",
+			lb.refinancer.ClosesInLastDay(now), lb.config.RefinanceMinIntervalMinutes)
+	}
+
+	if _, ok := lb.refinancer.TakeProposal(creditID, now); !ok {
+		return "", fmt.Errorf("找不到借貸 %d 的有效提議（可能已過期或不再划算）", creditID)
+	}
+
+	// 提議可能是數十分鐘前產生的，關閉前以目前的借貸與市場利率重新評估
+	candidate, err := lb.reevaluateRefinance(creditID, now)
+	if err != nil {
+		return "", err
+	}
+
+	return lb.closeCredit(candidate)
+}
+
+// reevaluateRefinance 以目前的借貸與市場利率重新評估單筆借貸，已不划算時返回錯誤
+func (lb *LendingBot) reevaluateRefinance(creditID int64, now time.Time) (*RefinanceCandidate, error) {
+	credits, err := lb.client.GetFundingCredits(lb.config.GetFundingSymbol())
+	if err != nil {
+		return nil, fmt.Errorf("取得借貸訂單失敗: %w", err)
+	}
+	var credit *bitfinex.FundingCredit
+	for _, c := range credits {
+		if c != nil && c.ID == creditID {
+			credit = c
+			break
+		}
+	}
+	if credit == nil {
+		return nil, fmt.Errorf("借貸 %d 已不存在（可能已到期或被關閉）", creditID)
+	}
+
+	marketRate, err := lb.refinanceMarketRate()
+	if err != nil {
+		return nil, fmt.Errorf("取得市場利率失敗: %w", err)
+	}
+
+	candidates := lb.refinancer.Evaluate([]*bitfinex.FundingCredit{credit}, marketRate, now)
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("目前市場利率 %.4f%% 下關閉借貸 %d 已不划算，取消再融資", marketRate*100, creditID)
+	}
+	return candidates[0], nil
+}
+
+// GetRefinanceStatus 獲取再融資狀態
+func (lb *LendingBot) GetRefinanceStatus() string {
+	now := lb.now()
+	status := fmt.Sprintf("模式: %s, 近24小時已關閉: %d/%d 筆",
+		lb.config.RefinanceMode, lb.refinancer.ClosesInLastDay(now), lb.config.RefinanceMaxClosesPerDay)
+
+	proposals := lb.refinancer.Proposals(now)
+	if len(proposals) == 0 {
+		return status + "\n目前沒有待確認的提議"
+	}
+	for _, candidate := range proposals {
+		status += "\n" + candidate.describe(lb.config.Currency)
+	}
+	return status
+}
+
+// notify 發送 Telegram 通知（未設置回調時只記錄日誌）
+func (lb *LendingBot) notify(message string) {
+	if lb.notifyCallback == nil {
+		return
+	}
+	if err := lb.notifyCallback(message); err != nil {
+		log.Printf("發送 Telegram 通知失敗: %v", err)
+	}
+}
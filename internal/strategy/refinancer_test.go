@@ -0,0 +1,184 @@
+package strategy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kfrico/BitfinexLendingBot/internal/bitfinex"
+	"github.com/kfrico/BitfinexLendingBot/internal/config"
+	"github.com/kfrico/BitfinexLendingBot/internal/simulator"
+)
+
+func newRefinanceTestConfig() *config.Config {
+	cfg := &config.Config{
+		Currency:                       "USD",
+		MinLoan:                        150,
+		MinDailyLendRate:               0.01,
+		SpreadLend:                     2,
+		GapTop:                         1,
+		EnableRefinancing:              true,
+		RefinanceMode:                  "auto",
+		RefinanceMinImprovementPercent: 50,
+		RefinanceMinRemainingDays:      2,
+		RefinanceRefillHours:           1,
+		RefinanceMaxClosesPerDay:       2,
+		RefinanceMinIntervalMinutes:    60,
+	}
+	cfg.ApplyDefaults()
+	return cfg
+}
+
+func testCredit(id int64, rate float64, period int64, opened time.Time) *bitfinex.FundingCredit {
+	return &bitfinex.FundingCredit{
+		ID:        id,
+		Amount:    1000,
+		RateType:  "FIXED",
+		Rate:      rate,
+		Period:    period,
+		MTSOpened: opened.UnixNano() / int64(time.Millisecond),
+	}
+}
+
+func TestRefinancer_Evaluate(t *testing.T) {
+	refinancer := NewRefinancer(newRefinanceTestConfig())
+	now := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+	opened := now.Add(-36 * time.Hour)
+
+	credits := []*bitfinex.FundingCredit{
+		testCredit(1, 0.0002, 30, opened), // 利率低、剩餘期間長：值得關閉
+		testCredit(2, 0.0004, 30, opened), // 改善幅度不足 50%
+		testCredit(3, 0.0002, 2, opened),  // 剩餘不到 2 天
+		{ID: 4, Amount: 1000, RateType: "FRR", RateReal: 0.0001, Period: 30, MTSOpened: opened.UnixNano() / int64(time.Millisecond)}, // FRR 不比較
+		testCredit(5, 0.0001, 30, opened), // 利率更低：增益最高
+	}
+
+	candidates := refinancer.Evaluate(credits, 0.0005, now)
+	if len(candidates) != 2 {
+		t.Fatalf("Evaluate() returned %d candidates, want 2", len(candidates))
+	}
+	if candidates[0].Credit.ID != 5 || candidates[1].Credit.ID != 1 {
+		t.Errorf("candidates order = [%d %d], want [5 1]", candidates[0].Credit.ID, candidates[1].Credit.ID)
+	}
+
+	c := candidates[1]
+	if c.RemainingDays < 28.4 || c.RemainingDays > 28.6 {
+		t.Errorf("RemainingDays = %v, want 28.5", c.RemainingDays)
+	}
+	// 持有：1000 × 0.0002 × (28.5 + 0.5) × 0.85；關閉：1000 × 0.0005 × (28.5 - 1/24) × 0.85
+	wantKeep := 1000 * 0.0002 * 29 * 0.85
+	wantRefi := 1000 * 0.0005 * (28.5 - 1.0/24) * 0.85
+	if diff := c.KeepInterest - wantKeep; diff > 1e-6 || diff < -1e-6 {
+		t.Errorf("KeepInterest = %v, want %v", c.KeepInterest, wantKeep)
+	}
+	if diff := c.Gain - (wantRefi - wantKeep); diff > 1e-6 || diff < -1e-6 {
+		t.Errorf("Gain = %v, want %v", c.Gain, wantRefi-wantKeep)
+	}
+}
+
+func TestRefinancer_MinGain(t *testing.T) {
+	cfg := newRefinanceTestConfig()
+	cfg.RefinanceMinGain = 100
+	refinancer := NewRefinancer(cfg)
+	now := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+
+	candidates := refinancer.Evaluate([]*bitfinex.FundingCredit{testCredit(1, 0.0002, 30, now.Add(-time.Hour))}, 0.0005, now)
+	if len(candidates) != 0 {
+		t.Errorf("Evaluate() returned %d candidates below minimum gain, want 0", len(candidates))
+	}
+}
+
+func TestRefinancer_Allowance(t *testing.T) {
+	refinancer := NewRefinancer(newRefinanceTestConfig())
+	now := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+
+	if got := refinancer.Allowance(now); got != 1 {
+		t.Fatalf("Allowance() = %d, want 1 (one close per interval)", got)
+	}
+
+	refinancer.RecordClose(now)
+	if got := refinancer.Allowance(now.Add(30 * time.Minute)); got != 0 {
+		t.Errorf("Allowance() within interval = %d, want 0", got)
+	}
+	if got := refinancer.Allowance(now.Add(61 * time.Minute)); got != 1 {
+		t.Errorf("Allowance() after interval = %d, want 1", got)
+	}
+
+	refinancer.RecordClose(now.Add(61 * time.Minute))
+	if got := refinancer.Allowance(now.Add(3 * time.Hour)); got != 0 {
+		t.Errorf("Allowance() after daily cap = %d, want 0", got)
+	}
+	if got := refinancer.Allowance(now.Add(25 * time.Hour)); got != 1 {
+		t.Errorf("Allowance() next day = %d, want 1", got)
+	}
+}
+
+func TestRefinancer_Proposals(t *testing.T) {
+	refinancer := NewRefinancer(newRefinanceTestConfig())
+	now := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+	credits := []*bitfinex.FundingCredit{testCredit(1, 0.0002, 30, now.Add(-time.Hour))}
+
+	fresh := refinancer.UpdateProposals(refinancer.Evaluate(credits, 0.0005, now))
+	if len(fresh) != 1 {
+		t.Fatalf("first UpdateProposals() returned %d new proposals, want 1", len(fresh))
+	}
+	if fresh := refinancer.UpdateProposals(refinancer.Evaluate(credits, 0.0005, now.Add(10*time.Minute))); len(fresh) != 0 {
+		t.Errorf("repeated proposal reported as new")
+	}
+
+	// 提議時間沿用首次提議，超過有效時間後無法確認
+	if _, ok := refinancer.TakeProposal(1, now.Add(31*time.Minute)); ok {
+		t.Error("expired proposal should not be confirmable")
+	}
+	if _, ok := refinancer.TakeProposal(1, now.Add(5*time.Minute)); !ok {
+		t.Fatal("valid proposal should be confirmable")
+	}
+	if _, ok := refinancer.TakeProposal(1, now.Add(5*time.Minute)); ok {
+		t.Error("proposal should only be confirmed once")
+	}
+}
+
+// candleBookMarket 在 bookMarket 上提供固定收盤利率的K線
+type candleBookMarket struct {
+	bookMarket
+	close float64
+}
+
+func (m *candleBookMarket) Candles(symbol, timeFrame string, limit int, at time.Time) ([]*bitfinex.Candle, error) {
+	return []*bitfinex.Candle{{MTS: at.Add(-time.Hour).UnixNano() / int64(time.Millisecond), Close: m.close}}, nil
+}
+
+func TestLendingBot_ConfirmRefinanceReevaluates(t *testing.T) {
+	now := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+	cfg := newRefinanceTestConfig()
+	cfg.RefinanceMode = "confirm"
+	cfg.TestMode = true
+
+	market := &candleBookMarket{bookMarket: bookMarket{bestAsk: 0.0005}, close: 0.0005}
+	exchange := simulator.NewExchange(market, simulator.Options{Currency: "USD", InitialBalance: 1000, Start: now})
+	bot := NewLendingBot(cfg, exchange)
+	bot.SetClock(func() time.Time { return now }, func(time.Duration) {})
+	bot.client = &creditsExchange{Exchange: exchange, credits: []*bitfinex.FundingCredit{
+		testCredit(1, 0.0002, 30, now.Add(-time.Hour)),
+	}}
+
+	bot.runRefinancing()
+	if len(bot.refinancer.Proposals(now)) != 1 {
+		t.Fatal("expected a refinance proposal at the high market rate")
+	}
+
+	// 確認前市場利率回落，提議已不划算
+	market.bestAsk, market.close = 0.00025, 0.00025
+	if _, err := bot.ConfirmRefinance(1); err == nil {
+		t.Fatal("ConfirmRefinance() should abort when the current market no longer pays")
+	}
+	if closes := bot.refinancer.ClosesInLastDay(now); closes != 0 {
+		t.Errorf("aborted refinance recorded %d closes", closes)
+	}
+
+	// 市場利率回升後重新提議，確認時仍划算即關閉
+	market.bestAsk, market.close = 0.0005, 0.0005
+	bot.runRefinancing()
+	if _, err := bot.ConfirmRefinance(1); err != nil {
+		t.Fatalf("ConfirmRefinance() error = %v", err)
+	}
+}
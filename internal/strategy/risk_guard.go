@@ -0,0 +1,251 @@
+package strategy
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/kfrico/BitfinexLendingBot/internal/bitfinex"
+	"github.com/kfrico/BitfinexLendingBot/internal/config"
+	"github.com/kfrico/BitfinexLendingBot/internal/constants"
+)
+
+// RiskGuard 位於策略輸出與下單之間的熔斷器
+type RiskGuard struct {
+	config *config.Config
+
+	mu         sync.Mutex
+	halted     bool
+	haltReason string
+	haltedAt   time.Time
+	placements []placementRecord
+}
+
+// placementRecord 下單紀錄（用於時間窗口限額）
+type placementRecord struct {
+	Time   time.Time
+	Amount float64
+}
+
+// MarketReference 風險檢查使用的市場參考數據
+type MarketReference struct {
+	FundingBook []*bitfinex.FundingBookEntry
+	BookErr     error
+	FRR         float64            // 0 代表無法取得
+	Candles     []*bitfinex.Candle // 近期K線，空代表無法取得
+}
+
+// NewRiskGuard 創建風險檢查器
+func NewRiskGuard(cfg *config.Config) *RiskGuard {
+	return &RiskGuard{config: cfg}
+}
+
+// IsHalted 返回是否處於熔斷狀態、原因及熔斷時間
+func (rg *RiskGuard) IsHalted() (bool, string, time.Time) {
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+	return rg.halted, rg.haltReason, rg.haltedAt
+}
+
+// Trip 進入熔斷狀態，返回是否為新觸發
+func (rg *RiskGuard) Trip(reason string, now time.Time) bool {
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+	if rg.halted {
+		return false
+	}
+	rg.halted = true
+	rg.haltReason = reason
+	rg.haltedAt = now
+	return true
+}
+
+// Resume 解除熔斷狀態，返回解除前是否處於熔斷
+func (rg *RiskGuard) Resume() bool {
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+	wasHalted := rg.halted
+	rg.halted = false
+	rg.haltReason = ""
+	rg.haltedAt = time.Time{}
+	return wasHalted
+}
+
+// CheckMarketData 檢查 funding book 是否為空或異常
+func (rg *RiskGuard) CheckMarketData(ref *MarketReference) error {
+	if ref.BookErr != nil {
+		return fmt.Errorf("無法取得 funding book: %v", ref.BookErr)
+	}
+	if len(ref.FundingBook) == 0 {
+		return fmt.Errorf("funding book 為空")
+	}
+
+	asks := 0
+	for i, entry := range ref.FundingBook {
+		if entry == nil || math.IsNaN(entry.Rate) || math.IsNaN(entry.Amount) {
+			return fmt.Errorf("funding book 第 %d 檔數據無效", i)
+		}
+		if entry.Rate <= 0 || entry.Rate > constants.MaxFundingDailyRate {
+			return fmt.Errorf("funding book 第 %d 檔利率異常: %.6f%%", i, entry.Rate*100)
+		}
+		if entry.Amount > 0 {
+			asks++
+		}
+	}
+	if asks == 0 {
+		return fmt.Errorf("funding book 沒有任何 ask 掛單")
+	}
+
+	return nil
+}
+
+// CheckOfferRates 以近期 FRR 與K線區間檢查訂單利率（含利率加成的實際掛單利率）
+// 只有高於上限視為異常；低於K線區間只記錄，避免最低日利率的保底訂單觸發熔斷
+// 高額持有單為固定設定利率、FRR 單沒有固定利率，兩者不檢查
+func (rg *RiskGuard) CheckOfferRates(offers []*LoanOffer, ref *MarketReference) error {
+	maxByFRR := 0.0
+	if ref.FRR > 0 && rg.config.RiskMaxRateFRRMultiplier > 0 {
+		maxByFRR = ref.FRR * rg.config.RiskMaxRateFRRMultiplier
+	}
+
+	bandLow, bandHigh := 0.0, 0.0
+	if len(ref.Candles) > 0 && rg.config.RiskCandleBandPercent > 0 {
+		low, high := math.Inf(1), 0.0
+		for _, candle := range ref.Candles {
+			if candle.Low > 0 && candle.Low < low {
+				low = candle.Low
+			}
+			if candle.High > high {
+				high = candle.High
+			}
+		}
+		band := rg.config.RiskCandleBandPercent / 100.0
+		if high > 0 && !math.IsInf(low, 1) {
+			bandLow = low * (1 - band)
+			bandHigh = high * (1 + band)
+		}
+	}
+
+	for i, offer := range offers {
+		if offer.UseFRR || offer.HighHold {
+			continue
+		}
+		if math.IsNaN(offer.Rate) || offer.Rate <= 0 {
+			return fmt.Errorf("訂單 #%d 利率無效: %v", i+1, offer.Rate)
+		}
+		if maxByFRR > 0 && offer.Rate > maxByFRR {
+			return fmt.Errorf("訂單 #%d 利率 %.6f%% 超過 FRR %.6f%% 的 %.1f 倍",
+				i+1, offer.Rate*100, ref.FRR*100, rg.config.RiskMaxRateFRRMultiplier)
+		}
+		if bandHigh > 0 && offer.Rate > bandHigh {
+			return fmt.Errorf("訂單 #%d 利率 %.6f%% 超出近期K線區間 %.6f%%-%.6f%%",
+				i+1, offer.Rate*100, bandLow*100, bandHigh*100)
+		}
+		// 低於區間多為最低日利率或調降後的利率下限，只記錄不熔斷
+		if bandHigh > 0 && offer.Rate < bandLow {
+			log.Printf("⚠️ 風險檢查：訂單 #%d 利率 %.6f%% 低於近期K線區間 %.6f%%-%.6f%%",
+				i+1, offer.Rate*100, bandLow*100, bandHigh*100)
+		}
+	}
+
+	return nil
+}
+
+// ApplyPlacementLimits 依時間窗口內的下單金額與筆數上限裁減訂單，返回可下單的訂單與說明
+func (rg *RiskGuard) ApplyPlacementLimits(offers []*LoanOffer, now time.Time) ([]*LoanOffer, string) {
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+
+	rg.pruneLocked(now)
+
+	maxNotional := rg.config.RiskMaxNotionalPerWindow
+	maxOrders := rg.config.RiskMaxOrdersPerWindow
+	if maxNotional <= 0 && maxOrders <= 0 {
+		return offers, ""
+	}
+
+	priorNotional := 0.0
+	for _, record := range rg.placements {
+		priorNotional += record.Amount
+	}
+	priorOrders := len(rg.placements)
+
+	usedNotional, usedOrders := priorNotional, priorOrders
+
+	allowed := make([]*LoanOffer, 0, len(offers))
+	for _, offer := range offers {
+		if maxOrders > 0 && usedOrders >= maxOrders {
+			break
+		}
+		if maxNotional > 0 && usedNotional+offer.Amount > maxNotional {
+			continue
+		}
+		allowed = append(allowed, offer)
+		usedOrders++
+		usedNotional += offer.Amount
+	}
+
+	if len(allowed) == len(offers) {
+		return allowed, ""
+	}
+
+	return allowed, fmt.Sprintf("時間窗口 %d 分鐘內已達下單上限，%d 筆訂單中僅允許 %d 筆 (窗口內已下單 %d 筆 / %.2f)",
+		rg.config.RiskWindowMinutes, len(offers), len(allowed), priorOrders, priorNotional)
+}
+
+// RecordPlacement 記錄成功下單
+func (rg *RiskGuard) RecordPlacement(amount float64, now time.Time) {
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+	rg.placements = append(rg.placements, placementRecord{Time: now, Amount: amount})
+	rg.pruneLocked(now)
+}
+
+// ReleasePlacement 撤單時扣回時間窗口內的下單紀錄，使每次執行重新掛出同一筆資金只計入淨增加的曝險
+// 由最新的紀錄開始扣減，扣完的紀錄不再計入筆數；窗口外下的單不在紀錄中，無需扣回
+func (rg *RiskGuard) ReleasePlacement(amount float64, now time.Time) {
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+	rg.pruneLocked(now)
+
+	for i := len(rg.placements) - 1; i >= 0 && amount > 1e-9; i-- {
+		released := math.Min(amount, rg.placements[i].Amount)
+		rg.placements[i].Amount -= released
+		amount -= released
+	}
+
+	kept := rg.placements[:0]
+	for _, record := range rg.placements {
+		if record.Amount > 1e-9 {
+			kept = append(kept, record)
+		}
+	}
+	rg.placements = kept
+}
+
+// WindowUsage 返回時間窗口內的下單筆數與金額
+func (rg *RiskGuard) WindowUsage(now time.Time) (int, float64) {
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+	rg.pruneLocked(now)
+
+	total := 0.0
+	for _, record := range rg.placements {
+		total += record.Amount
+	}
+	return len(rg.placements), total
+}
+
+// pruneLocked 移除時間窗口外的下單紀錄（需持有鎖）
+func (rg *RiskGuard) pruneLocked(now time.Time) {
+	window := time.Duration(rg.config.RiskWindowMinutes) * time.Minute
+	kept := rg.placements[:0]
+	for _, record := range rg.placements {
+		if now.Sub(record.Time) < window {
+			kept = append(kept, record)
+		}
+	}
+	rg.placements = kept
+}
@@ -0,0 +1,248 @@
+package strategy
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/kfrico/BitfinexLendingBot/internal/bitfinex"
+	"github.com/kfrico/BitfinexLendingBot/internal/config"
+	"github.com/kfrico/BitfinexLendingBot/internal/simulator"
+)
+
+func newRiskGuardTestConfig() *config.Config {
+	return &config.Config{
+		EnableRiskGuard:          true,
+		RiskMaxRateFRRMultiplier: 3,
+		RiskCandleBandPercent:    50,
+		RiskWindowMinutes:        60,
+	}
+}
+
+func TestRiskGuard_CheckMarketData(t *testing.T) {
+	guard := NewRiskGuard(newRiskGuardTestConfig())
+
+	tests := []struct {
+		name    string
+		ref     *MarketReference
+		wantErr bool
+	}{
+		{
+			name: "healthy book",
+			ref: &MarketReference{FundingBook: []*bitfinex.FundingBookEntry{
+				{Rate: 0.0002, Amount: 1000},
+				{Rate: 0.0001, Amount: -500},
+			}},
+		},
+		{
+			name:    "fetch error",
+			ref:     &MarketReference{BookErr: errors.New("timeout")},
+			wantErr: true,
+		},
+		{
+			name:    "empty book",
+			ref:     &MarketReference{FundingBook: []*bitfinex.FundingBookEntry{}},
+			wantErr: true,
+		},
+		{
+			name: "garbled rate",
+			ref: &MarketReference{FundingBook: []*bitfinex.FundingBookEntry{
+				{Rate: 0.5, Amount: 1000},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "NaN amount",
+			ref: &MarketReference{FundingBook: []*bitfinex.FundingBookEntry{
+				{Rate: 0.0002, Amount: math.NaN()},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "bids only",
+			ref: &MarketReference{FundingBook: []*bitfinex.FundingBookEntry{
+				{Rate: 0.0002, Amount: -1000},
+			}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := guard.CheckMarketData(tt.ref)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CheckMarketData() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRiskGuard_CheckOfferRates(t *testing.T) {
+	guard := NewRiskGuard(newRiskGuardTestConfig())
+	ref := &MarketReference{
+		FRR: 0.0003,
+		Candles: []*bitfinex.Candle{
+			{High: 0.0004, Low: 0.0002},
+			{High: 0.0006, Low: 0.00025},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		offers  []*LoanOffer
+		wantErr bool
+	}{
+		{
+			name:   "rates inside bands",
+			offers: []*LoanOffer{{Amount: 300, Rate: 0.0002}, {Amount: 300, Rate: 0.0008}},
+		},
+		{
+			name:    "above FRR multiplier",
+			offers:  []*LoanOffer{{Amount: 300, Rate: 0.00095}},
+			wantErr: true,
+		},
+		{
+			name:   "below candle band is only logged",
+			offers: []*LoanOffer{{Amount: 300, Rate: 0.00005}},
+		},
+		{
+			name:   "high hold and FRR offers are exempt",
+			offers: []*LoanOffer{{Amount: 5000, Rate: 0.01, HighHold: true}, {Amount: 300, UseFRR: true}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := guard.CheckOfferRates(tt.offers, ref)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CheckOfferRates() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRiskGuard_ApplyPlacementLimits(t *testing.T) {
+	cfg := newRiskGuardTestConfig()
+	cfg.RiskMaxOrdersPerWindow = 3
+	cfg.RiskMaxNotionalPerWindow = 1000
+	guard := NewRiskGuard(cfg)
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	guard.RecordPlacement(400, now.Add(-90*time.Minute)) // 已超出窗口
+	guard.RecordPlacement(400, now.Add(-10*time.Minute))
+
+	offers := []*LoanOffer{
+		{Amount: 300},
+		{Amount: 500}, // 超過剩餘金額，跳過
+		{Amount: 200},
+		{Amount: 100}, // 超過筆數上限
+	}
+
+	allowed, note := guard.ApplyPlacementLimits(offers, now)
+	if len(allowed) != 2 || allowed[0].Amount != 300 || allowed[1].Amount != 200 {
+		t.Fatalf("unexpected allowed offers: %+v", allowed)
+	}
+	if note == "" {
+		t.Error("expected a note when offers are trimmed")
+	}
+
+	orders, notional := guard.WindowUsage(now)
+	if orders != 1 || notional != 400 {
+		t.Errorf("WindowUsage() = %d, %.2f; want 1, 400", orders, notional)
+	}
+}
+
+func TestRiskGuard_TripAndResume(t *testing.T) {
+	guard := NewRiskGuard(newRiskGuardTestConfig())
+	now := time.Now()
+
+	if !guard.Trip("book empty", now) {
+		t.Fatal("first trip should report a new halt")
+	}
+	if guard.Trip("again", now) {
+		t.Error("second trip should not report a new halt")
+	}
+	if halted, reason, _ := guard.IsHalted(); !halted || reason != "book empty" {
+		t.Errorf("IsHalted() = %v, %q", halted, reason)
+	}
+	if !guard.Resume() {
+		t.Error("Resume() should report previous halt")
+	}
+	if halted, _, _ := guard.IsHalted(); halted {
+		t.Error("guard should not be halted after resume")
+	}
+}
+
+func TestLendingBot_RiskGuardChecksRateWithBonus(t *testing.T) {
+	now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	cfg := newRiskGuardTestConfig()
+	cfg.Currency = "USD"
+	cfg.MinLoan = 150
+	cfg.MinDailyLendRate = 0.02
+	cfg.SpreadLend = 2
+	cfg.GapTop = 2
+	cfg.RiskMaxRateFRRMultiplier = 2
+	cfg.RateBonus = 0.05 // 策略利率約 0.03%，加成後 0.08% 超過 FRR 0.03% 的 2 倍
+	cfg.ApplyDefaults()
+
+	exchange := simulator.NewExchange(&bookMarket{bestAsk: 0.0003}, simulator.Options{Currency: "USD", InitialBalance: 1000, Start: now})
+	bot := NewLendingBot(cfg, exchange)
+	bot.SetClock(exchange.Now, func(time.Duration) {})
+	if err := bot.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	if halted, _, _ := bot.riskGuard.IsHalted(); !halted {
+		t.Error("risk guard should check the rate after the bonus is added")
+	}
+	if offers, _ := exchange.GetFundingOffers(cfg.GetFundingSymbol()); len(offers) != 0 {
+		t.Errorf("no offers should be placed while halted, got %d", len(offers))
+	}
+}
+
+func TestLendingBot_PlacementLimitsCountNetExposure(t *testing.T) {
+	now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	cfg := newRiskGuardTestConfig()
+	cfg.Currency = "USD"
+	cfg.MinLoan = 150
+	cfg.MinDailyLendRate = 0.02
+	cfg.SpreadLend = 2
+	cfg.GapTop = 2
+	cfg.RiskMaxNotionalPerWindow = 1500 // 約為帳戶資金的 1.5 倍
+	cfg.RiskMaxOrdersPerWindow = 4
+	cfg.ApplyDefaults()
+
+	exchange := simulator.NewExchange(&bookMarket{bestAsk: 0.0003}, simulator.Options{
+		Currency:       "USD",
+		InitialBalance: 1000,
+		FillTimeFrame:  "1h",
+		FillInterval:   time.Hour,
+		Start:          now,
+	})
+	bot := NewLendingBot(cfg, exchange)
+	bot.SetClock(exchange.Now, func(time.Duration) {})
+
+	// 每 10 分鐘重新掛出同一筆資金，不應因重複計入而被裁減
+	for cycle := 0; cycle < 6; cycle++ {
+		if err := exchange.Advance(now.Add(time.Duration(cycle*10) * time.Minute)); err != nil {
+			t.Fatalf("Advance() error = %v", err)
+		}
+		if err := bot.Execute(); err != nil {
+			t.Fatalf("cycle %d: Execute() error = %v", cycle, err)
+		}
+
+		offers, _ := exchange.GetFundingOffers(cfg.GetFundingSymbol())
+		placed := 0.0
+		for _, offer := range offers {
+			placed += offer.Amount
+		}
+		if len(offers) != 2 || math.Abs(placed-1000) > floatTolerance {
+			t.Fatalf("cycle %d: %d offers / %.2f on book, want 2 / 1000", cycle, len(offers), placed)
+		}
+	}
+
+	if orders, notional := bot.riskGuard.WindowUsage(exchange.Now()); orders != 2 || math.Abs(notional-1000) > floatTolerance {
+		t.Errorf("WindowUsage() = %d, %.2f; want only the net 2 orders / 1000", orders, notional)
+	}
+}
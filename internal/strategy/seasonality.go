@@ -0,0 +1,167 @@
+package strategy
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kfrico/BitfinexLendingBot/internal/bitfinex"
+	"github.com/kfrico/BitfinexLendingBot/internal/constants"
+)
+
+// 星期顯示名稱（time.Weekday 順序）
+var weekdayNames = [...]string{"週日", "週一", "週二", "週三", "週四", "週五", "週六"}
+
+// SeasonalityModel 依「星期 × 小時」學習利率溢價
+//
+// 每根小時K線的溢價定義為收盤利率 / 前24小時平均收盤利率，
+// 同一時段（共 168 個）的溢價取平均即為該時段的季節性係數：
+// 大於 1 代表歷史上該時段利率偏高（熱門時段），小於 1 代表冷清時段。
+type SeasonalityModel struct {
+	mu        sync.RWMutex
+	factors   [constants.HoursPerWeek]float64
+	samples   [constants.HoursPerWeek]int
+	maxAdjust float64 // 係數調整上限（小數，0.2 代表 ±20%）
+	trainedAt time.Time
+	candles   int
+}
+
+// NewSeasonalityModel 創建季節性模型，maxAdjustPercent 為係數最大調整幅度（%）
+func NewSeasonalityModel(maxAdjustPercent float64) *SeasonalityModel {
+	model := &SeasonalityModel{maxAdjust: maxAdjustPercent / 100}
+	for i := range model.factors {
+		model.factors[i] = 1
+	}
+	return model
+}
+
+// hourOfWeek 返回時間所屬的時段索引（UTC，週日 0 時為 0）
+func hourOfWeek(t time.Time) int {
+	t = t.UTC()
+	return int(t.Weekday())*24 + t.Hour()
+}
+
+// Train 以小時K線重新學習各時段係數，K線順序不限
+func (sm *SeasonalityModel) Train(candles []*bitfinex.Candle, now time.Time) error {
+	sorted := make([]*bitfinex.Candle, 0, len(candles))
+	for _, candle := range candles {
+		if candle != nil && candle.Close > 0 {
+			sorted = append(sorted, candle)
+		}
+	}
+	if len(sorted) <= constants.SeasonalityBaselineCandles {
+		return fmt.Errorf("K線數量不足: %d 根（至少需要 %d 根）", len(sorted), constants.SeasonalityBaselineCandles+1)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].MTS < sorted[j].MTS })
+
+	var sums [constants.HoursPerWeek]float64
+	var counts [constants.HoursPerWeek]int
+
+	window := 0.0
+	for i := 0; i < constants.SeasonalityBaselineCandles; i++ {
+		window += sorted[i].Close
+	}
+
+	for i := constants.SeasonalityBaselineCandles; i < len(sorted); i++ {
+		baseline := window / constants.SeasonalityBaselineCandles
+		if baseline > 0 {
+			bucket := hourOfWeek(time.Unix(0, sorted[i].MTS*int64(time.Millisecond)))
+			sums[bucket] += sorted[i].Close / baseline
+			counts[bucket]++
+		}
+		window += sorted[i].Close - sorted[i-constants.SeasonalityBaselineCandles].Close
+	}
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	for i := range sm.factors {
+		sm.samples[i] = counts[i]
+		if counts[i] < constants.SeasonalityMinSamples {
+			sm.factors[i] = 1
+			continue
+		}
+		sm.factors[i] = sm.clamp(sums[i] / float64(counts[i]))
+	}
+	sm.trainedAt = now
+	sm.candles = len(sorted)
+
+	return nil
+}
+
+// clamp 將係數限制在 1 ± maxAdjust 之內
+func (sm *SeasonalityModel) clamp(factor float64) float64 {
+	return math.Max(1-sm.maxAdjust, math.Min(1+sm.maxAdjust, factor))
+}
+
+// IsTrained 是否已完成學習
+func (sm *SeasonalityModel) IsTrained() bool {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	return !sm.trainedAt.IsZero()
+}
+
+// TrainedAt 返回上次學習時間
+func (sm *SeasonalityModel) TrainedAt() time.Time {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	return sm.trainedAt
+}
+
+// FactorAt 返回指定時間所屬時段的係數
+func (sm *SeasonalityModel) FactorAt(t time.Time) float64 {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	return sm.factors[hourOfWeek(t)]
+}
+
+// Factor 返回當前掛單應使用的係數：目前時段與往後 leadHours 個時段的平均，
+// 讓掛單在熱門時段來臨前提高利率、在冷清時段來臨前降低利率
+func (sm *SeasonalityModel) Factor(now time.Time, leadHours int) float64 {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	if sm.trainedAt.IsZero() {
+		return 1
+	}
+
+	sum := 0.0
+	for h := 0; h <= leadHours; h++ {
+		sum += sm.factors[hourOfWeek(now.Add(time.Duration(h)*time.Hour))]
+	}
+	return sum / float64(leadHours+1)
+}
+
+// Report 返回季節性模型摘要（目前係數與未來24小時最熱、最冷時段）
+func (sm *SeasonalityModel) Report(now time.Time, leadHours int) string {
+	if !sm.IsTrained() {
+		return "尚未完成學習（等待下次策略執行）"
+	}
+
+	factor := sm.Factor(now, leadHours)
+	report := fmt.Sprintf("目前係數: %.3f (%+.1f%%)", factor, (factor-1)*100)
+
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	hottest, quietest := now, now
+	for h := 1; h < 24; h++ {
+		t := now.Add(time.Duration(h) * time.Hour)
+		if sm.factors[hourOfWeek(t)] > sm.factors[hourOfWeek(hottest)] {
+			hottest = t
+		}
+		if sm.factors[hourOfWeek(t)] < sm.factors[hourOfWeek(quietest)] {
+			quietest = t
+		}
+	}
+
+	report += fmt.Sprintf("\n未來24小時最熱時段: %s %02d:00 UTC (%.3f)",
+		weekdayNames[hottest.UTC().Weekday()], hottest.UTC().Hour(), sm.factors[hourOfWeek(hottest)])
+	report += fmt.Sprintf("\n未來24小時最冷時段: %s %02d:00 UTC (%.3f)",
+		weekdayNames[quietest.UTC().Weekday()], quietest.UTC().Hour(), sm.factors[hourOfWeek(quietest)])
+	report += fmt.Sprintf("\n學習樣本: %d 根小時K線, 更新於 %s", sm.candles, sm.trainedAt.UTC().Format("2006-01-02 15:04 UTC"))
+
+	return report
+}
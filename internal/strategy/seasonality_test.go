@@ -0,0 +1,135 @@
+package strategy
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kfrico/BitfinexLendingBot/internal/bitfinex"
+	"github.com/kfrico/BitfinexLendingBot/internal/config"
+	"github.com/kfrico/BitfinexLendingBot/internal/constants"
+	"github.com/kfrico/BitfinexLendingBot/internal/history"
+	"github.com/kfrico/BitfinexLendingBot/internal/simulator"
+)
+
+// seasonalCandles 產生四週的小時K線：週一 14:00 利率翻倍、週日 03:00 利率減半
+func seasonalCandles(start time.Time) []*bitfinex.Candle {
+	var candles []*bitfinex.Candle
+	for i := 0; i < 28*24; i++ {
+		t := start.Add(time.Duration(i) * time.Hour)
+		rate := 0.0004
+		switch {
+		case t.Weekday() == time.Monday && t.Hour() == 14:
+			rate *= 2
+		case t.Weekday() == time.Sunday && t.Hour() == 3:
+			rate *= 0.5
+		}
+		candles = append(candles, &bitfinex.Candle{
+			MTS:   t.UnixNano() / int64(time.Millisecond),
+			Close: rate,
+			High:  rate,
+			Low:   rate,
+		})
+	}
+	// API 返回由新到舊
+	for i, j := 0, len(candles)-1; i < j; i, j = i+1, j-1 {
+		candles[i], candles[j] = candles[j], candles[i]
+	}
+	return candles
+}
+
+func TestSeasonalityModel_Train(t *testing.T) {
+	start := time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC) // 週日
+	model := NewSeasonalityModel(20)
+
+	if f := model.Factor(start, 1); f != 1 {
+		t.Errorf("untrained factor = %v, want 1", f)
+	}
+
+	if err := model.Train(seasonalCandles(start), start.Add(28*24*time.Hour)); err != nil {
+		t.Fatalf("Train() error = %v", err)
+	}
+
+	monday14 := time.Date(2024, 1, 8, 14, 0, 0, 0, time.UTC)
+	sunday03 := time.Date(2024, 1, 14, 3, 0, 0, 0, time.UTC)
+	quiet := time.Date(2024, 1, 10, 9, 0, 0, 0, time.UTC)
+
+	// 溢價被限制在 ±20%
+	if f := model.FactorAt(monday14); math.Abs(f-1.2) > 1e-9 {
+		t.Errorf("Monday 14:00 factor = %v, want 1.2 (clamped)", f)
+	}
+	if f := model.FactorAt(sunday03); f >= 1 {
+		t.Errorf("Sunday 03:00 factor = %v, want < 1", f)
+	}
+	if f := model.FactorAt(quiet); math.Abs(f-1) > 0.01 {
+		t.Errorf("ordinary hour factor = %v, want about 1", f)
+	}
+
+	// 熱門時段前一小時即開始提高利率
+	lead := model.Factor(monday14.Add(-time.Hour), 1)
+	if lead <= 1.05 {
+		t.Errorf("lead factor before hot hour = %v, want > 1.05", lead)
+	}
+	if noLead := model.Factor(monday14.Add(-time.Hour), 0); noLead >= lead {
+		t.Errorf("factor without lead = %v, want less than %v", noLead, lead)
+	}
+
+	report := model.Report(monday14.Add(-2*time.Hour), 1)
+	if !strings.Contains(report, "最熱時段: 週一 14:00 UTC") {
+		t.Errorf("report = %q, want Monday 14:00 as hottest", report)
+	}
+}
+
+func TestSeasonalityModel_TrainRequiresBaseline(t *testing.T) {
+	model := NewSeasonalityModel(20)
+	candles := seasonalCandles(time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC))[:10]
+	if err := model.Train(candles, time.Now()); err == nil {
+		t.Error("Train() with too few candles should fail")
+	}
+	if model.IsTrained() {
+		t.Error("model should remain untrained")
+	}
+}
+
+func TestLendingBot_RefreshSeasonalityFromStore(t *testing.T) {
+	start := time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC)
+	now := start.Add(28 * 24 * time.Hour)
+	cfg := &config.Config{
+		Currency:          "USD",
+		MinLoan:           150,
+		MinDailyLendRate:  0.02,
+		EnableSeasonality: true,
+		DataDir:           t.TempDir(),
+	}
+	cfg.ApplyDefaults()
+
+	// 測試市場不提供K線，只能由已保存的數據學習
+	exchange := simulator.NewExchange(&bookMarket{bestAsk: 0.0003}, simulator.Options{Currency: "USD", InitialBalance: 1000, Start: now})
+	bot := NewLendingBot(cfg, exchange)
+	bot.SetClock(func() time.Time { return now }, func(time.Duration) {})
+
+	bot.refreshSeasonality()
+	if bot.seasonality.IsTrained() {
+		t.Fatal("model should stay untrained without stored or live candles")
+	}
+
+	// 保存回看區間與之後的K線，只應使用 now 之前的部分
+	store := history.NewStore(cfg.DataDir)
+	candles := append(seasonalCandles(start), seasonalCandles(now)...)
+	if _, err := store.SaveCandles(cfg.GetFundingSymbol(), constants.SeasonalityTimeFrame, candles); err != nil {
+		t.Fatalf("SaveCandles() error = %v", err)
+	}
+
+	bot.refreshSeasonality()
+	if !bot.seasonality.IsTrained() {
+		t.Fatal("model should be trained from stored candles")
+	}
+	if bot.seasonality.candles != 28*24 {
+		t.Errorf("trained on %d candles, want %d (candles after now excluded)", bot.seasonality.candles, 28*24)
+	}
+	monday14 := time.Date(2024, 2, 5, 14, 0, 0, 0, time.UTC)
+	if f := bot.seasonality.FactorAt(monday14); f <= 1.1 {
+		t.Errorf("hot hour factor = %v, want > 1.1", f)
+	}
+}
@@ -3,45 +3,50 @@ package strategy
 import (
 	"log"
 	"math"
+	"time"
 
 	"github.com/kfrico/BitfinexLendingBot/internal/bitfinex"
 	"github.com/kfrico/BitfinexLendingBot/internal/config"
 	"github.com/kfrico/BitfinexLendingBot/internal/constants"
+	"github.com/kfrico/BitfinexLendingBot/internal/history"
+	"github.com/kfrico/BitfinexLendingBot/internal/rates"
 )
 
 // SmartStrategy 智能策略引擎
 type SmartStrategy struct {
-	config   *config.Config
-	analyzer *MarketAnalyzer
+	config        *config.Config
+	analyzer      *MarketAnalyzer
+	rateConverter *rates.Converter
+	statePath     string             // 市場快照保存路徑，空字串代表不保存（回測）
+	amounts       func() amountRules // 下單金額規則（由 LendingBot 依幣種規格提供）
+	// absorption 吸收量來源（由 LendingBot 提供），nil 時 absorption 分配方式維持平均分配
+	absorption func(rates []float64) []float64
+	floor      func() float64 // 分散單最低日利率（由 LendingBot 依資金使用率調整）
 }
 
 // NewSmartStrategy 創建智能策略引擎
 func NewSmartStrategy(cfg *config.Config) *SmartStrategy {
 	return &SmartStrategy{
-		config:   cfg,
-		analyzer: NewMarketAnalyzer(),
+		config:        cfg,
+		analyzer:      NewMarketAnalyzer(),
+		rateConverter: rates.NewConverterWithFees(cfg.GetFeeModel()),
+		amounts: func() amountRules {
+			return newAmountRules(cfg, bitfinex.DefaultCurrencyInfo(cfg.Currency))
+		},
+		floor: cfg.GetMinDailyRateDecimal,
 	}
 }
 
 // CalculateSmartOffers 計算智能貸出訂單
 func (ss *SmartStrategy) CalculateSmartOffers(fundsAvailable float64, fundingBook []*bitfinex.FundingBookEntry) []*LoanOffer {
 	var loanOffers []*LoanOffer
+	rules := ss.amounts()
 
-	if fundsAvailable < ss.config.MinLoan {
+	if fundsAvailable < rules.minLoan {
 		return loanOffers
 	}
 
-	// 添加市場數據到分析器
-	if len(fundingBook) > 0 {
-		currentRate := fundingBook[0].Rate
-		totalVolume := ss.calculateTotalVolume(fundingBook)
-		ss.analyzer.AddRateSnapshot(currentRate, totalVolume)
-	}
-
-	// 分析市場狀況
-	marketCondition := ss.analyzer.AnalyzeMarket(fundingBook)
-	log.Printf("市場狀況 - 趨勢: %s, 波動率: %.6f, 利率比例: %.2f",
-		marketCondition.Trend, marketCondition.Volatility, marketCondition.RateRatio)
+	marketCondition := ss.analyzeMarket(fundingBook)
 
 	// 動態資金配置
 	highHoldRatio, spreadRatio := ss.calculateOptimalAllocation(marketCondition)
@@ -54,13 +59,13 @@ func (ss *SmartStrategy) CalculateSmartOffers(fundsAvailable float64, fundingBoo
 		highHoldRatio*100, highHoldAmount, spreadRatio*100, spreadAmount)
 
 	// 高額持有策略（動態利率）
-	if ss.config.HighHoldAmount > ss.config.MinLoan && highHoldAmount >= ss.config.HighHoldAmount {
+	if ss.config.HighHoldAmount > rules.minLoan && highHoldAmount >= ss.config.HighHoldAmount {
 		highHoldOffers := ss.calculateSmartHighHoldOffers(&splitFundsAvailable, marketCondition, fundingBook)
 		loanOffers = append(loanOffers, highHoldOffers...)
 	}
 
 	// 分散貸出策略（智能優化）
-	if splitFundsAvailable >= ss.config.MinLoan {
+	if splitFundsAvailable >= rules.minLoan {
 		remainingSlots := ss.getRemainingOrderSlots(len(loanOffers))
 		if remainingSlots != 0 {
 			spreadOffers := ss.calculateSmartSpreadOffers(splitFundsAvailable, fundingBook, marketCondition, remainingSlots)
@@ -71,6 +76,63 @@ func (ss *SmartStrategy) CalculateSmartOffers(fundsAvailable float64, fundingBoo
 	return loanOffers
 }
 
+// CalculateSmartSpreadOffers 只計算智能分散訂單（策略組合模式使用，高額持有單另行計算）
+func (ss *SmartStrategy) CalculateSmartSpreadOffers(fundsAvailable float64, fundingBook []*bitfinex.FundingBookEntry, maxOrders int) []*LoanOffer {
+	if fundsAvailable < ss.amounts().minLoan || maxOrders == 0 {
+		return nil
+	}
+
+	marketCondition := ss.analyzeMarket(fundingBook)
+	return ss.calculateSmartSpreadOffers(fundsAvailable, fundingBook, marketCondition, maxOrders)
+}
+
+// analyzeMarket 記錄訂單簿快照並分析市場狀況
+func (ss *SmartStrategy) analyzeMarket(fundingBook []*bitfinex.FundingBookEntry) *MarketCondition {
+	// 依配置切換估計器（支援執行中調整參數）
+	ss.analyzer.UseEstimators(ss.config.SmartTrendEstimator, ss.config.SmartVolatilityEstimator)
+
+	// 添加市場數據到分析器
+	if len(fundingBook) > 0 {
+		currentRate := fundingBook[0].Rate
+		totalVolume := ss.calculateTotalVolume(fundingBook)
+		ss.analyzer.AddRateSnapshot(currentRate, totalVolume)
+		if ss.statePath != "" {
+			if err := ss.analyzer.Save(ss.statePath); err != nil {
+				log.Printf("保存市場快照失敗: %v", err)
+			}
+		}
+	}
+
+	// 分析市場狀況
+	marketCondition := ss.analyzer.AnalyzeMarket(fundingBook)
+	log.Printf("市場狀況 - 趨勢: %s (%s), 波動率: %.6f, 利率比例: %.2f",
+		marketCondition.Trend, marketCondition.TrendReading.Detail, marketCondition.Volatility, marketCondition.RateRatio)
+
+	return marketCondition
+}
+
+// MarketReport 返回市場分析器的估計器讀數
+func (ss *SmartStrategy) MarketReport() string {
+	ss.analyzer.UseEstimators(ss.config.SmartTrendEstimator, ss.config.SmartVolatilityEstimator)
+	return ss.analyzer.Report()
+}
+
+// analyzerTimeFrames 可用於補足市場快照的K線時間框架（由短到長）
+var analyzerTimeFrames = []string{"1m", "5m", "15m", "30m", "1h", "3h", "6h", "12h", "1D"}
+
+// AnalyzerTimeFrame 返回不超過執行間隔的最長K線時間框架，使補入的快照與實際記錄頻率一致
+func AnalyzerTimeFrame(minutesRun int) string {
+	timeFrame := analyzerTimeFrames[0]
+	for _, tf := range analyzerTimeFrames {
+		duration, err := history.TimeFrameDuration(tf)
+		if err != nil || duration > time.Duration(minutesRun)*time.Minute {
+			break
+		}
+		timeFrame = tf
+	}
+	return timeFrame
+}
+
 // calculateOptimalAllocation 計算最佳資金配置
 func (ss *SmartStrategy) calculateOptimalAllocation(condition *MarketCondition) (highHoldRatio, spreadRatio float64) {
 	baseHighHold := 0.5 // 基礎50%配置
@@ -118,7 +180,7 @@ func (ss *SmartStrategy) calculateSmartHighHoldOffers(splitFundsAvailable *float
 	if ss.config.MaxLoan > 0 && highHold > ss.config.MaxLoan {
 		highHold = ss.config.MaxLoan
 	}
-	highHold = floorToCents(highHold)
+	highHold = ss.amounts().floor(highHold)
 
 	// 計算動態利率
 	dynamicRate := ss.calculateDynamicHighHoldRate(condition, fundingBook)
@@ -138,10 +200,11 @@ func (ss *SmartStrategy) calculateSmartHighHoldOffers(splitFundsAvailable *float
 		}
 
 		offer := &LoanOffer{
-			Amount: highHold,
-			Rate:   dynamicRate,
-			Period: period,
-			UseFRR: false, // 高額持有單固定走一般利率單
+			Amount:   highHold,
+			Rate:     dynamicRate,
+			Period:   period,
+			UseFRR:   false, // 高額持有單固定走一般利率單
+			HighHold: true,
 		}
 		offers = append(offers, offer)
 		*splitFundsAvailable -= highHold
@@ -186,12 +249,13 @@ func (ss *SmartStrategy) calculateDynamicHighHoldRate(condition *MarketCondition
 func (ss *SmartStrategy) calculateSmartSpreadOffers(splitFundsAvailable float64, fundingBook []*bitfinex.FundingBookEntry, condition *MarketCondition, maxOrders int) []*LoanOffer {
 	var offers []*LoanOffer
 	useFRR := ss.config.IsMinDailyLendRateFRR()
+	rules := ss.amounts()
 
 	numSplits := ss.config.SpreadLend
 	if maxOrders > 0 && numSplits > maxOrders {
 		numSplits = maxOrders
 	}
-	if numSplits <= 0 || splitFundsAvailable < ss.config.MinLoan {
+	if numSplits <= 0 || splitFundsAvailable < rules.minLoan {
 		return offers
 	}
 
@@ -201,7 +265,7 @@ func (ss *SmartStrategy) calculateSmartSpreadOffers(splitFundsAvailable float64,
 		numSplits = int(float64(numSplits) * constants.ReducedSplitsMultiplier)
 	}
 
-	orderAmounts := buildOrderAmounts(splitFundsAvailable, numSplits, ss.config.MinLoan, ss.config.MaxLoan)
+	orderAmounts := buildOrderAmounts(splitFundsAvailable, numSplits, rules)
 	if len(orderAmounts) == 0 {
 		return offers
 	}
@@ -213,7 +277,7 @@ func (ss *SmartStrategy) calculateSmartSpreadOffers(splitFundsAvailable float64,
 	gapClimb := (gapTop - gapBottom) / float64(len(orderAmounts))
 	nextLend := gapBottom
 
-	minDailyRate := ss.config.GetMinDailyRateDecimal()
+	minDailyRate := ss.floor()
 
 	log.Printf("智能分散策略 - 實際分散筆數: %d, 深度範圍: %.0f-%.0f, Funding Book數據: %d筆",
 		len(orderAmounts), gapBottom, gapTop, len(fundingBook))
@@ -245,7 +309,7 @@ func (ss *SmartStrategy) calculateSmartSpreadOffers(splitFundsAvailable float64,
 			currentDepthIndex = orderIndex
 		}
 
-		if allocAmount < ss.config.MinLoan {
+		if allocAmount < rules.minLoan {
 			break
 		}
 
@@ -270,6 +334,8 @@ func (ss *SmartStrategy) calculateSmartSpreadOffers(splitFundsAvailable float64,
 		orderIndex++ // 增加訂單索引確保下一個訂單有不同的深度索引
 	}
 
+	offers = applyAllocationProfile(offers, ss.config, rules, fundingBook, maxOrders, ss.absorption)
+
 	return offers
 }
 
@@ -439,16 +505,8 @@ func (ss *SmartStrategy) calculateSyntheticRate(depthIndex int, minDailyRate flo
 
 // calculateSmartPeriod 計算智能期間
 func (ss *SmartStrategy) calculateSmartPeriod(dailyRate float64, condition *MarketCondition) int {
-	oneTwentyThreshold := ss.config.GetOneTwentyDayThresholdDecimal()
-	thirtyThreshold := ss.config.GetThirtyDayThresholdDecimal()
-
 	// 基礎期間邏輯
-	basePeriod := constants.DefaultPeriodDays
-	if ss.config.OneTwentyDayLendRateThreshold > 0 && dailyRate >= oneTwentyThreshold {
-		basePeriod = constants.Period120Days
-	} else if ss.config.ThirtyDayLendRateThreshold > 0 && dailyRate >= thirtyThreshold {
-		basePeriod = constants.Period30Days
-	}
+	basePeriod := thresholdPeriod(ss.config, ss.rateConverter, dailyRate)
 
 	// 根據市場狀況智能調整
 	switch condition.Trend {
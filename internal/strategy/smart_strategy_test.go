@@ -6,6 +6,8 @@ import (
 
 	"github.com/kfrico/BitfinexLendingBot/internal/bitfinex"
 	"github.com/kfrico/BitfinexLendingBot/internal/config"
+	"github.com/kfrico/BitfinexLendingBot/internal/constants"
+	"github.com/kfrico/BitfinexLendingBot/internal/rates"
 )
 
 const floatTolerance = 1e-9
@@ -233,6 +235,36 @@ func TestSmartStrategy_CalculateSmartPeriod(t *testing.T) {
 	}
 }
 
+func TestThresholdPeriod_NetRates(t *testing.T) {
+	cfg := &config.Config{
+		ThirtyDayLendRateThreshold:    0.034, // 淨日利率
+		OneTwentyDayLendRateThreshold: 0.0425,
+		RateThresholdMode:             constants.RateThresholdNet,
+	}
+	converter := rates.NewConverterWithFees(cfg.GetFeeModel())
+
+	tests := []struct {
+		dailyRate float64
+		expected  int
+	}{
+		{dailyRate: 0.00039, expected: 2},   // 淨 0.03315%
+		{dailyRate: 0.00041, expected: 30},  // 淨 0.03485%
+		{dailyRate: 0.00049, expected: 30},  // 淨 0.04165%
+		{dailyRate: 0.00051, expected: 120}, // 淨 0.04335%
+	}
+	for _, tt := range tests {
+		if period := thresholdPeriod(cfg, converter, tt.dailyRate); period != tt.expected {
+			t.Errorf("thresholdPeriod(%v) = %d, want %d", tt.dailyRate, period, tt.expected)
+		}
+	}
+
+	// 毛利率模式下同樣的閾值直接比較
+	cfg.RateThresholdMode = constants.RateThresholdGross
+	if period := thresholdPeriod(cfg, converter, 0.00039); period != 30 {
+		t.Errorf("thresholdPeriod() gross mode = %d, want 30", period)
+	}
+}
+
 func TestSmartStrategy_CalculateSmartOffers(t *testing.T) {
 	cfg := &config.Config{
 		MinLoan:                       150.0,
@@ -313,6 +345,7 @@ func TestSmartStrategy_CalculateSmartOffers(t *testing.T) {
 
 func TestSmartStrategy_OrderLimitReservesSlotsForHighHold(t *testing.T) {
 	cfg := &config.Config{
+		Currency:                      "USD",
 		MinLoan:                       150.0,
 		MaxLoan:                       300.0,
 		SpreadLend:                    15,
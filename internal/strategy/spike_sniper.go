@@ -0,0 +1,265 @@
+package strategy
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/kfrico/BitfinexLendingBot/internal/bitfinex"
+	"github.com/kfrico/BitfinexLendingBot/internal/config"
+	"github.com/kfrico/BitfinexLendingBot/internal/constants"
+	"github.com/kfrico/BitfinexLendingBot/internal/tracker"
+)
+
+// SpikeDetector 以最佳 ask 利率的 EMA 偵測利率飆升
+type SpikeDetector struct {
+	config *config.Config
+
+	mu        sync.Mutex
+	ema       float64
+	samples   int
+	lastRate  float64
+	lastFired time.Time
+}
+
+// NewSpikeDetector 創建利率飆升偵測器
+func NewSpikeDetector(cfg *config.Config) *SpikeDetector {
+	return &SpikeDetector{config: cfg}
+}
+
+// Observe 加入一筆最佳 ask 利率樣本，返回是否觸發飆升與觸發前的 EMA
+// 樣本數未達 EMA 期間或仍在冷卻時間內不會觸發
+func (sd *SpikeDetector) Observe(rate float64, now time.Time) (bool, float64) {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+
+	if rate <= 0 {
+		return false, sd.ema
+	}
+
+	prevEMA := sd.ema
+	sd.lastRate = rate
+	if sd.samples == 0 {
+		sd.ema = rate
+	} else {
+		alpha := 2.0 / (float64(sd.config.SpikeEMAPeriod) + 1.0)
+		sd.ema = alpha*rate + (1-alpha)*sd.ema
+	}
+	sd.samples++
+
+	if sd.samples <= sd.config.SpikeEMAPeriod || prevEMA <= 0 {
+		return false, prevEMA
+	}
+	if rate < prevEMA*(1+sd.config.SpikeThresholdPercent/100) {
+		return false, prevEMA
+	}
+
+	cooldown := time.Duration(sd.config.SpikeCooldownMinutes) * time.Minute
+	if !sd.lastFired.IsZero() && now.Sub(sd.lastFired) < cooldown {
+		return false, prevEMA
+	}
+
+	sd.lastFired = now
+	return true, prevEMA
+}
+
+// Status 返回偵測器狀態摘要
+func (sd *SpikeDetector) Status(now time.Time) string {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+
+	if sd.samples == 0 {
+		return "尚未取得訂單簿樣本"
+	}
+
+	status := fmt.Sprintf("最佳 ask: %.4f%%, EMA: %.4f%% (觸發門檻 %.4f%%)",
+		sd.lastRate*100, sd.ema*100, sd.ema*(1+sd.config.SpikeThresholdPercent/100)*100)
+	if sd.samples <= sd.config.SpikeEMAPeriod {
+		status += fmt.Sprintf("\n暖機中: %d/%d 筆樣本", sd.samples, sd.config.SpikeEMAPeriod)
+	}
+	if !sd.lastFired.IsZero() {
+		status += fmt.Sprintf("\n上次觸發: %s", sd.lastFired.Format("2006-01-02 15:04:05"))
+		cooldown := time.Duration(sd.config.SpikeCooldownMinutes) * time.Minute
+		if remaining := cooldown - now.Sub(sd.lastFired); remaining > 0 {
+			status += fmt.Sprintf("（冷卻剩餘 %s）", remaining.Round(time.Second))
+		}
+	}
+	return status
+}
+
+// bestAskRate 返回訂單簿中最低的 ask 利率
+func bestAskRate(fundingBook []*bitfinex.FundingBookEntry) float64 {
+	asks := askLevels(fundingBook)
+	if len(asks) == 0 {
+		return 0
+	}
+	return asks[0].Rate
+}
+
+// isLiveSpikeOffer 檢查訂單是否為仍在保留時間內的飆升掛單
+func (lb *LendingBot) isLiveSpikeOffer(info tracker.OrderInfo) bool {
+	ttl := time.Duration(lb.config.SpikeOfferTTLMinutes) * time.Minute
+	return info.Spike && lb.now().Sub(info.CreatedAt) < ttl
+}
+
+// spikeReserve 返回主策略應保留給飆升掛單的金額（扣除已在訂單簿上的飆升掛單）
+func (lb *LendingBot) spikeReserve(spikeOnBook float64) float64 {
+	if !lb.config.EnableSpikeSniper {
+		return 0
+	}
+	reserve := lb.config.SpikeCapital - spikeOnBook
+	if reserve < 0 {
+		return 0
+	}
+	return reserve
+}
+
+// CheckRateSpike 輪詢訂單簿，偵測到利率飆升時立即以保留資金掛出長天期訂單
+func (lb *LendingBot) CheckRateSpike() (bool, error) {
+	fundingBook, err := lb.client.GetFundingBook(lb.config.GetFundingSymbol(), constants.SpikeBookDepth)
+	if err != nil {
+		return false, err
+	}
+
+	rate := bestAskRate(fundingBook)
+	spiked, ema := lb.spikeDetector.Observe(rate, lb.now())
+	if !spiked {
+		return false, nil
+	}
+
+	log.Printf("⚡ 偵測到利率飆升：最佳 ask %.4f%% 高於 EMA %.4f%% 達 %.1f%%",
+		rate*100, ema*100, (rate/ema-1)*100)
+
+	placed, amount := lb.placeSpikeTranche(fundingBook, rate)
+
+	if lb.notifyCallback != nil {
+		message := fmt.Sprintf("⚡ 利率飆升\n最佳 ask: %.4f%% (EMA %.4f%%, +%.1f%%)", rate*100, ema*100, (rate/ema-1)*100)
+		if placed > 0 {
+			message += fmt.Sprintf("\n已掛出 %d 筆 %d 天訂單，共 %.2f %s", placed, lb.config.SpikePeriodDays, amount, lb.config.Currency)
+		} else {
+			message += "\n沒有可用的飆升保留資金，未掛單"
+		}
+		if err := lb.notifyCallback(message); err != nil {
+			log.Printf("發送利率飆升通知失敗: %v", err)
+		}
+	}
+
+	if lb.config.SpikeRepriceOffers {
+		log.Println("⚡ 依飆升後的訂單簿重新計算並掛出一般訂單")
+		if err := lb.Execute(); err != nil {
+			return true, err
+		}
+	}
+
+	return true, nil
+}
+
+// placeSpikeTranche 以飆升利率掛出保留資金，返回成功筆數與金額
+func (lb *LendingBot) placeSpikeTranche(fundingBook []*bitfinex.FundingBookEntry, rate float64) (int, float64) {
+	lb.execMu.Lock()
+	defer lb.execMu.Unlock()
+
+	if lb.config.EnableRiskGuard {
+		if halted, reason, _ := lb.riskGuard.IsHalted(); halted {
+			log.Printf("⛔ 風險控管熔斷中，不掛出飆升訂單: %s", reason)
+			return 0, 0
+		}
+	}
+
+	fundingSymbol := lb.config.GetFundingSymbol()
+	offers, err := lb.client.GetFundingOffers(fundingSymbol)
+	if err != nil {
+		log.Printf("取得掛單失敗，不掛出飆升訂單: %v", err)
+		return 0, 0
+	}
+	spikeOnBook := 0.0
+	for _, offer := range offers {
+		if info, ok := lb.orderTracker.GetOrderInfo(offer.ID); ok && info.Spike {
+			spikeOnBook += offer.Amount
+		}
+	}
+
+	fundsAvailable, err := lb.getAvailableFunds()
+	if err != nil {
+		log.Printf("取得餘額錯誤，不掛出飆升訂單: %v", err)
+		return 0, 0
+	}
+
+	amount := lb.spikeReserve(spikeOnBook)
+	if fundsAvailable < amount {
+		amount = fundsAvailable
+	}
+
+	rules := lb.amountRules()
+	amounts := buildOrderAmounts(amount, lb.config.SpikeOrders, rules)
+	if len(amounts) == 0 {
+		log.Printf("飆升保留資金不足（可用 %.2f，已掛出 %.2f）", amount, spikeOnBook)
+		return 0, 0
+	}
+
+	loanOffers := make([]*LoanOffer, 0, len(amounts))
+	for _, allocAmount := range amounts {
+		loanOffers = append(loanOffers, &LoanOffer{
+			Amount:   allocAmount,
+			Rate:     rate,
+			Period:   lb.config.SpikePeriodDays,
+			Strategy: constants.StrategySpike,
+		})
+	}
+
+	// 短暫飆升不應鎖住超過上限的長期資金
+	if lb.config.HasPeriodExposureLimits() {
+		lb.applyPeriodLimits(loanOffers)
+	}
+
+	if lb.config.EnableRiskGuard {
+		var ok bool
+		if loanOffers, ok = lb.applyRiskGuard(loanOffers, fundingBook, nil); !ok {
+			return 0, 0
+		}
+	}
+
+	placed, total := 0, 0.0
+	for _, offer := range loanOffers {
+		offer.Amount = rules.floor(offer.Amount)
+		if !lb.rateConverter.ValidateDailyRate(offer.Rate) {
+			log.Printf("跳過無效利率: %.6f", offer.Rate)
+			continue
+		}
+
+		if lb.config.IsDryRun() {
+			log.Printf("🧪 [測試模式] 模擬飆升下單 => Rate: %.6f%%, Amount: %.4f, Period: %d",
+				lb.rateConverter.DecimalToPercentage(offer.Rate), offer.Amount, offer.Period)
+		} else {
+			log.Printf("⚡ 飆升下單 => Rate: %.6f%%, Amount: %.4f, Period: %d",
+				lb.rateConverter.DecimalToPercentage(offer.Rate), offer.Amount, offer.Period)
+
+			orderID, err := lb.client.SubmitFundingOffer(fundingSymbol, offer.Amount, offer.Rate, offer.Period, false)
+			if err != nil {
+				log.Printf("飆升下單失敗: %v", err)
+				continue
+			}
+			lb.orderTracker.TrackOrder(orderID, tracker.OrderInfo{
+				CreatedAt: lb.now(),
+				Spike:     true,
+				Strategy:  offer.Strategy,
+				Amount:    offer.Amount,
+				Rate:      offer.Rate,
+				Period:    offer.Period,
+			})
+			log.Printf("成功創建飆升訂單 ID: %d，已加入追蹤", orderID)
+		}
+
+		lb.recordPlacement(offer)
+		placed++
+		total += offer.Amount
+	}
+
+	return placed, total
+}
+
+// GetSpikeSniperStatus 獲取利率飆升狙擊狀態
+func (lb *LendingBot) GetSpikeSniperStatus() string {
+	return lb.spikeDetector.Status(lb.now())
+}
@@ -0,0 +1,188 @@
+package strategy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kfrico/BitfinexLendingBot/internal/bitfinex"
+	"github.com/kfrico/BitfinexLendingBot/internal/config"
+	"github.com/kfrico/BitfinexLendingBot/internal/simulator"
+)
+
+// bookMarket 可調整最佳 ask 利率的測試市場
+type bookMarket struct {
+	bestAsk float64
+}
+
+func (m *bookMarket) FundingBook(symbol string, limit int, at time.Time) ([]*bitfinex.FundingBookEntry, error) {
+	return []*bitfinex.FundingBookEntry{
+		{Rate: m.bestAsk, Amount: 5000, Period: 2, Count: 1},
+		{Rate: m.bestAsk * 1.1, Amount: 5000, Period: 2, Count: 1},
+		{Rate: m.bestAsk * 0.9, Amount: -5000, Period: 2, Count: 1},
+	}, nil
+}
+
+func (m *bookMarket) FundingRate(symbol string, at time.Time) (float64, error) {
+	return m.bestAsk, nil
+}
+
+func (m *bookMarket) Candles(symbol, timeFrame string, limit int, at time.Time) ([]*bitfinex.Candle, error) {
+	return nil, nil
+}
+
+func newSpikeTestConfig() *config.Config {
+	cfg := &config.Config{
+		Currency:              "USD",
+		MinLoan:               150,
+		MinDailyLendRate:      0.01,
+		SpreadLend:            2,
+		GapBottom:             0,
+		GapTop:                1,
+		EnableSpikeSniper:     true,
+		SpikeEMAPeriod:        3,
+		SpikeThresholdPercent: 30,
+		SpikeCapital:          1000,
+		SpikeCooldownMinutes:  30,
+		SpikeOfferTTLMinutes:  20,
+	}
+	cfg.ApplyDefaults()
+	return cfg
+}
+
+func TestSpikeDetector_WarmupThresholdAndCooldown(t *testing.T) {
+	detector := NewSpikeDetector(newSpikeTestConfig())
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// 暖機期間即使大幅跳升也不觸發
+	for i, rate := range []float64{0.0004, 0.0004, 0.0008} {
+		if spiked, _ := detector.Observe(rate, now.Add(time.Duration(i)*time.Second)); spiked {
+			t.Fatalf("sample %d triggered during warm-up", i)
+		}
+	}
+	for i := 0; i < 10; i++ {
+		detector.Observe(0.0004, now)
+	}
+
+	if spiked, _ := detector.Observe(0.00045, now); spiked {
+		t.Error("rise below threshold should not trigger")
+	}
+	spiked, ema := detector.Observe(0.0008, now)
+	if !spiked {
+		t.Fatal("rate 100% above EMA should trigger")
+	}
+	if ema < 0.0004 || ema > 0.00045 {
+		t.Errorf("EMA before spike = %v, want about 0.0004", ema)
+	}
+
+	if spiked, _ := detector.Observe(0.002, now.Add(10*time.Minute)); spiked {
+		t.Error("spike within cooldown should not trigger")
+	}
+	for i := 0; i < 10; i++ {
+		detector.Observe(0.0004, now.Add(31*time.Minute))
+	}
+	if spiked, _ := detector.Observe(0.0008, now.Add(31*time.Minute)); !spiked {
+		t.Error("spike after cooldown should trigger")
+	}
+}
+
+func TestLendingBot_CheckRateSpikePlacesReservedTranche(t *testing.T) {
+	cfg := newSpikeTestConfig()
+	market := &bookMarket{bestAsk: 0.0004}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	fill, _ := simulator.NewFillModel("touch", 1)
+	exchange := simulator.NewExchange(market, simulator.Options{
+		Currency:       "USD",
+		InitialBalance: 5000,
+		FillModel:      fill,
+		FillTimeFrame:  "15m",
+		FillInterval:   15 * time.Minute,
+		Start:          now,
+		Clock:          func() time.Time { return now },
+	})
+
+	bot := NewLendingBot(cfg, exchange)
+	bot.SetClock(exchange.Now, func(time.Duration) {})
+
+	// 主策略保留飆升資金，只動用 4000
+	if err := bot.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if total := offeredAmount(t, exchange); total != 4000 {
+		t.Fatalf("main strategy offered %.2f, want 4000 (1000 reserved)", total)
+	}
+
+	for i := 0; i < 5; i++ {
+		if spiked, err := bot.CheckRateSpike(); err != nil || spiked {
+			t.Fatalf("CheckRateSpike() = %v, %v during calm market", spiked, err)
+		}
+	}
+
+	market.bestAsk = 0.001
+	spiked, err := bot.CheckRateSpike()
+	if err != nil || !spiked {
+		t.Fatalf("CheckRateSpike() = %v, %v, want spike", spiked, err)
+	}
+
+	offers, _ := exchange.GetFundingOffers("fUSD")
+	var spikeOffer *bitfinex.FundingOffer
+	for _, offer := range offers {
+		if offer.Period == cfg.SpikePeriodDays {
+			spikeOffer = offer
+		}
+	}
+	if spikeOffer == nil || spikeOffer.Amount != 1000 || spikeOffer.Rate != 0.001 {
+		t.Fatalf("spike offer = %+v, want 1000 at 0.1%% for %d days", spikeOffer, cfg.SpikePeriodDays)
+	}
+
+	// 保留時間內主策略不取消飆升掛單，也不再保留資金
+	now = now.Add(10 * time.Minute)
+	if err := bot.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if !hasOffer(t, exchange, spikeOffer.ID) {
+		t.Error("spike offer cancelled before TTL")
+	}
+	if total := offeredAmount(t, exchange); total != 5000 {
+		t.Errorf("offered %.2f, want 5000 including the spike tranche", total)
+	}
+
+	// 超過保留時間後由主策略取消並重新保留資金
+	now = now.Add(15 * time.Minute)
+	if err := bot.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if hasOffer(t, exchange, spikeOffer.ID) {
+		t.Error("spike offer should be cancelled after TTL")
+	}
+	if total := offeredAmount(t, exchange); total != 4000 {
+		t.Errorf("offered %.2f after TTL, want 4000", total)
+	}
+}
+
+func offeredAmount(t *testing.T, exchange *simulator.Exchange) float64 {
+	t.Helper()
+	offers, err := exchange.GetFundingOffers("fUSD")
+	if err != nil {
+		t.Fatalf("GetFundingOffers() error = %v", err)
+	}
+	total := 0.0
+	for _, offer := range offers {
+		total += offer.Amount
+	}
+	return total
+}
+
+func hasOffer(t *testing.T, exchange *simulator.Exchange, id int64) bool {
+	t.Helper()
+	offers, err := exchange.GetFundingOffers("fUSD")
+	if err != nil {
+		t.Fatalf("GetFundingOffers() error = %v", err)
+	}
+	for _, offer := range offers {
+		if offer.ID == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,442 @@
+package strategy
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kfrico/BitfinexLendingBot/internal/bitfinex"
+	"github.com/kfrico/BitfinexLendingBot/internal/config"
+	"github.com/kfrico/BitfinexLendingBot/internal/constants"
+	"github.com/kfrico/BitfinexLendingBot/internal/tracker"
+)
+
+// blendStrategyOrder 權重相同時的策略順序（與單一策略模式的優先級一致）
+var blendStrategyOrder = []string{constants.StrategyKline, constants.StrategySmart, constants.StrategyTraditional}
+
+// blendShare 組合模式中單一策略分到的資金與分散單筆數
+type blendShare struct {
+	Strategy string
+	Weight   float64 // 正規化後的權重 (0-1)
+	Funds    float64
+	Slots    int
+}
+
+// ActiveStrategyName 返回配置目前使用的策略名稱（組合模式 > K線 > 智能 > 傳統）
+func ActiveStrategyName(cfg *config.Config) string {
+	switch {
+	case cfg.EnableStrategyBlend:
+		return constants.StrategyBlend
+	case cfg.EnableKlineStrategy:
+		return constants.StrategyKline
+	case cfg.EnableSmartStrategy:
+		return constants.StrategySmart
+	default:
+		return constants.StrategyTraditional
+	}
+}
+
+// planBlendShares 依權重分配資金與筆數
+// 分到的資金不足最小貸出金額或分不到筆數的策略會剔除（從權重最小的開始），其份額由其餘策略依權重分攤
+func planBlendShares(weights map[string]float64, funds float64, slots int, rules amountRules) []blendShare {
+	var names []string
+	for _, name := range blendStrategyOrder {
+		if weights[name] > 0 {
+			names = append(names, name)
+		}
+	}
+	sort.SliceStable(names, func(i, j int) bool {
+		return weights[names[i]] > weights[names[j]]
+	})
+
+	for len(names) > 0 {
+		total := 0.0
+		rawWeights := make([]float64, len(names))
+		for i, name := range names {
+			rawWeights[i] = weights[name]
+			total += weights[name]
+		}
+		slotCounts := apportionSlots(slots, rawWeights)
+
+		shares := make([]blendShare, len(names))
+		feasible := true
+		for i, name := range names {
+			weight := rawWeights[i] / total
+			shares[i] = blendShare{
+				Strategy: name,
+				Weight:   weight,
+				Funds:    rules.floor(funds * weight),
+				Slots:    slotCounts[i],
+			}
+			if shares[i].Funds < rules.minLoan || shares[i].Slots == 0 {
+				feasible = false
+			}
+		}
+		if feasible {
+			return shares
+		}
+
+		// 剔除權重最小的策略後重新分配
+		names = names[:len(names)-1]
+	}
+
+	return nil
+}
+
+// apportionSlots 以最大餘數法依權重分配筆數
+func apportionSlots(total int, weights []float64) []int {
+	counts := make([]int, len(weights))
+	if total <= 0 || len(weights) == 0 {
+		return counts
+	}
+
+	sum := 0.0
+	for _, weight := range weights {
+		sum += weight
+	}
+
+	type remainder struct {
+		index int
+		value float64
+	}
+	remainders := make([]remainder, len(weights))
+	assigned := 0
+	for i, weight := range weights {
+		exact := float64(total) * weight / sum
+		counts[i] = int(math.Floor(exact))
+		assigned += counts[i]
+		remainders[i] = remainder{index: i, value: exact - float64(counts[i])}
+	}
+
+	sort.SliceStable(remainders, func(i, j int) bool {
+		return remainders[i].value > remainders[j].value
+	})
+	for i := 0; assigned < total; i++ {
+		counts[remainders[i%len(remainders)].index]++
+		assigned++
+	}
+
+	return counts
+}
+
+// dedupeBlendOffers 合併利率幾乎相同的分散單
+// 利率相差在 tolerance（相對比例）內、期間與 FRR 模式相同，且合併後不超過 MAX_LOAN 時合併，
+// 合併後沿用金額較大一方的利率、期間與策略
+func dedupeBlendOffers(offers []*LoanOffer, tolerance float64, rules amountRules) []*LoanOffer {
+	sorted := append([]*LoanOffer(nil), offers...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].UseFRR != sorted[j].UseFRR {
+			return !sorted[i].UseFRR
+		}
+		return sorted[i].Rate < sorted[j].Rate
+	})
+
+	var result []*LoanOffer
+	for _, offer := range sorted {
+		if len(result) > 0 {
+			last := result[len(result)-1]
+			sameTerms := last.UseFRR == offer.UseFRR && last.Period == offer.Period
+			closeRate := math.Abs(offer.Rate-last.Rate) <= tolerance*math.Max(offer.Rate, last.Rate)
+			withinMax := rules.maxLoan <= 0 || last.Amount+offer.Amount <= rules.maxLoan
+			if sameTerms && closeRate && withinMax {
+				if offer.Amount > last.Amount {
+					last.Rate = offer.Rate
+					last.Strategy = offer.Strategy
+				}
+				last.Amount = rules.floor(last.Amount + offer.Amount)
+				continue
+			}
+		}
+
+		merged := *offer
+		result = append(result, &merged)
+	}
+
+	return result
+}
+
+// calculateBlendOffers 依 STRATEGY_WEIGHTS 將資金分給多個策略，合併成一組訂單
+// 高額持有單先以全部資金計算一次，剩餘資金與 SPREAD_LEND 筆數（受 ORDER_LIMIT 限制）再依權重分配
+func (lb *LendingBot) calculateBlendOffers(fundsAvailable float64, fundingBook []*bitfinex.FundingBookEntry) []*LoanOffer {
+	var loanOffers []*LoanOffer
+	rules := lb.amountRules()
+
+	if fundsAvailable < rules.minLoan {
+		return loanOffers
+	}
+
+	splitFundsAvailable := fundsAvailable
+
+	// 高額持有策略
+	if lb.config.HighHoldAmount > rules.minLoan {
+		loanOffers = append(loanOffers, lb.calculateHighHoldOffers(&splitFundsAvailable)...)
+	}
+
+	if splitFundsAvailable < rules.minLoan {
+		return loanOffers
+	}
+	remainingSlots := lb.getRemainingOrderSlots(len(loanOffers))
+	if remainingSlots == 0 {
+		return loanOffers
+	}
+	slots := lb.config.SpreadLend
+	if remainingSlots > 0 && remainingSlots < slots {
+		slots = remainingSlots
+	}
+
+	shares := planBlendShares(lb.config.StrategyWeights, splitFundsAvailable, slots, rules)
+	if len(shares) == 0 {
+		log.Printf("策略組合：資金 %.2f 不足以分配給任何策略", splitFundsAvailable)
+		return loanOffers
+	}
+
+	var spreadOffers []*LoanOffer
+	for _, share := range shares {
+		log.Printf("策略組合 - %s: 權重 %.1f%%, 資金 %.2f, 最多 %d 筆",
+			share.Strategy, share.Weight*100, share.Funds, share.Slots)
+
+		offers := lb.calculateStrategySpreadOffers(share.Strategy, share.Funds, share.Slots, fundingBook)
+		for _, offer := range offers {
+			offer.Strategy = share.Strategy
+		}
+		spreadOffers = append(spreadOffers, offers...)
+	}
+
+	deduped := dedupeBlendOffers(spreadOffers, lb.config.BlendDedupePercent/100, rules)
+	if merged := len(spreadOffers) - len(deduped); merged > 0 {
+		log.Printf("策略組合：合併 %d 筆利率相近的訂單", merged)
+	}
+
+	return append(loanOffers, deduped...)
+}
+
+// calculateStrategySpreadOffers 以指定策略計算分散單
+func (lb *LendingBot) calculateStrategySpreadOffers(name string, funds float64, maxOrders int, fundingBook []*bitfinex.FundingBookEntry) []*LoanOffer {
+	switch name {
+	case constants.StrategyKline:
+		return lb.calculateKlineSpreadOffers(funds, lb.calculateKlineTargetRate(), fundingBook, maxOrders)
+	case constants.StrategySmart:
+		return lb.smartStrategy.CalculateSmartSpreadOffers(funds, fundingBook, maxOrders)
+	default:
+		return lb.calculateSpreadOffers(funds, fundingBook, maxOrders)
+	}
+}
+
+// labelOffers 為尚未標記來源的訂單標記策略，高額持有單固定標記為 highhold
+func labelOffers(offers []*LoanOffer, strategyName string) {
+	for _, offer := range offers {
+		if offer.Strategy != "" {
+			continue
+		}
+		if offer.HighHold {
+			offer.Strategy = constants.StrategyHighHold
+		} else {
+			offer.Strategy = strategyName
+		}
+	}
+}
+
+// StrategyStats 單一策略的掛單與成交統計
+type StrategyStats struct {
+	PlacedCount   int
+	PlacedAmount  float64
+	FilledCount   int
+	FilledAmount  float64
+	FilledRateSum float64 // 成交金額 × 日利率，用於計算平均成交利率
+}
+
+// AvgFillRate 返回金額加權平均成交日利率
+func (s StrategyStats) AvgFillRate() float64 {
+	if s.FilledAmount <= 0 {
+		return 0
+	}
+	return s.FilledRateSum / s.FilledAmount
+}
+
+// FillRatio 返回成交金額佔掛單金額的比例
+func (s StrategyStats) FillRatio() float64 {
+	if s.PlacedAmount <= 0 {
+		return 0
+	}
+	return s.FilledAmount / s.PlacedAmount
+}
+
+// strategyStatsBook 依策略累計掛單與成交
+type strategyStatsBook struct {
+	mu    sync.Mutex
+	stats map[string]*StrategyStats
+}
+
+func newStrategyStatsBook() *strategyStatsBook {
+	return &strategyStatsBook{stats: make(map[string]*StrategyStats)}
+}
+
+func (b *strategyStatsBook) entry(name string) *StrategyStats {
+	if name == "" {
+		name = constants.StrategyTraditional
+	}
+	stats, ok := b.stats[name]
+	if !ok {
+		stats = &StrategyStats{}
+		b.stats[name] = stats
+	}
+	return stats
+}
+
+// RecordPlaced 記錄一筆掛單
+func (b *strategyStatsBook) RecordPlaced(name string, amount float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	stats := b.entry(name)
+	stats.PlacedCount++
+	stats.PlacedAmount += amount
+}
+
+// RecordFilled 記錄成交金額
+func (b *strategyStatsBook) RecordFilled(name string, amount float64, rate float64) {
+	if amount <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	stats := b.entry(name)
+	stats.FilledCount++
+	stats.FilledAmount += amount
+	stats.FilledRateSum += amount * rate
+}
+
+// Snapshot 返回統計的副本
+func (b *strategyStatsBook) Snapshot() map[string]StrategyStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	snapshot := make(map[string]StrategyStats, len(b.stats))
+	for name, stats := range b.stats {
+		snapshot[name] = *stats
+	}
+	return snapshot
+}
+
+// recordFilledOrders 比對訂單簿與追蹤中的訂單，已不在訂單簿上的追蹤訂單以活躍借貸確認成交金額：
+// 有對應借貸時記為成交（或部分成交），沒有時視為被手動或交易所取消，不計入策略統計與成交比例
+// 無法取得活躍借貸時保留追蹤，下次執行再確認
+// 返回本週期已確認的掛單與成交金額，供自適應利率加成計算成交比例
+func (lb *LendingBot) recordFilledOrders(offers []*bitfinex.FundingOffer) fillCycle {
+	var cycle fillCycle
+	onBook := make(map[int64]bool, len(offers))
+	for _, offer := range offers {
+		onBook[offer.ID] = true
+	}
+
+	var missing []int64
+	for _, orderID := range lb.orderTracker.GetTrackedOrders() {
+		if !onBook[orderID] {
+			missing = append(missing, orderID)
+		}
+	}
+	if len(missing) == 0 {
+		return cycle
+	}
+
+	credits, err := lb.client.GetFundingCredits(lb.config.GetFundingSymbol())
+	if err != nil {
+		log.Printf("取得借貸訂單失敗，%d 筆已離開訂單簿的訂單下次再確認成交: %v", len(missing), err)
+		return cycle
+	}
+
+	claimed := make(map[int64]bool)
+	for _, orderID := range missing {
+		info, ok := lb.orderTracker.GetOrderInfo(orderID)
+		if !ok {
+			continue
+		}
+
+		filled, filledAt := matchOfferCredits(info, credits, claimed)
+		if filled <= 0 {
+			log.Printf("訂單 ID: %d 已離開訂單簿但沒有對應的借貸，視為已取消", orderID)
+			lb.orderTracker.ResolveOrder(orderID, tracker.OutcomeCancelled, 0, lb.now())
+			continue
+		}
+
+		outcome := tracker.OutcomeFilled
+		if filled < info.Amount-1e-9 {
+			outcome = tracker.OutcomePartiallyFilled
+		}
+		lb.strategyStats.RecordFilled(info.Strategy, filled, info.Rate)
+		lb.recordAbsorptionFill(info.Rate, filled, info.CreatedAt, filledAt)
+		cycle.record(info.Strategy, info.Amount, filled)
+		lb.orderTracker.ResolveOrder(orderID, outcome, filled, lb.now())
+	}
+	return cycle
+}
+
+// matchOfferCredits 找出由掛單成交產生的活躍借貸，返回成交金額（不超過掛單金額）與最早的成交時間
+// 借貸需在掛單建立後開始、期間相同，固定利率單的利率需相同；已被其他訂單認領的借貸不重複計算
+func matchOfferCredits(info tracker.OrderInfo, credits []*bitfinex.FundingCredit, claimed map[int64]bool) (float64, time.Time) {
+	createdMs := info.CreatedAt.Add(-constants.CreditMatchSlack).UnixNano() / int64(time.Millisecond)
+
+	filled := 0.0
+	var filledAt time.Time
+	for _, credit := range credits {
+		if claimed[credit.ID] || credit.MTSOpened < createdMs {
+			continue
+		}
+		if info.Period > 0 && credit.Period != int64(info.Period) {
+			continue
+		}
+		if !strings.EqualFold(credit.RateType, "frr") && math.Abs(credit.Rate-info.Rate) > 1e-10 {
+			continue
+		}
+		amount := math.Abs(credit.Amount)
+		if filled+amount > info.Amount+1e-9 {
+			continue
+		}
+
+		claimed[credit.ID] = true
+		filled += amount
+		opened := time.Unix(0, credit.MTSOpened*int64(time.Millisecond))
+		if filledAt.IsZero() || opened.Before(filledAt) {
+			filledAt = opened
+		}
+	}
+	return filled, filledAt
+}
+
+// recordPartialFill 取消訂單前記錄已部分成交的金額
+// 部分成交的時間無法從訂單簿得知，不計入吸收量模型的成交速度
+func (lb *LendingBot) recordPartialFill(info tracker.OrderInfo, remaining float64) {
+	lb.strategyStats.RecordFilled(info.Strategy, info.Amount-remaining, info.Rate)
+}
+
+// GetStrategyStats 獲取各策略的掛單與成交統計
+func (lb *LendingBot) GetStrategyStats() map[string]StrategyStats {
+	return lb.strategyStats.Snapshot()
+}
+
+// GetStrategyStatsReport 獲取各策略績效比較報告（供 Telegram 指令使用）
+func (lb *LendingBot) GetStrategyStatsReport() string {
+	snapshot := lb.strategyStats.Snapshot()
+	if len(snapshot) == 0 {
+		return "尚無掛單記錄"
+	}
+
+	names := make([]string, 0, len(snapshot))
+	for name := range snapshot {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	report := ""
+	for i, name := range names {
+		stats := snapshot[name]
+		if i > 0 {
+			report += "\n"
+		}
+		report += fmt.Sprintf("%s: 掛單 %d 筆 %.2f, 成交 %.2f (%.1f%%), 平均成交利率 %.4f%%",
+			name, stats.PlacedCount, stats.PlacedAmount, stats.FilledAmount, stats.FillRatio()*100, stats.AvgFillRate()*100)
+	}
+	return report
+}
@@ -0,0 +1,205 @@
+package strategy
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kfrico/BitfinexLendingBot/internal/bitfinex"
+	"github.com/kfrico/BitfinexLendingBot/internal/config"
+	"github.com/kfrico/BitfinexLendingBot/internal/constants"
+	"github.com/kfrico/BitfinexLendingBot/internal/simulator"
+	"github.com/kfrico/BitfinexLendingBot/internal/tracker"
+)
+
+func TestApportionSlots(t *testing.T) {
+	tests := []struct {
+		total    int
+		weights  []float64
+		expected []int
+	}{
+		{10, []float64{50, 30, 20}, []int{5, 3, 2}},
+		{3, []float64{50, 30, 20}, []int{1, 1, 1}},
+		{2, []float64{50, 30, 20}, []int{1, 1, 0}},
+		{4, []float64{1, 1, 1}, []int{2, 1, 1}},
+		{0, []float64{1, 1}, []int{0, 0}},
+	}
+
+	for _, tt := range tests {
+		if actual := apportionSlots(tt.total, tt.weights); !reflect.DeepEqual(actual, tt.expected) {
+			t.Errorf("apportionSlots(%d, %v) = %v, want %v", tt.total, tt.weights, actual, tt.expected)
+		}
+	}
+}
+
+func TestPlanBlendShares(t *testing.T) {
+	weights := map[string]float64{
+		constants.StrategyKline:       50,
+		constants.StrategySmart:       30,
+		constants.StrategyTraditional: 20,
+	}
+
+	shares := planBlendShares(weights, 10000, 10, usdAmountRules)
+	if len(shares) != 3 {
+		t.Fatalf("planBlendShares() returned %d shares, want 3", len(shares))
+	}
+	want := []blendShare{
+		{Strategy: constants.StrategyKline, Weight: 0.5, Funds: 5000, Slots: 5},
+		{Strategy: constants.StrategySmart, Weight: 0.3, Funds: 3000, Slots: 3},
+		{Strategy: constants.StrategyTraditional, Weight: 0.2, Funds: 2000, Slots: 2},
+	}
+	for i := range want {
+		if shares[i].Strategy != want[i].Strategy || shares[i].Funds != want[i].Funds || shares[i].Slots != want[i].Slots {
+			t.Errorf("share %d = %+v, want %+v", i, shares[i], want[i])
+		}
+	}
+
+	// 資金不足時剔除權重最小的策略，其份額由其餘策略分攤
+	shares = planBlendShares(weights, 600, 10, usdAmountRules)
+	if len(shares) != 2 || shares[0].Funds != 375 || shares[1].Funds != 225 {
+		t.Errorf("planBlendShares() with small funds = %+v, want kline 375 and smart 225", shares)
+	}
+
+	// 筆數不足時同樣剔除
+	shares = planBlendShares(weights, 10000, 2, usdAmountRules)
+	if len(shares) != 2 || shares[0].Slots != 1 || shares[1].Slots != 1 {
+		t.Errorf("planBlendShares() with 2 slots = %+v, want two strategies with one slot each", shares)
+	}
+
+	if shares := planBlendShares(weights, 100, 10, usdAmountRules); shares != nil {
+		t.Errorf("planBlendShares() below min loan = %+v, want nil", shares)
+	}
+}
+
+func TestDedupeBlendOffers(t *testing.T) {
+	offers := []*LoanOffer{
+		{Amount: 300, Rate: 0.00050, Period: 2, Strategy: constants.StrategyKline},
+		{Amount: 200, Rate: 0.00030, Period: 2, Strategy: constants.StrategyTraditional},
+		{Amount: 400, Rate: 0.000502, Period: 2, Strategy: constants.StrategySmart},
+		{Amount: 500, Rate: 0.000503, Period: 30, Strategy: constants.StrategySmart},
+		{Amount: 200, Rate: 0.000301, Period: 2, Strategy: constants.StrategyKline},
+	}
+
+	result := dedupeBlendOffers(offers, 0.01, amountRules{precision: 2})
+	if len(result) != 3 {
+		t.Fatalf("dedupeBlendOffers() returned %d offers, want 3: %+v", len(result), result)
+	}
+	if result[0].Amount != 400 || result[0].Rate != 0.00030 {
+		t.Errorf("first offer = %+v, want 400 at 0.0003", result[0])
+	}
+	if result[1].Amount != 700 || result[1].Strategy != constants.StrategySmart || result[1].Rate != 0.000502 {
+		t.Errorf("merged offer = %+v, want 700 from smart at 0.000502", result[1])
+	}
+	if result[2].Period != 30 || result[2].Amount != 500 {
+		t.Errorf("different period should not merge, got %+v", result[2])
+	}
+	if offers[0].Amount != 300 {
+		t.Error("dedupeBlendOffers() should not modify the input offers")
+	}
+
+	// 合併後超過 MAX_LOAN 時保留兩筆
+	if result := dedupeBlendOffers(offers[:3], 0.01, amountRules{precision: 2, maxLoan: 600}); len(result) != 3 {
+		t.Errorf("dedupeBlendOffers() with max loan returned %d offers, want 3", len(result))
+	}
+}
+
+func TestLendingBot_ExecuteStrategyBlend(t *testing.T) {
+	cfg := &config.Config{
+		Currency:            "USD",
+		OrderLimit:          6,
+		MinLoan:             150,
+		MinDailyLendRate:    0.01,
+		SpreadLend:          10,
+		GapBottom:           0,
+		GapTop:              2,
+		KlineTimeFrame:      "15m",
+		KlinePeriod:         4,
+		KlineSmoothMethod:   "max",
+		EnableStrategyBlend: true,
+		StrategyWeights: map[string]float64{
+			constants.StrategyKline:       50,
+			constants.StrategyTraditional: 50,
+		},
+	}
+	cfg.ApplyDefaults()
+
+	market := &bookMarket{bestAsk: 0.0004}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	fill, _ := simulator.NewFillModel("touch", 1)
+	exchange := simulator.NewExchange(market, simulator.Options{
+		Currency:       "USD",
+		InitialBalance: 3000,
+		FillModel:      fill,
+		FillTimeFrame:  "15m",
+		FillInterval:   15 * time.Minute,
+		Start:          now,
+		Clock:          func() time.Time { return now },
+	})
+
+	bot := NewLendingBot(cfg, exchange)
+	bot.SetClock(exchange.Now, func(time.Duration) {})
+
+	if err := bot.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	offers, _ := exchange.GetFundingOffers("fUSD")
+	if len(offers) == 0 || len(offers) > cfg.OrderLimit {
+		t.Fatalf("placed %d offers, want between 1 and ORDER_LIMIT %d", len(offers), cfg.OrderLimit)
+	}
+
+	stats := bot.GetStrategyStats()
+	if stats[constants.StrategyKline].PlacedCount == 0 || stats[constants.StrategyTraditional].PlacedCount == 0 {
+		t.Fatalf("expected offers from both strategies, got %+v", stats)
+	}
+	total := stats[constants.StrategyKline].PlacedAmount + stats[constants.StrategyTraditional].PlacedAmount
+	if total != 3000 {
+		t.Errorf("placed %.2f in total, want 3000", total)
+	}
+	for _, offer := range offers {
+		info, ok := bot.orderTracker.GetOrderInfo(offer.ID)
+		if !ok || (info.Strategy != constants.StrategyKline && info.Strategy != constants.StrategyTraditional) {
+			t.Errorf("offer %d tracked as %+v, want a blend strategy label", offer.ID, info)
+		}
+	}
+
+	// 已不在訂單簿上且沒有對應借貸的訂單視為取消，不計入成交
+	bot.orderTracker.TrackOrder(998, tracker.OrderInfo{CreatedAt: now, Strategy: constants.StrategyKline, Amount: 300, Rate: 0.0006, Period: 2})
+	// 有對應借貸的訂單依借貸金額計入成交
+	bot.orderTracker.TrackOrder(999, tracker.OrderInfo{CreatedAt: now, Strategy: constants.StrategyKline, Amount: 200, Rate: 0.0005, Period: 2})
+	opened := now.Add(time.Minute).UnixNano() / int64(time.Millisecond)
+	bot.client = &creditsExchange{Exchange: exchange, credits: []*bitfinex.FundingCredit{
+		{ID: 1, Amount: 500, Rate: 0.0005, Period: 2, MTSOpened: opened},                               // 金額超過掛單
+		{ID: 2, Amount: 200, Rate: 0.0005, Period: 2, MTSOpened: now.Add(-time.Hour).UnixNano() / 1e6}, // 掛單前已開始
+		{ID: 3, Amount: 120, Rate: 0.0005, Period: 2, MTSOpened: opened},
+		{ID: 4, Amount: 80, Rate: 0.0005, Period: 2, MTSOpened: opened},
+	}}
+	cycle := bot.recordFilledOrders(offers)
+	kline := bot.GetStrategyStats()[constants.StrategyKline]
+	if kline.FilledAmount != 200 || kline.AvgFillRate() != 0.0005 {
+		t.Errorf("kline fills = %+v, want 200 filled at 0.05%%", kline)
+	}
+	if cycle.placed != 200 || cycle.filled != 200 {
+		t.Errorf("fill cycle = %+v, want only the confirmed order", cycle)
+	}
+	if bot.orderTracker.IsTrackedOrder(998) || bot.orderTracker.IsTrackedOrder(999) {
+		t.Error("orders off the book should no longer be tracked")
+	}
+	outcomes := map[int64]string{}
+	for _, record := range bot.orderTracker.GetHistory() {
+		outcomes[record.ID] = record.Outcome
+	}
+	if outcomes[998] != tracker.OutcomeCancelled || outcomes[999] != tracker.OutcomeFilled {
+		t.Errorf("outcomes = %v, want 998 cancelled and 999 filled", outcomes)
+	}
+}
+
+// creditsExchange 以固定的活躍借貸取代模擬交易所的借貸查詢
+type creditsExchange struct {
+	*simulator.Exchange
+	credits []*bitfinex.FundingCredit
+}
+
+func (e *creditsExchange) GetFundingCredits(symbol string) ([]*bitfinex.FundingCredit, error) {
+	return e.credits, nil
+}
@@ -0,0 +1,233 @@
+package strategy
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/kfrico/BitfinexLendingBot/internal/config"
+	"github.com/kfrico/BitfinexLendingBot/internal/constants"
+)
+
+// UtilizationSample 一次資金使用率取樣
+type UtilizationSample struct {
+	Time    time.Time
+	Lent    float64 // 已借出金額
+	Offered float64 // 掛單中金額
+	Idle    float64 // 閒置金額（可用餘額扣除保留金額）
+}
+
+// Total 資金錢包可運用的總金額
+func (s UtilizationSample) Total() float64 {
+	return s.Lent + s.Offered + s.Idle
+}
+
+// Utilization 借出比例（0-1），沒有資金時為 0
+func (s UtilizationSample) Utilization() float64 {
+	total := s.Total()
+	if total <= 0 {
+		return 0
+	}
+	return s.Lent / total
+}
+
+// UtilizationController 資金使用率控制器：追蹤時間窗口內的借出比例，
+// 閒置資金持續超過門檻時觸發掛單，平均使用率持續低於目標時逐步調降最低日利率
+type UtilizationController struct {
+	mu           sync.Mutex
+	samples      []UtilizationSample // 時間窗口內的取樣
+	idleSince    time.Time           // 閒置資金開始超過門檻的時間，零值表示目前未超過
+	floorCut     float64             // 最低日利率調降比例（0-1）
+	lastAdjust   time.Time           // 上次評估利率下限的時間
+	sweepPending bool                // 已觸發掛單，等待下一次取樣確認效果
+	sweepIdle    float64             // 觸發掛單時的閒置金額
+	backoff      int                 // 寬限時間倍數：觸發後閒置資金未減少（策略無法掛出）時加倍
+}
+
+// NewUtilizationController 創建資金使用率控制器
+func NewUtilizationController() *UtilizationController {
+	return &UtilizationController{backoff: 1}
+}
+
+// Observe 記錄一次取樣並返回是否應立即掛出閒置資金
+// 上次觸發後閒置資金幾乎未減少時（熔斷、期間上限或金額上限使策略無法掛出），寬限時間加倍避免反覆重新掛單
+// 每經過一個時間窗口評估一次平均使用率：低於目標時利率下限多調降一步，達到目標時回升一步
+// halted 為 true 時（風險控管熔斷中）只記錄取樣，不觸發掛單
+func (u *UtilizationController) Observe(sample UtilizationSample, cfg *config.Config, halted bool) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	now := sample.Time
+	window := time.Duration(cfg.UtilizationWindowHours) * time.Hour
+	if sample.Total() > 0 {
+		u.samples = append(u.samples, sample)
+	}
+	cutoff := now.Add(-window)
+	for len(u.samples) > 0 && u.samples[0].Time.Before(cutoff) {
+		u.samples = u.samples[1:]
+	}
+
+	// 確認上次觸發的效果
+	if u.sweepPending {
+		u.sweepPending = false
+		if sample.Idle > u.sweepIdle*(1-constants.IdleSweepMinReduction) {
+			if u.backoff < constants.MaxIdleSweepBackoff {
+				u.backoff *= 2
+			}
+			log.Printf("觸發掛單後閒置資金未減少 (%.2f)，寬限時間延長為 %d 分鐘",
+				sample.Idle, cfg.IdleGraceMinutes*u.backoff)
+		} else {
+			u.backoff = 1
+		}
+	}
+
+	// 閒置資金寬限
+	sweep := false
+	if sample.Idle >= cfg.GetIdleCashThreshold() && sample.Idle > 0 {
+		if u.idleSince.IsZero() {
+			u.idleSince = now
+		}
+		grace := time.Duration(cfg.IdleGraceMinutes*u.backoff) * time.Minute
+		if !halted && now.Sub(u.idleSince) >= grace {
+			sweep = true
+			u.idleSince = time.Time{}
+			u.sweepPending = true
+			u.sweepIdle = sample.Idle
+		}
+	} else {
+		u.idleSince = time.Time{}
+		u.backoff = 1
+	}
+
+	// 利率下限調整
+	if u.lastAdjust.IsZero() {
+		u.lastAdjust = now
+	}
+	if now.Sub(u.lastAdjust) >= window && len(u.samples) > 0 {
+		u.lastAdjust = now
+		average := u.average()
+		target := cfg.UtilizationTargetPercent / 100
+		step := cfg.UtilizationFloorStepPercent / 100
+		maxCut := cfg.UtilizationFloorMaxCutPercent / 100
+
+		previous := u.floorCut
+		if average < target {
+			u.floorCut = math.Min(u.floorCut+step, maxCut)
+		} else {
+			u.floorCut = math.Max(u.floorCut-step, 0)
+		}
+		if u.floorCut != previous {
+			log.Printf("平均資金使用率 %.1f%% (目標 %.1f%%)，最低日利率調降比例 %.1f%% -> %.1f%%",
+				average*100, cfg.UtilizationTargetPercent, previous*100, u.floorCut*100)
+		}
+	}
+
+	return sweep
+}
+
+// average 時間窗口內的平均使用率（需持有鎖）
+func (u *UtilizationController) average() float64 {
+	if len(u.samples) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, sample := range u.samples {
+		sum += sample.Utilization()
+	}
+	return sum / float64(len(u.samples))
+}
+
+// FloorCut 返回目前最低日利率的調降比例（0-1）
+func (u *UtilizationController) FloorCut() float64 {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.floorCut
+}
+
+// Report 返回使用率控制狀態
+func (u *UtilizationController) Report(cfg *config.Config, now time.Time) string {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if len(u.samples) == 0 {
+		return "尚無取樣"
+	}
+	latest := u.samples[len(u.samples)-1]
+	report := fmt.Sprintf("目前使用率: %.1f%% (借出 %.2f / 掛單 %.2f / 閒置 %.2f)",
+		latest.Utilization()*100, latest.Lent, latest.Offered, latest.Idle)
+	report += fmt.Sprintf("\n%d 小時平均: %.1f%%，目標 %.1f%%", cfg.UtilizationWindowHours, u.average()*100, cfg.UtilizationTargetPercent)
+	if !u.idleSince.IsZero() {
+		report += fmt.Sprintf("\n閒置資金已超過門檻 %.0f 分鐘（寬限 %d 分鐘）", now.Sub(u.idleSince).Minutes(), cfg.IdleGraceMinutes*u.backoff)
+	}
+	report += fmt.Sprintf("\n最低日利率調降: %.1f%% (上限 %.1f%%)", u.floorCut*100, cfg.UtilizationFloorMaxCutPercent)
+	return report
+}
+
+// ladderFloor 返回分散單使用的最低日利率（資金使用率持續偏低時依控制器調降）
+func (lb *LendingBot) ladderFloor() float64 {
+	floor := lb.config.GetMinDailyRateDecimal()
+	if !lb.config.EnableUtilizationTarget {
+		return floor
+	}
+	return floor * (1 - lb.utilization.FloorCut())
+}
+
+// CheckUtilization 取樣資金使用率，閒置資金持續超過門檻超過寬限時間時返回 true（應立即執行策略）
+func (lb *LendingBot) CheckUtilization() (bool, error) {
+	if !lb.config.EnableUtilizationTarget {
+		return false, nil
+	}
+
+	balance, err := lb.getAvailableFunds()
+	if err != nil {
+		return false, err
+	}
+	offers, err := lb.client.GetFundingOffers(lb.config.GetFundingSymbol())
+	if err != nil {
+		return false, err
+	}
+	credits, err := lb.client.GetFundingCredits(lb.config.GetFundingSymbol())
+	if err != nil {
+		return false, err
+	}
+
+	// 與 Execute 相同扣除保留金額與飆升保留資金，這些資金不會被主策略掛出
+	spikeOnBook := 0.0
+	sample := UtilizationSample{Time: lb.now()}
+	for _, offer := range offers {
+		sample.Offered += math.Abs(offer.Amount)
+		if info, tracked := lb.orderTracker.GetOrderInfo(offer.ID); tracked && lb.isLiveSpikeOffer(info) {
+			spikeOnBook += offer.Amount
+		}
+	}
+	for _, credit := range credits {
+		sample.Lent += math.Abs(credit.Amount)
+	}
+	sample.Idle = math.Max(0, balance-lb.config.ReserveAmount-lb.spikeReserve(spikeOnBook))
+
+	halted := false
+	if lb.config.EnableRiskGuard {
+		halted, _, _ = lb.riskGuard.IsHalted()
+	}
+
+	sweep := lb.utilization.Observe(sample, lb.config, halted)
+	log.Printf("資金使用率 %.1f%% - 借出: %.2f, 掛單: %.2f, 閒置: %.2f",
+		sample.Utilization()*100, sample.Lent, sample.Offered, sample.Idle)
+	if sweep {
+		log.Printf("閒置資金 %.2f 超過門檻 %.2f 已達 %d 分鐘，觸發掛單",
+			sample.Idle, lb.config.GetIdleCashThreshold(), lb.config.IdleGraceMinutes)
+	}
+	return sweep, nil
+}
+
+// GetUtilizationReport 獲取資金使用率報告（供 Telegram 指令使用）
+func (lb *LendingBot) GetUtilizationReport() string {
+	report := lb.utilization.Report(lb.config, lb.now())
+	if cut := lb.utilization.FloorCut(); cut > 0 && !lb.config.IsMinDailyLendRateFRR() {
+		report += fmt.Sprintf("\n分散單最低日利率: %s -> %.4f%%", lb.config.GetMinDailyRateDisplay(),
+			lb.rateConverter.DecimalToPercentage(lb.ladderFloor()))
+	}
+	return report
+}
@@ -0,0 +1,131 @@
+package strategy
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/kfrico/BitfinexLendingBot/internal/config"
+	"github.com/kfrico/BitfinexLendingBot/internal/simulator"
+)
+
+func TestUtilizationController_IdleSweep(t *testing.T) {
+	cfg := &config.Config{MinLoan: 150, EnableUtilizationTarget: true}
+	cfg.ApplyDefaults()
+	now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	controller := NewUtilizationController()
+
+	// 閒置資金超過門檻但未達 30 分鐘寬限
+	if controller.Observe(UtilizationSample{Time: now, Lent: 9000, Idle: 1000}, cfg, false) {
+		t.Error("Observe() should not sweep before the grace period")
+	}
+	if controller.Observe(UtilizationSample{Time: now.Add(20 * time.Minute), Lent: 9000, Idle: 1000}, cfg, false) {
+		t.Error("Observe() should not sweep after 20 minutes")
+	}
+	if !controller.Observe(UtilizationSample{Time: now.Add(30 * time.Minute), Lent: 9000, Idle: 1000}, cfg, false) {
+		t.Error("Observe() should sweep once idle cash exceeds the grace period")
+	}
+
+	// 觸發後重新計算寬限；閒置低於門檻時重置
+	if controller.Observe(UtilizationSample{Time: now.Add(40 * time.Minute), Lent: 9000, Idle: 1000}, cfg, false) {
+		t.Error("Observe() should restart the grace period after a sweep")
+	}
+	controller.Observe(UtilizationSample{Time: now.Add(50 * time.Minute), Lent: 9900, Idle: 100}, cfg, false)
+	if controller.Observe(UtilizationSample{Time: now.Add(80 * time.Minute), Lent: 9000, Idle: 1000}, cfg, false) {
+		t.Error("Observe() should reset the grace period when idle cash drops below the threshold")
+	}
+}
+
+func TestUtilizationController_SweepBackoff(t *testing.T) {
+	cfg := &config.Config{MinLoan: 150, EnableUtilizationTarget: true}
+	cfg.ApplyDefaults()
+	now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	controller := NewUtilizationController()
+	idle := UtilizationSample{Lent: 9000, Idle: 1000}
+	observe := func(minutes int, halted bool) bool {
+		sample := idle
+		sample.Time = now.Add(time.Duration(minutes) * time.Minute)
+		return controller.Observe(sample, cfg, halted)
+	}
+
+	// 熔斷中不觸發
+	observe(0, false)
+	if observe(30, true) {
+		t.Error("Observe() should not sweep while halted")
+	}
+	if !observe(31, false) {
+		t.Fatal("Observe() should sweep once the halt is lifted")
+	}
+
+	// 觸發後閒置資金未減少，寬限時間加倍為 60 分鐘
+	observe(35, false)
+	if observe(90, false) {
+		t.Error("Observe() should back off after an ineffective sweep")
+	}
+	if !observe(95, false) {
+		t.Error("Observe() should sweep after the doubled grace period")
+	}
+}
+
+func TestLendingBot_CheckUtilizationExcludesSpikeReserve(t *testing.T) {
+	now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	cfg := &config.Config{
+		Currency:                "USD",
+		MinLoan:                 150,
+		MinDailyLendRate:        0.02,
+		EnableUtilizationTarget: true,
+		EnableSpikeSniper:       true,
+		SpikeCapital:            1000,
+	}
+	cfg.ApplyDefaults()
+	exchange := simulator.NewExchange(&bookMarket{bestAsk: 0.0003}, simulator.Options{Currency: "USD", InitialBalance: 1000, Start: now})
+	clock := now
+	bot := NewLendingBot(cfg, exchange)
+	bot.SetClock(func() time.Time { return clock }, func(time.Duration) {})
+
+	for _, minutes := range []int{0, 60} {
+		clock = now.Add(time.Duration(minutes) * time.Minute)
+		sweep, err := bot.CheckUtilization()
+		if err != nil {
+			t.Fatalf("CheckUtilization() error = %v", err)
+		}
+		if sweep {
+			t.Errorf("minute %d: spike reserve should not count as idle cash", minutes)
+		}
+	}
+}
+
+func TestUtilizationController_FloorCut(t *testing.T) {
+	cfg := &config.Config{
+		MinLoan:                       150,
+		MinDailyLendRate:              0.02,
+		EnableUtilizationTarget:       true,
+		UtilizationWindowHours:        1,
+		UtilizationFloorMaxCutPercent: 12,
+	}
+	cfg.ApplyDefaults()
+	now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	controller := NewUtilizationController()
+
+	// 使用率 50% 持續低於 90% 目標，每小時調降 5%，最多 12%
+	expected := []float64{0, 0.05, 0.10, 0.12, 0.12}
+	for i, want := range expected {
+		controller.Observe(UtilizationSample{Time: now.Add(time.Duration(i) * time.Hour), Lent: 5000, Offered: 5000}, cfg, false)
+		if cut := controller.FloorCut(); math.Abs(cut-want) > floatTolerance {
+			t.Errorf("hour %d: FloorCut() = %v, want %v", i, cut, want)
+		}
+	}
+
+	bot := NewLendingBot(cfg, nil)
+	bot.utilization = controller
+	if floor := bot.ladderFloor(); math.Abs(floor-0.0002*0.88) > floatTolerance {
+		t.Errorf("ladderFloor() = %v, want %v", floor, 0.0002*0.88)
+	}
+
+	// 使用率回到目標後逐步回升
+	controller.Observe(UtilizationSample{Time: now.Add(5 * time.Hour), Lent: 9500, Offered: 500}, cfg, false)
+	controller.Observe(UtilizationSample{Time: now.Add(6 * time.Hour), Lent: 9500, Offered: 500}, cfg, false)
+	if cut := controller.FloorCut(); math.Abs(cut-0.07) > floatTolerance {
+		t.Errorf("FloorCut() after recovery = %v, want 0.07", cut)
+	}
+}
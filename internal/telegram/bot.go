@@ -14,28 +14,66 @@ import (
 	"github.com/kfrico/BitfinexLendingBot/internal/config"
 	"github.com/kfrico/BitfinexLendingBot/internal/constants"
 	"github.com/kfrico/BitfinexLendingBot/internal/rates"
+	"github.com/kfrico/BitfinexLendingBot/internal/simulator"
 )
 
 // LendingBot interface 用於避免循環依賴
 type LendingBot interface {
 	GetActiveLendingCredits() ([]*bitfinex.FundingCredit, error)
 	CheckRateThreshold() (bool, float64, error)
+	GetTrackedOrderStats() (int, int)
+	GetMaturityReport() (string, error)
+	GetPeriodExposureReport() (string, error)
+	GetRateBonusReport() string
+	ResumeRiskGuard() bool
+	GetRiskGuardStatus() string
+	GetSeasonalityReport() string
+	GetSpikeSniperStatus() string
+	ConfirmRefinance(creditID int64) (string, error)
+	GetRefinanceStatus() string
+	GetStrategyStatsReport() string
+	GetMarketAnalysisReport() string
+	GetKlineConsensusReport() string
+	GetAbsorptionReport() string
+	GetUtilizationReport() string
+	GetOrphanReport() (string, error)
+	AdoptOffer(offerID int64) (string, error)
+	ReleaseOffer(offerID int64) (string, error)
+	GetCurrencyInfo() bitfinex.CurrencyInfo
+	GetEffectiveMinLoan() float64
+}
+
+// Lane 單一幣種的配置、帳戶與貸出機器人
+type Lane struct {
+	Config        *config.Config
+	Client        bitfinex.FundingAPI // 正式帳戶或模擬交易帳戶
+	LendingBot    LendingBot
+	PaperExchange *simulator.Exchange // 模擬交易帳戶（僅 PAPER_TRADING 模式）
+}
+
+// botState 所有幣種共用的可變狀態
+type botState struct {
+	mu                  sync.Mutex
+	authenticatedChatID int64
+	activeLane          int // 參數調整等單幣種指令作用的幣種
 }
 
 // Bot Telegram 機器人封裝
+// config、bitfinexClient、lendingBot、paperExchange 為指令作用的幣種，由 laneView 依幣種填入
 type Bot struct {
-	api                 *tgbotapi.BotAPI
-	config              *config.Config
-	bitfinexClient      *bitfinex.Client
-	rateConverter       *rates.Converter
-	authenticatedChatID int64
-	chatIDMutex         sync.Mutex
-	restartCallback     func() error // 重啟回調函數
-	lendingBot          LendingBot   // 借貸機器人引用
+	api             *tgbotapi.BotAPI
+	config          *config.Config
+	bitfinexClient  bitfinex.FundingAPI // 正式帳戶或模擬交易帳戶
+	rateConverter   *rates.Converter
+	state           *botState
+	lanes           []*Lane
+	restartCallback func() error        // 重啟回調函數
+	lendingBot      LendingBot          // 借貸機器人引用
+	paperExchange   *simulator.Exchange // 模擬交易帳戶（僅 PAPER_TRADING 模式）
 }
 
 // NewBot 創建新的 Telegram 機器人
-func NewBot(cfg *config.Config, bfxClient *bitfinex.Client) (*Bot, error) {
+func NewBot(cfg *config.Config) (*Bot, error) {
 	api, err := tgbotapi.NewBotAPI(cfg.TelegramBotToken)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create telegram bot: %w", err)
@@ -44,10 +82,10 @@ func NewBot(cfg *config.Config, bfxClient *bitfinex.Client) (*Bot, error) {
 	log.Printf("Authorized on account %s", api.Self.UserName)
 
 	return &Bot{
-		api:            api,
-		config:         cfg,
-		bitfinexClient: bfxClient,
-		rateConverter:  rates.NewConverter(),
+		api:           api,
+		config:        cfg,
+		rateConverter: rates.NewConverterWithFees(cfg.GetFeeModel()),
+		state:         &botState{},
 	}, nil
 }
 
@@ -134,23 +172,23 @@ func (b *Bot) handleMessage(message *tgbotapi.Message) {
 
 // isAuthenticated 檢查是否已驗證
 func (b *Bot) isAuthenticated(chatID int64) bool {
-	b.chatIDMutex.Lock()
-	defer b.chatIDMutex.Unlock()
-	return b.authenticatedChatID == chatID
+	b.state.mu.Lock()
+	defer b.state.mu.Unlock()
+	return b.state.authenticatedChatID == chatID
 }
 
 // setAuthenticated 設置已驗證的聊天ID
 func (b *Bot) setAuthenticated(chatID int64) {
-	b.chatIDMutex.Lock()
-	defer b.chatIDMutex.Unlock()
-	b.authenticatedChatID = chatID
+	b.state.mu.Lock()
+	defer b.state.mu.Unlock()
+	b.state.authenticatedChatID = chatID
 }
 
 // getAuthenticatedChatID 獲取已驗證的聊天ID
 func (b *Bot) GetAuthenticatedChatID() int64 {
-	b.chatIDMutex.Lock()
-	defer b.chatIDMutex.Unlock()
-	return b.authenticatedChatID
+	b.state.mu.Lock()
+	defer b.state.mu.Unlock()
+	return b.state.authenticatedChatID
 }
 
 // sendMessage 發送訊息
@@ -174,9 +212,42 @@ func (b *Bot) SetRestartCallback(callback func() error) {
 	b.restartCallback = callback
 }
 
-// SetLendingBot 設置借貸機器人引用
-func (b *Bot) SetLendingBot(lendingBot LendingBot) {
-	b.lendingBot = lendingBot
+// AddLane 註冊一個幣種（第一個註冊的幣種為預設操作幣種）
+func (b *Bot) AddLane(lane *Lane) {
+	b.lanes = append(b.lanes, lane)
+}
+
+// isMultiCurrency 是否同時貸出多個幣種
+func (b *Bot) isMultiCurrency() bool {
+	return len(b.lanes) > 1
+}
+
+// findLane 依幣種名稱尋找已註冊的幣種
+func (b *Bot) findLane(currency string) (int, bool) {
+	for i, lane := range b.lanes {
+		if strings.EqualFold(lane.Config.Currency, currency) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// activeLane 目前操作的幣種
+func (b *Bot) activeLane() *Lane {
+	b.state.mu.Lock()
+	defer b.state.mu.Unlock()
+	return b.lanes[b.state.activeLane]
+}
+
+// laneView 返回作用於指定幣種的機器人副本，訊息處理為併發執行，不直接修改共用的 Bot
+func (b *Bot) laneView(lane *Lane) *Bot {
+	view := *b
+	view.config = lane.Config
+	view.bitfinexClient = lane.Client
+	view.lendingBot = lane.LendingBot
+	view.paperExchange = lane.PaperExchange
+	view.rateConverter = rates.NewConverterWithFees(lane.Config.GetFeeModel())
+	return &view
 }
 
 // handleAuthentication 處理身份驗證
@@ -194,11 +265,54 @@ func (b *Bot) handleAuthentication(chatID int64, text string) {
 
 // handleCommand 處理指令
 func (b *Bot) handleCommand(chatID int64, text string) {
+	if len(b.lanes) == 0 {
+		b.sendMessage(chatID, "❌ 借貸機器人未初始化")
+		return
+	}
+
+	// 跨幣種指令
+	parts := strings.Fields(text)
+	switch {
+	case len(parts) > 0 && parts[0] == "/currency":
+		b.handleCurrency(chatID, parts)
+		return
+	case len(parts) == 2 && (parts[0] == "/status" || parts[0] == "/lending"):
+		// 指定幣種查看詳細狀態，例如 /status BTC
+		index, ok := b.findLane(parts[1])
+		if !ok {
+			b.sendMessage(chatID, fmt.Sprintf("未設定的幣種: %s，可用幣種: %s", parts[1], b.currencyList()))
+			return
+		}
+		b.laneView(b.lanes[index]).dispatchCommand(chatID, parts[0])
+		return
+	case b.isMultiCurrency() && text == "/status":
+		b.handleStatusOverview(chatID)
+		return
+	case b.isMultiCurrency() && text == "/lending":
+		b.handleLendingOverview(chatID)
+		return
+	}
+
+	b.laneView(b.activeLane()).dispatchCommand(chatID, text)
+}
+
+// dispatchCommand 處理作用於單一幣種的指令
+func (b *Bot) dispatchCommand(chatID int64, text string) {
 	switch {
 	case text == "/help" || text == "/start":
 		b.handleHelp(chatID)
 	case text == "/restart":
 		b.handleRestart(chatID)
+	case text == "/resume":
+		b.handleResume(chatID)
+	case text == "/refinance" || strings.HasPrefix(text, "/refinance "):
+		b.handleRefinance(chatID, text)
+	case text == "/orphans":
+		b.handleOrphans(chatID)
+	case strings.HasPrefix(text, "/adopt "):
+		b.handleOfferOwnership(chatID, text, true)
+	case strings.HasPrefix(text, "/release "):
+		b.handleOfferOwnership(chatID, text, false)
 	case text == "/rate":
 		b.handleRate(chatID)
 	case text == "/check":
@@ -254,6 +368,9 @@ func (b *Bot) handleHelp(chatID int64) {
 /status - 顯示系統狀態
 /strategy - 顯示當前策略狀態
 /lending - 查看當前活躍的借貸訂單
+/orphans - 查看未被程式追蹤的掛單（手動掛單或重啟前留下的掛單）
+/currency [幣種] - 查看或切換參數調整作用的幣種（多幣種模式）
+/status [幣種] 、/lending [幣種] - 多幣種時不帶幣種為總覽，帶幣種為單一幣種詳細狀態
 
 ⚙️ 設置指令:
 /threshold [數值] - 設置利率通知閾值
@@ -272,10 +389,14 @@ func (b *Bot) handleHelp(chatID int64) {
 /klinestrategy off - 停用K線策略
 /smartstrategy on - 啟用智能策略 (中等優先級)
 /smartstrategy off - 停用智能策略
-/smoothmethod [方法] - 設置K線利率平滑方法 (max/sma/ema/hla/p90)
+/smoothmethod [方法] - 設置K線利率平滑方法 (max/sma/ema/hla/p90/vwap/vwp90/median/bbu/bbm)
 
 🔄 控制指令:
 /restart - 手動重新啟動，清除所有訂單，重新運行
+/resume - 解除風險控管熔斷，恢復下單
+/refinance [借貸ID] - 查看再融資提議，或確認關閉指定借貸
+/adopt [訂單ID] - 接管未追蹤的掛單，下次執行時取消並重新掛單
+/release [訂單ID] - 停止追蹤程式掛單，之後視為手動掛單不再取消
 /help - 顯示此幫助訊息
 
 💡 策略優先級: K線策略 > 智能策略 > 傳統策略`
@@ -0,0 +1,142 @@
+package telegram
+
+import (
+	"fmt"
+	"strings"
+)
+
+// currencyList 已註冊的幣種清單
+func (b *Bot) currencyList() string {
+	currencies := make([]string, 0, len(b.lanes))
+	for _, lane := range b.lanes {
+		currencies = append(currencies, lane.Config.Currency)
+	}
+	return strings.Join(currencies, ", ")
+}
+
+// handleCurrency 處理幣種指令：無參數時列出幣種，帶幣種時切換參數調整作用的幣種
+func (b *Bot) handleCurrency(chatID int64, parts []string) {
+	if len(parts) == 1 {
+		b.sendMessage(chatID, fmt.Sprintf("💱 目前操作幣種: %s\n可用幣種: %s\n使用 /currency [幣種] 切換",
+			b.activeLane().Config.Currency, b.currencyList()))
+		return
+	}
+	if len(parts) != 2 {
+		b.sendMessage(chatID, "格式錯誤，請使用 /currency [幣種] 格式")
+		return
+	}
+
+	index, ok := b.findLane(parts[1])
+	if !ok {
+		b.sendMessage(chatID, fmt.Sprintf("未設定的幣種: %s，可用幣種: %s", parts[1], b.currencyList()))
+		return
+	}
+
+	b.state.mu.Lock()
+	b.state.activeLane = index
+	b.state.mu.Unlock()
+
+	b.sendMessage(chatID, fmt.Sprintf("✅ 已切換至 %s\n之後的參數調整、/rate、/check、/strategy 等指令皆作用於 %s",
+		b.lanes[index].Config.Currency, b.lanes[index].Config.Currency))
+}
+
+// handleStatusOverview 多幣種狀態總覽：各幣種的資金、掛單與借貸收益
+func (b *Bot) handleStatusOverview(chatID int64) {
+	statusMsg := fmt.Sprintf("📊 系統狀態報告 (%d 個幣種)", len(b.lanes))
+
+	totalOffers, totalCredits := 0, 0
+	for _, lane := range b.lanes {
+		view := b.laneView(lane)
+		cfg := lane.Config
+
+		statusMsg += fmt.Sprintf("\n\n💱 %s", cfg.Currency)
+		if balance, err := lane.Client.GetFundingBalance(strings.ToUpper(cfg.Currency)); err != nil {
+			statusMsg += fmt.Sprintf("\n總餘額: 獲取失敗 (%v)", err)
+		} else {
+			statusMsg += fmt.Sprintf("\n總餘額: %.4f %s", balance, cfg.Currency)
+		}
+
+		if credits, err := lane.LendingBot.GetActiveLendingCredits(); err != nil {
+			statusMsg += fmt.Sprintf("\n借貸訂單: 獲取失敗 (%v)", err)
+		} else {
+			summary := summarizeCredits(credits, view.frrFallbackRate(credits), view.rateConverter)
+			totalCredits += summary.Count
+			statusMsg += fmt.Sprintf("\n借出中: %.4f %s (%d 筆)", summary.Amount, cfg.Currency, summary.Count)
+			statusMsg += fmt.Sprintf("\n每日收益: %s", summary.DailyEarnings.FormatAmount(cfg.Currency))
+			annual := summary.AnnualRate()
+			statusMsg += fmt.Sprintf("\n年化: %.2f%% (淨 %.2f%%)", annual.Gross, annual.Net)
+		}
+
+		if offers, err := lane.Client.GetFundingOffers(cfg.GetFundingSymbol()); err != nil {
+			statusMsg += fmt.Sprintf("\n未完成掛單: 獲取失敗 (%v)", err)
+		} else {
+			totalOffers += len(offers)
+			statusMsg += fmt.Sprintf("\n未完成掛單: %d 筆", len(offers))
+		}
+
+		statusMsg += fmt.Sprintf("\n策略: %s, 最低日利率: %s, 執行間隔: %d 分鐘",
+			activeStrategyLabel(cfg.EnableStrategyBlend, cfg.EnableKlineStrategy, cfg.EnableSmartStrategy),
+			cfg.GetMinDailyRateDisplay(), cfg.MinutesRun)
+		if cfg.EnableRiskGuard {
+			statusMsg += "\n風險控管: " + firstLine(lane.LendingBot.GetRiskGuardStatus())
+		}
+	}
+
+	statusMsg += fmt.Sprintf("\n\n📦 合計: 借貸 %d 筆, 掛單 %d 筆", totalCredits, totalOffers)
+	statusMsg += fmt.Sprintf("\n💡 目前操作幣種: %s，使用 /status [幣種] 查看詳細狀態", b.activeLane().Config.Currency)
+
+	b.sendMessage(chatID, statusMsg)
+}
+
+// handleLendingOverview 多幣種借貸總覽：各幣種的借出金額與收益
+func (b *Bot) handleLendingOverview(chatID int64) {
+	message := "💰 當前活躍的借貸訂單 (多幣種)\n"
+
+	totalCredits := 0
+	for _, lane := range b.lanes {
+		cfg := lane.Config
+		message += fmt.Sprintf("\n💱 %s\n", cfg.Currency)
+
+		credits, err := lane.LendingBot.GetActiveLendingCredits()
+		if err != nil {
+			message += fmt.Sprintf("❌ 獲取借貸訂單失敗: %v\n", err)
+			continue
+		}
+		if len(credits) == 0 {
+			message += "📭 目前沒有活躍的借貸訂單\n"
+			continue
+		}
+
+		view := b.laneView(lane)
+		summary := summarizeCredits(credits, view.frrFallbackRate(credits), view.rateConverter)
+		totalCredits += summary.Count
+		message += fmt.Sprintf("📦 訂單數: %d\n", summary.Count)
+		message += fmt.Sprintf("💵 借出金額: %.4f %s\n", summary.Amount, cfg.Currency)
+		annual := summary.AnnualRate()
+		message += fmt.Sprintf("💰 每日收益: %s\n", summary.DailyEarnings.FormatAmount(cfg.Currency))
+		message += fmt.Sprintf("📈 年化收益率: %.2f%% (手續費 %.2f%%，淨 %.2f%%)\n", annual.Gross, annual.Fee, annual.Net)
+	}
+
+	message += fmt.Sprintf("\n📊 總訂單數: %d\n💡 使用 /lending [幣種] 查看訂單明細", totalCredits)
+	b.sendMessage(chatID, message)
+}
+
+// activeStrategyLabel 依策略優先級返回目前使用的策略名稱
+func activeStrategyLabel(blend, kline, smart bool) string {
+	switch {
+	case blend:
+		return "策略組合"
+	case kline:
+		return "K線策略"
+	case smart:
+		return "智能策略"
+	default:
+		return "傳統策略"
+	}
+}
+
+// firstLine 返回多行文字的第一行
+func firstLine(text string) string {
+	line, _, _ := strings.Cut(text, "\n")
+	return line
+}
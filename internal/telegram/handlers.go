@@ -6,7 +6,9 @@ import (
 	"strings"
 	"time"
 
+	"github.com/kfrico/BitfinexLendingBot/internal/bitfinex"
 	"github.com/kfrico/BitfinexLendingBot/internal/constants"
+	"github.com/kfrico/BitfinexLendingBot/internal/rates"
 )
 
 // handleRate 處理利率查詢指令
@@ -22,8 +24,8 @@ func (b *Bot) handleRate(chatID int64) {
 		thresholdInfo = fmt.Sprintf("\n目前設定的閾值為: %.4f%%", b.config.NotifyRateThreshold)
 	}
 
-	message := fmt.Sprintf("目前貸出利率: %.4f%%%s",
-		b.rateConverter.DecimalDailyToPercentageDaily(rate), thresholdInfo)
+	yield := b.rateConverter.Split(b.rateConverter.DecimalDailyToPercentageDaily(rate), false)
+	message := fmt.Sprintf("目前貸出利率: %s%s", yield.FormatRate(), thresholdInfo)
 	b.sendMessage(chatID, message)
 }
 
@@ -60,16 +62,26 @@ func (b *Bot) handleCheck(chatID int64) {
 func (b *Bot) handleStatus(chatID int64) {
 	// 獲取剩餘金額
 	availableFunds, err := b.bitfinexClient.GetFundingBalance(strings.ToUpper(b.config.Currency))
+	currencyInfo := b.lendingBot.GetCurrencyInfo()
+	precision := currencyInfo.Precision
 	var balanceInfo string
 	if err != nil {
 		balanceInfo = fmt.Sprintf("剩餘金額: 獲取失敗 (%v)", err)
 	} else {
-		balanceInfo = fmt.Sprintf("💰 資金狀況:\n總餘額: %.2f %s",
-			availableFunds, b.config.Currency)
+		balanceInfo = fmt.Sprintf("💰 資金狀況:\n總餘額: %.*f %s",
+			precision, availableFunds, b.config.Currency)
 	}
 
-	statusMsg := fmt.Sprintf("📊 系統狀態報告\n\n%s\n\n💱 基本設定:\n幣種: %s\n最小貸出金額: %.2f\n最大貸出金額: %.2f",
-		balanceInfo, b.config.Currency, b.config.MinLoan, b.config.MaxLoan)
+	statusMsg := fmt.Sprintf("📊 系統狀態報告\n\n%s\n\n💱 基本設定:\n幣種: %s\n最小貸出金額: %.*f\n最大貸出金額: %.*f",
+		balanceInfo, b.config.Currency, precision, b.config.MinLoan, precision, b.config.MaxLoan)
+
+	// 交易所最低掛單金額高於 MIN_LOAN 時實際以交易所為準
+	if effectiveMinLoan := b.lendingBot.GetEffectiveMinLoan(); effectiveMinLoan > b.config.MinLoan {
+		statusMsg += fmt.Sprintf("\n實際最小貸出金額: %.*f（交易所最低掛單）", precision, effectiveMinLoan)
+	}
+	if currencyInfo.USDPrice > 0 {
+		statusMsg += fmt.Sprintf("\n金額精度: %d 位小數, 參考價格: %.4f USD", precision, currencyInfo.USDPrice)
+	}
 
 	// 添加保留金額信息
 	if b.config.ReserveAmount > 0 {
@@ -89,7 +101,10 @@ func (b *Bot) handleStatus(chatID int64) {
 	statusMsg += fmt.Sprintf("\n執行間隔: %d 分鐘", b.config.MinutesRun)
 
 	// 添加運行模式信息
-	if b.config.TestMode {
+	if b.paperExchange != nil {
+		statusMsg += fmt.Sprintf("\n\n📝 運行模式: 模擬交易 (%s 市場數據)", b.config.PaperMarketSource)
+		statusMsg += b.getPaperAccountSummary()
+	} else if b.config.TestMode {
 		statusMsg += fmt.Sprintf("\n\n🧪 運行模式: 測試模式 (模擬交易)")
 	} else {
 		statusMsg += fmt.Sprintf("\n\n🚀 運行模式: 正式模式 (真實交易)")
@@ -105,9 +120,52 @@ func (b *Bot) handleStatus(chatID int64) {
 		statusMsg += "\n未啟用"
 	}
 
+	// 添加未完成掛單與隱藏掛單信息
+	statusMsg += fmt.Sprintf("\n\n🙈 隱藏掛單:")
+	statusMsg += fmt.Sprintf("\n策略: %s", b.getHiddenOfferPolicyDescription())
+	if offers, err := b.bitfinexClient.GetFundingOffers(b.config.GetFundingSymbol()); err != nil {
+		statusMsg += fmt.Sprintf("\n未完成掛單: 獲取失敗 (%v)", err)
+	} else {
+		hiddenCount := 0
+		for _, offer := range offers {
+			if offer.Hidden {
+				hiddenCount++
+			}
+		}
+		statusMsg += fmt.Sprintf("\n未完成掛單: %d 筆 (隱藏 %d 筆)", len(offers), hiddenCount)
+	}
+	if b.lendingBot != nil {
+		trackedCount, trackedHidden := b.lendingBot.GetTrackedOrderStats()
+		statusMsg += fmt.Sprintf("\n程式追蹤掛單: %d 筆 (隱藏 %d 筆)", trackedCount, trackedHidden)
+	}
+
+	// 添加風險控管信息
+	if b.lendingBot != nil {
+		statusMsg += fmt.Sprintf("\n\n🛡️ 風險控管:\n%s", b.lendingBot.GetRiskGuardStatus())
+	}
+
+	// 添加利率飆升狙擊信息
+	if b.config.EnableSpikeSniper && b.lendingBot != nil {
+		statusMsg += fmt.Sprintf("\n\n⚡ 利率飆升狙擊 (保留 %.2f %s, 門檻 +%.0f%%):\n%s",
+			b.config.SpikeCapital, b.config.Currency, b.config.SpikeThresholdPercent, b.lendingBot.GetSpikeSniperStatus())
+	}
+
+	// 添加再融資信息
+	if b.config.EnableRefinancing && b.lendingBot != nil {
+		statusMsg += fmt.Sprintf("\n\n💱 再融資 (利率提高 %.0f%% 以上):\n%s",
+			b.config.RefinanceMinImprovementPercent, b.lendingBot.GetRefinanceStatus())
+	}
+
 	// 添加當前策略信息
 	statusMsg += fmt.Sprintf("\n\n🎯 當前策略:")
-	if b.config.EnableKlineStrategy {
+	if b.config.EnableStrategyBlend {
+		statusMsg += fmt.Sprintf("\n策略組合 (啟用)")
+		for _, name := range []string{constants.StrategyKline, constants.StrategySmart, constants.StrategyTraditional} {
+			if weight := b.config.StrategyWeights[name]; weight > 0 {
+				statusMsg += fmt.Sprintf("\n%s 權重: %g", name, weight)
+			}
+		}
+	} else if b.config.EnableKlineStrategy {
 		statusMsg += fmt.Sprintf("\nK線策略 (啟用)")
 		statusMsg += fmt.Sprintf("\n時間框架: %s", b.config.KlineTimeFrame)
 		statusMsg += fmt.Sprintf("\n週期數: %d", b.config.KlinePeriod)
@@ -115,6 +173,8 @@ func (b *Bot) handleStatus(chatID int64) {
 	} else if b.config.EnableSmartStrategy {
 		statusMsg += fmt.Sprintf("\n智能策略 (啟用)")
 		statusMsg += fmt.Sprintf("\n利率範圍增加: %.1f%%", b.config.RateRangeIncreasePercent*100)
+		statusMsg += fmt.Sprintf("\n趨勢估計器: %s", b.config.SmartTrendEstimator)
+		statusMsg += fmt.Sprintf("\n波動率估計器: %s", b.config.SmartVolatilityEstimator)
 	} else {
 		statusMsg += fmt.Sprintf("\n傳統策略 (啟用)")
 	}
@@ -123,6 +183,7 @@ func (b *Bot) handleStatus(chatID int64) {
 	if b.config.RateRangeIncreasePercent > 0 {
 		statusMsg += fmt.Sprintf("\n📊 利率範圍增加: %.1f%%", b.config.RateRangeIncreasePercent*100)
 	}
+	statusMsg += fmt.Sprintf("\n💰 金額分配: %s", b.config.GetAllocationProfile())
 
 	statusMsg += fmt.Sprintf("\n\n💡 使用 /strategy 查看詳細策略狀態")
 
@@ -376,13 +437,119 @@ func (b *Bot) handleRestart(chatID int64) {
 	b.sendMessage(chatID, "✅ 重啟完成！所有訂單已清除並重新下單")
 }
 
+// handleResume 處理解除熔斷指令
+func (b *Bot) handleResume(chatID int64) {
+	if b.lendingBot == nil {
+		b.sendMessage(chatID, "❌ 貸出機器人未初始化，請聯繫管理員")
+		return
+	}
+
+	if !b.config.EnableRiskGuard {
+		b.sendMessage(chatID, "風險控管未啟用 (ENABLE_RISK_GUARD)")
+		return
+	}
+
+	if !b.lendingBot.ResumeRiskGuard() {
+		b.sendMessage(chatID, "目前未處於熔斷狀態，無需恢復")
+		return
+	}
+
+	b.sendMessage(chatID, "✅ 已解除熔斷，下次執行時恢復下單\n如需立即執行請使用 /restart")
+}
+
+// handleRefinance 處理再融資指令：無參數時列出提議，帶借貸 ID 時確認關閉
+func (b *Bot) handleRefinance(chatID int64, text string) {
+	if b.lendingBot == nil {
+		b.sendMessage(chatID, "❌ 貸出機器人未初始化，請聯繫管理員")
+		return
+	}
+
+	if !b.config.EnableRefinancing {
+		b.sendMessage(chatID, "再融資未啟用 (ENABLE_REFINANCING)")
+		return
+	}
+
+	parts := strings.Fields(text)
+	if len(parts) == 1 {
+		b.sendMessage(chatID, "💱 再融資\n"+b.lendingBot.GetRefinanceStatus())
+		return
+	}
+	if len(parts) != 2 {
+		b.sendMessage(chatID, "格式錯誤，請使用 /refinance [借貸ID] 格式")
+		return
+	}
+
+	creditID, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || creditID <= 0 {
+		b.sendMessage(chatID, "請輸入有效的借貸 ID")
+		return
+	}
+
+	result, err := b.lendingBot.ConfirmRefinance(creditID)
+	if err != nil {
+		b.sendMessage(chatID, fmt.Sprintf("❌ 再融資失敗: %v", err))
+		return
+	}
+	b.sendMessage(chatID, result)
+}
+
+// handleOrphans 處理未追蹤掛單查詢指令
+func (b *Bot) handleOrphans(chatID int64) {
+	if b.lendingBot == nil {
+		b.sendMessage(chatID, "❌ 貸出機器人未初始化，請聯繫管理員")
+		return
+	}
+
+	report, err := b.lendingBot.GetOrphanReport()
+	if err != nil {
+		b.sendMessage(chatID, fmt.Sprintf("❌ 獲取掛單失敗: %v", err))
+		return
+	}
+	b.sendMessage(chatID, "📋 未追蹤掛單\n"+report)
+}
+
+// handleOfferOwnership 處理接管（adopt 為 true）或釋放掛單指令
+func (b *Bot) handleOfferOwnership(chatID int64, text string, adopt bool) {
+	if b.lendingBot == nil {
+		b.sendMessage(chatID, "❌ 貸出機器人未初始化，請聯繫管理員")
+		return
+	}
+
+	parts := strings.Fields(text)
+	if len(parts) != 2 {
+		b.sendMessage(chatID, fmt.Sprintf("格式錯誤，請使用 %s [訂單ID] 格式", parts[0]))
+		return
+	}
+
+	offerID, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || offerID <= 0 {
+		b.sendMessage(chatID, "請輸入有效的訂單 ID")
+		return
+	}
+
+	var result string
+	if adopt {
+		result, err = b.lendingBot.AdoptOffer(offerID)
+	} else {
+		result, err = b.lendingBot.ReleaseOffer(offerID)
+	}
+	if err != nil {
+		b.sendMessage(chatID, fmt.Sprintf("❌ %v", err))
+		return
+	}
+	b.sendMessage(chatID, result)
+}
+
 // handleStrategyStatus 處理策略狀態查詢指令
 func (b *Bot) handleStrategyStatus(chatID int64) {
 	var strategyType string
 	var strategyPriority string
 
 	// 根據策略優先級確定當前啟用的策略
-	if b.config.EnableKlineStrategy {
+	if b.config.EnableStrategyBlend {
+		strategyType = "策略組合 (啟用)"
+		strategyPriority = "依權重同時使用多個策略"
+	} else if b.config.EnableKlineStrategy {
 		strategyType = "K線策略 (啟用)"
 		strategyPriority = "最高優先級"
 	} else if b.config.EnableSmartStrategy {
@@ -395,8 +562,21 @@ func (b *Bot) handleStrategyStatus(chatID int64) {
 
 	statusMsg := fmt.Sprintf("📊 當前策略狀態\n策略類型: %s\n優先級: %s", strategyType, strategyPriority)
 
-	// K線策略設定
-	if b.config.EnableKlineStrategy {
+	// 策略組合設定
+	if b.config.EnableStrategyBlend {
+		statusMsg += fmt.Sprintf("\n\n🧩 策略組合設定:")
+		total := 0.0
+		for _, weight := range b.config.StrategyWeights {
+			total += weight
+		}
+		for _, name := range []string{constants.StrategyKline, constants.StrategySmart, constants.StrategyTraditional} {
+			if weight := b.config.StrategyWeights[name]; weight > 0 && total > 0 {
+				statusMsg += fmt.Sprintf("\n%s: %.1f%%", name, weight/total*100)
+			}
+		}
+		statusMsg += fmt.Sprintf("\n利率相差 %.1f%% 內的訂單合併", b.config.BlendDedupePercent)
+	} else if b.config.EnableKlineStrategy {
+		// K線策略設定
 		statusMsg += fmt.Sprintf("\n\n📈 K線策略設定:")
 		statusMsg += fmt.Sprintf("\n時間框架: %s", b.config.KlineTimeFrame)
 		statusMsg += fmt.Sprintf("\nK線週期數: %d", b.config.KlinePeriod)
@@ -404,6 +584,9 @@ func (b *Bot) handleStrategyStatus(chatID int64) {
 
 		// 添加平滑方法信息
 		smoothMethodDesc := getSmoothMethodDescription(b.config.KlineSmoothMethod)
+		if smoothMethodDesc == "" {
+			smoothMethodDesc = "未知方法"
+		}
 		statusMsg += fmt.Sprintf("\n利率平滑方法: %s - %s", b.config.KlineSmoothMethod, smoothMethodDesc)
 
 		// 計算分析時間範圍
@@ -470,10 +653,86 @@ func (b *Bot) handleStrategyStatus(chatID int64) {
 	} else {
 		statusMsg += fmt.Sprintf("\n\n⚙️ 傳統策略設定:")
 		statusMsg += fmt.Sprintf("\n固定高額持有利率: %.4f%%", b.config.HighHoldRate)
+		if b.config.IsVolumeGapMode() {
+			statusMsg += fmt.Sprintf("\n深度模式: 累計掛單量 (%.0f - %.0f %s)", b.config.GapBottom, b.config.GapTop, b.config.Currency)
+		} else {
+			statusMsg += fmt.Sprintf("\n深度模式: 訂單簿檔位 (%.0f - %.0f)", b.config.GapBottom, b.config.GapTop)
+		}
 		statusMsg += fmt.Sprintf("\n固定分散貸出參數")
 		statusMsg += fmt.Sprintf("\n固定期間選擇邏輯")
 	}
 
+	// 智能策略估計器讀數（單獨使用或在策略組合中）
+	if b.config.UsesStrategy(constants.StrategySmart) && b.lendingBot != nil {
+		statusMsg += "\n\n🔬 市場分析:\n" + b.lendingBot.GetMarketAnalysisReport()
+	}
+
+	// K線各時間框架視窗（單獨使用或在策略組合中）
+	if b.config.UsesStrategy(constants.StrategyKline) && b.lendingBot != nil {
+		statusMsg += "\n\n📐 K線時間框架:\n" + b.lendingBot.GetKlineConsensusReport()
+	}
+
+	// 各策略績效
+	if b.lendingBot != nil {
+		statusMsg += "\n\n📊 各策略績效 (本次啟動後):\n" + b.lendingBot.GetStrategyStatsReport()
+	}
+
+	// 季節性模型
+	if b.config.EnableSeasonality && b.lendingBot != nil {
+		statusMsg += fmt.Sprintf("\n\n📆 季節性模型 (最大調整 ±%.0f%%, 提前 %d 小時):", b.config.SeasonalityMaxAdjustPct, b.config.GetSeasonalityLeadHours())
+		statusMsg += "\n" + b.lendingBot.GetSeasonalityReport()
+	}
+
+	// 到期分散規劃
+	if b.config.EnableMaturityPlanner && b.lendingBot != nil {
+		statusMsg += fmt.Sprintf("\n\n📅 到期分散規劃 (期間調整幅度 ±%.0f%%):", b.config.GetMaturityPeriodFlex()*100)
+		if report, err := b.lendingBot.GetMaturityReport(); err != nil {
+			statusMsg += fmt.Sprintf("\n獲取失敗: %v", err)
+		} else {
+			statusMsg += "\n" + report
+		}
+	}
+
+	// 期間曝險限制
+	if b.config.HasPeriodExposureLimits() && b.lendingBot != nil {
+		statusMsg += "\n\n⏳ 期間曝險:"
+		if report, err := b.lendingBot.GetPeriodExposureReport(); err != nil {
+			statusMsg += fmt.Sprintf("\n獲取失敗: %v", err)
+		} else {
+			statusMsg += "\n" + report
+		}
+	}
+
+	// 自適應利率加成
+	if b.config.EnableAdaptiveRateBonus && b.lendingBot != nil {
+		statusMsg += "\n\n🎯 自適應利率加成:\n" + b.lendingBot.GetRateBonusReport()
+	}
+
+	// 資金使用率
+	if b.config.EnableUtilizationTarget && b.lendingBot != nil {
+		statusMsg += "\n\n📈 資金使用率:\n" + b.lendingBot.GetUtilizationReport()
+	}
+
+	// 訂單簿吸收量
+	if b.config.GetAllocationProfile() == constants.AllocationProfileAbsorption && b.lendingBot != nil {
+		statusMsg += "\n\n🧽 訂單簿吸收量:\n" + b.lendingBot.GetAbsorptionReport()
+	}
+
+	// 隱藏掛單策略與手續費影響
+	statusMsg += fmt.Sprintf("\n\n🙈 隱藏掛單策略:")
+	statusMsg += fmt.Sprintf("\n%s", b.getHiddenOfferPolicyDescription())
+	statusMsg += fmt.Sprintf("\n\n💸 手續費影響:")
+	fees := b.rateConverter.Fees()
+	statusMsg += fmt.Sprintf("\n公開掛單手續費: %.0f%% 利息", fees.Rate*100)
+	statusMsg += fmt.Sprintf("\n隱藏掛單手續費: %.0f%% 利息", fees.HiddenRate*100)
+	if b.config.HighHoldRate > 0 {
+		publicNet := b.rateConverter.NetRate(b.config.HighHoldRate, false)
+		hiddenNet := b.rateConverter.NetRate(b.config.HighHoldRate, true)
+		statusMsg += fmt.Sprintf("\n以高額持有利率 %.4f%% 為例:", b.config.HighHoldRate)
+		statusMsg += fmt.Sprintf("\n  公開淨日利率: %.4f%% (年化 %.2f%%)", publicNet, publicNet*constants.DaysPerYear)
+		statusMsg += fmt.Sprintf("\n  隱藏淨日利率: %.4f%% (年化 %.2f%%)", hiddenNet, hiddenNet*constants.DaysPerYear)
+	}
+
 	// 顯示策略優先級順序
 	statusMsg += fmt.Sprintf("\n\n🔄 策略優先級順序:")
 	statusMsg += fmt.Sprintf("\n1️⃣ K線策略 (%s)", getStrategyStatus(b.config.EnableKlineStrategy))
@@ -487,6 +746,42 @@ func (b *Bot) handleStrategyStatus(chatID int64) {
 	b.sendMessage(chatID, statusMsg)
 }
 
+// getPaperAccountSummary 獲取模擬交易帳戶摘要
+func (b *Bot) getPaperAccountSummary() string {
+	stats := b.paperExchange.Stats()
+	balance := b.paperExchange.Balance()
+	lent := b.paperExchange.LentAmount()
+
+	summary := fmt.Sprintf("\n模擬時間: %s", b.paperExchange.Now().Format("2006-01-02 15:04"))
+	summary += fmt.Sprintf("\n帳戶總額: %.2f %s (初始 %.2f)", balance, b.config.Currency, stats.InitialBalance)
+	if balance > 0 {
+		summary += fmt.Sprintf("\n借出中: %.2f (%.1f%%)", lent, lent/balance*100)
+	}
+	summary += fmt.Sprintf("\n累計利息: %.4f (手續費 %.4f)", stats.InterestEarned, stats.FeesPaid)
+	summary += fmt.Sprintf("\n成交: %d 筆 / %.2f, 已到期: %d 筆", stats.FilledCount, stats.FilledAmount, stats.MaturedCount)
+
+	return summary
+}
+
+// getHiddenOfferPolicyDescription 獲取隱藏掛單策略描述
+func (b *Bot) getHiddenOfferPolicyDescription() string {
+	if !b.config.HasHiddenOfferPolicy() {
+		return "未啟用 (全部公開掛單)"
+	}
+
+	var rules []string
+	if b.config.HideHighHoldOffers {
+		rules = append(rules, "高額持有單隱藏")
+	}
+	if b.config.HiddenOfferMinAmount > 0 {
+		rules = append(rules, fmt.Sprintf("單筆 ≥ %.2f %s 隱藏", b.config.HiddenOfferMinAmount, b.config.Currency))
+	}
+	if b.config.HiddenOfferRandomPercent > 0 {
+		rules = append(rules, fmt.Sprintf("分散單隨機隱藏 %.0f%%", b.config.HiddenOfferRandomPercent))
+	}
+	return strings.Join(rules, "、")
+}
+
 // getStrategyStatus 獲取策略狀態文字
 func getStrategyStatus(enabled bool) string {
 	if enabled {
@@ -566,35 +861,10 @@ func (b *Bot) handleLendingCredits(chatID int64) {
 
 	message := "💰 當前活躍的借貸訂單\n\n"
 
-	frrFallbackRate := 0.0
-	for _, credit := range credits {
-		if credit.EffectiveDailyRate() == 0 {
-			rate, err := b.bitfinexClient.GetCurrentFundingRate(b.config.GetFundingSymbol())
-			if err != nil {
-				break
-			}
-			frrFallbackRate = rate
-			break
-		}
-	}
+	frrFallbackRate := b.frrFallbackRate(credits)
 
 	// 先計算所有訂單的統計信息
-	totalAmount := 0.0
-	totalDailyEarnings := 0.0
-	totalPeriodEarnings := 0.0
-
-	for _, credit := range credits {
-		effectiveRate := credit.EffectiveDailyRate()
-		if effectiveRate == 0 && frrFallbackRate > 0 {
-			effectiveRate = frrFallbackRate
-		}
-		dailyEarnings := credit.Amount * effectiveRate
-		periodEarnings := dailyEarnings * float64(credit.Period)
-
-		totalAmount += credit.Amount
-		totalDailyEarnings += dailyEarnings
-		totalPeriodEarnings += periodEarnings
-	}
+	summary := summarizeCredits(credits, frrFallbackRate, b.rateConverter)
 
 	// 限制顯示數量，避免消息過長
 	displayCount := len(credits)
@@ -605,27 +875,28 @@ func (b *Bot) handleLendingCredits(chatID int64) {
 	for i := 0; i < displayCount; i++ {
 		credit := credits[i]
 
-		// 計算收益
+		// 計算收益（借貸訂單無法得知是否為隱藏掛單，以一般手續費估算）
 		rawRate := credit.EffectiveDailyRate()
 		effectiveRate := rawRate
 		if effectiveRate == 0 && frrFallbackRate > 0 {
 			effectiveRate = frrFallbackRate
 		}
-		dailyEarnings := credit.Amount * effectiveRate
-		periodEarnings := dailyEarnings * float64(credit.Period)
+		rate := b.rateConverter.Split(b.rateConverter.DecimalToPercentage(effectiveRate), false)
+		dailyEarnings := b.rateConverter.Split(credit.Amount*effectiveRate, false)
+		periodEarnings := b.rateConverter.Split(credit.Amount*effectiveRate*float64(credit.Period), false)
 
 		// 格式化開始時間
 		openTime := time.Unix(credit.MTSOpened/1000, 0)
 
 		message += fmt.Sprintf("📊 訂單 #%d (ID: %d)\n", i+1, credit.ID)
 		message += fmt.Sprintf("💵 金額: %.2f %s\n", credit.Amount, b.config.Currency)
-		message += fmt.Sprintf("📈 日利率: %.4f%%\n", b.rateConverter.DecimalToPercentage(effectiveRate))
+		message += fmt.Sprintf("📈 日利率: %s\n", rate.FormatRate())
 		if strings.EqualFold(credit.RateType, "frr") || (rawRate == 0 && frrFallbackRate > 0) {
 			message += "🔖 來源: FRR\n"
 		}
-		message += fmt.Sprintf("💰 日收益: %.4f %s\n", dailyEarnings, b.config.Currency)
+		message += fmt.Sprintf("💰 日收益: %s\n", dailyEarnings.FormatAmount(b.config.Currency))
 		message += fmt.Sprintf("⏰ 期間: %d 天\n", credit.Period)
-		message += fmt.Sprintf("💎 期間總收益: %.4f %s\n", periodEarnings, b.config.Currency)
+		message += fmt.Sprintf("💎 期間總收益: %s\n", periodEarnings.FormatAmount(b.config.Currency))
 		message += fmt.Sprintf("🕐 開始時間: %s\n", openTime.Format("2006-01-02 15:04:05"))
 		message += fmt.Sprintf("📊 狀態: %s\n", credit.Status)
 		message += "\n"
@@ -638,42 +909,116 @@ func (b *Bot) handleLendingCredits(chatID int64) {
 	// 添加統計信息
 	message += fmt.Sprintf("📊 統計信息:\n")
 	message += fmt.Sprintf("📦 總訂單數: %d\n", len(credits))
-	message += fmt.Sprintf("💵 總借出金額: %.2f %s\n", totalAmount, b.config.Currency)
-	message += fmt.Sprintf("💰 每日總收益: %.4f %s\n", totalDailyEarnings, b.config.Currency)
+	message += fmt.Sprintf("💵 總借出金額: %.2f %s\n", summary.Amount, b.config.Currency)
+	message += fmt.Sprintf("💰 每日總收益: %s\n", summary.DailyEarnings.FormatAmount(b.config.Currency))
 
 	if len(credits) <= 10 {
-		message += fmt.Sprintf("💎 總期間收益: %.4f %s\n", totalPeriodEarnings, b.config.Currency)
+		message += fmt.Sprintf("💎 總期間收益: %s\n", summary.PeriodEarnings.FormatAmount(b.config.Currency))
 	}
 
 	// 計算年化收益率
-	if totalAmount > 0 {
-		annualRate := (totalDailyEarnings / totalAmount) * 365 * 100
-		message += fmt.Sprintf("📈 年化收益率: %.2f%%", annualRate)
+	if summary.Amount > 0 {
+		annual := summary.AnnualRate()
+		message += fmt.Sprintf("📈 年化收益率: %.2f%% (手續費 %.2f%%，淨 %.2f%%)", annual.Gross, annual.Fee, annual.Net)
 	}
 
 	b.sendMessage(chatID, message)
 }
 
+// creditSummary 借貸訂單統計（收益拆分為毛收益、手續費與淨收益）
+type creditSummary struct {
+	Count          int
+	Amount         float64
+	DailyEarnings  rates.Yield
+	PeriodEarnings rates.Yield
+}
+
+// AnnualRate 年化收益率（百分比）
+func (s creditSummary) AnnualRate() rates.Yield {
+	if s.Amount <= 0 {
+		return rates.Yield{}
+	}
+	factor := constants.DaysPerYear * constants.PercentageToDecimal / s.Amount
+	return rates.Yield{
+		Gross: s.DailyEarnings.Gross * factor,
+		Fee:   s.DailyEarnings.Fee * factor,
+		Net:   s.DailyEarnings.Net * factor,
+	}
+}
+
+// summarizeCredits 統計借貸訂單的金額與收益，FRR 訂單沒有利率時使用 frrFallbackRate
+// 借貸訂單無法得知是否為隱藏掛單，手續費以一般掛單費率估算
+func summarizeCredits(credits []*bitfinex.FundingCredit, frrFallbackRate float64, converter *rates.Converter) creditSummary {
+	summary := creditSummary{Count: len(credits)}
+	gross := 0.0
+	for _, credit := range credits {
+		effectiveRate := credit.EffectiveDailyRate()
+		if effectiveRate == 0 && frrFallbackRate > 0 {
+			effectiveRate = frrFallbackRate
+		}
+		dailyEarnings := credit.Amount * effectiveRate
+
+		summary.Amount += credit.Amount
+		gross += dailyEarnings
+		summary.PeriodEarnings.Gross += dailyEarnings * float64(credit.Period)
+	}
+	summary.DailyEarnings = converter.Split(gross, false)
+	summary.PeriodEarnings = converter.Split(summary.PeriodEarnings.Gross, false)
+	return summary
+}
+
+// frrFallbackRate 有 FRR 訂單尚未帶利率時，以目前 FRR 估算收益
+func (b *Bot) frrFallbackRate(credits []*bitfinex.FundingCredit) float64 {
+	for _, credit := range credits {
+		if credit.EffectiveDailyRate() == 0 {
+			rate, err := b.bitfinexClient.GetCurrentFundingRate(b.config.GetFundingSymbol())
+			if err != nil {
+				return 0
+			}
+			return rate
+		}
+	}
+	return 0
+}
+
+// smoothMethods K線利率平滑方法與說明（依顯示順序）
+var smoothMethods = []struct {
+	name        string
+	description string
+}{
+	{"max", "最高值 (激進)"},
+	{"sma", "簡單移動平均 (保守)"},
+	{"ema", "指數移動平均 (平滑敏感)"},
+	{"hla", "高低點平均 (平衡)"},
+	{"p90", "90百分位數 (避免極值)"},
+	{"vwap", "成交量加權平均 (忽略低量K線)"},
+	{"vwp90", "成交量加權90百分位數 (低量高點不主導)"},
+	{"median", "高點中位數 (排除單根極值)"},
+	{"bbu", "布林通道上軌 (平均 + 2 倍標準差)"},
+	{"bbm", "布林通道中軌 (典型利率平均)"},
+}
+
+// smoothMethodList 列出所有可用的平滑方法
+func smoothMethodList() string {
+	list := "可用方法:"
+	for _, method := range smoothMethods {
+		list += fmt.Sprintf("\n%s - %s", method.name, method.description)
+	}
+	return list
+}
+
 // handleSetSmoothMethod 處理設置平滑方法指令
 func (b *Bot) handleSetSmoothMethod(chatID int64, text string) {
 	parts := strings.Split(text, " ")
 	if len(parts) != 2 {
-		b.sendMessage(chatID, "格式錯誤，請使用 /smoothmethod [方法] 格式\n\n可用方法:\nmax - 最高值 (激進)\nsma - 簡單移動平均 (保守)\nema - 指數移動平均 (平滑敏感)\nhla - 高低點平均 (平衡)\np90 - 90百分位數 (避免極值)")
+		b.sendMessage(chatID, "格式錯誤，請使用 /smoothmethod [方法] 格式\n\n"+smoothMethodList())
 		return
 	}
 
 	method := strings.ToLower(parts[1])
-	validMethods := map[string]string{
-		"max": "最高值 (激進)",
-		"sma": "簡單移動平均 (保守)",
-		"ema": "指數移動平均 (平滑敏感)",
-		"hla": "高低點平均 (平衡)",
-		"p90": "90百分位數 (避免極值)",
-	}
-
-	description, isValid := validMethods[method]
-	if !isValid {
-		b.sendMessage(chatID, "無效的平滑方法，可用方法:\nmax - 最高值 (激進)\nsma - 簡單移動平均 (保守)\nema - 指數移動平均 (平滑敏感)\nhla - 高低點平均 (平衡)\np90 - 90百分位數 (避免極值)")
+	description := getSmoothMethodDescription(method)
+	if description == "" {
+		b.sendMessage(chatID, "無效的平滑方法，"+smoothMethodList())
 		return
 	}
 
@@ -681,18 +1026,12 @@ func (b *Bot) handleSetSmoothMethod(chatID int64, text string) {
 	b.sendMessage(chatID, fmt.Sprintf("✅ K線利率平滑方法已設定為: %s - %s\n\n下次執行K線策略時將使用新的平滑方法", method, description))
 }
 
-// getSmoothMethodDescription 獲取平滑方法的描述
+// getSmoothMethodDescription 獲取平滑方法的描述，未知方法返回空字串
 func getSmoothMethodDescription(method string) string {
-	descriptions := map[string]string{
-		"max": "最高值 (激進)",
-		"sma": "簡單移動平均 (保守)",
-		"ema": "指數移動平均 (平滑敏感)",
-		"hla": "高低點平均 (平衡)",
-		"p90": "90百分位數 (避免極值)",
-	}
-
-	if desc, exists := descriptions[method]; exists {
-		return desc
+	for _, m := range smoothMethods {
+		if m.name == method {
+			return m.description
+		}
 	}
-	return "未知方法"
+	return ""
 }
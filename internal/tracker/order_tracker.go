@@ -1,30 +1,149 @@
 package tracker
 
 import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
 	"sync"
 	"time"
+
+	"github.com/kfrico/BitfinexLendingBot/internal/constants"
+)
+
+// 訂單結束原因
+const (
+	OutcomeFilled          = "filled"           // 已不在訂單簿上，且有對應的借貸確認成交
+	OutcomeCancelled       = "cancelled"        // 未成交即被取消（程式取消或離開訂單簿但沒有對應的借貸）
+	OutcomePartiallyFilled = "partially_filled" // 程式取消前已部分成交
+	OutcomeReleased        = "released"         // 使用者釋放，改為手動掛單
+	OutcomeClosedOffline   = "closed_offline"   // 程式停止期間已從訂單簿消失（成交或被手動取消）
 )
 
+// OrderInfo 追蹤訂單的附加資訊
+type OrderInfo struct {
+	CreatedAt time.Time `json:"created_at"` // 創建時間
+	Hidden    bool      `json:"hidden"`     // 是否為隱藏掛單
+	Spike     bool      `json:"spike"`      // 是否為利率飆升狙擊掛單
+	Strategy  string    `json:"strategy"`   // 產生此訂單的策略
+	Amount    float64   `json:"amount"`     // 掛單金額（用於推算成交金額）
+	Rate      float64   `json:"rate"`       // 掛單日利率
+	Period    int       `json:"period"`     // 掛單期間（天）
+}
+
+// OrderRecord 已結束的追蹤訂單
+type OrderRecord struct {
+	ID int64 `json:"id"`
+	OrderInfo
+	Outcome      string    `json:"outcome"`
+	FilledAmount float64   `json:"filled_amount"` // 成交金額（推算值）
+	ClosedAt     time.Time `json:"closed_at"`
+}
+
+// trackerState 保存到檔案的追蹤狀態
+type trackerState struct {
+	Orders  map[int64]OrderInfo `json:"orders"`
+	History []OrderRecord       `json:"history"`
+}
+
 // BotOrderTracker 追蹤程式創建的訂單
+// 設置保存路徑後，每次變更都寫回檔案，重新啟動後可接續管理先前的掛單
 type BotOrderTracker struct {
-	mu           sync.RWMutex
-	createdOrders map[int64]time.Time // orderID -> 創建時間
+	mu            sync.RWMutex
+	createdOrders map[int64]OrderInfo // orderID -> 訂單資訊
+	history       []OrderRecord       // 已結束的訂單（由舊到新）
+	path          string              // 保存路徑，空字串表示只保存在記憶體
 	botStartTime  time.Time
 }
 
 // NewBotOrderTracker 創建新的訂單追蹤器
 func NewBotOrderTracker() *BotOrderTracker {
 	return &BotOrderTracker{
-		createdOrders: make(map[int64]time.Time),
+		createdOrders: make(map[int64]OrderInfo),
 		botStartTime:  time.Now(),
 	}
 }
 
+// Open 從檔案載入追蹤狀態並啟用保存，檔案不存在時不視為錯誤。返回載入的追蹤中訂單數量
+func (t *BotOrderTracker) Open(path string) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.path = path
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("讀取訂單追蹤記錄失敗: %w", err)
+	}
+
+	var state trackerState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return 0, fmt.Errorf("解析訂單追蹤記錄失敗: %w", err)
+	}
+	for orderID, info := range state.Orders {
+		t.createdOrders[orderID] = info
+	}
+	t.history = append(state.History, t.history...)
+	t.trimHistoryLocked()
+	return len(state.Orders), nil
+}
+
+// saveLocked 將追蹤狀態寫回檔案（需持有鎖），未設置保存路徑時不做任何事
+func (t *BotOrderTracker) saveLocked() {
+	if t.path == "" {
+		return
+	}
+	if err := t.writeLocked(); err != nil {
+		log.Printf("保存訂單追蹤記錄失敗: %v", err)
+	}
+}
+
+func (t *BotOrderTracker) writeLocked() error {
+	data, err := json.Marshal(trackerState{Orders: t.createdOrders, History: t.history})
+	if err != nil {
+		return fmt.Errorf("序列化訂單追蹤記錄失敗: %w", err)
+	}
+	if dir := filepath.Dir(t.path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("建立目錄失敗: %w", err)
+		}
+	}
+
+	tmpPath := t.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o644); err != nil {
+		return fmt.Errorf("寫入訂單追蹤記錄失敗: %w", err)
+	}
+	return os.Rename(tmpPath, t.path)
+}
+
+// trimHistoryLocked 保留最近 OrderHistorySize 筆已結束記錄（需持有鎖）
+func (t *BotOrderTracker) trimHistoryLocked() {
+	if len(t.history) > constants.OrderHistorySize {
+		t.history = t.history[len(t.history)-constants.OrderHistorySize:]
+	}
+}
+
 // TrackOrder 記錄程式創建的訂單
-func (t *BotOrderTracker) TrackOrder(orderID int64) {
+func (t *BotOrderTracker) TrackOrder(orderID int64, info OrderInfo) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
-	t.createdOrders[orderID] = time.Now()
+	if info.CreatedAt.IsZero() {
+		info.CreatedAt = time.Now()
+	}
+	t.createdOrders[orderID] = info
+	t.saveLocked()
+}
+
+// GetOrderInfo 獲取追蹤訂單的資訊
+func (t *BotOrderTracker) GetOrderInfo(orderID int64) (OrderInfo, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	info, exists := t.createdOrders[orderID]
+	return info, exists
 }
 
 // IsTrackedOrder 檢查是否為程式創建的訂單
@@ -35,36 +154,67 @@ func (t *BotOrderTracker) IsTrackedOrder(orderID int64) bool {
 	return exists
 }
 
-// RemoveOrder 移除已追蹤的訂單（訂單完成或取消時）
-func (t *BotOrderTracker) RemoveOrder(orderID int64) {
+// ResolveOrder 結束追蹤訂單並記錄結束原因與成交金額，訂單未被追蹤時返回 false
+func (t *BotOrderTracker) ResolveOrder(orderID int64, outcome string, filledAmount float64, at time.Time) bool {
 	t.mu.Lock()
 	defer t.mu.Unlock()
+
+	info, exists := t.createdOrders[orderID]
+	if !exists {
+		return false
+	}
 	delete(t.createdOrders, orderID)
+	t.history = append(t.history, OrderRecord{
+		ID:           orderID,
+		OrderInfo:    info,
+		Outcome:      outcome,
+		FilledAmount: filledAmount,
+		ClosedAt:     at,
+	})
+	t.trimHistoryLocked()
+	t.saveLocked()
+	return true
 }
 
 // GetTrackedOrders 獲取所有追蹤的訂單ID
 func (t *BotOrderTracker) GetTrackedOrders() []int64 {
 	t.mu.RLock()
 	defer t.mu.RUnlock()
-	
+
 	orders := make([]int64, 0, len(t.createdOrders))
 	for orderID := range t.createdOrders {
 		orders = append(orders, orderID)
 	}
+	sort.Slice(orders, func(i, j int) bool { return orders[i] < orders[j] })
 	return orders
 }
 
-// CleanOldOrders 清理舊訂單記錄（避免記憶體洩漏）
-func (t *BotOrderTracker) CleanOldOrders(maxAge time.Duration) {
+// GetHistory 獲取已結束訂單記錄的副本（由舊到新）
+func (t *BotOrderTracker) GetHistory() []OrderRecord {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	history := make([]OrderRecord, len(t.history))
+	copy(history, t.history)
+	return history
+}
+
+// CleanOldOrders 清理結束超過 maxAge 的訂單記錄
+// 追蹤中的訂單不依時間清理，由每次執行時比對訂單簿結束，避免長期掛單失去管理
+// now 需與 ResolveOrder 的結束時間使用同一個時鐘（回測與模擬交易為模擬時間）
+func (t *BotOrderTracker) CleanOldOrders(now time.Time, maxAge time.Duration) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
-	
-	now := time.Now()
-	for orderID, createdTime := range t.createdOrders {
-		if now.Sub(createdTime) > maxAge {
-			delete(t.createdOrders, orderID)
+
+	kept := t.history[:0]
+	for _, record := range t.history {
+		if now.Sub(record.ClosedAt) <= maxAge {
+			kept = append(kept, record)
 		}
 	}
+	if len(kept) != len(t.history) {
+		t.history = kept
+		t.saveLocked()
+	}
 }
 
 // GetOrderCount 獲取追蹤的訂單數量
@@ -74,7 +224,21 @@ func (t *BotOrderTracker) GetOrderCount() int {
 	return len(t.createdOrders)
 }
 
+// GetHiddenOrderCount 獲取追蹤中隱藏掛單的數量
+func (t *BotOrderTracker) GetHiddenOrderCount() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	count := 0
+	for _, info := range t.createdOrders {
+		if info.Hidden {
+			count++
+		}
+	}
+	return count
+}
+
 // GetBotStartTime 獲取機器人啟動時間
 func (t *BotOrderTracker) GetBotStartTime() time.Time {
 	return t.botStartTime
-}
\ No newline at end of file
+}
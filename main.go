@@ -6,6 +6,7 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -15,18 +16,18 @@ import (
 	"github.com/kfrico/BitfinexLendingBot/internal/bitfinex"
 	"github.com/kfrico/BitfinexLendingBot/internal/config"
 	"github.com/kfrico/BitfinexLendingBot/internal/constants"
-	"github.com/kfrico/BitfinexLendingBot/internal/rates"
+	"github.com/kfrico/BitfinexLendingBot/internal/simulator"
 	"github.com/kfrico/BitfinexLendingBot/internal/strategy"
 	"github.com/kfrico/BitfinexLendingBot/internal/telegram"
 )
 
 // Application 應用程式主結構
 type Application struct {
-	config        *config.Config
-	bfxClient     *bitfinex.Client
-	telegramBot   *telegram.Bot
-	lendingBot    *strategy.LendingBot
-	rateConverter *rates.Converter
+	config      *config.Config // 主配置（帳戶層級設定）
+	bfxClient   *bitfinex.Client
+	currencies  *bitfinex.CurrencyMetadata // 各幣種金額精度與最低掛單金額（所有幣種共用快取）
+	lanes       []*currencyLane            // 每個幣種一個貸出機器人
+	telegramBot *telegram.Bot
 
 	// 併發控制
 	ctx    context.Context
@@ -34,6 +35,13 @@ type Application struct {
 	wg     sync.WaitGroup
 }
 
+// currencyLane 單一幣種的配置、帳戶與貸出機器人，各自獨立排程
+type currencyLane struct {
+	config        *config.Config
+	paperExchange *simulator.Exchange // 模擬交易帳戶（僅 PAPER_TRADING 模式）
+	lendingBot    *strategy.LendingBot
+}
+
 // NewApplication 創建新的應用程式實例
 func NewApplication(configPath string) (*Application, error) {
 	// 載入配置
@@ -42,44 +50,104 @@ func NewApplication(configPath string) (*Application, error) {
 		return nil, fmt.Errorf("failed to load config: %w", err)
 	}
 
+	currencyConfigs, err := cfg.CurrencyConfigs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build currency configs: %w", err)
+	}
+
 	// 創建 Bitfinex 客戶端
 	bfxClient := bitfinex.NewClient(cfg.BitfinexApiKey, cfg.BitfinexSecretKey)
 
 	// 創建 Telegram 機器人
-	telegramBot, err := telegram.NewBot(cfg, bfxClient)
+	telegramBot, err := telegram.NewBot(cfg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create telegram bot: %w", err)
 	}
 
-	// 創建貸出機器人
-	lendingBot := strategy.NewLendingBot(cfg, bfxClient)
-
-	// 創建利率轉換器
-	rateConverter := rates.NewConverter()
-
 	// 創建 context 和 cancel 函數
 	ctx, cancel := context.WithCancel(context.Background())
 
 	app := &Application{
-		config:        cfg,
-		bfxClient:     bfxClient,
-		telegramBot:   telegramBot,
-		lendingBot:    lendingBot,
-		rateConverter: rateConverter,
-		ctx:           ctx,
-		cancel:        cancel,
+		config:      cfg,
+		bfxClient:   bfxClient,
+		currencies:  bitfinex.NewCurrencyMetadata(bfxClient),
+		telegramBot: telegramBot,
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+
+	for _, currencyConfig := range currencyConfigs {
+		lane, err := app.newCurrencyLane(currencyConfig)
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("failed to set up %s: %w", currencyConfig.Currency, err)
+		}
+		app.lanes = append(app.lanes, lane)
 	}
 
 	// 設置 Telegram bot 重啟回調
 	telegramBot.SetRestartCallback(app.handleRestart)
 
-	// 設置借貸機器人的通知回調
-	lendingBot.SetNotifyCallback(telegramBot.SendNotification)
+	return app, nil
+}
 
-	// 設置 Telegram bot 的借貸機器人引用
-	telegramBot.SetLendingBot(lendingBot)
+// newCurrencyLane 創建單一幣種的貸出機器人並註冊到 Telegram
+func (app *Application) newCurrencyLane(cfg *config.Config) (*currencyLane, error) {
+	lane := &currencyLane{config: cfg}
 
-	return app, nil
+	// 模擬交易模式下，所有帳戶操作改由模擬交易所處理
+	var fundingAPI bitfinex.FundingAPI = app.bfxClient
+	if cfg.PaperTrading {
+		paperExchange, err := simulator.NewPaperExchange(cfg, app.bfxClient)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create paper exchange: %w", err)
+		}
+		lane.paperExchange = paperExchange
+		fundingAPI = paperExchange
+	}
+
+	// 創建貸出機器人
+	lane.lendingBot = strategy.NewLendingBot(cfg, fundingAPI)
+	lane.lendingBot.SetCurrencyInfoProvider(app.currencies)
+	if lane.paperExchange != nil {
+		lane.lendingBot.SetClock(lane.paperExchange.Now, time.Sleep)
+	}
+
+	// 恢復智能策略的市場快照（模擬交易不寫入正式環境的快照檔）
+	lane.lendingBot.BootstrapMarketAnalyzer(lane.paperExchange == nil)
+
+	// 恢復自適應利率加成（模擬交易從 RATE_BONUS 開始，不寫入記錄檔）
+	if lane.paperExchange == nil {
+		lane.lendingBot.RestoreRateBonus()
+	}
+
+	// 設置借貸機器人的通知回調，多幣種時標示幣種
+	if app.config.IsMultiCurrency() {
+		prefix := fmt.Sprintf("[%s] ", cfg.Currency)
+		lane.lendingBot.SetNotifyCallback(func(message string) error {
+			return app.telegramBot.SendNotification(prefix + message)
+		})
+	} else {
+		lane.lendingBot.SetNotifyCallback(app.telegramBot.SendNotification)
+	}
+
+	// 載入程式訂單追蹤記錄並與訂單簿比對（模擬交易不寫入記錄檔），需在處理未追蹤掛單之前
+	if lane.paperExchange == nil {
+		lane.lendingBot.RestoreOrderTracker()
+	}
+
+	// 依 ORPHAN_POLICY 處理重啟前留下的未追蹤掛單（需在設置通知回調之後）
+	lane.lendingBot.AdoptStartupOffers()
+
+	// 設置 Telegram bot 的幣種引用
+	app.telegramBot.AddLane(&telegram.Lane{
+		Config:        cfg,
+		Client:        fundingAPI,
+		LendingBot:    lane.lendingBot,
+		PaperExchange: lane.paperExchange,
+	})
+
+	return lane, nil
 }
 
 // Run 運行應用程式
@@ -87,7 +155,10 @@ func (app *Application) Run() error {
 	log.Printf("Config loaded successfully: %+v", app.config)
 
 	// 顯示運行模式
-	if app.config.TestMode {
+	if app.config.PaperTrading {
+		log.Println("📝 === 模擬交易模式啟動 ===")
+		log.Println("📝 所有下單、取消與借貸查詢都在模擬帳戶中進行")
+	} else if app.config.TestMode {
 		log.Println("🧪 === 測試模式啟動 ===")
 		log.Println("🧪 不會執行真實的下單操作")
 		log.Println("🧪 但會執行真實的取消操作")
@@ -104,12 +175,27 @@ func (app *Application) Run() error {
 	app.startWorkers()
 
 	log.Printf("Scheduler started at: %v", time.Now())
-	if app.config.RunOnlyOnNewCredits {
-		log.Printf("⚙️ 執行模式: 觸發條件執行（新借貸訂單或餘額變化）")
-	} else {
-		log.Printf("⚙️ 執行模式: 定時執行，間隔: %d 分鐘", app.config.MinutesRun)
+	for _, lane := range app.lanes {
+		cfg := lane.config
+		log.Printf("💱 幣種: %s", cfg.Currency)
+		if lane.paperExchange != nil {
+			log.Printf("📝 市場數據: %s，模擬帳戶初始資金: %.2f %s",
+				cfg.PaperMarketSource, cfg.PaperInitialBalance, cfg.Currency)
+		}
+		if cfg.RunOnlyOnNewCredits {
+			log.Printf("⚙️ 執行模式: 觸發條件執行（新借貸訂單或餘額變化）")
+		} else {
+			log.Printf("⚙️ 執行模式: 定時執行，間隔: %d 分鐘", cfg.MinutesRun)
+		}
+		log.Printf("💰 借貸檢查間隔: %d 分鐘", cfg.LendingCheckMinutes)
+		if cfg.EnableUtilizationTarget {
+			log.Printf("📈 資金使用率目標: %.0f%%，閒置資金 %.2f %s 超過 %d 分鐘即掛單",
+				cfg.UtilizationTargetPercent, cfg.GetIdleCashThreshold(), cfg.Currency, cfg.IdleGraceMinutes)
+		}
+		if cfg.EnableSpikeSniper {
+			log.Printf("⚡ 利率飆升狙擊: 每 %d 秒輪詢，保留資金 %.2f %s", cfg.SpikePollSeconds, cfg.SpikeCapital, cfg.Currency)
+		}
 	}
-	log.Printf("💰 借貸檢查間隔: %d 分鐘", app.config.LendingCheckMinutes)
 	log.Printf("📊 利率檢查: 每小時")
 	log.Println("🔄 按 Ctrl+C 優雅關閉...")
 
@@ -133,25 +219,53 @@ func (app *Application) startWorkers() {
 		app.telegramBot.StartWithContext(app.ctx)
 	})
 
+	// 每個幣種各自獨立排程
+	for _, lane := range app.lanes {
+		app.startLaneWorkers(lane)
+	}
+}
+
+// startLaneWorkers 啟動單一幣種的工作 goroutines
+func (app *Application) startLaneWorkers(lane *currencyLane) {
+	currency := lane.config.Currency
+
 	// 啟動每小時利率檢查
 	app.wg.Add(1)
-	go app.runWorker("HourlyRateCheck", func() {
+	go app.runWorker("HourlyRateCheck-"+currency, func() {
 		defer app.wg.Done()
-		app.scheduleHourlyRateCheck()
+		app.scheduleHourlyRateCheck(lane)
 	})
 
 	// 啟動借貸訂單檢查
 	app.wg.Add(1)
-	go app.runWorker("LendingCheck", func() {
+	go app.runWorker("LendingCheck-"+currency, func() {
 		defer app.wg.Done()
-		app.scheduleLendingCheck()
+		app.scheduleLendingCheck(lane)
 	})
 
+	// 啟動利率飆升狙擊
+	if lane.config.EnableSpikeSniper {
+		app.wg.Add(1)
+		go app.runWorker("SpikeSniper-"+currency, func() {
+			defer app.wg.Done()
+			app.scheduleSpikeSniper(lane)
+		})
+	}
+
+	// 啟動模擬交易撮合
+	if lane.paperExchange != nil {
+		app.wg.Add(1)
+		go app.runWorker("PaperExchange-"+currency, func() {
+			defer app.wg.Done()
+			app.schedulePaperExchange(lane)
+		})
+	}
+
 	// 啟動主要業務邏輯調度
 	app.wg.Add(1)
-	go app.runWorker("MainTask", func() {
+	go app.runWorker("MainTask-"+currency, func() {
 		defer app.wg.Done()
-		app.scheduleMainTask()
+		app.scheduleMainTask(lane)
 	})
 }
 
@@ -195,51 +309,89 @@ func (app *Application) shutdown() error {
 }
 
 // scheduleMainTask 調度主要任務
-func (app *Application) scheduleMainTask() {
+func (app *Application) scheduleMainTask(lane *currencyLane) {
+	currency := lane.config.Currency
+
 	// 如果啟用了僅在觸發條件時執行的模式（新借貸訂單或餘額變化），則不進行定時執行
-	if app.config.RunOnlyOnNewCredits {
-		log.Println("啟用了觸發條件執行模式（新借貸訂單或餘額變化），主要任務將由檢查觸發")
+	if lane.config.RunOnlyOnNewCredits {
+		log.Printf("[%s] 啟用了觸發條件執行模式（新借貸訂單或餘額變化），主要任務將由檢查觸發", currency)
 		// 先執行第一次初始化
-		app.executeMainTask()
-		
+		app.executeMainTask(lane)
+
 		// 等待 context 取消
 		<-app.ctx.Done()
-		log.Println("主要任務調度器收到停止信號")
+		log.Printf("[%s] 主要任務調度器收到停止信號", currency)
 		return
 	}
 
 	// 傳統的定時執行模式
-	log.Printf("啟用定時執行模式，間隔: %d 分鐘", app.config.MinutesRun)
+	log.Printf("[%s] 啟用定時執行模式，間隔: %d 分鐘", currency, lane.config.MinutesRun)
 	// 先執行第一次
-	app.executeMainTask()
+	app.executeMainTask(lane)
 
-	ticker := time.NewTicker(time.Duration(app.config.MinutesRun) * time.Minute)
+	ticker := time.NewTicker(time.Duration(lane.config.MinutesRun) * time.Minute)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-app.ctx.Done():
-			log.Println("主要任務調度器收到停止信號")
+			log.Printf("[%s] 主要任務調度器收到停止信號", currency)
 			return
 		case <-ticker.C:
-			app.executeMainTask()
+			app.executeMainTask(lane)
 		}
 	}
 }
 
 // executeMainTask 執行主要任務
-func (app *Application) executeMainTask() {
-	if err := app.lendingBot.Execute(); err != nil {
-		log.Printf("執行貸出策略失敗: %v", err)
+func (app *Application) executeMainTask(lane *currencyLane) {
+	if err := lane.lendingBot.Execute(); err != nil {
+		log.Printf("[%s] 執行貸出策略失敗: %v", lane.config.Currency, err)
+	}
+}
+
+// scheduleSpikeSniper 高頻輪詢訂單簿，偵測利率飆升
+func (app *Application) scheduleSpikeSniper(lane *currencyLane) {
+	ticker := time.NewTicker(time.Duration(lane.config.SpikePollSeconds) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-app.ctx.Done():
+			log.Printf("[%s] 利率飆升狙擊調度器收到停止信號", lane.config.Currency)
+			return
+		case <-ticker.C:
+			if _, err := lane.lendingBot.CheckRateSpike(); err != nil {
+				log.Printf("[%s] 利率飆升檢查失敗: %v", lane.config.Currency, err)
+			}
+		}
+	}
+}
+
+// schedulePaperExchange 定期推進模擬交易所，撮合掛單並計算利息
+func (app *Application) schedulePaperExchange(lane *currencyLane) {
+	ticker := time.NewTicker(constants.PaperAdvanceInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-app.ctx.Done():
+			log.Printf("[%s] 模擬交易撮合調度器收到停止信號", lane.config.Currency)
+			return
+		case <-ticker.C:
+			if err := lane.paperExchange.Advance(lane.paperExchange.Now()); err != nil {
+				log.Printf("[%s] 模擬交易撮合失敗: %v", lane.config.Currency, err)
+			}
+		}
 	}
 }
 
 // scheduleHourlyRateCheck 調度每小時利率檢查
-func (app *Application) scheduleHourlyRateCheck() {
+func (app *Application) scheduleHourlyRateCheck(lane *currencyLane) {
 	for {
 		select {
 		case <-app.ctx.Done():
-			log.Println("利率檢查調度器收到停止信號")
+			log.Printf("[%s] 利率檢查調度器收到停止信號", lane.config.Currency)
 			return
 		default:
 		}
@@ -251,34 +403,35 @@ func (app *Application) scheduleHourlyRateCheck() {
 		}
 
 		delay := next.Sub(now)
-		log.Printf("下次執行時間: %s, 等待時間: %s", next.Format("2006-01-02 15:04:05"), delay)
+		log.Printf("[%s] 下次執行時間: %s, 等待時間: %s", lane.config.Currency, next.Format("2006-01-02 15:04:05"), delay)
 
 		// 使用 context 支持的 sleep
 		select {
 		case <-app.ctx.Done():
-			log.Println("利率檢查調度器在等待中收到停止信號")
+			log.Printf("[%s] 利率檢查調度器在等待中收到停止信號", lane.config.Currency)
 			return
 		case <-time.After(delay):
-			app.checkRateThreshold()
+			app.checkRateThreshold(lane)
 		}
 	}
 }
 
 // checkRateThreshold 檢查利率閾值
-func (app *Application) checkRateThreshold() {
-	log.Println("定時檢查貸出利率（基於5分鐘K線12根高點）...")
+func (app *Application) checkRateThreshold(lane *currencyLane) {
+	cfg := lane.config
+	log.Printf("[%s] 定時檢查貸出利率（基於5分鐘K線12根高點）...", cfg.Currency)
 
-	exceeded, percentageRate, err := app.lendingBot.CheckRateThreshold()
+	exceeded, percentageRate, err := lane.lendingBot.CheckRateThreshold()
 	if err != nil {
-		log.Printf("取得利率數據失敗: %v", err)
+		log.Printf("[%s] 取得利率數據失敗: %v", cfg.Currency, err)
 		return
 	}
 
-	log.Printf("最近1小時最高利率: %.4f%%, 閾值: %.4f%%", percentageRate, app.config.NotifyRateThreshold)
+	log.Printf("[%s] 最近1小時最高利率: %.4f%%, 閾值: %.4f%%", cfg.Currency, percentageRate, cfg.NotifyRateThreshold)
 
 	if exceeded {
-		message := fmt.Sprintf("⚠️ 定時檢查提醒: 最近1小時最高利率 %.4f%% 已超過閾值 %.4f%%\n\n📊 檢查方式: 5分鐘K線最近12根高點分析",
-			percentageRate, app.config.NotifyRateThreshold)
+		message := fmt.Sprintf("⚠️ 定時檢查提醒 (%s): 最近1小時最高利率 %.4f%% 已超過閾值 %.4f%%\n\n📊 檢查方式: 5分鐘K線最近12根高點分析",
+			cfg.Currency, percentageRate, cfg.NotifyRateThreshold)
 
 		if err := app.telegramBot.SendNotification(message); err != nil {
 			log.Printf("發送 Telegram 通知失敗: %v", err)
@@ -286,18 +439,24 @@ func (app *Application) checkRateThreshold() {
 			log.Printf("成功發送利率提醒")
 		}
 	} else {
-		log.Println("最近1小時最高利率低於閾值，無需發送通知")
+		log.Printf("[%s] 最近1小時最高利率低於閾值，無需發送通知", cfg.Currency)
 	}
 }
 
-// handleRestart 處理重啟請求
+// handleRestart 處理重啟請求（所有幣種依序重新下單）
 func (app *Application) handleRestart() error {
 	log.Println("收到重啟請求，開始執行重啟邏輯...")
 
 	// 執行主要任務（這會取消所有訂單並重新下單）
-	if err := app.lendingBot.Execute(); err != nil {
-		log.Printf("重啟執行失敗: %v", err)
-		return fmt.Errorf("重啟執行失敗: %w", err)
+	var failed []string
+	for _, lane := range app.lanes {
+		if err := lane.lendingBot.Execute(); err != nil {
+			log.Printf("[%s] 重啟執行失敗: %v", lane.config.Currency, err)
+			failed = append(failed, fmt.Sprintf("%s: %v", lane.config.Currency, err))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("重啟執行失敗: %s", strings.Join(failed, "; "))
 	}
 
 	log.Println("重啟完成！")
@@ -305,36 +464,48 @@ func (app *Application) handleRestart() error {
 }
 
 // scheduleLendingCheck 調度借貸訂單檢查
-func (app *Application) scheduleLendingCheck() {
+func (app *Application) scheduleLendingCheck(lane *currencyLane) {
 	// 先執行第一次檢查
-	app.executeLendingCheck()
+	app.executeLendingCheck(lane)
 
-	ticker := time.NewTicker(time.Duration(app.config.LendingCheckMinutes) * time.Minute)
+	ticker := time.NewTicker(time.Duration(lane.config.LendingCheckMinutes) * time.Minute)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-app.ctx.Done():
-			log.Println("借貸檢查調度器收到停止信號")
+			log.Printf("[%s] 借貸檢查調度器收到停止信號", lane.config.Currency)
 			return
 		case <-ticker.C:
-			app.executeLendingCheck()
+			app.executeLendingCheck(lane)
 		}
 	}
 }
 
 // executeLendingCheck 執行借貸訂單檢查
-func (app *Application) executeLendingCheck() {
-	hasNewCredits, err := app.lendingBot.CheckNewLendingCredits()
+func (app *Application) executeLendingCheck(lane *currencyLane) {
+	hasNewCredits, err := lane.lendingBot.CheckNewLendingCredits()
 	if err != nil {
-		log.Printf("檢查借貸訂單失敗: %v", err)
+		log.Printf("[%s] 檢查借貸訂單失敗: %v", lane.config.Currency, err)
 		return
 	}
-	
+
 	// 如果啟用了觸發條件執行模式，且滿足觸發條件（新借貸訂單或餘額變化），觸發主要任務執行
-	if app.config.RunOnlyOnNewCredits && hasNewCredits {
-		log.Println("滿足執行觸發條件，觸發主要任務執行")
-		app.executeMainTask()
+	trigger := lane.config.RunOnlyOnNewCredits && hasNewCredits
+	if trigger {
+		log.Printf("[%s] 滿足執行觸發條件，觸發主要任務執行", lane.config.Currency)
+	}
+
+	// 資金使用率控制：閒置資金持續超過門檻時，不論執行模式都立即掛單
+	if sweep, err := lane.lendingBot.CheckUtilization(); err != nil {
+		log.Printf("[%s] 檢查資金使用率失敗: %v", lane.config.Currency, err)
+	} else if sweep && !trigger {
+		log.Printf("[%s] 閒置資金超過寬限時間，觸發主要任務執行", lane.config.Currency)
+		trigger = true
+	}
+
+	if trigger {
+		app.executeMainTask(lane)
 	}
 }
 
@@ -353,6 +524,12 @@ func main() {
 		},
 	}
 
+	app.Commands = []cli.Command{
+		backtestCommand(),
+		optimizeCommand(),
+		collectCommand(),
+	}
+
 	app.Action = func(c *cli.Context) error {
 		configPath := c.String("config")
 